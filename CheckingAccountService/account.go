@@ -3,23 +3,40 @@ package CheckingAccountService
 import (
 	"errors"
 	"fmt"
+	"github.com/agemmell/banking-cqrs-es-go/EventRegistry"
 	"reflect"
+	"sort"
 	"time"
 )
 
-type Event interface {
-	AggregateID() string
-	EventType() string
-	EventTimestamp() int64
-}
+type Event = EventRegistry.Event
 
 type Account struct {
-	id        string
-	name      string
-	balance   int
-	version   uint
-	newEvents []Event
-	open      bool
+	id                   string
+	name                 string
+	product              string
+	balance              int
+	version              uint
+	newEvents            []Event
+	status               AccountStatus
+	freezeScope          string
+	freezeReason         string
+	statusBeforeFreeze   AccountStatus
+	feeSchedule          FeeSchedule
+	fees                 map[string]chargedFee // <fee ID> -> fee
+	lastMaintenanceMonth string
+	holds                map[string]hold // <hold ID> -> hold
+	primaryHolderID      string
+	registeredHolder     bool                 // false for accounts opened before customers had to register
+	holders              map[string]string    // <principal ID> -> name
+	signatories          map[string]signatory // <principal ID> -> signatory
+	perTransactionLimit  int
+	dailyLimit           int
+	recentWithdrawals    []withdrawal
+	standingOrders       map[string]StandingOrder // <standing order ID> -> StandingOrder
+	lastActivityAt       int64                    // when the customer last opened, reopened or transacted on the account
+	closedAt             int64
+	clock                Clock
 }
 
 func (a *Account) AggregateID() string {
@@ -30,6 +47,47 @@ func (a *Account) Version() uint {
 	return a.version
 }
 
+func (a *Account) Status() AccountStatus {
+	return a.status
+}
+
+func (a *Account) Product() string {
+	return a.product
+}
+
+// LedgerBalance: the balance of all settled transactions
+func (a *Account) LedgerBalance() int {
+	return a.balance
+}
+
+// AvailableBalance: the ledger balance less any funds reserved by holds that have not yet expired
+func (a *Account) AvailableBalance(now int64) int {
+	available := a.balance
+	for _, hold := range a.holds {
+		if !hold.hasExpired(now) {
+			available -= hold.amount
+		}
+	}
+	return available
+}
+
+// SetClock: the clock command handlers use to timestamp events and evaluate time-dependent rules
+func (a *Account) SetClock(clock Clock) {
+	a.clock = clock
+}
+
+func (a *Account) now() int64 {
+	if a.clock == nil {
+		return time.Now().UnixNano()
+	}
+	return a.clock.Now().UnixNano()
+}
+
+// SetFeeSchedule: the fees to charge this account, as configured for its product
+func (a *Account) SetFeeSchedule(feeSchedule FeeSchedule) {
+	a.feeSchedule = feeSchedule
+}
+
 func (a *Account) raiseEvent(event Event) error {
 	err := a.ApplyEvent(event)
 	if err != nil {
@@ -54,15 +112,146 @@ func (a *Account) ApplyEvent(event Event) error {
 	case *AccountWasOpened:
 		a.id = eventType.ID
 		a.name = eventType.Name
-		a.open = true
+		a.product = eventType.Product
+		if a.product == "" {
+			a.product = ProductStandardChecking
+		}
+		a.status = AccountActive
 		a.balance = 0
+		a.fees = map[string]chargedFee{}
+		a.holds = map[string]hold{}
+		a.primaryHolderID = eventType.HolderID
+		a.registeredHolder = eventType.HolderID != ""
+		if a.primaryHolderID == "" {
+			a.primaryHolderID = eventType.ID
+		}
+		a.holders = map[string]string{a.primaryHolderID: eventType.Name}
+		a.signatories = map[string]signatory{}
+		a.standingOrders = map[string]StandingOrder{}
+		a.lastActivityAt = eventType.Timestamp
 	case *MoneyWasDeposited:
 		a.balance += eventType.Amount
+		if eventType.ChangedBy != SystemPrincipal {
+			a.lastActivityAt = eventType.Timestamp
+		}
 	case *MoneyWasWithdrawn:
 		a.balance -= eventType.Amount
+		a.recordWithdrawal(eventType.Amount, eventType.Timestamp)
+		if eventType.ChangedBy != SystemPrincipal {
+			a.lastActivityAt = eventType.Timestamp
+		}
 	case *WithdrawFailedDueToInsufficientFunds:
 	case *AccountWasClosed:
-		a.open = false
+		a.status = AccountClosed
+		a.closedAt = eventType.Timestamp
+	case *AccountWasReopened:
+		a.status = AccountActive
+		a.closedAt = 0
+		a.lastActivityAt = eventType.Timestamp
+	case *AccountBecameDormant:
+		a.status = AccountDormant
+	case *AccountWasReactivated:
+		a.status = AccountActive
+		a.lastActivityAt = eventType.Timestamp
+	case *FeeWasCharged:
+		a.balance -= eventType.Amount
+		a.fees[eventType.FeeID] = chargedFee{feeType: eventType.FeeType, amount: eventType.Amount}
+		if eventType.FeeType == FeeTypeMonthlyMaintenance {
+			a.lastMaintenanceMonth = yearMonth(eventType.Timestamp)
+		}
+	case *FeeWasWaived:
+		if eventType.FeeType == FeeTypeMonthlyMaintenance {
+			a.lastMaintenanceMonth = yearMonth(eventType.Timestamp)
+		}
+	case *FeeWasRefunded:
+		a.balance += eventType.Amount
+		fee := a.fees[eventType.FeeID]
+		fee.refunded = true
+		a.fees[eventType.FeeID] = fee
+	case *AccountWasFrozen:
+		// widening a freeze keeps the status the account goes back to when it is unfrozen
+		if a.status != AccountFrozen {
+			a.statusBeforeFreeze = a.status
+		}
+		a.status = AccountFrozen
+		a.freezeScope = eventType.Scope
+		a.freezeReason = eventType.ReasonCode
+	case *AccountWasUnfrozen:
+		a.status = a.statusBeforeFreeze
+		a.freezeScope = ""
+		a.freezeReason = ""
+	case *AccountHolderWasRenamed:
+		a.holders[eventType.HolderID] = eventType.Name
+		if eventType.HolderID == a.primaryHolderID {
+			a.name = eventType.Name
+		}
+	case *JointHolderWasAdded:
+		a.holders[eventType.HolderID] = eventType.Name
+	case *JointHolderWasRemoved:
+		delete(a.holders, eventType.HolderID)
+	case *AuthorizedSignatoryWasAdded:
+		permissions := map[string]bool{}
+		for _, permission := range eventType.Permissions {
+			permissions[permission] = true
+		}
+		a.signatories[eventType.SignatoryID] = signatory{name: eventType.Name, permissions: permissions}
+	case *AuthorizedSignatoryWasRemoved:
+		delete(a.signatories, eventType.SignatoryID)
+	case *WithdrawalLimitsWereChanged:
+		a.perTransactionLimit = eventType.PerTransactionLimit
+		a.dailyLimit = eventType.DailyLimit
+	case *StandingOrderWasCreated:
+		a.standingOrders[eventType.StandingOrderID] = StandingOrder{
+			ID:               eventType.StandingOrderID,
+			PayeeAccountID:   eventType.PayeeAccountID,
+			Amount:           eventType.Amount,
+			Frequency:        eventType.Frequency,
+			FirstPaymentDate: eventType.FirstPaymentDate,
+			NextPaymentDate:  eventType.FirstPaymentDate,
+		}
+	case *StandingOrderWasAmended:
+		standingOrder := a.standingOrders[eventType.StandingOrderID]
+		standingOrder.PayeeAccountID = eventType.PayeeAccountID
+		standingOrder.Amount = eventType.Amount
+		a.standingOrders[eventType.StandingOrderID] = standingOrder
+	case *StandingOrderWasCancelled:
+		delete(a.standingOrders, eventType.StandingOrderID)
+	case *StandingOrderPaymentWasMade:
+		standingOrder := a.standingOrders[eventType.StandingOrderID]
+		standingOrder.PaymentsMade++
+		standingOrder.NextPaymentDate = eventType.NextPaymentDate
+		standingOrder.FailedAttempts = 0
+		standingOrder.LastAttemptAt = eventType.Timestamp
+		a.standingOrders[eventType.StandingOrderID] = standingOrder
+	case *StandingOrderPaymentFailed:
+		standingOrder := a.standingOrders[eventType.StandingOrderID]
+		standingOrder.FailedAttempts = eventType.Attempt
+		standingOrder.LastAttemptAt = eventType.Timestamp
+		a.standingOrders[eventType.StandingOrderID] = standingOrder
+	case *StandingOrderPaymentWasReturned:
+		a.balance += eventType.Amount
+		// the payment was not made after all, so it is due again unless the standing order has since been cancelled
+		if standingOrder, ok := a.standingOrders[eventType.StandingOrderID]; ok {
+			standingOrder.PaymentsMade--
+			standingOrder.NextPaymentDate = eventType.PaymentDate
+			standingOrder.FailedAttempts = eventType.Attempt
+			standingOrder.LastAttemptAt = eventType.Timestamp
+			a.standingOrders[eventType.StandingOrderID] = standingOrder
+		}
+	case *StandingOrderPaymentWasSkipped:
+		standingOrder := a.standingOrders[eventType.StandingOrderID]
+		standingOrder.PaymentsMade++
+		standingOrder.NextPaymentDate = eventType.NextPaymentDate
+		standingOrder.FailedAttempts = 0
+		a.standingOrders[eventType.StandingOrderID] = standingOrder
+	case *HoldWasPlaced:
+		a.holds[eventType.HoldID] = hold{amount: eventType.Amount, expiresAt: eventType.ExpiresAt}
+	case *HoldWasReleased:
+		delete(a.holds, eventType.HoldID)
+	case *HoldWasCaptured:
+		a.balance -= eventType.Amount
+		a.recordWithdrawal(eventType.Amount, eventType.Timestamp)
+		delete(a.holds, eventType.HoldID)
 	default:
 		eventStruct := reflect.TypeOf(eventType).String()
 		return errors.New(fmt.Sprintf("unknown event %s", eventStruct))
@@ -84,58 +273,100 @@ func (a *Account) LoadFromEvents(events []Event) error {
 
 // Command Handlers: protect aggregate invariants before throwing an event
 
-// OpenAccount: open a new account
-func (a *Account) OpenAccount(id string, name string) error {
+// OpenAccount: open a new account held by a customer
+func (a *Account) OpenAccount(id string, customerID string, name string, product string) error {
 
-	if len(a.id) > 0 && len(a.name) > 0 && a.version == 0 {
+	if a.status != AccountPending {
 		return errors.New(fmt.Sprintf("cannot open an already open account [account: %+v]", a))
 	}
 
+	if customerID == SystemPrincipal {
+		return errors.New(fmt.Sprintf("the bank cannot be the holder of account %s", id))
+	}
+
 	event := AccountWasOpened{
 		ID:        id,
+		HolderID:  customerID,
 		Name:      name,
-		Timestamp: time.Now().UnixNano(),
+		Product:   product,
+		Timestamp: a.now(),
 	}
 
 	return a.raiseEvent(&event)
 }
 
-// DepositMoney: deposit money into an account
-func (a *Account) DepositMoney(amount int) error {
+// DepositMoney: deposit money into an account. A deposit the bank makes, as SystemPrincipal, is not customer activity,
+// so it neither keeps the account out of dormancy nor reactivates it.
+func (a *Account) DepositMoney(amount int, changedBy string) error {
 
-	if a.open == false {
-		return errors.New(fmt.Sprintf("cannot deposit money into an unopened account [account: %+v]", a))
+	err := a.ensureAllowed(actionDeposit)
+	if err != nil {
+		return err
 	}
 
 	if amount <= 0 {
 		return errors.New(fmt.Sprintf("deposit Amount must be greater than 1 [Amount: %+v]", amount))
 	}
 
+	now := a.now()
+	if changedBy != SystemPrincipal {
+		err = a.reactivateIfDormant(now)
+		if err != nil {
+			return err
+		}
+	}
+
 	event := MoneyWasDeposited{
 		ID:        a.id,
 		Amount:    amount,
-		Timestamp: time.Now().UnixNano(),
+		ChangedBy: changedBy,
+		Timestamp: now,
 	}
 
 	return a.raiseEvent(&event)
 }
 
-// WithdrawMoney: withdraw money from an account
-func (a *Account) WithdrawMoney(amount int) error {
+// WithdrawMoney: withdraw money from an account. As with deposits, only a withdrawal the customer makes reactivates a
+// dormant account.
+func (a *Account) WithdrawMoney(amount int, changedBy string) error {
 
-	if a.open == false {
-		return errors.New(fmt.Sprintf("cannot withdraw money from an unopened account [account: %+v]", a))
+	err := a.ensureAllowed(actionWithdraw)
+	if err != nil {
+		return err
+	}
+
+	now := a.now()
+	err = a.ensureWithinWithdrawalLimits(amount, now)
+	if err != nil {
+		return err
 	}
 
-	if a.balance >= amount {
+	transactionFee := a.feeSchedule.FeeFor(FeeTypeTransaction)
+	if a.AvailableBalance(now) >= amount+transactionFee {
+		if changedBy != SystemPrincipal {
+			err = a.reactivateIfDormant(now)
+			if err != nil {
+				return err
+			}
+		}
+
 		event := MoneyWasWithdrawn{
 			ID:        a.id,
 			Amount:    amount,
 			Balance:   a.balance - amount,
-			Timestamp: time.Now().UnixNano(),
+			ChangedBy: changedBy,
+			Timestamp: now,
+		}
+
+		err = a.raiseEvent(&event)
+		if err != nil {
+			return err
 		}
 
-		return a.raiseEvent(&event)
+		if transactionFee > 0 {
+			return a.chargeFee(FeeTypeTransaction, transactionFee)
+		}
+		return nil
 	}
 
 	// TODO
@@ -159,27 +390,338 @@ func (a *Account) WithdrawMoney(amount int) error {
 		ID:        a.id,
 		Amount:    amount,
 		Balance:   a.balance,
-		Timestamp: time.Now().UnixNano(),
+		Timestamp: now,
 	}
 
 	return a.raiseEvent(&event)
 }
 
-// CloseAccount: close the account
+// CloseAccount: close an account with no balance and no holds that are still reserving funds
 func (a *Account) CloseAccount() error {
 
-	if a.open == false {
-		return errors.New(fmt.Sprintf("cannot close a closed account [account: %+v]", a))
+	err := a.ensureAllowed(actionClose)
+	if err != nil {
+		return err
 	}
 
-	if a.balance > 0 {
+	if a.balance != 0 {
 		return errors.New(fmt.Sprintf("cannot close an account with a balance [account: %+v]", a))
 	}
 
+	now := a.now()
+	for _, hold := range a.holds {
+		if !hold.hasExpired(now) {
+			return errors.New(fmt.Sprintf("cannot close an account with outstanding holds [account: %+v]", a))
+		}
+	}
+
+	// holds that have expired no longer reserve anything, but are released so the account closes with none
+	err = a.releaseExpiredHolds(now)
+	if err != nil {
+		return err
+	}
+
 	event := AccountWasClosed{
 		ID:        a.id,
-		Timestamp: time.Now().UnixNano(),
+		Timestamp: now,
+	}
+
+	return a.raiseEvent(&event)
+}
+
+// ChargeFee: charge a fee from the account's fee schedule, unless the fee is waived
+func (a *Account) ChargeFee(feeType string) error {
+
+	err := a.ensureAllowed(actionChargeFee)
+	if err != nil {
+		return err
+	}
+
+	amount := a.feeSchedule.FeeFor(feeType)
+	if amount <= 0 {
+		return errors.New(fmt.Sprintf("no %s fee in the %s fee schedule", feeType, a.product))
+	}
+
+	switch feeType {
+	case FeeTypeMonthlyMaintenance:
+		month := yearMonth(a.now())
+		if a.lastMaintenanceMonth == month {
+			return errors.New(fmt.Sprintf("monthly maintenance fee already charged for %s [account: %+v]", month, a))
+		}
+		if a.feeSchedule.WaivesMaintenanceFee(a.balance) {
+			event := FeeWasWaived{
+				ID:        a.id,
+				FeeType:   feeType,
+				Amount:    amount,
+				Timestamp: a.now(),
+			}
+			return a.raiseEvent(&event)
+		}
+	case FeeTypeUnarrangedOverdraft:
+		if a.balance >= 0 {
+			return errors.New(fmt.Sprintf("cannot charge an unarranged overdraft fee to an account in credit [account: %+v]", a))
+		}
+	case FeeTypeTransaction:
+		return errors.New("transaction fees are only charged when money is withdrawn")
+	}
+
+	return a.chargeFee(feeType, amount)
+}
+
+// RefundFee: reverse a fee that was previously charged
+func (a *Account) RefundFee(feeID string) error {
+
+	err := a.ensureAllowed(actionRefundFee)
+	if err != nil {
+		return err
+	}
+
+	fee, ok := a.fees[feeID]
+	if !ok {
+		return errors.New(fmt.Sprintf("fee %s was never charged [account: %+v]", feeID, a))
+	}
+	if fee.refunded {
+		return errors.New(fmt.Sprintf("fee %s was already refunded [account: %+v]", feeID, a))
+	}
+
+	event := FeeWasRefunded{
+		ID:        a.id,
+		FeeID:     feeID,
+		FeeType:   fee.feeType,
+		Amount:    fee.amount,
+		Balance:   a.balance + fee.amount,
+		Timestamp: a.now(),
+	}
+
+	return a.raiseEvent(&event)
+}
+
+// PlaceHold: reserve funds for a card authorization until it is captured, released or expires
+func (a *Account) PlaceHold(holdID string, amount int, expiresAt int64) error {
+
+	err := a.ensureAllowed(actionPlaceHold)
+	if err != nil {
+		return err
+	}
+
+	if amount <= 0 {
+		return errors.New(fmt.Sprintf("hold Amount must be greater than 0 [Amount: %+v]", amount))
+	}
+
+	if _, ok := a.holds[holdID]; ok {
+		return errors.New(fmt.Sprintf("hold %s has already been placed [account: %+v]", holdID, a))
+	}
+
+	now := a.now()
+	if expiresAt <= now {
+		return errors.New(fmt.Sprintf("hold %s expires in the past [ExpiresAt: %d]", holdID, expiresAt))
+	}
+
+	if a.AvailableBalance(now) < amount {
+		return errors.New(fmt.Sprintf("insufficient available funds to place hold %s [Amount: %d, Available: %d]", holdID, amount, a.AvailableBalance(now)))
+	}
+
+	event := HoldWasPlaced{
+		ID:        a.id,
+		HoldID:    holdID,
+		Amount:    amount,
+		ExpiresAt: expiresAt,
+		Timestamp: now,
+	}
+
+	return a.raiseEvent(&event)
+}
+
+// ReleaseHold: release the funds reserved by a hold without moving any money
+func (a *Account) ReleaseHold(holdID string) error {
+
+	err := a.ensureAllowed(actionReleaseHold)
+	if err != nil {
+		return err
+	}
+
+	hold, ok := a.holds[holdID]
+	if !ok {
+		return errors.New(fmt.Sprintf("hold %s is not outstanding [account: %+v]", holdID, a))
+	}
+
+	event := HoldWasReleased{
+		ID:        a.id,
+		HoldID:    holdID,
+		Amount:    hold.amount,
+		Reason:    HoldReleasedByMerchant,
+		Timestamp: a.now(),
+	}
+
+	return a.raiseEvent(&event)
+}
+
+// CaptureHold: settle a hold, debiting up to the held amount from the ledger balance
+func (a *Account) CaptureHold(holdID string, amount int) error {
+
+	err := a.ensureAllowed(actionCaptureHold)
+	if err != nil {
+		return err
+	}
+
+	hold, ok := a.holds[holdID]
+	if !ok {
+		return errors.New(fmt.Sprintf("hold %s is not outstanding [account: %+v]", holdID, a))
+	}
+
+	now := a.now()
+	if hold.hasExpired(now) {
+		return errors.New(fmt.Sprintf("cannot capture expired hold %s [account: %+v]", holdID, a))
+	}
+
+	if amount <= 0 || amount > hold.amount {
+		return errors.New(fmt.Sprintf("capture Amount must be between 1 and the held amount [Amount: %d, Held: %d]", amount, hold.amount))
+	}
+
+	event := HoldWasCaptured{
+		ID:         a.id,
+		HoldID:     holdID,
+		HeldAmount: hold.amount,
+		Amount:     amount,
+		Balance:    a.balance - amount,
+		Timestamp:  now,
 	}
 
 	return a.raiseEvent(&event)
 }
+
+// ExpireHolds: release every hold that has passed its expiry time
+func (a *Account) ExpireHolds() error {
+
+	err := a.ensureAllowed(actionReleaseHold)
+	if err != nil {
+		return err
+	}
+
+	return a.releaseExpiredHolds(a.now())
+}
+
+// releaseExpiredHolds: release every hold that had passed its expiry time by now
+func (a *Account) releaseExpiredHolds(now int64) error {
+	var expiredHoldIDs []string
+	for holdID, hold := range a.holds {
+		if hold.hasExpired(now) {
+			expiredHoldIDs = append(expiredHoldIDs, holdID)
+		}
+	}
+	// release in a stable order so replaying the command produces the same events
+	sort.Strings(expiredHoldIDs)
+
+	for _, holdID := range expiredHoldIDs {
+		event := HoldWasReleased{
+			ID:        a.id,
+			HoldID:    holdID,
+			Amount:    a.holds[holdID].amount,
+			Reason:    HoldExpired,
+			Timestamp: now,
+		}
+		err := a.raiseEvent(&event)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FreezeAccount: block withdrawals, or all customer transactions, for compliance reasons. A debits freeze can be
+// widened to block everything while it is in place.
+func (a *Account) FreezeAccount(scope string, reasonCode string) error {
+
+	err := a.ensureAllowed(actionFreeze)
+	if err != nil {
+		return err
+	}
+
+	if scope != FreezeDebits && scope != FreezeAll {
+		return errors.New(fmt.Sprintf("unknown freeze scope %s", scope))
+	}
+
+	if reasonCode == "" {
+		return errors.New("a reason code is required to freeze an account")
+	}
+
+	if a.status == AccountFrozen && scope != FreezeAll {
+		return errors.New(fmt.Sprintf("account %s is already frozen for %s and a freeze can only be widened to %s", a.id, a.freezeScope, FreezeAll))
+	}
+
+	event := AccountWasFrozen{
+		ID:         a.id,
+		Scope:      scope,
+		ReasonCode: reasonCode,
+		Timestamp:  a.now(),
+	}
+
+	return a.raiseEvent(&event)
+}
+
+// UnfreezeAccount: lift a freeze, returning the account to the status it had before it was frozen
+func (a *Account) UnfreezeAccount(reasonCode string) error {
+
+	err := a.ensureAllowed(actionUnfreeze)
+	if err != nil {
+		return err
+	}
+
+	if reasonCode == "" {
+		return errors.New("a reason code is required to unfreeze an account")
+	}
+
+	event := AccountWasUnfrozen{
+		ID:         a.id,
+		ReasonCode: reasonCode,
+		Timestamp:  a.now(),
+	}
+
+	return a.raiseEvent(&event)
+}
+
+// SetWithdrawalLimits: change the most that can be withdrawn in one transaction and in any 24 hours (zero means no
+// limit)
+func (a *Account) SetWithdrawalLimits(perTransactionLimit int, dailyLimit int, changedBy string) error {
+
+	err := a.ensureAllowed(actionChangeLimits)
+	if err != nil {
+		return err
+	}
+
+	if perTransactionLimit < 0 || dailyLimit < 0 {
+		return errors.New(fmt.Sprintf("withdrawal limits cannot be negative [PerTransaction: %d, Daily: %d]", perTransactionLimit, dailyLimit))
+	}
+
+	if dailyLimit > 0 && perTransactionLimit > dailyLimit {
+		return errors.New(fmt.Sprintf("per transaction limit cannot exceed the daily limit [PerTransaction: %d, Daily: %d]", perTransactionLimit, dailyLimit))
+	}
+
+	event := WithdrawalLimitsWereChanged{
+		ID:                  a.id,
+		PerTransactionLimit: perTransactionLimit,
+		DailyLimit:          dailyLimit,
+		ChangedBy:           changedBy,
+		Timestamp:           a.now(),
+	}
+
+	return a.raiseEvent(&event)
+}
+
+func (a *Account) chargeFee(feeType string, amount int) error {
+	event := FeeWasCharged{
+		ID:        a.id,
+		FeeID:     fmt.Sprintf("%s/%d", a.id, a.version),
+		FeeType:   feeType,
+		Amount:    amount,
+		Balance:   a.balance - amount,
+		Timestamp: a.now(),
+	}
+
+	return a.raiseEvent(&event)
+}
+
+func yearMonth(timestamp int64) string {
+	return time.Unix(0, timestamp).UTC().Format("2006-01")
+}
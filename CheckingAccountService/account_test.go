@@ -1,8 +1,10 @@
 package CheckingAccountService
 
 import (
+	"github.com/agemmell/banking-cqrs-es-go/Seacrest"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 type UnknownEvent struct{}
@@ -78,12 +80,14 @@ func TestAccount_OpenAccount(t *testing.T) {
 	// When
 	id := "ABCD"
 	name := "Alex Gemmell"
-	err := account.OpenAccount(id, name)
+	err := account.OpenAccount(id, "CUST1", name, ProductStandardChecking)
 	assert.Nil(t, err)
 
 	// Then
 	assert.Equal(t, id, account.id)
 	assert.Equal(t, name, account.name)
+	assert.Equal(t, ProductStandardChecking, account.product)
+	assert.Equal(t, map[string]string{"CUST1": name}, account.Holders())
 	assert.Equal(t, 0, account.balance)
 	assert.Equal(t, uint(1), account.version)
 	assert.Len(t, account.newEvents, 1)
@@ -108,7 +112,7 @@ func TestAccount_DepositMoney(t *testing.T) {
 	// When
 	amount := 1234
 
-	err = account.DepositMoney(amount)
+	err = account.DepositMoney(amount, "CUST1")
 	assert.Nil(t, err)
 
 	// Then
@@ -140,7 +144,7 @@ func TestAccount_WithdrawMoney(t *testing.T) {
 
 	// When
 	withdrawAmount := 199
-	err = account.WithdrawMoney(withdrawAmount)
+	err = account.WithdrawMoney(withdrawAmount, "CUST1")
 	assert.Nil(t, err)
 
 	// Then
@@ -178,7 +182,7 @@ func TestAccount_WithdrawFailedDueToInsufficientFunds(t *testing.T) {
 
 	// When
 	withdrawAmount := 1
-	err = account.WithdrawMoney(withdrawAmount)
+	err = account.WithdrawMoney(withdrawAmount, "CUST1")
 	assert.Nil(t, err)
 
 	// Then
@@ -222,8 +226,781 @@ func TestAccount_CloseAccount(t *testing.T) {
 	assert.Equal(t, id, account.id)
 	assert.Equal(t, name, account.name)
 	assert.Equal(t, 0, account.balance)
-	assert.Equal(t, false, account.open)
+	assert.Equal(t, AccountClosed, account.status)
 	assert.Equal(t, uint(4), account.version)
 	assert.Len(t, account.newEvents, 1)
 	assert.IsType(t, &AccountWasClosed{}, account.newEvents[0])
 }
+
+func TestAccount_WithdrawMoneyChargesTransactionFee(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	id := "ABCD"
+	accountWasOpened := AccountWasOpened{
+		ID:   id,
+		Name: "Alex Gemmell",
+	}
+	moneyWasDeposited := MoneyWasDeposited{
+		ID:     id,
+		Amount: 1099,
+	}
+	events := append([]Event{}, &accountWasOpened, &moneyWasDeposited)
+	account := Account{}
+	err := account.LoadFromEvents(events)
+	assert.Nil(t, err)
+	account.SetFeeSchedule(FeeSchedule{TransactionFee: 2})
+
+	// When
+	err = account.WithdrawMoney(100, "CUST1")
+	assert.Nil(t, err)
+
+	// Then
+	assert.Equal(t, 997, account.balance)
+	assert.Len(t, account.newEvents, 2)
+	assert.IsType(t, &MoneyWasWithdrawn{}, account.newEvents[0])
+	feeWasCharged, ok := account.newEvents[1].(*FeeWasCharged)
+	assert.True(t, ok)
+	assert.Equal(t, FeeTypeTransaction, feeWasCharged.FeeType)
+	assert.Equal(t, 2, feeWasCharged.Amount)
+	assert.Equal(t, 997, feeWasCharged.Balance)
+}
+
+func TestAccount_ChargeMonthlyMaintenanceFee(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	id := "ABCD"
+	accountWasOpened := AccountWasOpened{
+		ID:   id,
+		Name: "Alex Gemmell",
+	}
+	moneyWasDeposited := MoneyWasDeposited{
+		ID:     id,
+		Amount: 50,
+	}
+	events := append([]Event{}, &accountWasOpened, &moneyWasDeposited)
+	account := Account{}
+	err := account.LoadFromEvents(events)
+	assert.Nil(t, err)
+	account.SetFeeSchedule(FeeSchedule{MonthlyMaintenanceFee: 5, MinimumBalanceForWaiver: 1000})
+
+	// When
+	err = account.ChargeFee(FeeTypeMonthlyMaintenance)
+	assert.Nil(t, err)
+
+	// Then
+	assert.Equal(t, 45, account.balance)
+	assert.Len(t, account.newEvents, 1)
+	feeWasCharged, ok := account.newEvents[0].(*FeeWasCharged)
+	assert.True(t, ok)
+	assert.Equal(t, FeeTypeMonthlyMaintenance, feeWasCharged.FeeType)
+	assert.Equal(t, "ABCD/2", feeWasCharged.FeeID)
+
+	// And the fee cannot be charged twice in the same month
+	err = account.ChargeFee(FeeTypeMonthlyMaintenance)
+	assert.NotNil(t, err)
+}
+
+func TestAccount_ChargeMonthlyMaintenanceFeeWaived(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	id := "ABCD"
+	accountWasOpened := AccountWasOpened{
+		ID:   id,
+		Name: "Alex Gemmell",
+	}
+	moneyWasDeposited := MoneyWasDeposited{
+		ID:     id,
+		Amount: 1000,
+	}
+	events := append([]Event{}, &accountWasOpened, &moneyWasDeposited)
+	account := Account{}
+	err := account.LoadFromEvents(events)
+	assert.Nil(t, err)
+	account.SetFeeSchedule(FeeSchedule{MonthlyMaintenanceFee: 5, MinimumBalanceForWaiver: 1000})
+
+	// When
+	err = account.ChargeFee(FeeTypeMonthlyMaintenance)
+	assert.Nil(t, err)
+
+	// Then
+	assert.Equal(t, 1000, account.balance)
+	assert.Len(t, account.newEvents, 1)
+	assert.IsType(t, &FeeWasWaived{}, account.newEvents[0])
+}
+
+func TestAccount_ChargeUnarrangedOverdraftFee(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	id := "ABCD"
+	accountWasOpened := AccountWasOpened{
+		ID:   id,
+		Name: "Alex Gemmell",
+	}
+	events := append([]Event{}, &accountWasOpened)
+	account := Account{}
+	err := account.LoadFromEvents(events)
+	assert.Nil(t, err)
+	account.SetFeeSchedule(FeeSchedule{UnarrangedOverdraftFee: 20})
+
+	// When the account is in credit
+	err = account.ChargeFee(FeeTypeUnarrangedOverdraft)
+
+	// Then
+	assert.NotNil(t, err)
+	assert.Empty(t, account.newEvents)
+
+	// When the account is overdrawn
+	err = account.LoadFromEvents([]Event{&FeeWasCharged{ID: id, FeeID: "ABCD/1", FeeType: FeeTypeMonthlyMaintenance, Amount: 5}})
+	assert.Nil(t, err)
+	err = account.ChargeFee(FeeTypeUnarrangedOverdraft)
+	assert.Nil(t, err)
+
+	// Then
+	assert.Equal(t, -25, account.balance)
+	assert.Len(t, account.newEvents, 1)
+	assert.IsType(t, &FeeWasCharged{}, account.newEvents[0])
+}
+
+func TestAccount_RefundFee(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	id := "ABCD"
+	accountWasOpened := AccountWasOpened{
+		ID:   id,
+		Name: "Alex Gemmell",
+	}
+	feeWasCharged := FeeWasCharged{
+		ID:      id,
+		FeeID:   "ABCD/1",
+		FeeType: FeeTypeMonthlyMaintenance,
+		Amount:  5,
+		Balance: -5,
+	}
+	events := append([]Event{}, &accountWasOpened, &feeWasCharged)
+	account := Account{}
+	err := account.LoadFromEvents(events)
+	assert.Nil(t, err)
+
+	// When
+	err = account.RefundFee("ABCD/1")
+	assert.Nil(t, err)
+
+	// Then
+	assert.Equal(t, 0, account.balance)
+	assert.Len(t, account.newEvents, 1)
+	feeWasRefunded, ok := account.newEvents[0].(*FeeWasRefunded)
+	assert.True(t, ok)
+	assert.Equal(t, FeeTypeMonthlyMaintenance, feeWasRefunded.FeeType)
+	assert.Equal(t, 5, feeWasRefunded.Amount)
+
+	// And a fee cannot be refunded twice
+	err = account.RefundFee("ABCD/1")
+	assert.NotNil(t, err)
+	err = account.RefundFee("ABCD/99")
+	assert.NotNil(t, err)
+}
+
+func TestAccount_PlaceHoldReducesAvailableBalance(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	id := "ABCD"
+	accountWasOpened := AccountWasOpened{
+		ID:   id,
+		Name: "Alex Gemmell",
+	}
+	moneyWasDeposited := MoneyWasDeposited{
+		ID:     id,
+		Amount: 1000,
+	}
+	events := append([]Event{}, &accountWasOpened, &moneyWasDeposited)
+	account := Account{}
+	err := account.LoadFromEvents(events)
+	assert.Nil(t, err)
+	now := time.Now().UnixNano()
+
+	// When
+	err = account.PlaceHold("HOLD1", 800, now+int64(time.Hour))
+	assert.Nil(t, err)
+
+	// Then
+	assert.Equal(t, 1000, account.LedgerBalance())
+	assert.Equal(t, 200, account.AvailableBalance(now))
+	assert.Equal(t, 1000, account.AvailableBalance(now+int64(2*time.Hour)))
+	assert.Len(t, account.newEvents, 1)
+	assert.IsType(t, &HoldWasPlaced{}, account.newEvents[0])
+
+	// And a withdrawal is checked against the available balance
+	err = account.WithdrawMoney(500, "CUST1")
+	assert.Nil(t, err)
+	assert.Len(t, account.newEvents, 2)
+	assert.IsType(t, &WithdrawFailedDueToInsufficientFunds{}, account.newEvents[1])
+
+	// And a second hold cannot exceed the available balance
+	err = account.PlaceHold("HOLD2", 201, now+int64(time.Hour))
+	assert.NotNil(t, err)
+}
+
+func TestAccount_CaptureHold(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	id := "ABCD"
+	accountWasOpened := AccountWasOpened{
+		ID:   id,
+		Name: "Alex Gemmell",
+	}
+	moneyWasDeposited := MoneyWasDeposited{
+		ID:     id,
+		Amount: 1000,
+	}
+	holdWasPlaced := HoldWasPlaced{
+		ID:        id,
+		HoldID:    "HOLD1",
+		Amount:    800,
+		ExpiresAt: time.Now().Add(time.Hour).UnixNano(),
+	}
+	events := append([]Event{}, &accountWasOpened, &moneyWasDeposited, &holdWasPlaced)
+	account := Account{}
+	err := account.LoadFromEvents(events)
+	assert.Nil(t, err)
+
+	// When
+	err = account.CaptureHold("HOLD1", 750)
+	assert.Nil(t, err)
+
+	// Then
+	assert.Equal(t, 250, account.LedgerBalance())
+	assert.Equal(t, 250, account.AvailableBalance(time.Now().UnixNano()))
+	assert.Len(t, account.newEvents, 1)
+	holdWasCaptured, ok := account.newEvents[0].(*HoldWasCaptured)
+	assert.True(t, ok)
+	assert.Equal(t, 800, holdWasCaptured.HeldAmount)
+	assert.Equal(t, 750, holdWasCaptured.Amount)
+	assert.Equal(t, 250, holdWasCaptured.Balance)
+
+	// And the hold cannot be captured or released again
+	assert.NotNil(t, account.CaptureHold("HOLD1", 50))
+	assert.NotNil(t, account.ReleaseHold("HOLD1"))
+}
+
+func TestAccount_CloseAccountReleasesExpiredHolds(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	id := "ABCD"
+	now := time.Date(2020, time.June, 30, 12, 0, 0, 0, time.UTC)
+	accountWasOpened := AccountWasOpened{
+		ID:   id,
+		Name: "Alex Gemmell",
+	}
+	holdWasPlaced := HoldWasPlaced{
+		ID:        id,
+		HoldID:    "HOLD1",
+		Amount:    100,
+		ExpiresAt: now.Add(time.Hour).UnixNano(),
+	}
+	events := append([]Event{}, &accountWasOpened, &holdWasPlaced)
+	account := Account{}
+	err := account.LoadFromEvents(events)
+	assert.Nil(t, err)
+
+	// When the hold has not yet expired
+	account.SetClock(Seacrest.NewFakeClock(now))
+	err = account.CloseAccount()
+
+	// Then
+	assert.NotNil(t, err)
+	assert.Empty(t, account.newEvents)
+
+	// When the hold has expired without being released
+	account.SetClock(Seacrest.NewFakeClock(now.Add(2 * time.Hour)))
+	err = account.CloseAccount()
+
+	// Then
+	assert.Nil(t, err)
+	assert.Len(t, account.newEvents, 2)
+	expired, ok := account.newEvents[0].(*HoldWasReleased)
+	assert.True(t, ok)
+	assert.Equal(t, "HOLD1", expired.HoldID)
+	assert.Equal(t, HoldExpired, expired.Reason)
+	_, ok = account.newEvents[1].(*AccountWasClosed)
+	assert.True(t, ok)
+	assert.Empty(t, account.holds)
+	assert.Equal(t, AccountClosed, account.Status())
+}
+
+func TestAccount_ReleaseAndExpireHolds(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	id := "ABCD"
+	accountWasOpened := AccountWasOpened{
+		ID:   id,
+		Name: "Alex Gemmell",
+	}
+	moneyWasDeposited := MoneyWasDeposited{
+		ID:     id,
+		Amount: 1000,
+	}
+	activeHold := HoldWasPlaced{
+		ID:        id,
+		HoldID:    "HOLD1",
+		Amount:    100,
+		ExpiresAt: time.Now().Add(time.Hour).UnixNano(),
+	}
+	expiredHold := HoldWasPlaced{
+		ID:        id,
+		HoldID:    "HOLD2",
+		Amount:    200,
+		ExpiresAt: time.Now().Add(-time.Hour).UnixNano(),
+	}
+	events := append([]Event{}, &accountWasOpened, &moneyWasDeposited, &activeHold, &expiredHold)
+	account := Account{}
+	err := account.LoadFromEvents(events)
+	assert.Nil(t, err)
+
+	// When
+	err = account.ExpireHolds()
+	assert.Nil(t, err)
+	err = account.ReleaseHold("HOLD1")
+	assert.Nil(t, err)
+
+	// Then
+	assert.Len(t, account.newEvents, 2)
+	expired, ok := account.newEvents[0].(*HoldWasReleased)
+	assert.True(t, ok)
+	assert.Equal(t, "HOLD2", expired.HoldID)
+	assert.Equal(t, HoldExpired, expired.Reason)
+	released, ok := account.newEvents[1].(*HoldWasReleased)
+	assert.True(t, ok)
+	assert.Equal(t, "HOLD1", released.HoldID)
+	assert.Equal(t, HoldReleasedByMerchant, released.Reason)
+	assert.Empty(t, account.holds)
+	assert.Equal(t, 1000, account.LedgerBalance())
+}
+
+func TestAccount_FreezeDebits(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	id := "ABCD"
+	accountWasOpened := AccountWasOpened{
+		ID:   id,
+		Name: "Alex Gemmell",
+	}
+	moneyWasDeposited := MoneyWasDeposited{
+		ID:     id,
+		Amount: 1000,
+	}
+	events := append([]Event{}, &accountWasOpened, &moneyWasDeposited)
+	account := Account{}
+	err := account.LoadFromEvents(events)
+	assert.Nil(t, err)
+
+	// When
+	err = account.FreezeAccount(FreezeDebits, ReasonSuspectedFraud)
+	assert.Nil(t, err)
+
+	// Then
+	assert.Equal(t, AccountFrozen, account.Status())
+	assert.Len(t, account.newEvents, 1)
+	assert.IsType(t, &AccountWasFrozen{}, account.newEvents[0])
+
+	// And deposits are still accepted but withdrawals and closing are blocked
+	assert.Nil(t, account.DepositMoney(10, "CUST1"))
+	err = account.WithdrawMoney(10, "CUST1")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "cannot withdraw money from an account that is frozen")
+	assert.NotNil(t, account.CloseAccount())
+	assert.Len(t, account.newEvents, 2)
+}
+
+func TestAccount_WidenFreeze(t *testing.T) {
+	t.Parallel()
+
+	// Given an account frozen for debits
+	account := Account{}
+	err := account.LoadFromEvents([]Event{
+		&AccountWasOpened{ID: "ABCD", Name: "Alex Gemmell"},
+		&AccountWasFrozen{ID: "ABCD", Scope: FreezeDebits, ReasonCode: ReasonSuspectedFraud},
+	})
+	assert.Nil(t, err)
+
+	// When
+	narrowErr := account.FreezeAccount(FreezeDebits, ReasonSuspectedFraud)
+	err = account.FreezeAccount(FreezeAll, ReasonCourtOrder)
+	depositErr := account.DepositMoney(10, "CUST1")
+	refreezeErr := account.FreezeAccount(FreezeAll, ReasonSanctionsScreening)
+
+	// Then the freeze is widened in place, and lifting it returns the account to active
+	assert.Equal(t, "account ABCD is already frozen for Debits and a freeze can only be widened to All", narrowErr.Error())
+	assert.Nil(t, err)
+	assert.Equal(t, AccountFrozen, account.Status())
+	assert.Contains(t, depositErr.Error(), "cannot deposit money into an account that is frozen")
+	assert.Contains(t, refreezeErr.Error(), "cannot freeze an account that is frozen")
+	assert.Nil(t, account.UnfreezeAccount(ReasonReviewCompleted))
+	assert.Equal(t, AccountActive, account.Status())
+	assert.Len(t, account.newEvents, 2)
+}
+
+func TestAccount_FreezeAll(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	id := "ABCD"
+	accountWasOpened := AccountWasOpened{
+		ID:   id,
+		Name: "Alex Gemmell",
+	}
+	accountWasFrozen := AccountWasFrozen{
+		ID:         id,
+		Scope:      FreezeAll,
+		ReasonCode: ReasonSanctionsScreening,
+	}
+	events := append([]Event{}, &accountWasOpened, &accountWasFrozen)
+	account := Account{}
+	err := account.LoadFromEvents(events)
+	assert.Nil(t, err)
+
+	// When
+	err = account.DepositMoney(10, "CUST1")
+
+	// Then
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "cannot deposit money into an account that is frozen")
+	assert.Empty(t, account.newEvents)
+}
+
+func TestAccount_UnfreezeAccount(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	id := "ABCD"
+	accountWasOpened := AccountWasOpened{
+		ID:   id,
+		Name: "Alex Gemmell",
+	}
+	accountWasFrozen := AccountWasFrozen{
+		ID:         id,
+		Scope:      FreezeAll,
+		ReasonCode: ReasonSanctionsScreening,
+	}
+	events := append([]Event{}, &accountWasOpened, &accountWasFrozen)
+	account := Account{}
+	err := account.LoadFromEvents(events)
+	assert.Nil(t, err)
+
+	// When
+	err = account.UnfreezeAccount(ReasonReviewCompleted)
+	assert.Nil(t, err)
+
+	// Then
+	assert.Equal(t, AccountActive, account.Status())
+	assert.Len(t, account.newEvents, 1)
+	assert.IsType(t, &AccountWasUnfrozen{}, account.newEvents[0])
+	assert.Nil(t, account.DepositMoney(10, "CUST1"))
+
+	// And an account that isn't frozen cannot be unfrozen
+	assert.NotNil(t, account.UnfreezeAccount(ReasonReviewCompleted))
+}
+
+func TestAccount_CommandsRejectedOnClosedAccount(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	id := "ABCD"
+	accountWasOpened := AccountWasOpened{
+		ID:   id,
+		Name: "Alex Gemmell",
+	}
+	accountWasClosed := AccountWasClosed{
+		ID: id,
+	}
+	events := append([]Event{}, &accountWasOpened, &accountWasClosed)
+	account := Account{}
+	err := account.LoadFromEvents(events)
+	assert.Nil(t, err)
+
+	// Then
+	assert.NotNil(t, account.OpenAccount(id, "CUST1", "Alex Gemmell", ProductStandardChecking))
+	assert.NotNil(t, account.DepositMoney(10, "CUST1"))
+	assert.NotNil(t, account.WithdrawMoney(10, "CUST1"))
+	assert.NotNil(t, account.CloseAccount())
+	assert.NotNil(t, account.FreezeAccount(FreezeAll, ReasonCourtOrder))
+	assert.NotNil(t, account.PlaceHold("HOLD1", 10, time.Now().Add(time.Hour).UnixNano()))
+	assert.Empty(t, account.newEvents)
+}
+
+func TestAccount_ChangeHolderName(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	id := "ABCD"
+	accountWasOpened := AccountWasOpened{
+		ID:       id,
+		HolderID: "CUST1",
+		Name:     "Alex Gemmell",
+	}
+	events := append([]Event{}, &accountWasOpened)
+	account := Account{}
+	err := account.LoadFromEvents(events)
+	assert.Nil(t, err)
+
+	// When
+	err = account.ChangeHolderName("", "Alexander Gemmell", "CUST1")
+	assert.Nil(t, err)
+
+	// Then
+	assert.Equal(t, "Alexander Gemmell", account.name)
+	assert.Len(t, account.newEvents, 1)
+	accountHolderWasRenamed, ok := account.newEvents[0].(*AccountHolderWasRenamed)
+	assert.True(t, ok)
+	assert.Equal(t, "CUST1", accountHolderWasRenamed.HolderID)
+	assert.Equal(t, "Alex Gemmell", accountHolderWasRenamed.PreviousName)
+	assert.Equal(t, "CUST1", accountHolderWasRenamed.ChangedBy)
+}
+
+func TestAccount_JointHolders(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	id := "ABCD"
+	accountWasOpened := AccountWasOpened{
+		ID:       id,
+		HolderID: "CUST1",
+		Name:     "Alex Gemmell",
+	}
+	events := append([]Event{}, &accountWasOpened)
+	account := Account{}
+	err := account.LoadFromEvents(events)
+	assert.Nil(t, err)
+
+	// When
+	err = account.AddJointHolder("CUST2", "Sam Gemmell", "CUST1")
+	assert.Nil(t, err)
+
+	// Then
+	assert.Equal(t, map[string]string{"CUST1": "Alex Gemmell", "CUST2": "Sam Gemmell"}, account.Holders())
+	assert.Nil(t, account.Authorize("CUST2", PermissionClose))
+	assert.NotNil(t, account.AddJointHolder("CUST2", "Sam Gemmell", "CUST1"))
+
+	// When
+	err = account.RemoveJointHolder("CUST2", "CUST1")
+	assert.Nil(t, err)
+
+	// Then
+	assert.Equal(t, map[string]string{"CUST1": "Alex Gemmell"}, account.Holders())
+	assert.NotNil(t, account.Authorize("CUST2", PermissionView))
+	assert.NotNil(t, account.RemoveJointHolder("CUST1", "CUST1"))
+	assert.Len(t, account.newEvents, 2)
+}
+
+func TestAccount_AuthorizedSignatoryPermissions(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	id := "ABCD"
+	accountWasOpened := AccountWasOpened{
+		ID:       id,
+		HolderID: "CUST1",
+		Name:     "Alex Gemmell",
+	}
+	events := append([]Event{}, &accountWasOpened)
+	account := Account{}
+	err := account.LoadFromEvents(events)
+	assert.Nil(t, err)
+
+	// When
+	err = account.AddAuthorizedSignatory("CUST3", "Jo Bloggs", []string{PermissionView, PermissionWithdraw}, "CUST1")
+	assert.Nil(t, err)
+
+	// Then
+	assert.Nil(t, account.Authorize("CUST3", PermissionView))
+	assert.Nil(t, account.Authorize("CUST3", PermissionWithdraw))
+	assert.NotNil(t, account.Authorize("CUST3", PermissionClose))
+	assert.NotNil(t, account.Authorize("CUST3", PermissionManageHolders))
+	assert.NotNil(t, account.Authorize("CUST4", PermissionView))
+	assert.NotNil(t, account.Authorize("", PermissionClose))
+	assert.Nil(t, account.Authorize(SystemPrincipal, PermissionClose))
+	assert.Nil(t, account.Authorize(SystemPrincipal, PermissionOperate))
+	assert.NotNil(t, account.Authorize("CUST1", PermissionOperate))
+	assert.NotNil(t, account.AddAuthorizedSignatory("CUST4", "Jo Bloggs", []string{PermissionManageHolders}, "CUST1"))
+	assert.NotNil(t, account.AddAuthorizedSignatory("CUST4", "Jo Bloggs", []string{PermissionOperate}, "CUST1"))
+	assert.NotNil(t, account.AddAuthorizedSignatory(SystemPrincipal, "The bank", []string{PermissionView}, "CUST1"))
+
+	// When
+	err = account.RemoveAuthorizedSignatory("CUST3", "CUST1")
+	assert.Nil(t, err)
+
+	// Then
+	assert.NotNil(t, account.Authorize("CUST3", PermissionView))
+	assert.Len(t, account.newEvents, 2)
+}
+
+func TestAccount_WithdrawMoneyPerTransactionLimit(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	id := "ABCD"
+	accountWasOpened := AccountWasOpened{
+		ID:   id,
+		Name: "Alex Gemmell",
+	}
+	moneyWasDeposited := MoneyWasDeposited{
+		ID:     id,
+		Amount: 1000,
+	}
+	withdrawalLimitsWereChanged := WithdrawalLimitsWereChanged{
+		ID:                  id,
+		PerTransactionLimit: 300,
+	}
+	events := append([]Event{}, &accountWasOpened, &moneyWasDeposited, &withdrawalLimitsWereChanged)
+	account := Account{}
+	err := account.LoadFromEvents(events)
+	assert.Nil(t, err)
+
+	// When
+	err = account.WithdrawMoney(301, "CUST1")
+
+	// Then
+	limitExceeded, ok := err.(*WithdrawalLimitExceededError)
+	assert.True(t, ok)
+	assert.Equal(t, LimitPerTransaction, limitExceeded.Limit)
+	assert.Equal(t, 300, limitExceeded.LimitAmount)
+	assert.Empty(t, account.newEvents)
+	assert.Nil(t, account.WithdrawMoney(300, "CUST1"))
+}
+
+func TestAccount_WithdrawMoneyDailyLimitUsesRolling24Hours(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	id := "ABCD"
+	now := time.Date(2020, time.June, 30, 12, 0, 0, 0, time.UTC)
+	accountWasOpened := AccountWasOpened{
+		ID:   id,
+		Name: "Alex Gemmell",
+	}
+	moneyWasDeposited := MoneyWasDeposited{
+		ID:     id,
+		Amount: 1000,
+	}
+	withdrawalLimitsWereChanged := WithdrawalLimitsWereChanged{
+		ID:         id,
+		DailyLimit: 500,
+	}
+	outsideWindow := MoneyWasWithdrawn{
+		ID:        id,
+		Amount:    400,
+		Balance:   600,
+		Timestamp: now.Add(-25 * time.Hour).UnixNano(),
+	}
+	insideWindow := MoneyWasWithdrawn{
+		ID:        id,
+		Amount:    300,
+		Balance:   300,
+		Timestamp: now.Add(-23 * time.Hour).UnixNano(),
+	}
+	events := append([]Event{}, &accountWasOpened, &moneyWasDeposited, &withdrawalLimitsWereChanged, &outsideWindow, &insideWindow)
+	account := Account{}
+	err := account.LoadFromEvents(events)
+	assert.Nil(t, err)
+	account.SetClock(Seacrest.NewFakeClock(now))
+
+	// When
+	err = account.WithdrawMoney(201, "CUST1")
+
+	// Then
+	limitExceeded, ok := err.(*WithdrawalLimitExceededError)
+	assert.True(t, ok)
+	assert.Equal(t, LimitDaily, limitExceeded.Limit)
+	assert.Equal(t, 300, limitExceeded.AlreadyWithdrawn)
+	assert.Empty(t, account.newEvents)
+
+	// When the clock moves past the earlier withdrawal's window
+	account.SetClock(Seacrest.NewFakeClock(now.Add(2 * time.Hour)))
+	err = account.WithdrawMoney(201, "CUST1")
+
+	// Then
+	assert.Nil(t, err)
+	assert.Len(t, account.newEvents, 1)
+	moneyWasWithdrawn, ok := account.newEvents[0].(*MoneyWasWithdrawn)
+	assert.True(t, ok)
+	assert.Equal(t, now.Add(2*time.Hour).UnixNano(), moneyWasWithdrawn.Timestamp)
+}
+
+func TestAccount_CapturedHoldsCountTowardsTheDailyLimit(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	id := "ABCD"
+	now := time.Date(2020, time.June, 30, 12, 0, 0, 0, time.UTC)
+	accountWasOpened := AccountWasOpened{
+		ID:   id,
+		Name: "Alex Gemmell",
+	}
+	moneyWasDeposited := MoneyWasDeposited{
+		ID:     id,
+		Amount: 1000,
+	}
+	withdrawalLimitsWereChanged := WithdrawalLimitsWereChanged{
+		ID:         id,
+		DailyLimit: 500,
+	}
+	holdWasPlaced := HoldWasPlaced{
+		ID:        id,
+		HoldID:    "HOLD1",
+		Amount:    400,
+		ExpiresAt: now.Add(time.Hour).UnixNano(),
+	}
+	events := append([]Event{}, &accountWasOpened, &moneyWasDeposited, &withdrawalLimitsWereChanged, &holdWasPlaced)
+	account := Account{}
+	err := account.LoadFromEvents(events)
+	assert.Nil(t, err)
+	account.SetClock(Seacrest.NewFakeClock(now))
+	err = account.CaptureHold("HOLD1", 400)
+	assert.Nil(t, err)
+
+	// When
+	err = account.WithdrawMoney(101, "CUST1")
+
+	// Then
+	limitExceeded, ok := err.(*WithdrawalLimitExceededError)
+	assert.True(t, ok)
+	assert.Equal(t, LimitDaily, limitExceeded.Limit)
+	assert.Equal(t, 400, limitExceeded.AlreadyWithdrawn)
+	assert.Nil(t, account.WithdrawMoney(100, "CUST1"))
+}
+
+func TestAccount_SetWithdrawalLimits(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	id := "ABCD"
+	accountWasOpened := AccountWasOpened{
+		ID:   id,
+		Name: "Alex Gemmell",
+	}
+	events := append([]Event{}, &accountWasOpened)
+	account := Account{}
+	err := account.LoadFromEvents(events)
+	assert.Nil(t, err)
+
+	// When
+	err = account.SetWithdrawalLimits(100, 500, "ABCD")
+	assert.Nil(t, err)
+
+	// Then
+	assert.Equal(t, 100, account.perTransactionLimit)
+	assert.Equal(t, 500, account.dailyLimit)
+	assert.Len(t, account.newEvents, 1)
+	assert.IsType(t, &WithdrawalLimitsWereChanged{}, account.newEvents[0])
+	assert.NotNil(t, account.SetWithdrawalLimits(600, 500, "ABCD"))
+	assert.NotNil(t, account.SetWithdrawalLimits(-1, 0, "ABCD"))
+}
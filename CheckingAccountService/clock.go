@@ -0,0 +1,13 @@
+package CheckingAccountService
+
+import "github.com/agemmell/banking-cqrs-es-go/Seacrest"
+
+// Clock: the source of the current time for command handlers, so time-dependent rules can be tested. The service
+// shares its clock and ID generator types with the event store so one fake can drive both.
+type Clock = Seacrest.Clock
+
+// SystemClock: the wall clock
+type SystemClock = Seacrest.SystemClock
+
+// IDGenerator: the source of the IDs the service generates
+type IDGenerator = Seacrest.IDGenerator
@@ -0,0 +1,134 @@
+package CheckingAccountService
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ReopenWindowDays: how long after closure a customer can change their mind and reopen the account
+const ReopenWindowDays = 90
+
+// ReopenAccount: bring a recently closed account back into use
+func (a *Account) ReopenAccount(reopenedBy string) error {
+
+	err := a.ensureAllowed(actionReopen)
+	if err != nil {
+		return err
+	}
+
+	now := a.now()
+	deadline := time.Unix(0, a.closedAt).AddDate(0, 0, ReopenWindowDays).UnixNano()
+	if now > deadline {
+		return errors.New(fmt.Sprintf("accounts can only be reopened within %d days of closure [account: %s]", ReopenWindowDays, a.id))
+	}
+
+	event := AccountWasReopened{
+		ID:         a.id,
+		ReopenedBy: reopenedBy,
+		Timestamp:  now,
+	}
+
+	return a.raiseEvent(&event)
+}
+
+// MarkDormant: flag an account that has had no customer activity for at least the inactivity period
+func (a *Account) MarkDormant(inactivityPeriod time.Duration) error {
+
+	err := a.ensureAllowed(actionMarkDormant)
+	if err != nil {
+		return err
+	}
+
+	if a.status == AccountDormant {
+		return errors.New(fmt.Sprintf("account %s is already dormant", a.id))
+	}
+
+	if inactivityPeriod <= 0 {
+		return errors.New(fmt.Sprintf("inactivity period must be greater than 0 [InactivityPeriod: %s]", inactivityPeriod))
+	}
+
+	now := a.now()
+	if !a.inactiveFor(inactivityPeriod, now) {
+		return errors.New(fmt.Sprintf("account %s has had customer activity in the last %s", a.id, inactivityPeriod))
+	}
+
+	event := AccountBecameDormant{
+		ID:             a.id,
+		LastActivityAt: a.lastActivityAt,
+		Timestamp:      now,
+	}
+
+	return a.raiseEvent(&event)
+}
+
+// inactiveFor: whether the account's last customer activity was at least the inactivity period ago
+func (a *Account) inactiveFor(inactivityPeriod time.Duration, now int64) bool {
+	return now-a.lastActivityAt >= int64(inactivityPeriod)
+}
+
+// reactivateIfDormant: a dormant account comes back into use with the first new customer transaction. Command handlers
+// call this once the transaction has been validated, just before raising its event.
+func (a *Account) reactivateIfDormant(now int64) error {
+	if a.status != AccountDormant {
+		return nil
+	}
+
+	event := AccountWasReactivated{
+		ID:        a.id,
+		Timestamp: now,
+	}
+
+	return a.raiseEvent(&event)
+}
+
+// DormancyRun: what happened when accounts were checked for dormancy
+type DormancyRun struct {
+	Flagged int
+	Errors  []error
+}
+
+// DormancyMonitor: flags active accounts that have had no customer activity for the configured period. The service's
+// clock decides how long an account has been inactive.
+type DormancyMonitor struct {
+	service          *CheckingAccountService
+	inactivityPeriod time.Duration
+}
+
+func NewDormancyMonitor(service *CheckingAccountService, inactivityPeriod time.Duration) *DormancyMonitor {
+	return &DormancyMonitor{service, inactivityPeriod}
+}
+
+// Run: mark every active account that has been inactive for the monitor's period as dormant
+func (dm *DormancyMonitor) Run() (DormancyRun, error) {
+	run := DormancyRun{}
+
+	var accountIDs []string
+	for _, envelope := range dm.service.eventStore.GetAllEvents() {
+		if envelope.EventType == TypeAccountWasOpened {
+			accountIDs = append(accountIDs, envelope.AggregateID)
+		}
+	}
+	sort.Strings(accountIDs)
+
+	now := dm.service.clock.Now().UnixNano()
+	for _, accountID := range accountIDs {
+		account, err := dm.service.loadAccount(accountID)
+		if err != nil {
+			return run, err
+		}
+		if account.Status() != AccountActive || !account.inactiveFor(dm.inactivityPeriod, now) {
+			continue
+		}
+
+		err = dm.service.HandleCommand(MarkAccountDormant{ID: accountID, InactivityPeriod: dm.inactivityPeriod, ActingAs: SystemPrincipal})
+		if err != nil {
+			run.Errors = append(run.Errors, fmt.Errorf("account %s: %w", accountID, err))
+			continue
+		}
+		run.Flagged++
+	}
+
+	return run, nil
+}
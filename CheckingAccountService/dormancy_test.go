@@ -0,0 +1,145 @@
+package CheckingAccountService
+
+import (
+	"github.com/agemmell/banking-cqrs-es-go/Seacrest"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestAccount_ReopenAccount(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	clock := Seacrest.NewFakeClock(time.Date(2021, time.January, 1, 9, 0, 0, 0, time.UTC))
+	account := Account{}
+	account.SetClock(clock)
+	assert.Nil(t, account.OpenAccount("ABCD", "CUST1", "Alex Gemmell", ProductStandardChecking))
+	activeErr := account.ReopenAccount("CUST1")
+	assert.Nil(t, account.CloseAccount())
+	depositErr := account.DepositMoney(10, "CUST1")
+
+	// When
+	clock.Set(clock.Now().AddDate(0, 0, ReopenWindowDays))
+	err := account.ReopenAccount("CUST1")
+
+	// Then
+	assert.Contains(t, activeErr.Error(), "cannot reopen an account that is active")
+	assert.NotNil(t, depositErr)
+	assert.Nil(t, err)
+	assert.Equal(t, AccountActive, account.Status())
+	assert.Nil(t, account.DepositMoney(10, "CUST1"))
+}
+
+func TestAccount_ReopenAccountAfterWindow(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	clock := Seacrest.NewFakeClock(time.Date(2021, time.January, 1, 9, 0, 0, 0, time.UTC))
+	account := Account{}
+	account.SetClock(clock)
+	assert.Nil(t, account.OpenAccount("ABCD", "CUST1", "Alex Gemmell", ProductStandardChecking))
+	assert.Nil(t, account.CloseAccount())
+
+	// When
+	clock.Set(clock.Now().AddDate(0, 0, ReopenWindowDays).Add(time.Second))
+	err := account.ReopenAccount("CUST1")
+
+	// Then
+	assert.Equal(t, "accounts can only be reopened within 90 days of closure [account: ABCD]", err.Error())
+	assert.Equal(t, AccountClosed, account.Status())
+}
+
+func TestAccount_DormantAccountReactivatedByTransaction(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	clock := Seacrest.NewFakeClock(time.Date(2021, time.January, 1, 9, 0, 0, 0, time.UTC))
+	account := Account{}
+	account.SetClock(clock)
+	assert.Nil(t, account.OpenAccount("ABCD", "CUST1", "Alex Gemmell", ProductStandardChecking))
+	assert.Nil(t, account.DepositMoney(100, "CUST1"))
+	clock.Set(clock.Now().AddDate(1, 0, 0).Add(-time.Second))
+	tooSoonErr := account.MarkDormant(365 * 24 * time.Hour)
+	clock.Set(clock.Now().Add(time.Second))
+	assert.Nil(t, account.MarkDormant(365*24*time.Hour))
+	alreadyDormantErr := account.MarkDormant(365 * 24 * time.Hour)
+
+	// When
+	clock.Set(clock.Now().Add(time.Hour))
+	err := account.WithdrawMoney(40, "CUST1")
+
+	// Then
+	assert.Equal(t, "account ABCD has had customer activity in the last 8760h0m0s", tooSoonErr.Error())
+	assert.Equal(t, "account ABCD is already dormant", alreadyDormantErr.Error())
+	assert.Nil(t, err)
+	newEvents := account.GetNewEvents()
+	assert.Len(t, newEvents, 5)
+	assert.Equal(t, TypeAccountBecameDormant, newEvents[2].EventType())
+	assert.Equal(t, &AccountWasReactivated{ID: "ABCD", Timestamp: clock.Now().UnixNano()}, newEvents[3])
+	assert.Equal(t, TypeMoneyWasWithdrawn, newEvents[4].EventType())
+	assert.Equal(t, AccountActive, account.Status())
+}
+
+func TestAccount_BankActivityLeavesADormantAccountDormant(t *testing.T) {
+	t.Parallel()
+
+	// Given an account with a standing order whose first payment falls due after it has become dormant
+	start := time.Date(2021, time.January, 1, 9, 0, 0, 0, time.UTC)
+	clock := Seacrest.NewFakeClock(start)
+	account := Account{}
+	account.SetClock(clock)
+	assert.Nil(t, account.OpenAccount("ABCD", "CUST1", "Alex Gemmell", ProductStandardChecking))
+	assert.Nil(t, account.DepositMoney(500, "CUST1"))
+	paymentDate := start.AddDate(1, 1, 0).UnixNano()
+	assert.Nil(t, account.CreateStandingOrder("RENT", "EFGH", 100, FrequencyMonthly, paymentDate, "CUST1"))
+	clock.Set(start.AddDate(1, 0, 0))
+	assert.Nil(t, account.MarkDormant(365*24*time.Hour))
+
+	// When the bank makes the payment, places a hold and pays money in
+	clock.Set(start.AddDate(1, 1, 0))
+	paymentErr := account.MakeStandingOrderPayment("RENT", paymentDate)
+	holdErr := account.PlaceHold("H1", 50, clock.Now().Add(time.Hour).UnixNano())
+	depositErr := account.DepositMoney(10, SystemPrincipal)
+
+	// Then
+	assert.Nil(t, paymentErr)
+	assert.Nil(t, holdErr)
+	assert.Nil(t, depositErr)
+	assert.Equal(t, AccountDormant, account.Status())
+	assert.Equal(t, 410, account.LedgerBalance())
+	assert.Equal(t, start.UnixNano(), account.lastActivityAt)
+}
+
+func Test_DormancyMonitor(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	eventStore := Seacrest.NewEventStore()
+	clock := Seacrest.NewFakeClock(time.Date(2021, time.January, 1, 9, 0, 0, 0, time.UTC))
+	checkingAccountService := New(eventStore, WithClock(clock), WithCustomerVerifier(verifiedCustomers{}))
+	assert.Nil(t, checkingAccountService.HandleCommand(OpenAccount{ID: "IDLE", CustomerID: "CUST1"}))
+	assert.Nil(t, checkingAccountService.HandleCommand(OpenAccount{ID: "BUSY", CustomerID: "CUST2"}))
+	assert.Nil(t, checkingAccountService.HandleCommand(OpenAccount{ID: "SHUT", CustomerID: "CUST3"}))
+	assert.Nil(t, checkingAccountService.HandleCommand(CloseAccount{ID: "SHUT", ActingAs: SystemPrincipal}))
+	clock.Set(clock.Now().AddDate(0, 6, 0))
+	assert.Nil(t, checkingAccountService.HandleCommand(DepositMoney{ID: "BUSY", Amount: 10, ActingAs: "CUST2"}))
+	clock.Set(clock.Now().AddDate(0, 6, 0))
+	monitor := NewDormancyMonitor(&checkingAccountService, 365*24*time.Hour)
+
+	// When
+	run, err := monitor.Run()
+	secondRun, secondErr := monitor.Run()
+
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, DormancyRun{Flagged: 1}, run)
+	assert.Nil(t, secondErr)
+	assert.Equal(t, DormancyRun{}, secondRun)
+	summary, err := checkingAccountService.GetAccountSummary("IDLE", SystemPrincipal)
+	assert.Nil(t, err)
+	assert.Equal(t, AccountDormant, summary.Status)
+	summary, err = checkingAccountService.GetAccountSummary("BUSY", SystemPrincipal)
+	assert.Nil(t, err)
+	assert.Equal(t, AccountActive, summary.Status)
+}
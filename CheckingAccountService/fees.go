@@ -0,0 +1,19 @@
+package CheckingAccountService
+
+import "github.com/agemmell/banking-cqrs-es-go/ProductCatalog"
+
+// Fee types are recorded on FeeWasCharged, FeeWasWaived and FeeWasRefunded events as the reason the fee was raised
+const FeeTypeMonthlyMaintenance = ProductCatalog.FeeTypeMonthlyMaintenance
+const FeeTypeTransaction = ProductCatalog.FeeTypeTransaction
+const FeeTypeUnarrangedOverdraft = ProductCatalog.FeeTypeUnarrangedOverdraft
+
+const ProductStandardChecking = ProductCatalog.StandardChecking
+
+// FeeSchedule: fee schedules are part of each checking product in the product catalog
+type FeeSchedule = ProductCatalog.FeeSchedule
+
+type chargedFee struct {
+	feeType  string
+	amount   int
+	refunded bool
+}
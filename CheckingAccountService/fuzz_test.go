@@ -0,0 +1,64 @@
+package CheckingAccountService
+
+import (
+	"encoding/json"
+	"github.com/agemmell/banking-cqrs-es-go/EventRegistry"
+	"github.com/agemmell/banking-cqrs-es-go/Seacrest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func FuzzTransformEnvelopeToEvent(f *testing.F) {
+	for _, eventType := range EventRegistry.Default.EventTypes() {
+		f.Add(eventType, []byte(`{}`))
+		f.Add(eventType, []byte(`null`))
+	}
+	clock := Seacrest.NewFakeClock(time.Date(2021, time.January, 1, 9, 0, 0, 0, time.UTC))
+	eventStore := Seacrest.NewEventStore(Seacrest.WithClock(clock))
+	cas := New(eventStore, WithClock(clock), WithCustomerVerifier(verifiedCustomers{}))
+	for _, command := range []Command{
+		OpenAccount{ID: "ABCD", CustomerID: "CUST1"},
+		DepositMoney{ID: "ABCD", Amount: 100, ActingAs: SystemPrincipal},
+		WithdrawMoney{ID: "ABCD", Amount: 30, ActingAs: SystemPrincipal},
+		PlaceHold{ID: "ABCD", HoldID: "H1", Amount: 10, ExpiresAt: clock.Now().Add(time.Hour).UnixNano(), ActingAs: SystemPrincipal},
+		CaptureHold{ID: "ABCD", HoldID: "H1", Amount: 10, ActingAs: SystemPrincipal},
+		FreezeAccount{ID: "ABCD", Scope: FreezeDebits, ReasonCode: ReasonSuspectedFraud, ActingAs: SystemPrincipal},
+		UnfreezeAccount{ID: "ABCD", ReasonCode: ReasonReviewCompleted, ActingAs: SystemPrincipal},
+		WithdrawMoney{ID: "ABCD", Amount: 60, ActingAs: SystemPrincipal},
+		CloseAccount{ID: "ABCD", ActingAs: SystemPrincipal},
+	} {
+		err := cas.HandleCommand(command)
+		if err != nil {
+			f.Fatalf("seed command %+v failed: %s", command, err)
+		}
+	}
+	for _, envelope := range eventStore.GetAllEvents() {
+		f.Add(envelope.EventType, envelope.Payload)
+	}
+
+	f.Fuzz(func(t *testing.T, eventType string, payload []byte) {
+		envelope := Seacrest.EventEnvelope{EventID: "1", Order: 1, AggregateID: "ABCD", EventType: eventType, Payload: payload}
+		event, err := cas.TransformEnvelopeToEvent(envelope)
+		if err != nil {
+			return
+		}
+		if event.EventType() != eventType {
+			t.Fatalf("%s envelope decoded to a %s event", eventType, event.EventType())
+		}
+
+		// whatever was decoded must come back the same after being persisted again
+		encoded, err := json.Marshal(event)
+		if err != nil {
+			t.Fatalf("decoded event %+v cannot be encoded: %s", event, err)
+		}
+		envelope.Payload = encoded
+		decoded, err := cas.TransformEnvelopeToEvent(envelope)
+		if err != nil {
+			t.Fatalf("re-encoded event %s cannot be decoded: %s", encoded, err)
+		}
+		if !reflect.DeepEqual(event, decoded) {
+			t.Fatalf("event changed on the way through the event store: %+v became %+v", event, decoded)
+		}
+	})
+}
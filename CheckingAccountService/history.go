@@ -0,0 +1,85 @@
+package CheckingAccountService
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// AsOf: a point in the event history to query at. Set exactly one of Timestamp or Position.
+type AsOf struct {
+	Timestamp int64 // include events that happened at or before this time (unix nanoseconds)
+	Position  uint  // include events persisted at or before this global position
+}
+
+// AsOfTime: a point in history at a moment in business time
+func AsOfTime(moment time.Time) AsOf {
+	return AsOf{Timestamp: moment.UnixNano()}
+}
+
+// AsOfPosition: a point in history after the event persisted at a global position
+func AsOfPosition(position uint) AsOf {
+	return AsOf{Position: position}
+}
+
+func (ao AsOf) Validate() error {
+	if (ao.Timestamp == 0) == (ao.Position == 0) {
+		return errors.New(fmt.Sprintf("a point in history needs either a Timestamp or a Position [Timestamp: %d, Position: %d]", ao.Timestamp, ao.Position))
+	}
+	return nil
+}
+
+func (ao AsOf) String() string {
+	if ao.Position != 0 {
+		return fmt.Sprintf("event %d", ao.Position)
+	}
+	return time.Unix(0, ao.Timestamp).UTC().Format(time.RFC3339)
+}
+
+// GetAccountSummaryAsOf: an account's state at a point in its history, as seen by a principal who had permission to
+// view it then. Only the account's own events are replayed, and only up to the point asked about.
+func (cas *CheckingAccountService) GetAccountSummaryAsOf(aggregateID string, actingAs string, asOf AsOf) (AccountSummary, error) {
+	err := asOf.Validate()
+	if err != nil {
+		return AccountSummary{}, err
+	}
+
+	envelopes := cas.eventStore.GetEventsByAggregateID(aggregateID)
+	var events []Event
+	now := asOf.Timestamp
+	// an account's events happen in version order, so its history ends at the first event after the point asked about
+	for version := uint(0); version < uint(len(envelopes)); version++ {
+		envelope, ok := envelopes[version]
+		if !ok {
+			return AccountSummary{}, errors.New(fmt.Sprintf("missing event version %d for aggregate %s", version, aggregateID))
+		}
+		if asOf.Position != 0 && envelope.Order > asOf.Position {
+			break
+		}
+		event, err := cas.TransformEnvelopeToEvent(envelope)
+		if err != nil {
+			return AccountSummary{}, err
+		}
+		if asOf.Timestamp != 0 && event.EventTimestamp() > asOf.Timestamp {
+			break
+		}
+		if asOf.Position != 0 {
+			now = event.EventTimestamp()
+		}
+		events = append(events, event)
+	}
+
+	account, err := cas.rebuildAccount(events)
+	if err != nil {
+		return AccountSummary{}, err
+	}
+	if account.Status() == AccountPending {
+		return AccountSummary{}, errors.New(fmt.Sprintf("account %s did not exist as of %s", aggregateID, asOf))
+	}
+	err = account.Authorize(actingAs, PermissionView)
+	if err != nil {
+		return AccountSummary{}, err
+	}
+
+	return summarise(account, now), nil
+}
@@ -0,0 +1,45 @@
+package CheckingAccountService
+
+import (
+	"github.com/agemmell/banking-cqrs-es-go/Seacrest"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_GetAccountSummaryAsOf(t *testing.T) {
+	t.Parallel()
+
+	// Given an account opened in June, paid into on the 30th and withdrawn from in July
+	clock := Seacrest.NewFakeClock(time.Date(2020, time.June, 1, 9, 0, 0, 0, time.UTC))
+	eventStore := Seacrest.NewEventStore(Seacrest.WithClock(clock))
+	checkingAccountService := New(eventStore, WithClock(clock), WithCustomerVerifier(verifiedCustomers{}))
+	assert.Nil(t, checkingAccountService.HandleCommand(OpenAccount{ID: "ABCD", CustomerID: "CUST1"}))
+	clock.Set(time.Date(2020, time.June, 30, 12, 0, 0, 0, time.UTC))
+	assert.Nil(t, checkingAccountService.HandleCommand(DepositMoney{ID: "ABCD", Amount: 1000, ActingAs: SystemPrincipal}))
+	assert.Nil(t, checkingAccountService.HandleCommand(PlaceHold{ID: "ABCD", HoldID: "H1", Amount: 200, ExpiresAt: time.Date(2020, time.July, 5, 0, 0, 0, 0, time.UTC).UnixNano(), ActingAs: SystemPrincipal}))
+	clock.Set(time.Date(2020, time.July, 2, 12, 0, 0, 0, time.UTC))
+	assert.Nil(t, checkingAccountService.HandleCommand(WithdrawMoney{ID: "ABCD", Amount: 300, ActingAs: SystemPrincipal}))
+
+	// When
+	endOfJune, endOfJuneErr := checkingAccountService.GetAccountSummaryAsOf("ABCD", "CUST1", AsOfTime(time.Date(2020, time.June, 30, 23, 59, 0, 0, time.UTC)))
+	afterDeposit, afterDepositErr := checkingAccountService.GetAccountSummaryAsOf("ABCD", SystemPrincipal, AsOfPosition(2))
+	beforeOpening, beforeOpeningErr := checkingAccountService.GetAccountSummaryAsOf("ABCD", SystemPrincipal, AsOfTime(time.Date(2020, time.May, 31, 0, 0, 0, 0, time.UTC)))
+	_, strangerErr := checkingAccountService.GetAccountSummaryAsOf("ABCD", "CUST9", AsOfPosition(1))
+	_, invalidErr := checkingAccountService.GetAccountSummaryAsOf("ABCD", SystemPrincipal, AsOf{})
+	now, nowErr := checkingAccountService.GetAccountSummary("ABCD", SystemPrincipal)
+
+	// Then
+	assert.Nil(t, endOfJuneErr)
+	assert.Equal(t, 1000, endOfJune.LedgerBalance)
+	assert.Equal(t, 800, endOfJune.AvailableBalance)
+	assert.Nil(t, afterDepositErr)
+	assert.Equal(t, 1000, afterDeposit.LedgerBalance)
+	assert.Equal(t, 1000, afterDeposit.AvailableBalance)
+	assert.Equal(t, AccountSummary{}, beforeOpening)
+	assert.Equal(t, "account ABCD did not exist as of 2020-05-31T00:00:00Z", beforeOpeningErr.Error())
+	assert.Equal(t, "CUST9 does not have the View permission on account ABCD", strangerErr.Error())
+	assert.Equal(t, "a point in history needs either a Timestamp or a Position [Timestamp: 0, Position: 0]", invalidErr.Error())
+	assert.Nil(t, nowErr)
+	assert.Equal(t, 700, now.LedgerBalance)
+}
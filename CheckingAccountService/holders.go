@@ -0,0 +1,217 @@
+package CheckingAccountService
+
+import (
+	"errors"
+	"fmt"
+)
+
+// SystemPrincipal: the bank itself, acting on its own behalf, e.g. when it charges a fee or makes a standing order
+// payment. It holds every permission, and cannot be made a holder or signatory of an account.
+const SystemPrincipal = "system"
+
+// Permissions that can be granted to an authorized signatory. Account holders hold every permission but
+// PermissionOperate.
+const PermissionView = "View"
+const PermissionDeposit = "Deposit"
+const PermissionWithdraw = "Withdraw"
+const PermissionClose = "Close"
+
+// PermissionManageHolders is reserved for account holders and cannot be granted to a signatory
+const PermissionManageHolders = "ManageHolders"
+
+// PermissionOperate is reserved for the bank: charging and refunding fees, placing and settling holds, freezing
+// accounts, making standing order payments and marking accounts dormant
+const PermissionOperate = "Operate"
+
+// signatory: a person allowed to act on an account without being one of its holders
+type signatory struct {
+	name        string
+	permissions map[string]bool
+}
+
+// Holders: the names of everyone who holds the account, keyed by their principal ID
+func (a *Account) Holders() map[string]string {
+	holders := make(map[string]string, len(a.holders))
+	for holderID, name := range a.holders {
+		holders[holderID] = name
+	}
+	return holders
+}
+
+// Authorize: check that a principal may act on the account. Every command must name the principal it is acting as;
+// the bank acts as SystemPrincipal.
+func (a *Account) Authorize(principal string, permission string) error {
+	if principal == "" {
+		return errors.New(fmt.Sprintf("no principal is acting on account %s [permission: %s]", a.id, permission))
+	}
+	if principal == SystemPrincipal {
+		return nil
+	}
+	if permission == PermissionOperate {
+		return errors.New(fmt.Sprintf("only the bank has the %s permission on account %s", permission, a.id))
+	}
+	if _, ok := a.holders[principal]; ok {
+		return nil
+	}
+	if signatory, ok := a.signatories[principal]; ok && signatory.permissions[permission] {
+		return nil
+	}
+	return errors.New(fmt.Sprintf("%s does not have the %s permission on account %s", principal, permission, a.id))
+}
+
+// ChangeHolderName: correct or change the name of one of the account holders
+func (a *Account) ChangeHolderName(holderID string, name string, changedBy string) error {
+
+	err := a.ensureAllowed(actionManageHolders)
+	if err != nil {
+		return err
+	}
+
+	if holderID == "" {
+		holderID = a.primaryHolderID
+	}
+
+	previousName, ok := a.holders[holderID]
+	if !ok {
+		return errors.New(fmt.Sprintf("%s is not a holder of account %s", holderID, a.id))
+	}
+
+	if name == "" || name == previousName {
+		return errors.New(fmt.Sprintf("holder name must be changed to a new, non-empty name [Name: %s]", name))
+	}
+
+	event := AccountHolderWasRenamed{
+		ID:           a.id,
+		HolderID:     holderID,
+		Name:         name,
+		PreviousName: previousName,
+		ChangedBy:    changedBy,
+		Timestamp:    a.now(),
+	}
+
+	return a.raiseEvent(&event)
+}
+
+// AddJointHolder: add another holder who has the same rights over the account as the primary holder
+func (a *Account) AddJointHolder(holderID string, name string, changedBy string) error {
+
+	err := a.ensureAllowed(actionManageHolders)
+	if err != nil {
+		return err
+	}
+
+	if holderID == "" || name == "" {
+		return errors.New("a joint holder requires an ID and a name")
+	}
+
+	if holderID == SystemPrincipal {
+		return errors.New(fmt.Sprintf("the bank cannot be a holder of account %s", a.id))
+	}
+
+	if _, ok := a.holders[holderID]; ok {
+		return errors.New(fmt.Sprintf("%s is already a holder of account %s", holderID, a.id))
+	}
+
+	event := JointHolderWasAdded{
+		ID:        a.id,
+		HolderID:  holderID,
+		Name:      name,
+		ChangedBy: changedBy,
+		Timestamp: a.now(),
+	}
+
+	return a.raiseEvent(&event)
+}
+
+// RemoveJointHolder: remove a joint holder; the primary holder can never be removed
+func (a *Account) RemoveJointHolder(holderID string, changedBy string) error {
+
+	err := a.ensureAllowed(actionManageHolders)
+	if err != nil {
+		return err
+	}
+
+	if holderID == a.primaryHolderID {
+		return errors.New(fmt.Sprintf("cannot remove the primary holder of account %s", a.id))
+	}
+
+	if _, ok := a.holders[holderID]; !ok {
+		return errors.New(fmt.Sprintf("%s is not a holder of account %s", holderID, a.id))
+	}
+
+	event := JointHolderWasRemoved{
+		ID:        a.id,
+		HolderID:  holderID,
+		ChangedBy: changedBy,
+		Timestamp: a.now(),
+	}
+
+	return a.raiseEvent(&event)
+}
+
+// AddAuthorizedSignatory: allow someone who is not a holder to act on the account with the given permissions
+func (a *Account) AddAuthorizedSignatory(signatoryID string, name string, permissions []string, changedBy string) error {
+
+	err := a.ensureAllowed(actionManageHolders)
+	if err != nil {
+		return err
+	}
+
+	if signatoryID == "" || name == "" {
+		return errors.New("an authorized signatory requires an ID and a name")
+	}
+
+	if signatoryID == SystemPrincipal {
+		return errors.New(fmt.Sprintf("the bank cannot be a signatory of account %s", a.id))
+	}
+
+	if _, ok := a.holders[signatoryID]; ok {
+		return errors.New(fmt.Sprintf("%s is already a holder of account %s", signatoryID, a.id))
+	}
+
+	if _, ok := a.signatories[signatoryID]; ok {
+		return errors.New(fmt.Sprintf("%s is already a signatory of account %s", signatoryID, a.id))
+	}
+
+	if len(permissions) == 0 {
+		return errors.New("an authorized signatory requires at least one permission")
+	}
+	for _, permission := range permissions {
+		if permission != PermissionView && permission != PermissionDeposit && permission != PermissionWithdraw && permission != PermissionClose {
+			return errors.New(fmt.Sprintf("the %s permission cannot be granted to a signatory", permission))
+		}
+	}
+
+	event := AuthorizedSignatoryWasAdded{
+		ID:          a.id,
+		SignatoryID: signatoryID,
+		Name:        name,
+		Permissions: permissions,
+		ChangedBy:   changedBy,
+		Timestamp:   a.now(),
+	}
+
+	return a.raiseEvent(&event)
+}
+
+// RemoveAuthorizedSignatory: revoke all of a signatory's permissions
+func (a *Account) RemoveAuthorizedSignatory(signatoryID string, changedBy string) error {
+
+	err := a.ensureAllowed(actionManageHolders)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := a.signatories[signatoryID]; !ok {
+		return errors.New(fmt.Sprintf("%s is not a signatory of account %s", signatoryID, a.id))
+	}
+
+	event := AuthorizedSignatoryWasRemoved{
+		ID:          a.id,
+		SignatoryID: signatoryID,
+		ChangedBy:   changedBy,
+		Timestamp:   a.now(),
+	}
+
+	return a.raiseEvent(&event)
+}
@@ -0,0 +1,15 @@
+package CheckingAccountService
+
+// Hold release reasons are recorded on HoldWasReleased events
+const HoldReleasedByMerchant = "ReleasedByMerchant"
+const HoldExpired = "Expired"
+
+// hold: funds reserved by a card authorization until it is captured, released or expires
+type hold struct {
+	amount    int
+	expiresAt int64
+}
+
+func (h hold) hasExpired(now int64) bool {
+	return h.expiresAt > 0 && now >= h.expiresAt
+}
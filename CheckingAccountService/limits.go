@@ -0,0 +1,80 @@
+package CheckingAccountService
+
+import (
+	"fmt"
+	"time"
+)
+
+// Withdrawal limits are recorded on WithdrawalLimitExceededError
+const LimitPerTransaction = "PerTransaction"
+const LimitDaily = "Daily"
+
+// The rolling window the daily withdrawal limit applies to
+const dailyLimitWindow = int64(24 * time.Hour)
+
+// PermissionChangeLimits is reserved for account holders and cannot be granted to a signatory
+const PermissionChangeLimits = "ChangeLimits"
+
+// WithdrawalLimitExceededError: a withdrawal was rejected because it would exceed one of the account's limits
+type WithdrawalLimitExceededError struct {
+	AccountID        string
+	Limit            string
+	LimitAmount      int
+	Amount           int
+	AlreadyWithdrawn int
+}
+
+func (e *WithdrawalLimitExceededError) Error() string {
+	return fmt.Sprintf("withdrawal of %d exceeds the %s limit of %d on account %s [already withdrawn: %d]",
+		e.Amount, e.Limit, e.LimitAmount, e.AccountID, e.AlreadyWithdrawn)
+}
+
+// withdrawal: a past withdrawal that may still count towards the daily limit
+type withdrawal struct {
+	amount    int
+	timestamp int64
+}
+
+// recordWithdrawal: remember a withdrawal or captured hold, forgetting any that can no longer fall within the daily
+// window. Events are applied in order so nothing before the latest withdrawal's window will ever be needed again.
+func (a *Account) recordWithdrawal(amount int, timestamp int64) {
+	var recent []withdrawal
+	for _, past := range a.recentWithdrawals {
+		if past.timestamp > timestamp-dailyLimitWindow {
+			recent = append(recent, past)
+		}
+	}
+	a.recentWithdrawals = append(recent, withdrawal{amount: amount, timestamp: timestamp})
+}
+
+// ensureWithinWithdrawalLimits: reject a withdrawal that breaks the per-transaction or rolling 24 hour limit
+func (a *Account) ensureWithinWithdrawalLimits(amount int, now int64) error {
+	if a.perTransactionLimit > 0 && amount > a.perTransactionLimit {
+		return &WithdrawalLimitExceededError{
+			AccountID:   a.id,
+			Limit:       LimitPerTransaction,
+			LimitAmount: a.perTransactionLimit,
+			Amount:      amount,
+		}
+	}
+
+	if a.dailyLimit > 0 {
+		alreadyWithdrawn := 0
+		for _, past := range a.recentWithdrawals {
+			if past.timestamp > now-dailyLimitWindow && past.timestamp <= now {
+				alreadyWithdrawn += past.amount
+			}
+		}
+		if alreadyWithdrawn+amount > a.dailyLimit {
+			return &WithdrawalLimitExceededError{
+				AccountID:        a.id,
+				Limit:            LimitDaily,
+				LimitAmount:      a.dailyLimit,
+				Amount:           amount,
+				AlreadyWithdrawn: alreadyWithdrawn,
+			}
+		}
+	}
+
+	return nil
+}
@@ -1,44 +1,238 @@
 package CheckingAccountService
 
+import (
+	"github.com/agemmell/banking-cqrs-es-go/EventRegistry"
+	"time"
+)
+
 // Commands
 
 type OpenAccount struct {
-	ID   string
-	Name string
+	ID         string
+	CustomerID string // the verified customer who will be the account's primary holder, in their registered name
+	Product    string
 }
 type DepositMoney struct {
-	ID     string
-	Amount int
+	ID       string
+	Amount   int
+	ActingAs string
 }
 type WithdrawMoney struct {
-	ID     string
-	Amount int
+	ID       string
+	Amount   int
+	ActingAs string
 }
 type CloseAccount struct {
-	ID string
+	ID       string
+	ActingAs string
+}
+type ChargeFee struct {
+	ID       string
+	FeeType  string
+	ActingAs string
+}
+type RefundFee struct {
+	ID       string
+	FeeID    string
+	ActingAs string
+}
+type PlaceHold struct {
+	ID        string
+	HoldID    string
+	Amount    int
+	ExpiresAt int64
+	ActingAs  string
+}
+type ReleaseHold struct {
+	ID       string
+	HoldID   string
+	ActingAs string
+}
+type CaptureHold struct {
+	ID       string
+	HoldID   string
+	Amount   int
+	ActingAs string
+}
+type ExpireHolds struct {
+	ID       string
+	ActingAs string
+}
+type FreezeAccount struct {
+	ID         string
+	Scope      string
+	ReasonCode string
+	ActingAs   string
+}
+type UnfreezeAccount struct {
+	ID         string
+	ReasonCode string
+	ActingAs   string
+}
+type ChangeHolderName struct {
+	ID       string
+	HolderID string
+	Name     string
+	ActingAs string
+}
+type AddJointHolder struct {
+	ID       string
+	HolderID string
+	Name     string
+	ActingAs string
+}
+type RemoveJointHolder struct {
+	ID       string
+	HolderID string
+	ActingAs string
+}
+type AddAuthorizedSignatory struct {
+	ID          string
+	SignatoryID string
+	Name        string
+	Permissions []string
+	ActingAs    string
+}
+type RemoveAuthorizedSignatory struct {
+	ID          string
+	SignatoryID string
+	ActingAs    string
+}
+type CreateStandingOrder struct {
+	ID               string
+	StandingOrderID  string
+	PayeeAccountID   string
+	Amount           int
+	Frequency        string
+	FirstPaymentDate int64
+	ActingAs         string
+}
+type AmendStandingOrder struct {
+	ID              string
+	StandingOrderID string
+	PayeeAccountID  string
+	Amount          int
+	ActingAs        string
+}
+type CancelStandingOrder struct {
+	ID              string
+	StandingOrderID string
+	ActingAs        string
+}
+type MakeStandingOrderPayment struct {
+	ID              string
+	StandingOrderID string
+	PaymentDate     int64
+	ActingAs        string
+}
+type SkipStandingOrderPayment struct {
+	ID              string
+	StandingOrderID string
+	PaymentDate     int64
+	ActingAs        string
+}
+type ReturnStandingOrderPayment struct {
+	ID              string
+	StandingOrderID string
+	Amount          int
+	PaymentDate     int64
+	ActingAs        string
+}
+type SetWithdrawalLimits struct {
+	ID                  string
+	PerTransactionLimit int
+	DailyLimit          int
+	ActingAs            string
+}
+type ReopenAccount struct {
+	ID       string
+	ActingAs string
+}
+type MarkAccountDormant struct {
+	ID               string
+	InactivityPeriod time.Duration
+	ActingAs         string
 }
 
-func (c OpenAccount) isCommand()   {}
-func (c DepositMoney) isCommand()  {}
-func (c WithdrawMoney) isCommand() {}
-func (c CloseAccount) isCommand()  {}
+func (c OpenAccount) isCommand()                {}
+func (c DepositMoney) isCommand()               {}
+func (c WithdrawMoney) isCommand()              {}
+func (c CloseAccount) isCommand()               {}
+func (c ChargeFee) isCommand()                  {}
+func (c RefundFee) isCommand()                  {}
+func (c PlaceHold) isCommand()                  {}
+func (c ReleaseHold) isCommand()                {}
+func (c CaptureHold) isCommand()                {}
+func (c ExpireHolds) isCommand()                {}
+func (c FreezeAccount) isCommand()              {}
+func (c UnfreezeAccount) isCommand()            {}
+func (c ChangeHolderName) isCommand()           {}
+func (c AddJointHolder) isCommand()             {}
+func (c RemoveJointHolder) isCommand()          {}
+func (c AddAuthorizedSignatory) isCommand()     {}
+func (c RemoveAuthorizedSignatory) isCommand()  {}
+func (c SetWithdrawalLimits) isCommand()        {}
+func (c CreateStandingOrder) isCommand()        {}
+func (c AmendStandingOrder) isCommand()         {}
+func (c CancelStandingOrder) isCommand()        {}
+func (c MakeStandingOrderPayment) isCommand()   {}
+func (c SkipStandingOrderPayment) isCommand()   {}
+func (c ReturnStandingOrderPayment) isCommand() {}
+func (c ReopenAccount) isCommand()              {}
+func (c MarkAccountDormant) isCommand()         {}
 
 // Events
 
+func init() {
+	EventRegistry.Register(func() EventRegistry.Event { return &AccountWasOpened{} })
+	EventRegistry.Register(func() EventRegistry.Event { return &MoneyWasDeposited{} })
+	EventRegistry.Register(func() EventRegistry.Event { return &MoneyWasWithdrawn{} })
+	EventRegistry.Register(func() EventRegistry.Event { return &WithdrawFailedDueToInsufficientFunds{} })
+	EventRegistry.Register(func() EventRegistry.Event { return &AccountWasClosed{} })
+	EventRegistry.Register(func() EventRegistry.Event { return &FeeWasCharged{} })
+	EventRegistry.Register(func() EventRegistry.Event { return &FeeWasWaived{} })
+	EventRegistry.Register(func() EventRegistry.Event { return &FeeWasRefunded{} })
+	EventRegistry.Register(func() EventRegistry.Event { return &AccountWasFrozen{} })
+	EventRegistry.Register(func() EventRegistry.Event { return &AccountWasUnfrozen{} })
+	EventRegistry.Register(func() EventRegistry.Event { return &AccountHolderWasRenamed{} })
+	EventRegistry.Register(func() EventRegistry.Event { return &JointHolderWasAdded{} })
+	EventRegistry.Register(func() EventRegistry.Event { return &JointHolderWasRemoved{} })
+	EventRegistry.Register(func() EventRegistry.Event { return &AuthorizedSignatoryWasAdded{} })
+	EventRegistry.Register(func() EventRegistry.Event { return &AuthorizedSignatoryWasRemoved{} })
+	EventRegistry.Register(func() EventRegistry.Event { return &WithdrawalLimitsWereChanged{} })
+	EventRegistry.Register(func() EventRegistry.Event { return &StandingOrderWasCreated{} })
+	EventRegistry.Register(func() EventRegistry.Event { return &StandingOrderWasAmended{} })
+	EventRegistry.Register(func() EventRegistry.Event { return &StandingOrderWasCancelled{} })
+	EventRegistry.Register(func() EventRegistry.Event { return &StandingOrderPaymentWasMade{} })
+	EventRegistry.Register(func() EventRegistry.Event { return &StandingOrderPaymentFailed{} })
+	EventRegistry.Register(func() EventRegistry.Event { return &StandingOrderPaymentWasSkipped{} })
+	EventRegistry.Register(func() EventRegistry.Event { return &StandingOrderPaymentWasReturned{} })
+	EventRegistry.Register(func() EventRegistry.Event { return &HoldWasPlaced{} })
+	EventRegistry.Register(func() EventRegistry.Event { return &HoldWasReleased{} })
+	EventRegistry.Register(func() EventRegistry.Event { return &HoldWasCaptured{} })
+	EventRegistry.Register(func() EventRegistry.Event { return &AccountWasReopened{} })
+	EventRegistry.Register(func() EventRegistry.Event { return &AccountBecameDormant{} })
+	EventRegistry.Register(func() EventRegistry.Event { return &AccountWasReactivated{} })
+}
+
 type AccountWasOpened struct {
 	ID        string
+	HolderID  string // the customer ID of the primary holder
 	Name      string
+	Product   string
 	Timestamp int64
 }
 type MoneyWasDeposited struct {
 	ID        string
 	Amount    int
+	ChangedBy string // SystemPrincipal when the bank made the deposit, e.g. a standing order payment from another account
 	Timestamp int64
 }
 type MoneyWasWithdrawn struct {
 	ID        string
 	Amount    int
 	Balance   int
+	ChangedBy string // SystemPrincipal when the bank made the withdrawal, e.g. a standing order payment
 	Timestamp int64
 }
 type WithdrawFailedDueToInsufficientFunds struct {
@@ -51,6 +245,176 @@ type AccountWasClosed struct {
 	ID        string
 	Timestamp int64
 }
+type FeeWasCharged struct {
+	ID        string
+	FeeID     string
+	FeeType   string
+	Amount    int
+	Balance   int
+	Timestamp int64
+}
+type FeeWasWaived struct {
+	ID        string
+	FeeType   string
+	Amount    int
+	Timestamp int64
+}
+type FeeWasRefunded struct {
+	ID        string
+	FeeID     string
+	FeeType   string
+	Amount    int
+	Balance   int
+	Timestamp int64
+}
+type AccountWasFrozen struct {
+	ID         string
+	Scope      string
+	ReasonCode string
+	Timestamp  int64
+}
+type AccountWasUnfrozen struct {
+	ID         string
+	ReasonCode string
+	Timestamp  int64
+}
+type AccountHolderWasRenamed struct {
+	ID           string
+	HolderID     string
+	Name         string
+	PreviousName string
+	ChangedBy    string
+	Timestamp    int64
+}
+type JointHolderWasAdded struct {
+	ID        string
+	HolderID  string
+	Name      string
+	ChangedBy string
+	Timestamp int64
+}
+type JointHolderWasRemoved struct {
+	ID        string
+	HolderID  string
+	ChangedBy string
+	Timestamp int64
+}
+type AuthorizedSignatoryWasAdded struct {
+	ID          string
+	SignatoryID string
+	Name        string
+	Permissions []string
+	ChangedBy   string
+	Timestamp   int64
+}
+type AuthorizedSignatoryWasRemoved struct {
+	ID          string
+	SignatoryID string
+	ChangedBy   string
+	Timestamp   int64
+}
+type WithdrawalLimitsWereChanged struct {
+	ID                  string
+	PerTransactionLimit int
+	DailyLimit          int
+	ChangedBy           string
+	Timestamp           int64
+}
+type StandingOrderWasCreated struct {
+	ID               string
+	StandingOrderID  string
+	PayeeAccountID   string
+	Amount           int
+	Frequency        string
+	FirstPaymentDate int64
+	ChangedBy        string
+	Timestamp        int64
+}
+type StandingOrderWasAmended struct {
+	ID              string
+	StandingOrderID string
+	PayeeAccountID  string
+	Amount          int
+	ChangedBy       string
+	Timestamp       int64
+}
+type StandingOrderWasCancelled struct {
+	ID              string
+	StandingOrderID string
+	ChangedBy       string
+	Timestamp       int64
+}
+type StandingOrderPaymentWasMade struct {
+	ID              string
+	StandingOrderID string
+	PayeeAccountID  string
+	Amount          int
+	PaymentDate     int64
+	NextPaymentDate int64
+	Timestamp       int64
+}
+type StandingOrderPaymentFailed struct {
+	ID              string
+	StandingOrderID string
+	Amount          int
+	Balance         int
+	PaymentDate     int64
+	Attempt         int
+	Timestamp       int64
+}
+type StandingOrderPaymentWasSkipped struct {
+	ID              string
+	StandingOrderID string
+	PaymentDate     int64
+	FailedAttempts  int
+	NextPaymentDate int64
+	Timestamp       int64
+}
+type StandingOrderPaymentWasReturned struct {
+	ID              string
+	StandingOrderID string
+	Amount          int
+	Balance         int
+	PaymentDate     int64
+	Attempt         int
+	Timestamp       int64
+}
+type HoldWasPlaced struct {
+	ID        string
+	HoldID    string
+	Amount    int
+	ExpiresAt int64
+	Timestamp int64
+}
+type HoldWasReleased struct {
+	ID        string
+	HoldID    string
+	Amount    int
+	Reason    string
+	Timestamp int64
+}
+type HoldWasCaptured struct {
+	ID         string
+	HoldID     string
+	HeldAmount int
+	Amount     int
+	Balance    int
+	Timestamp  int64
+}
+type AccountWasReopened struct {
+	ID         string
+	ReopenedBy string
+	Timestamp  int64
+}
+type AccountBecameDormant struct {
+	ID             string
+	LastActivityAt int64
+	Timestamp      int64
+}
+type AccountWasReactivated struct {
+	ID        string
+	Timestamp int64
+}
 
 func (e AccountWasOpened) AggregateID() string {
 	return e.ID
@@ -67,12 +431,108 @@ func (e WithdrawFailedDueToInsufficientFunds) AggregateID() string {
 func (e AccountWasClosed) AggregateID() string {
 	return e.ID
 }
+func (e FeeWasCharged) AggregateID() string {
+	return e.ID
+}
+func (e FeeWasWaived) AggregateID() string {
+	return e.ID
+}
+func (e FeeWasRefunded) AggregateID() string {
+	return e.ID
+}
+func (e AccountWasFrozen) AggregateID() string {
+	return e.ID
+}
+func (e AccountWasUnfrozen) AggregateID() string {
+	return e.ID
+}
+func (e AccountHolderWasRenamed) AggregateID() string {
+	return e.ID
+}
+func (e JointHolderWasAdded) AggregateID() string {
+	return e.ID
+}
+func (e JointHolderWasRemoved) AggregateID() string {
+	return e.ID
+}
+func (e AuthorizedSignatoryWasAdded) AggregateID() string {
+	return e.ID
+}
+func (e AuthorizedSignatoryWasRemoved) AggregateID() string {
+	return e.ID
+}
+func (e WithdrawalLimitsWereChanged) AggregateID() string {
+	return e.ID
+}
+func (e StandingOrderWasCreated) AggregateID() string {
+	return e.ID
+}
+func (e StandingOrderWasAmended) AggregateID() string {
+	return e.ID
+}
+func (e StandingOrderWasCancelled) AggregateID() string {
+	return e.ID
+}
+func (e StandingOrderPaymentWasMade) AggregateID() string {
+	return e.ID
+}
+func (e StandingOrderPaymentFailed) AggregateID() string {
+	return e.ID
+}
+func (e StandingOrderPaymentWasSkipped) AggregateID() string {
+	return e.ID
+}
+func (e StandingOrderPaymentWasReturned) AggregateID() string {
+	return e.ID
+}
+func (e HoldWasPlaced) AggregateID() string {
+	return e.ID
+}
+func (e HoldWasReleased) AggregateID() string {
+	return e.ID
+}
+func (e HoldWasCaptured) AggregateID() string {
+	return e.ID
+}
+func (e AccountWasReopened) AggregateID() string {
+	return e.ID
+}
+func (e AccountBecameDormant) AggregateID() string {
+	return e.ID
+}
+func (e AccountWasReactivated) AggregateID() string {
+	return e.ID
+}
 
 const TypeAccountWasOpened = "AccountWasOpened"
 const TypeMoneyWasDeposited = "MoneyWasDeposited"
 const TypeMoneyWasWithdrawn = "MoneyWasWithdrawn"
 const TypeWithdrawFailedDueToInsufficientFunds = "WithdrawFailedDueToInsufficientFunds"
 const TypeAccountWasClosed = "AccountWasClosed"
+const TypeFeeWasCharged = "FeeWasCharged"
+const TypeFeeWasWaived = "FeeWasWaived"
+const TypeFeeWasRefunded = "FeeWasRefunded"
+const TypeAccountWasFrozen = "AccountWasFrozen"
+const TypeAccountWasUnfrozen = "AccountWasUnfrozen"
+const TypeAccountHolderWasRenamed = "AccountHolderWasRenamed"
+const TypeJointHolderWasAdded = "JointHolderWasAdded"
+const TypeJointHolderWasRemoved = "JointHolderWasRemoved"
+const TypeAuthorizedSignatoryWasAdded = "AuthorizedSignatoryWasAdded"
+const TypeAuthorizedSignatoryWasRemoved = "AuthorizedSignatoryWasRemoved"
+const TypeWithdrawalLimitsWereChanged = "WithdrawalLimitsWereChanged"
+const TypeStandingOrderWasCreated = "StandingOrderWasCreated"
+const TypeStandingOrderWasAmended = "StandingOrderWasAmended"
+const TypeStandingOrderWasCancelled = "StandingOrderWasCancelled"
+const TypeStandingOrderPaymentWasMade = "StandingOrderPaymentWasMade"
+const TypeStandingOrderPaymentFailed = "StandingOrderPaymentFailed"
+const TypeStandingOrderPaymentWasSkipped = "StandingOrderPaymentWasSkipped"
+const TypeStandingOrderPaymentWasReturned = "StandingOrderPaymentWasReturned"
+const TypeHoldWasPlaced = "HoldWasPlaced"
+const TypeHoldWasReleased = "HoldWasReleased"
+const TypeHoldWasCaptured = "HoldWasCaptured"
+const TypeAccountWasReopened = "AccountWasReopened"
+const TypeAccountBecameDormant = "AccountBecameDormant"
+const TypeAccountWasReactivated = "AccountWasReactivated"
 
 func (e AccountWasOpened) EventType() string {
 	return TypeAccountWasOpened
@@ -89,6 +549,78 @@ func (e WithdrawFailedDueToInsufficientFunds) EventType() string {
 func (e AccountWasClosed) EventType() string {
 	return TypeAccountWasClosed
 }
+func (e FeeWasCharged) EventType() string {
+	return TypeFeeWasCharged
+}
+func (e FeeWasWaived) EventType() string {
+	return TypeFeeWasWaived
+}
+func (e FeeWasRefunded) EventType() string {
+	return TypeFeeWasRefunded
+}
+func (e AccountWasFrozen) EventType() string {
+	return TypeAccountWasFrozen
+}
+func (e AccountWasUnfrozen) EventType() string {
+	return TypeAccountWasUnfrozen
+}
+func (e AccountHolderWasRenamed) EventType() string {
+	return TypeAccountHolderWasRenamed
+}
+func (e JointHolderWasAdded) EventType() string {
+	return TypeJointHolderWasAdded
+}
+func (e JointHolderWasRemoved) EventType() string {
+	return TypeJointHolderWasRemoved
+}
+func (e AuthorizedSignatoryWasAdded) EventType() string {
+	return TypeAuthorizedSignatoryWasAdded
+}
+func (e AuthorizedSignatoryWasRemoved) EventType() string {
+	return TypeAuthorizedSignatoryWasRemoved
+}
+func (e WithdrawalLimitsWereChanged) EventType() string {
+	return TypeWithdrawalLimitsWereChanged
+}
+func (e StandingOrderWasCreated) EventType() string {
+	return TypeStandingOrderWasCreated
+}
+func (e StandingOrderWasAmended) EventType() string {
+	return TypeStandingOrderWasAmended
+}
+func (e StandingOrderWasCancelled) EventType() string {
+	return TypeStandingOrderWasCancelled
+}
+func (e StandingOrderPaymentWasMade) EventType() string {
+	return TypeStandingOrderPaymentWasMade
+}
+func (e StandingOrderPaymentFailed) EventType() string {
+	return TypeStandingOrderPaymentFailed
+}
+func (e StandingOrderPaymentWasSkipped) EventType() string {
+	return TypeStandingOrderPaymentWasSkipped
+}
+func (e StandingOrderPaymentWasReturned) EventType() string {
+	return TypeStandingOrderPaymentWasReturned
+}
+func (e HoldWasPlaced) EventType() string {
+	return TypeHoldWasPlaced
+}
+func (e HoldWasReleased) EventType() string {
+	return TypeHoldWasReleased
+}
+func (e HoldWasCaptured) EventType() string {
+	return TypeHoldWasCaptured
+}
+func (e AccountWasReopened) EventType() string {
+	return TypeAccountWasReopened
+}
+func (e AccountBecameDormant) EventType() string {
+	return TypeAccountBecameDormant
+}
+func (e AccountWasReactivated) EventType() string {
+	return TypeAccountWasReactivated
+}
 
 func (e AccountWasOpened) EventTimestamp() int64 {
 	return e.Timestamp
@@ -105,3 +637,75 @@ func (e WithdrawFailedDueToInsufficientFunds) EventTimestamp() int64 {
 func (e AccountWasClosed) EventTimestamp() int64 {
 	return e.Timestamp
 }
+func (e FeeWasCharged) EventTimestamp() int64 {
+	return e.Timestamp
+}
+func (e FeeWasWaived) EventTimestamp() int64 {
+	return e.Timestamp
+}
+func (e FeeWasRefunded) EventTimestamp() int64 {
+	return e.Timestamp
+}
+func (e AccountWasFrozen) EventTimestamp() int64 {
+	return e.Timestamp
+}
+func (e AccountWasUnfrozen) EventTimestamp() int64 {
+	return e.Timestamp
+}
+func (e AccountHolderWasRenamed) EventTimestamp() int64 {
+	return e.Timestamp
+}
+func (e JointHolderWasAdded) EventTimestamp() int64 {
+	return e.Timestamp
+}
+func (e JointHolderWasRemoved) EventTimestamp() int64 {
+	return e.Timestamp
+}
+func (e AuthorizedSignatoryWasAdded) EventTimestamp() int64 {
+	return e.Timestamp
+}
+func (e AuthorizedSignatoryWasRemoved) EventTimestamp() int64 {
+	return e.Timestamp
+}
+func (e WithdrawalLimitsWereChanged) EventTimestamp() int64 {
+	return e.Timestamp
+}
+func (e StandingOrderWasCreated) EventTimestamp() int64 {
+	return e.Timestamp
+}
+func (e StandingOrderWasAmended) EventTimestamp() int64 {
+	return e.Timestamp
+}
+func (e StandingOrderWasCancelled) EventTimestamp() int64 {
+	return e.Timestamp
+}
+func (e StandingOrderPaymentWasMade) EventTimestamp() int64 {
+	return e.Timestamp
+}
+func (e StandingOrderPaymentFailed) EventTimestamp() int64 {
+	return e.Timestamp
+}
+func (e StandingOrderPaymentWasSkipped) EventTimestamp() int64 {
+	return e.Timestamp
+}
+func (e StandingOrderPaymentWasReturned) EventTimestamp() int64 {
+	return e.Timestamp
+}
+func (e HoldWasPlaced) EventTimestamp() int64 {
+	return e.Timestamp
+}
+func (e HoldWasReleased) EventTimestamp() int64 {
+	return e.Timestamp
+}
+func (e HoldWasCaptured) EventTimestamp() int64 {
+	return e.Timestamp
+}
+func (e AccountWasReopened) EventTimestamp() int64 {
+	return e.Timestamp
+}
+func (e AccountBecameDormant) EventTimestamp() int64 {
+	return e.Timestamp
+}
+func (e AccountWasReactivated) EventTimestamp() int64 {
+	return e.Timestamp
+}
@@ -0,0 +1,345 @@
+package CheckingAccountService_test
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/agemmell/banking-cqrs-es-go/CheckingAccountService"
+	"github.com/agemmell/banking-cqrs-es-go/EventRegistry"
+	"github.com/agemmell/banking-cqrs-es-go/ProductCatalog"
+	"github.com/agemmell/banking-cqrs-es-go/Projections"
+	"github.com/agemmell/banking-cqrs-es-go/Seacrest"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var propertySeed = flag.Int64("property.seed", 0, "seed for the property tests (0 picks a new seed each run)")
+
+const propertyRuns = 200
+const propertyMaxSteps = 40
+
+var propertyStart = time.Date(2021, time.January, 4, 9, 0, 0, 0, time.UTC)
+var propertyAccounts = []string{"ACC1", "ACC2", "ACC3"}
+
+// anyCustomer: treats every customer as having passed KYC, with a name made from their ID
+type anyCustomer struct{}
+
+func (ac anyCustomer) VerifiedName(customerID string) (string, error) {
+	return "Customer " + strings.TrimPrefix(customerID, "CUST-"), nil
+}
+
+// step: one action in a generated sequence; the clock moves on before the command (if any) is handled
+type step struct {
+	Advance time.Duration
+	Command CheckingAccountService.Command
+}
+
+func (s step) String() string {
+	return fmt.Sprintf("advance %s, %T%+v", s.Advance, s.Command, s.Command)
+}
+
+func describeSteps(steps []step) string {
+	var lines []string
+	for i, s := range steps {
+		lines = append(lines, fmt.Sprintf("%d. %s", i+1, s))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// randomStep: a valid or invalid command against one of a handful of accounts, so sequences revisit the same
+// accounts, holds and fees often enough to reach interesting states
+func randomStep(r *rand.Rand, estimatedNow time.Time) step {
+	id := propertyAccounts[r.Intn(len(propertyAccounts))]
+	holdID := fmt.Sprintf("H%d", r.Intn(3))
+	amount := r.Intn(600) - 20
+	advance := time.Duration(r.Intn(3)) * time.Duration(r.Intn(12)+1) * 24 * time.Hour
+
+	var command CheckingAccountService.Command
+	switch r.Intn(16) {
+	case 0:
+		command = CheckingAccountService.OpenAccount{ID: id, CustomerID: "CUST-" + id}
+	case 1, 2, 3:
+		command = CheckingAccountService.DepositMoney{ID: id, Amount: amount, ActingAs: CheckingAccountService.SystemPrincipal}
+	case 4, 5:
+		command = CheckingAccountService.WithdrawMoney{ID: id, Amount: amount, ActingAs: CheckingAccountService.SystemPrincipal}
+	case 6:
+		expiresAt := estimatedNow.Add(time.Duration(r.Intn(96)-12) * time.Hour).UnixNano()
+		command = CheckingAccountService.PlaceHold{ID: id, HoldID: holdID, Amount: amount, ExpiresAt: expiresAt, ActingAs: CheckingAccountService.SystemPrincipal}
+	case 7:
+		command = CheckingAccountService.ReleaseHold{ID: id, HoldID: holdID, ActingAs: CheckingAccountService.SystemPrincipal}
+	case 8:
+		command = CheckingAccountService.CaptureHold{ID: id, HoldID: holdID, Amount: amount, ActingAs: CheckingAccountService.SystemPrincipal}
+	case 9:
+		command = CheckingAccountService.ExpireHolds{ID: id, ActingAs: CheckingAccountService.SystemPrincipal}
+	case 10:
+		feeTypes := []string{CheckingAccountService.FeeTypeMonthlyMaintenance, CheckingAccountService.FeeTypeUnarrangedOverdraft, CheckingAccountService.FeeTypeTransaction}
+		command = CheckingAccountService.ChargeFee{ID: id, FeeType: feeTypes[r.Intn(len(feeTypes))], ActingAs: CheckingAccountService.SystemPrincipal}
+	case 11:
+		command = CheckingAccountService.RefundFee{ID: id, FeeID: fmt.Sprintf("%s/%d", id, r.Intn(10)), ActingAs: CheckingAccountService.SystemPrincipal}
+	case 12:
+		scopes := []string{CheckingAccountService.FreezeDebits, CheckingAccountService.FreezeAll}
+		command = CheckingAccountService.FreezeAccount{ID: id, Scope: scopes[r.Intn(len(scopes))], ReasonCode: CheckingAccountService.ReasonSuspectedFraud, ActingAs: CheckingAccountService.SystemPrincipal}
+	case 13:
+		command = CheckingAccountService.UnfreezeAccount{ID: id, ReasonCode: CheckingAccountService.ReasonReviewCompleted, ActingAs: CheckingAccountService.SystemPrincipal}
+	case 14:
+		if r.Intn(2) == 0 {
+			command = CheckingAccountService.CloseAccount{ID: id, ActingAs: CheckingAccountService.SystemPrincipal}
+		} else {
+			command = CheckingAccountService.ReopenAccount{ID: id, ActingAs: CheckingAccountService.SystemPrincipal}
+		}
+	case 15:
+		if r.Intn(2) == 0 {
+			command = CheckingAccountService.SetWithdrawalLimits{ID: id, PerTransactionLimit: r.Intn(300), DailyLimit: r.Intn(600), ActingAs: CheckingAccountService.SystemPrincipal}
+		} else {
+			command = CheckingAccountService.MarkAccountDormant{ID: id, InactivityPeriod: 30 * 24 * time.Hour, ActingAs: CheckingAccountService.SystemPrincipal}
+		}
+	}
+
+	return step{Advance: advance, Command: command}
+}
+
+func randomSteps(r *rand.Rand) []step {
+	steps := make([]step, 1+r.Intn(propertyMaxSteps))
+	now := propertyStart
+	for i := range steps {
+		steps[i] = randomStep(r, now)
+		now = now.Add(steps[i].Advance)
+	}
+	return steps
+}
+
+// runSteps: handle the steps against a new service, checking the invariants after every step. Returns the number
+// of the step that broke an invariant and how, or 0 and nil when every invariant held.
+func runSteps(steps []step) (int, error) {
+	clock := Seacrest.NewFakeClock(propertyStart)
+	eventStore := Seacrest.NewEventStore(Seacrest.WithClock(clock), Seacrest.WithIDGenerator(Seacrest.NewSequentialIDGenerator("event")))
+	catalog := ProductCatalog.New(ProductCatalog.Product{
+		Code: ProductCatalog.StandardChecking,
+		Kind: ProductCatalog.KindChecking,
+		FeeSchedule: ProductCatalog.FeeSchedule{
+			MonthlyMaintenanceFee:   5,
+			TransactionFee:          1,
+			UnarrangedOverdraftFee:  20,
+			MinimumBalanceForWaiver: 1000,
+		},
+	})
+	cas := CheckingAccountService.New(eventStore,
+		CheckingAccountService.WithClock(clock),
+		CheckingAccountService.WithProductCatalog(catalog),
+		CheckingAccountService.WithCustomerVerifier(anyCustomer{}),
+	)
+
+	for i, s := range steps {
+		clock.Advance(s.Advance)
+		if s.Command == nil {
+			continue
+		}
+		before := len(eventStore.GetAllEvents())
+		commandErr := cas.HandleCommand(s.Command)
+		err := checkInvariants(&cas, eventStore, before, commandErr)
+		if err != nil {
+			return i + 1, err
+		}
+	}
+	return 0, nil
+}
+
+// checkInvariants: the properties that must hold whatever commands have been handled
+func checkInvariants(cas *CheckingAccountService.CheckingAccountService, eventStore *Seacrest.EventStore, before int, commandErr error) error {
+	envelopes := eventStore.GetAllEvents()
+	if commandErr != nil && len(envelopes) != before {
+		return errors.New(fmt.Sprintf("rejected command persisted %d events: %s", len(envelopes)-before, commandErr))
+	}
+
+	feesCharged := map[string]bool{}
+	for _, envelope := range envelopes {
+		event, err := EventRegistry.Decode(envelope)
+		if err != nil {
+			return err
+		}
+		switch event := event.(type) {
+		case *CheckingAccountService.FeeWasCharged:
+			feesCharged[event.ID] = true
+		case *CheckingAccountService.MoneyWasWithdrawn:
+			if event.Balance < 0 {
+				return errors.New(fmt.Sprintf("withdrawal overdrew account %s to %d", event.ID, event.Balance))
+			}
+		case *CheckingAccountService.HoldWasCaptured:
+			if event.Balance < 0 {
+				return errors.New(fmt.Sprintf("capture overdrew account %s to %d", event.ID, event.Balance))
+			}
+		}
+	}
+
+	bankFunds, err := Projections.BuildBankFunds(eventStore)
+	if err != nil {
+		return err
+	}
+	accountBalances, err := Projections.BuildAccountBalances(eventStore, func(Projections.AccountBalance) {})
+	if err != nil {
+		return err
+	}
+	totalBalance, totalHeld := 0, 0
+	for _, accountBalance := range accountBalances {
+		totalBalance += accountBalance.Balance
+		totalHeld += accountBalance.Held
+	}
+	if totalBalance != bankFunds.Total || totalHeld != bankFunds.Held {
+		return errors.New(fmt.Sprintf("TotalBankFunds %+v does not match the account balances (balance %d, held %d)", bankFunds, totalBalance, totalHeld))
+	}
+
+	for _, id := range propertyAccounts {
+		events, err := cas.GetEventsByAggregateID(id)
+		if err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			continue
+		}
+		account := CheckingAccountService.Account{}
+		err = account.LoadFromEvents(events)
+		if err != nil {
+			return err
+		}
+		if account.Version() != uint(len(events)) {
+			return errors.New(fmt.Sprintf("account %s is at version %d after %d events", id, account.Version(), len(events)))
+		}
+
+		summary, err := cas.GetAccountSummary(id, CheckingAccountService.SystemPrincipal)
+		if err != nil {
+			return err
+		}
+		if summary.LedgerBalance < 0 && !feesCharged[id] {
+			return errors.New(fmt.Sprintf("account %s went overdrawn to %d without being charged a fee", id, summary.LedgerBalance))
+		}
+		if summary.AvailableBalance > summary.LedgerBalance {
+			return errors.New(fmt.Sprintf("account %s has more available (%d) than its ledger balance (%d)", id, summary.AvailableBalance, summary.LedgerBalance))
+		}
+		if accountBalances[id].Balance != summary.LedgerBalance {
+			return errors.New(fmt.Sprintf("HighestBalanceOwners has account %s at %d but its ledger balance is %d", id, accountBalances[id].Balance, summary.LedgerBalance))
+		}
+	}
+
+	return nil
+}
+
+// shrink: the smallest sequence derived from a failing sequence that still fails, first by dropping steps and then
+// by making amounts and clock advances smaller
+func shrink(steps []step, fails func([]step) bool) []step {
+	for chunk := len(steps) / 2; chunk >= 1; chunk /= 2 {
+		for i := 0; i+chunk <= len(steps); {
+			candidate := append(append([]step{}, steps[:i]...), steps[i+chunk:]...)
+			if fails(candidate) {
+				steps = candidate
+				continue
+			}
+			i++
+		}
+	}
+
+	for shrunk := true; shrunk; {
+		shrunk = false
+		for i := range steps {
+			for _, smaller := range smallerSteps(steps[i]) {
+				candidate := append([]step{}, steps...)
+				candidate[i] = smaller
+				if fails(candidate) {
+					steps = candidate
+					shrunk = true
+					break
+				}
+			}
+		}
+	}
+	return steps
+}
+
+// smallerSteps: variations of a step with a smaller clock advance or command Amount
+func smallerSteps(s step) []step {
+	var smaller []step
+	if s.Advance > 0 {
+		smaller = append(smaller, step{0, s.Command}, step{s.Advance / 2, s.Command})
+	}
+
+	if s.Command == nil {
+		return smaller
+	}
+	command := reflect.ValueOf(s.Command)
+	amount := command.FieldByName("Amount")
+	if !amount.IsValid() || amount.Kind() != reflect.Int || amount.Int() == 0 {
+		return smaller
+	}
+	for _, value := range []int64{0, amount.Int() / 2, amount.Int() - 1} {
+		copied := reflect.New(command.Type()).Elem()
+		copied.Set(command)
+		copied.FieldByName("Amount").SetInt(value)
+		smaller = append(smaller, step{s.Advance, copied.Interface().(CheckingAccountService.Command)})
+	}
+	return smaller
+}
+
+func Test_AccountInvariantsHoldForRandomCommandSequences(t *testing.T) {
+	t.Parallel()
+
+	seed := *propertySeed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	r := rand.New(rand.NewSource(seed))
+
+	for run := 0; run < propertyRuns; run++ {
+		steps := randomSteps(r)
+		failedAt, err := runSteps(steps)
+		if err == nil {
+			continue
+		}
+
+		minimal := shrink(steps[:failedAt], func(candidate []step) bool {
+			_, err := runSteps(candidate)
+			return err != nil
+		})
+		_, minimalErr := runSteps(minimal)
+		t.Fatalf("invariant broken (seed %d, run %d): %s\nminimal reproduction:\n%s", seed, run, minimalErr, describeSteps(minimal))
+	}
+}
+
+func Test_ShrinkFindsMinimalSequence(t *testing.T) {
+	t.Parallel()
+
+	// Given a property that fails whenever more than 50 is deposited into an account that is then withdrawn from
+	fails := func(steps []step) bool {
+		deposited := false
+		for _, s := range steps {
+			switch command := s.Command.(type) {
+			case CheckingAccountService.DepositMoney:
+				deposited = deposited || command.Amount > 50
+			case CheckingAccountService.WithdrawMoney:
+				if deposited {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	steps := []step{
+		{time.Hour, CheckingAccountService.OpenAccount{ID: "ACC1"}},
+		{time.Hour, CheckingAccountService.DepositMoney{ID: "ACC1", Amount: 20, ActingAs: CheckingAccountService.SystemPrincipal}},
+		{time.Hour, CheckingAccountService.DepositMoney{ID: "ACC1", Amount: 400, ActingAs: CheckingAccountService.SystemPrincipal}},
+		{time.Hour, CheckingAccountService.ExpireHolds{ID: "ACC1", ActingAs: CheckingAccountService.SystemPrincipal}},
+		{time.Hour, CheckingAccountService.WithdrawMoney{ID: "ACC1", Amount: 300, ActingAs: CheckingAccountService.SystemPrincipal}},
+		{time.Hour, CheckingAccountService.DepositMoney{ID: "ACC1", Amount: 5, ActingAs: CheckingAccountService.SystemPrincipal}},
+	}
+
+	// When
+	minimal := shrink(steps, fails)
+
+	// Then
+	assert.Equal(t, []step{
+		{0, CheckingAccountService.DepositMoney{ID: "ACC1", Amount: 51, ActingAs: CheckingAccountService.SystemPrincipal}},
+		{0, CheckingAccountService.WithdrawMoney{ID: "ACC1", Amount: 0, ActingAs: CheckingAccountService.SystemPrincipal}},
+	}, minimal)
+}
@@ -0,0 +1,79 @@
+package CheckingAccountService
+
+import (
+	"github.com/agemmell/banking-cqrs-es-go/Scenario"
+	"github.com/agemmell/banking-cqrs-es-go/Seacrest"
+	"testing"
+	"time"
+)
+
+var scenarioStart = time.Date(2021, time.January, 4, 9, 0, 0, 0, time.UTC)
+
+func TestScenario_WithdrawMoneyChargesTransactionFee(t *testing.T) {
+	t.Parallel()
+
+	account := &Account{}
+	account.SetClock(Seacrest.NewFakeClock(scenarioStart))
+	account.SetFeeSchedule(FeeSchedule{TransactionFee: 1})
+
+	Scenario.ForAggregate(t, account).
+		Given(
+			&AccountWasOpened{ID: "ABCD", HolderID: "CUST1", Name: "Alex Gemmell"},
+			&MoneyWasDeposited{ID: "ABCD", Amount: 100},
+		).
+		When(func() error { return account.WithdrawMoney(40, "CUST1") }).
+		Then(
+			&MoneyWasWithdrawn{ID: "ABCD", Amount: 40, Balance: 60, ChangedBy: "CUST1", Timestamp: scenarioStart.UnixNano()},
+			&FeeWasCharged{ID: "ABCD", FeeID: "ABCD/3", FeeType: FeeTypeTransaction, Amount: 1, Balance: 59, Timestamp: scenarioStart.UnixNano()},
+		)
+}
+
+func TestScenario_CannotAddExistingHolderAsJointHolder(t *testing.T) {
+	t.Parallel()
+
+	account := &Account{}
+
+	Scenario.ForAggregate(t, account).
+		Given(
+			&AccountWasOpened{ID: "ABCD", HolderID: "CUST1", Name: "Alex Gemmell"},
+			&JointHolderWasAdded{ID: "ABCD", HolderID: "CUST2", Name: "Sam Gemmell", ChangedBy: "CUST1"},
+		).
+		When(func() error { return account.AddJointHolder("CUST2", "Sam Gemmell", "CUST1") }).
+		ThenError("CUST2 is already a holder of account ABCD")
+}
+
+func TestScenario_FirstTransactionReactivatesDormantAccount(t *testing.T) {
+	t.Parallel()
+
+	account := &Account{}
+
+	Scenario.ForAggregate(t, account).
+		IgnoringTimestamps().
+		Given(
+			&AccountWasOpened{ID: "ABCD", HolderID: "CUST1", Name: "Alex Gemmell"},
+			&AccountBecameDormant{ID: "ABCD"},
+		).
+		When(func() error { return account.DepositMoney(25, "CUST1") }).
+		Then(
+			&AccountWasReactivated{ID: "ABCD"},
+			&MoneyWasDeposited{ID: "ABCD", Amount: 25, ChangedBy: "CUST1"},
+		)
+}
+
+func TestScenario_ReopenAccountThroughService(t *testing.T) {
+	t.Parallel()
+
+	eventStore := Seacrest.NewEventStore()
+	clock := Seacrest.NewFakeClock(scenarioStart)
+	checkingAccountService := New(eventStore, WithClock(clock), WithCustomerVerifier(verifiedCustomers{}))
+
+	Scenario.ForEventStore(t, eventStore).
+		Given(
+			&AccountWasOpened{ID: "ABCD", HolderID: "CUST1", Name: "Alex Gemmell", Timestamp: scenarioStart.AddDate(0, -1, 0).UnixNano()},
+			&AccountWasClosed{ID: "ABCD", Timestamp: scenarioStart.AddDate(0, 0, -7).UnixNano()},
+		).
+		When(func() error {
+			return checkingAccountService.HandleCommand(ReopenAccount{ID: "ABCD", ActingAs: "CUST1"})
+		}).
+		Then(&AccountWasReopened{ID: "ABCD", ReopenedBy: "CUST1", Timestamp: scenarioStart.UnixNano()})
+}
@@ -0,0 +1,192 @@
+package CheckingAccountService
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// RetryPolicy: how a standing order payment that failed for lack of funds, or was returned by its payee, is retried
+type RetryPolicy struct {
+	MaxAttempts   int           // attempts before the payment is skipped
+	RetryInterval time.Duration // minimum time between attempts
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{MaxAttempts: 3, RetryInterval: 24 * time.Hour}
+}
+
+// SchedulerRun: what happened when due standing order payments were issued
+type SchedulerRun struct {
+	Paid    int
+	Failed  int
+	Skipped int
+	Errors  []error
+}
+
+// StandingOrderScheduler: issues standing order payments through the service when they fall due. Payments due on a
+// weekend or holiday are made on the following business day. The service's clock decides what is due.
+type StandingOrderScheduler struct {
+	service     *CheckingAccountService
+	retryPolicy RetryPolicy
+	holidays    map[string]bool // <yyyy-mm-dd> -> is a holiday
+}
+
+// NewStandingOrderScheduler: fails unless the retry policy makes at least one attempt at each payment
+func NewStandingOrderScheduler(service *CheckingAccountService, retryPolicy RetryPolicy, holidays ...time.Time) (*StandingOrderScheduler, error) {
+	if retryPolicy.MaxAttempts < 1 {
+		return nil, errors.New(fmt.Sprintf("a retry policy must make at least one attempt [MaxAttempts: %d]", retryPolicy.MaxAttempts))
+	}
+	holidayDates := map[string]bool{}
+	for _, holiday := range holidays {
+		holidayDates[holiday.UTC().Format("2006-01-02")] = true
+	}
+	return &StandingOrderScheduler{service, retryPolicy, holidayDates}, nil
+}
+
+// IsBusinessDay: payments are only made on weekdays that are not holidays
+func (sos *StandingOrderScheduler) IsBusinessDay(date time.Time) bool {
+	date = date.UTC()
+	if date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+		return false
+	}
+	return !sos.holidays[date.Format("2006-01-02")]
+}
+
+// NextBusinessDay: the start of the first business day on or after the given time, or the time itself if it already
+// falls on a business day
+func (sos *StandingOrderScheduler) NextBusinessDay(timestamp int64) int64 {
+	date := time.Unix(0, timestamp).UTC()
+	if sos.IsBusinessDay(date) {
+		return timestamp
+	}
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	for !sos.IsBusinessDay(day) {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day.UnixNano()
+}
+
+// RunDuePayments: make, retry or skip every standing order payment that is due, catching up on any missed while the
+// scheduler wasn't running
+func (sos *StandingOrderScheduler) RunDuePayments() (SchedulerRun, error) {
+	run := SchedulerRun{}
+
+	for _, accountID := range sos.accountsWithStandingOrders() {
+		account, err := sos.service.loadAccount(accountID)
+		if err != nil {
+			return run, err
+		}
+		if account.Status() == AccountClosed {
+			continue
+		}
+		for _, standingOrder := range account.StandingOrders() {
+			sos.runStandingOrder(accountID, standingOrder.ID, &run)
+		}
+	}
+
+	return run, nil
+}
+
+// runStandingOrder: keep issuing commands for a standing order until nothing more is due
+func (sos *StandingOrderScheduler) runStandingOrder(accountID string, standingOrderID string, run *SchedulerRun) {
+	for {
+		account, err := sos.service.loadAccount(accountID)
+		if err != nil {
+			run.Errors = append(run.Errors, err)
+			return
+		}
+		standingOrder, ok := account.standingOrders[standingOrderID]
+		if !ok {
+			return
+		}
+
+		now := sos.service.clock.Now().UnixNano()
+		var command Command
+		switch {
+		case now < sos.NextBusinessDay(standingOrder.NextPaymentDate):
+			// nothing is made, retried or skipped before the payment is due
+			return
+		case standingOrder.FailedAttempts >= sos.retryPolicy.MaxAttempts:
+			command = SkipStandingOrderPayment{accountID, standingOrderID, standingOrder.NextPaymentDate, SystemPrincipal}
+		case standingOrder.FailedAttempts > 0:
+			retryAt := sos.NextBusinessDay(standingOrder.LastAttemptAt + int64(sos.retryPolicy.RetryInterval))
+			if now < retryAt {
+				return
+			}
+			command = MakeStandingOrderPayment{accountID, standingOrderID, standingOrder.NextPaymentDate, SystemPrincipal}
+		default:
+			command = MakeStandingOrderPayment{accountID, standingOrderID, standingOrder.NextPaymentDate, SystemPrincipal}
+		}
+
+		if _, ok := command.(MakeStandingOrderPayment); ok {
+			// don't take the payment if it has nowhere to go; it stays due and is tried again on the next run
+			err = sos.ensurePayeeAccepts(standingOrder)
+			if err != nil {
+				run.Errors = append(run.Errors, fmt.Errorf("standing order %s payee %s: %w", standingOrderID, standingOrder.PayeeAccountID, err))
+				return
+			}
+		}
+
+		err = sos.service.HandleCommand(command)
+		if err != nil {
+			run.Errors = append(run.Errors, fmt.Errorf("standing order %s on account %s: %w", standingOrderID, accountID, err))
+			return
+		}
+
+		if _, ok := command.(SkipStandingOrderPayment); ok {
+			run.Skipped++
+			continue
+		}
+
+		account, err = sos.service.loadAccount(accountID)
+		if err != nil {
+			run.Errors = append(run.Errors, err)
+			return
+		}
+		if account.standingOrders[standingOrderID].FailedAttempts > 0 {
+			run.Failed++
+			continue
+		}
+
+		err = sos.service.HandleCommand(DepositMoney{ID: standingOrder.PayeeAccountID, Amount: standingOrder.Amount, ActingAs: SystemPrincipal})
+		if err != nil {
+			// the payee stopped accepting payments after it was checked, so the payment goes back to the payer as a
+			// failed attempt
+			run.Errors = append(run.Errors, fmt.Errorf("standing order %s payee %s: %w", standingOrderID, standingOrder.PayeeAccountID, err))
+			err = sos.service.HandleCommand(ReturnStandingOrderPayment{accountID, standingOrderID, standingOrder.Amount, standingOrder.NextPaymentDate, SystemPrincipal})
+			if err != nil {
+				run.Errors = append(run.Errors, fmt.Errorf("standing order %s return to %s: %w", standingOrderID, accountID, err))
+				return
+			}
+			run.Failed++
+			continue
+		}
+		run.Paid++
+	}
+}
+
+// ensurePayeeAccepts: fail if the standing order's payee could not be paid into right now
+func (sos *StandingOrderScheduler) ensurePayeeAccepts(standingOrder StandingOrder) error {
+	payee, err := sos.service.loadAccount(standingOrder.PayeeAccountID)
+	if err != nil {
+		return err
+	}
+	return payee.ensureAllowed(actionDeposit)
+}
+
+// accountsWithStandingOrders: every account that has ever created a standing order, in ID order
+func (sos *StandingOrderScheduler) accountsWithStandingOrders() []string {
+	seen := map[string]bool{}
+	var accountIDs []string
+	for _, envelope := range sos.service.eventStore.GetAllEvents() {
+		if envelope.EventType != TypeStandingOrderWasCreated || seen[envelope.AggregateID] {
+			continue
+		}
+		seen[envelope.AggregateID] = true
+		accountIDs = append(accountIDs, envelope.AggregateID)
+	}
+	sort.Strings(accountIDs)
+	return accountIDs
+}
@@ -0,0 +1,307 @@
+package CheckingAccountService
+
+import (
+	"github.com/agemmell/banking-cqrs-es-go/Seacrest"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_NthPaymentDate(t *testing.T) {
+	t.Parallel()
+
+	firstPaymentDate := time.Date(2020, time.January, 31, 9, 0, 0, 0, time.UTC).UnixNano()
+
+	assert.Equal(t, time.Date(2020, time.February, 29, 9, 0, 0, 0, time.UTC).UnixNano(), nthPaymentDate(firstPaymentDate, FrequencyMonthly, 1))
+	assert.Equal(t, time.Date(2020, time.March, 31, 9, 0, 0, 0, time.UTC).UnixNano(), nthPaymentDate(firstPaymentDate, FrequencyMonthly, 2))
+	assert.Equal(t, time.Date(2021, time.January, 31, 9, 0, 0, 0, time.UTC).UnixNano(), nthPaymentDate(firstPaymentDate, FrequencyMonthly, 12))
+	assert.Equal(t, time.Date(2020, time.February, 14, 9, 0, 0, 0, time.UTC).UnixNano(), nthPaymentDate(firstPaymentDate, FrequencyWeekly, 2))
+}
+
+func Test_StandingOrderScheduler_BusinessDays(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	eventStore := Seacrest.NewEventStore()
+	checkingAccountService := New(eventStore, WithCustomerVerifier(verifiedCustomers{}))
+	goodFriday := time.Date(2020, time.April, 10, 0, 0, 0, 0, time.UTC)
+	scheduler, err := NewStandingOrderScheduler(&checkingAccountService, DefaultRetryPolicy(), goodFriday)
+	assert.Nil(t, err)
+
+	// Then
+	assert.True(t, scheduler.IsBusinessDay(time.Date(2020, time.April, 9, 12, 0, 0, 0, time.UTC)))
+	assert.False(t, scheduler.IsBusinessDay(time.Date(2020, time.April, 10, 12, 0, 0, 0, time.UTC)))
+	assert.False(t, scheduler.IsBusinessDay(time.Date(2020, time.April, 11, 12, 0, 0, 0, time.UTC)))
+	assert.Equal(t,
+		time.Date(2020, time.April, 13, 0, 0, 0, 0, time.UTC).UnixNano(),
+		scheduler.NextBusinessDay(time.Date(2020, time.April, 10, 9, 0, 0, 0, time.UTC).UnixNano()))
+}
+
+func Test_StandingOrderScheduler_RunDuePayments(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	eventStore := Seacrest.NewEventStore()
+	clock := Seacrest.NewFakeClock(time.Date(2020, time.January, 15, 9, 0, 0, 0, time.UTC))
+	checkingAccountService := New(eventStore, WithClock(clock), WithCustomerVerifier(verifiedCustomers{}))
+	scheduler, err := NewStandingOrderScheduler(&checkingAccountService, RetryPolicy{MaxAttempts: 2, RetryInterval: 24 * time.Hour})
+	assert.Nil(t, err)
+
+	assert.Nil(t, checkingAccountService.HandleCommand(OpenAccount{ID: "PAYER", CustomerID: "CUST1"}))
+	assert.Nil(t, checkingAccountService.HandleCommand(OpenAccount{ID: "PAYEE", CustomerID: "CUST5"}))
+	assert.Nil(t, checkingAccountService.HandleCommand(DepositMoney{ID: "PAYER", Amount: 250, ActingAs: SystemPrincipal}))
+	createStandingOrder := CreateStandingOrder{
+		ID:               "PAYER",
+		StandingOrderID:  "RENT",
+		PayeeAccountID:   "PAYEE",
+		Amount:           100,
+		Frequency:        FrequencyMonthly,
+		FirstPaymentDate: time.Date(2020, time.February, 1, 9, 0, 0, 0, time.UTC).UnixNano(), // a Saturday
+		ActingAs:         SystemPrincipal,
+	}
+	assert.Nil(t, checkingAccountService.HandleCommand(createStandingOrder))
+
+	// When the first payment falls on a weekend
+	clock.Set(time.Date(2020, time.February, 1, 10, 0, 0, 0, time.UTC))
+	run, err := scheduler.RunDuePayments()
+
+	// Then it waits for the next business day
+	assert.Nil(t, err)
+	assert.Equal(t, SchedulerRun{}, run)
+
+	// When the next business day arrives
+	clock.Set(time.Date(2020, time.February, 3, 10, 0, 0, 0, time.UTC))
+	run, err = scheduler.RunDuePayments()
+
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, SchedulerRun{Paid: 1}, run)
+
+	// When the scheduler misses the March payment and the April payment can't be afforded
+	clock.Set(time.Date(2020, time.April, 1, 10, 0, 0, 0, time.UTC))
+	run, err = scheduler.RunDuePayments()
+
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, SchedulerRun{Paid: 1, Failed: 1}, run)
+
+	// When the retry interval has not yet passed
+	clock.Set(time.Date(2020, time.April, 1, 18, 0, 0, 0, time.UTC))
+	run, err = scheduler.RunDuePayments()
+
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, SchedulerRun{}, run)
+
+	// When the retry fails for the last time
+	clock.Set(time.Date(2020, time.April, 2, 10, 0, 0, 0, time.UTC))
+	run, err = scheduler.RunDuePayments()
+
+	// Then the payment is skipped
+	assert.Nil(t, err)
+	assert.Equal(t, SchedulerRun{Failed: 1, Skipped: 1}, run)
+
+	payer, err := checkingAccountService.loadAccount("PAYER")
+	assert.Nil(t, err)
+	payee, err := checkingAccountService.loadAccount("PAYEE")
+	assert.Nil(t, err)
+	assert.Equal(t, 50, payer.LedgerBalance())
+	assert.Equal(t, 200, payee.LedgerBalance())
+	assert.Equal(t, time.Date(2020, time.May, 1, 9, 0, 0, 0, time.UTC).UnixNano(), payer.StandingOrders()[0].NextPaymentDate)
+}
+
+func Test_StandingOrderSchedulerRequiresAnAttemptAtEachPayment(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	checkingAccountService := New(Seacrest.NewEventStore())
+
+	// When
+	_, zeroErr := NewStandingOrderScheduler(&checkingAccountService, RetryPolicy{})
+	_, negativeErr := NewStandingOrderScheduler(&checkingAccountService, RetryPolicy{MaxAttempts: -1, RetryInterval: time.Hour})
+
+	// Then
+	assert.Equal(t, "a retry policy must make at least one attempt [MaxAttempts: 0]", zeroErr.Error())
+	assert.Equal(t, "a retry policy must make at least one attempt [MaxAttempts: -1]", negativeErr.Error())
+}
+
+func Test_StandingOrderSchedulerOnlySkipsDuePayments(t *testing.T) {
+	t.Parallel()
+
+	// Given a payment that has failed its only attempt, and a clock that is behind the payment date
+	eventStore := Seacrest.NewEventStore()
+	clock := Seacrest.NewFakeClock(time.Date(2020, time.January, 15, 9, 0, 0, 0, time.UTC))
+	checkingAccountService := New(eventStore, WithClock(clock), WithCustomerVerifier(verifiedCustomers{}))
+	scheduler, err := NewStandingOrderScheduler(&checkingAccountService, RetryPolicy{MaxAttempts: 1, RetryInterval: 24 * time.Hour})
+	assert.Nil(t, err)
+	paymentDate := time.Date(2020, time.February, 3, 9, 0, 0, 0, time.UTC).UnixNano()
+	assert.Nil(t, checkingAccountService.PersistEvents(
+		AccountWasOpened{ID: "PAYER", HolderID: "CUST1", Name: "Alex Gemmell"},
+		AccountWasOpened{ID: "PAYEE", HolderID: "CUST5", Name: "Sam Landlord"},
+		StandingOrderWasCreated{ID: "PAYER", StandingOrderID: "RENT", PayeeAccountID: "PAYEE", Amount: 100, Frequency: FrequencyMonthly, FirstPaymentDate: paymentDate},
+		StandingOrderPaymentFailed{ID: "PAYER", StandingOrderID: "RENT", Amount: 100, PaymentDate: paymentDate, Attempt: 1, Timestamp: paymentDate},
+	))
+
+	// When
+	run, err := scheduler.RunDuePayments()
+
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, SchedulerRun{}, run)
+	assert.Len(t, eventStore.GetAllEvents(), 4)
+}
+
+func Test_AmendAndCancelStandingOrder(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	eventStore := Seacrest.NewEventStore()
+	checkingAccountService := New(eventStore, WithCustomerVerifier(verifiedCustomers{}))
+	assert.Nil(t, checkingAccountService.HandleCommand(OpenAccount{ID: "PAYER", CustomerID: "CUST1"}))
+	assert.Nil(t, checkingAccountService.HandleCommand(OpenAccount{ID: "SAVINGS", CustomerID: "CUST1"}))
+	createStandingOrder := CreateStandingOrder{
+		ID:               "PAYER",
+		StandingOrderID:  "SWEEP",
+		PayeeAccountID:   "SAVINGS",
+		Amount:           100,
+		Frequency:        FrequencyWeekly,
+		FirstPaymentDate: time.Date(2020, time.February, 3, 9, 0, 0, 0, time.UTC).UnixNano(),
+		ActingAs:         SystemPrincipal,
+	}
+	assert.Nil(t, checkingAccountService.HandleCommand(createStandingOrder))
+
+	// When
+	err := checkingAccountService.HandleCommand(AmendStandingOrder{ID: "PAYER", StandingOrderID: "SWEEP", Amount: 150, ActingAs: SystemPrincipal})
+	assert.Nil(t, err)
+
+	// Then
+	payer, err := checkingAccountService.loadAccount("PAYER")
+	assert.Nil(t, err)
+	assert.Equal(t, 150, payer.StandingOrders()[0].Amount)
+	assert.Equal(t, "SAVINGS", payer.StandingOrders()[0].PayeeAccountID)
+	assert.NotNil(t, checkingAccountService.HandleCommand(AmendStandingOrder{ID: "PAYER", StandingOrderID: "SWEEP", PayeeAccountID: "NOBODY", Amount: 150, ActingAs: SystemPrincipal}))
+
+	// When
+	err = checkingAccountService.HandleCommand(CancelStandingOrder{ID: "PAYER", StandingOrderID: "SWEEP", ActingAs: SystemPrincipal})
+	assert.Nil(t, err)
+
+	// Then
+	payer, err = checkingAccountService.loadAccount("PAYER")
+	assert.Nil(t, err)
+	assert.Empty(t, payer.StandingOrders())
+	events, err := checkingAccountService.GetAllEvents()
+	assert.Nil(t, err)
+	assert.IsType(t, &StandingOrderWasCancelled{}, events[len(events)-1])
+}
+
+func Test_StandingOrderScheduler_WaitsForAPayeeThatCannotBePaid(t *testing.T) {
+	t.Parallel()
+
+	// Given a payee that is frozen when the payment falls due
+	eventStore := Seacrest.NewEventStore()
+	clock := Seacrest.NewFakeClock(time.Date(2020, time.January, 15, 9, 0, 0, 0, time.UTC))
+	checkingAccountService := New(eventStore, WithClock(clock), WithCustomerVerifier(verifiedCustomers{}))
+	scheduler, err := NewStandingOrderScheduler(&checkingAccountService, DefaultRetryPolicy())
+	assert.Nil(t, err)
+	assert.Nil(t, checkingAccountService.HandleCommand(OpenAccount{ID: "PAYER", CustomerID: "CUST1"}))
+	assert.Nil(t, checkingAccountService.HandleCommand(OpenAccount{ID: "PAYEE", CustomerID: "CUST5"}))
+	assert.Nil(t, checkingAccountService.HandleCommand(DepositMoney{ID: "PAYER", Amount: 250, ActingAs: SystemPrincipal}))
+	assert.Nil(t, checkingAccountService.HandleCommand(CreateStandingOrder{
+		ID:               "PAYER",
+		StandingOrderID:  "RENT",
+		PayeeAccountID:   "PAYEE",
+		Amount:           100,
+		Frequency:        FrequencyMonthly,
+		FirstPaymentDate: time.Date(2020, time.February, 3, 9, 0, 0, 0, time.UTC).UnixNano(),
+		ActingAs:         SystemPrincipal,
+	}))
+	assert.Nil(t, checkingAccountService.HandleCommand(FreezeAccount{ID: "PAYEE", Scope: FreezeAll, ReasonCode: ReasonCourtOrder, ActingAs: SystemPrincipal}))
+
+	// When
+	clock.Set(time.Date(2020, time.February, 3, 10, 0, 0, 0, time.UTC))
+	frozenRun, err := scheduler.RunDuePayments()
+	assert.Nil(t, err)
+	payer, err := checkingAccountService.loadAccount("PAYER")
+	assert.Nil(t, err)
+	balanceWhileFrozen := payer.LedgerBalance()
+	assert.Nil(t, checkingAccountService.HandleCommand(UnfreezeAccount{ID: "PAYEE", ReasonCode: ReasonReviewCompleted, ActingAs: SystemPrincipal}))
+	unfrozenRun, err := scheduler.RunDuePayments()
+	assert.Nil(t, err)
+
+	// Then the payer is only debited once the payee can be paid
+	assert.Equal(t, 0, frozenRun.Paid)
+	assert.Len(t, frozenRun.Errors, 1)
+	assert.Equal(t, 250, balanceWhileFrozen)
+	assert.Equal(t, SchedulerRun{Paid: 1}, unfrozenRun)
+	payer, err = checkingAccountService.loadAccount("PAYER")
+	assert.Nil(t, err)
+	payee, err := checkingAccountService.loadAccount("PAYEE")
+	assert.Nil(t, err)
+	assert.Equal(t, 150, payer.LedgerBalance())
+	assert.Equal(t, 100, payee.LedgerBalance())
+}
+
+func Test_ReturnedStandingOrderPaymentIsDueAgain(t *testing.T) {
+	t.Parallel()
+
+	// Given a payment that was taken from a payer that has since been frozen and become dormant
+	eventStore := Seacrest.NewEventStore()
+	clock := Seacrest.NewFakeClock(time.Date(2020, time.January, 15, 9, 0, 0, 0, time.UTC))
+	checkingAccountService := New(eventStore, WithClock(clock), WithCustomerVerifier(verifiedCustomers{}))
+	paymentDate := time.Date(2020, time.February, 3, 9, 0, 0, 0, time.UTC).UnixNano()
+	assert.Nil(t, checkingAccountService.HandleCommand(OpenAccount{ID: "PAYER", CustomerID: "CUST1"}))
+	assert.Nil(t, checkingAccountService.HandleCommand(OpenAccount{ID: "PAYEE", CustomerID: "CUST5"}))
+	assert.Nil(t, checkingAccountService.HandleCommand(DepositMoney{ID: "PAYER", Amount: 250, ActingAs: SystemPrincipal}))
+	assert.Nil(t, checkingAccountService.HandleCommand(CreateStandingOrder{ID: "PAYER", StandingOrderID: "RENT", PayeeAccountID: "PAYEE", Amount: 100, Frequency: FrequencyMonthly, FirstPaymentDate: paymentDate, ActingAs: SystemPrincipal}))
+	clock.Set(time.Date(2020, time.February, 3, 10, 0, 0, 0, time.UTC))
+	assert.Nil(t, checkingAccountService.HandleCommand(MakeStandingOrderPayment{ID: "PAYER", StandingOrderID: "RENT", PaymentDate: paymentDate, ActingAs: SystemPrincipal}))
+	clock.Set(time.Date(2020, time.August, 3, 10, 0, 0, 0, time.UTC))
+	assert.Nil(t, checkingAccountService.HandleCommand(MarkAccountDormant{ID: "PAYER", InactivityPeriod: 90 * 24 * time.Hour, ActingAs: SystemPrincipal}))
+	assert.Nil(t, checkingAccountService.HandleCommand(FreezeAccount{ID: "PAYER", Scope: FreezeAll, ReasonCode: ReasonSuspectedFraud, ActingAs: SystemPrincipal}))
+
+	// When
+	err := checkingAccountService.HandleCommand(ReturnStandingOrderPayment{ID: "PAYER", StandingOrderID: "RENT", Amount: 100, PaymentDate: paymentDate, ActingAs: SystemPrincipal})
+
+	// Then
+	assert.Nil(t, err)
+	payer, err := checkingAccountService.loadAccount("PAYER")
+	assert.Nil(t, err)
+	assert.Equal(t, 250, payer.LedgerBalance())
+	assert.Equal(t, AccountFrozen, payer.Status())
+	assert.Nil(t, checkingAccountService.HandleCommand(UnfreezeAccount{ID: "PAYER", ReasonCode: ReasonReviewCompleted, ActingAs: SystemPrincipal}))
+	payer, err = checkingAccountService.loadAccount("PAYER")
+	assert.Nil(t, err)
+	assert.Equal(t, AccountDormant, payer.Status())
+	standingOrder := payer.StandingOrders()[0]
+	assert.Equal(t, paymentDate, standingOrder.NextPaymentDate)
+	assert.Equal(t, 0, standingOrder.PaymentsMade)
+	assert.Equal(t, 1, standingOrder.FailedAttempts)
+}
+
+func Test_StandingOrderCommandsFollowTheAccountLifecycle(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	eventStore := Seacrest.NewEventStore()
+	checkingAccountService := New(eventStore, WithCustomerVerifier(verifiedCustomers{}))
+	paymentDate := time.Date(2020, time.February, 3, 9, 0, 0, 0, time.UTC).UnixNano()
+	assert.Nil(t, checkingAccountService.HandleCommand(OpenAccount{ID: "PAYEE", CustomerID: "CUST5"}))
+	for _, accountID := range []string{"FROZEN", "CLOSED"} {
+		assert.Nil(t, checkingAccountService.HandleCommand(OpenAccount{ID: accountID, CustomerID: "CUST1"}))
+		assert.Nil(t, checkingAccountService.HandleCommand(CreateStandingOrder{ID: accountID, StandingOrderID: "RENT", PayeeAccountID: "PAYEE", Amount: 100, Frequency: FrequencyMonthly, FirstPaymentDate: paymentDate, ActingAs: SystemPrincipal}))
+	}
+	assert.Nil(t, checkingAccountService.HandleCommand(FreezeAccount{ID: "FROZEN", Scope: FreezeAll, ReasonCode: ReasonSuspectedFraud, ActingAs: SystemPrincipal}))
+	assert.Nil(t, checkingAccountService.HandleCommand(CloseAccount{ID: "CLOSED", ActingAs: SystemPrincipal}))
+
+	// When
+	skipFrozenErr := checkingAccountService.HandleCommand(SkipStandingOrderPayment{ID: "FROZEN", StandingOrderID: "RENT", PaymentDate: paymentDate, ActingAs: SystemPrincipal})
+	cancelFrozenErr := checkingAccountService.HandleCommand(CancelStandingOrder{ID: "FROZEN", StandingOrderID: "RENT", ActingAs: SystemPrincipal})
+	skipClosedErr := checkingAccountService.HandleCommand(SkipStandingOrderPayment{ID: "CLOSED", StandingOrderID: "RENT", PaymentDate: paymentDate, ActingAs: SystemPrincipal})
+	cancelClosedErr := checkingAccountService.HandleCommand(CancelStandingOrder{ID: "CLOSED", StandingOrderID: "RENT", ActingAs: SystemPrincipal})
+
+	// Then
+	assert.Nil(t, skipFrozenErr)
+	assert.Nil(t, cancelFrozenErr)
+	assert.Contains(t, skipClosedErr.Error(), "cannot skip a standing order payment on an account that is closed")
+	assert.Contains(t, cancelClosedErr.Error(), "cannot cancel a standing order on an account that is closed")
+}
@@ -4,13 +4,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/agemmell/banking-cqrs-es-go/CustomerService"
+	"github.com/agemmell/banking-cqrs-es-go/EventRegistry"
+	"github.com/agemmell/banking-cqrs-es-go/ProductCatalog"
 	"github.com/agemmell/banking-cqrs-es-go/Seacrest"
-	"github.com/icrowley/fake"
-	uuid "github.com/nu7hatch/gouuid"
-	"math/rand"
 	"reflect"
-	"sort"
-	"time"
 )
 
 type Command interface {
@@ -26,77 +24,159 @@ type StoresEvents interface {
 	GetEventsByAggregateID(aggregateID string) map[uint]Seacrest.EventEnvelope
 	WriteEventsToFile(filename string) error
 	PersistEvent(aggregateID string, eventType string, payload []byte) error
+	PersistEventsExpectingVersion(aggregateID string, expectedVersion uint, events []Seacrest.PendingEvent) error
 	LoadEventsFromFile(filename string) error
 }
 
+// VerifiesCustomers: checks a customer has passed KYC before an account is opened for them, and gives the name they
+// registered with, which the account is held in
+type VerifiesCustomers interface {
+	VerifiedName(customerID string) (string, error)
+}
+
 type CheckingAccountService struct {
 	eventStore StoresEvents
+	catalog    *ProductCatalog.Catalog
+	clock      Clock
+	customers  VerifiesCustomers
+	ids        IDGenerator
+}
+
+// Option: configures optional CheckingAccountService dependencies
+type Option func(cas *CheckingAccountService)
+
+// WithProductCatalog: offer the products in the given catalog instead of the default products
+func WithProductCatalog(catalog *ProductCatalog.Catalog) Option {
+	return func(cas *CheckingAccountService) {
+		cas.catalog = catalog
+	}
+}
+
+// WithClock: use the given clock instead of the system clock
+func WithClock(clock Clock) Option {
+	return func(cas *CheckingAccountService) {
+		cas.clock = clock
+	}
+}
+
+// WithIDGenerator: generate IDs with the given generator instead of random UUIDs
+func WithIDGenerator(ids IDGenerator) Option {
+	return func(cas *CheckingAccountService) {
+		cas.ids = ids
+	}
+}
+
+// WithCustomerVerifier: check customers with the given verifier instead of the customers in the service's event store
+func WithCustomerVerifier(customers VerifiesCustomers) Option {
+	return func(cas *CheckingAccountService) {
+		cas.customers = customers
+	}
 }
 
-func New(eventStore StoresEvents) CheckingAccountService {
-	return CheckingAccountService{eventStore}
+func New(eventStore StoresEvents, options ...Option) CheckingAccountService {
+	customers := CustomerService.New(eventStore)
+	cas := CheckingAccountService{eventStore, ProductCatalog.Default(), SystemClock{}, &customers, Seacrest.UUIDGenerator{}}
+	for _, option := range options {
+		option(&cas)
+	}
+	return cas
+}
+
+// SetFeeSchedule: configure the fees charged to accounts of a checking product, adding the product to the catalog if
+// it is new
+func (cas *CheckingAccountService) SetFeeSchedule(productCode string, feeSchedule FeeSchedule) error {
+	product, err := cas.catalog.Get(productCode, ProductCatalog.KindChecking)
+	if err != nil {
+		product = ProductCatalog.Product{Code: productCode, Name: productCode, Kind: ProductCatalog.KindChecking}
+	}
+	product.FeeSchedule = feeSchedule
+	return cas.catalog.Set(product)
 }
 
-// HandleCommand: Handles commands
+// HandleCommand: Handles commands. Every command on an existing account is authorized for the principal it is acting
+// as:
+//  - PermissionView: none; views are authorized by GetAccountSummary and GetAccountSummaryAsOf
+//  - PermissionDeposit: DepositMoney
+//  - PermissionWithdraw: WithdrawMoney, CreateStandingOrder, AmendStandingOrder and CancelStandingOrder
+//  - PermissionClose: CloseAccount and ReopenAccount
+//  - PermissionChangeLimits: SetWithdrawalLimits
+//  - PermissionManageHolders: ChangeHolderName, AddJointHolder, RemoveJointHolder, AddAuthorizedSignatory and
+//    RemoveAuthorizedSignatory
+//  - PermissionOperate: ChargeFee, RefundFee, PlaceHold, ReleaseHold, CaptureHold, ExpireHolds, FreezeAccount,
+//    UnfreezeAccount, MakeStandingOrderPayment, SkipStandingOrderPayment, ReturnStandingOrderPayment and
+//    MarkAccountDormant
+// OpenAccount is authorized by the customer having passed KYC.
 func (cas *CheckingAccountService) HandleCommand(command Command) error {
 
 	switch commandType := command.(type) {
 	case OpenAccount:
-		account := Account{}
-		err := account.OpenAccount(commandType.ID, commandType.Name)
+		product := commandType.Product
+		if product == "" {
+			product = ProductStandardChecking
+		}
+		_, err := cas.catalog.Get(product, ProductCatalog.KindChecking)
+		if err != nil {
+			return err
+		}
+		name, err := cas.customers.VerifiedName(commandType.CustomerID)
 		if err != nil {
 			return err
 		}
-		err = cas.PersistEvents(account.GetNewEvents()...)
+		account, err := cas.loadAccount(commandType.ID)
+		if err != nil {
+			return err
+		}
+		err = account.OpenAccount(commandType.ID, commandType.CustomerID, name, product)
+		if err != nil {
+			return err
+		}
+		err = cas.persistNewEvents(account)
 		if err != nil {
 			return err
 		}
 
 	case DepositMoney:
-		events, err := cas.GetEventsByAggregateID(commandType.ID)
+		account, err := cas.loadAccount(commandType.ID)
 		if err != nil {
 			return err
 		}
-		account := Account{}
-		err = account.LoadFromEvents(events)
+		err = account.Authorize(commandType.ActingAs, PermissionDeposit)
 		if err != nil {
 			return err
 		}
-		err = account.DepositMoney(commandType.Amount)
+		err = account.DepositMoney(commandType.Amount, commandType.ActingAs)
 		if err != nil {
 			return err
 		}
-		err = cas.PersistEvents(account.GetNewEvents()...)
+		err = cas.persistNewEvents(account)
 		if err != nil {
 			return err
 		}
 
 	case WithdrawMoney:
-		events, err := cas.GetEventsByAggregateID(commandType.ID)
+		account, err := cas.loadAccount(commandType.ID)
 		if err != nil {
 			return err
 		}
-		account := Account{}
-		err = account.LoadFromEvents(events)
+		err = account.Authorize(commandType.ActingAs, PermissionWithdraw)
 		if err != nil {
 			return err
 		}
-		err = account.WithdrawMoney(commandType.Amount)
+		err = account.WithdrawMoney(commandType.Amount, commandType.ActingAs)
 		if err != nil {
 			return err
 		}
-		err = cas.PersistEvents(account.GetNewEvents()...)
+		err = cas.persistNewEvents(account)
 		if err != nil {
 			return err
 		}
 
 	case CloseAccount:
-		events, err := cas.GetEventsByAggregateID(commandType.ID)
+		account, err := cas.loadAccount(commandType.ID)
 		if err != nil {
 			return err
 		}
-		account := Account{}
-		err = account.LoadFromEvents(events)
+		err = account.Authorize(commandType.ActingAs, PermissionClose)
 		if err != nil {
 			return err
 		}
@@ -104,7 +184,420 @@ func (cas *CheckingAccountService) HandleCommand(command Command) error {
 		if err != nil {
 			return err
 		}
-		err = cas.PersistEvents(account.GetNewEvents()...)
+		err = cas.persistNewEvents(account)
+		if err != nil {
+			return err
+		}
+
+	case ChargeFee:
+		account, err := cas.loadAccount(commandType.ID)
+		if err != nil {
+			return err
+		}
+		err = account.Authorize(commandType.ActingAs, PermissionOperate)
+		if err != nil {
+			return err
+		}
+		err = account.ChargeFee(commandType.FeeType)
+		if err != nil {
+			return err
+		}
+		err = cas.persistNewEvents(account)
+		if err != nil {
+			return err
+		}
+
+	case RefundFee:
+		account, err := cas.loadAccount(commandType.ID)
+		if err != nil {
+			return err
+		}
+		err = account.Authorize(commandType.ActingAs, PermissionOperate)
+		if err != nil {
+			return err
+		}
+		err = account.RefundFee(commandType.FeeID)
+		if err != nil {
+			return err
+		}
+		err = cas.persistNewEvents(account)
+		if err != nil {
+			return err
+		}
+
+	case PlaceHold:
+		account, err := cas.loadAccount(commandType.ID)
+		if err != nil {
+			return err
+		}
+		err = account.Authorize(commandType.ActingAs, PermissionOperate)
+		if err != nil {
+			return err
+		}
+		err = account.PlaceHold(commandType.HoldID, commandType.Amount, commandType.ExpiresAt)
+		if err != nil {
+			return err
+		}
+		err = cas.persistNewEvents(account)
+		if err != nil {
+			return err
+		}
+
+	case ReleaseHold:
+		account, err := cas.loadAccount(commandType.ID)
+		if err != nil {
+			return err
+		}
+		err = account.Authorize(commandType.ActingAs, PermissionOperate)
+		if err != nil {
+			return err
+		}
+		err = account.ReleaseHold(commandType.HoldID)
+		if err != nil {
+			return err
+		}
+		err = cas.persistNewEvents(account)
+		if err != nil {
+			return err
+		}
+
+	case CaptureHold:
+		account, err := cas.loadAccount(commandType.ID)
+		if err != nil {
+			return err
+		}
+		err = account.Authorize(commandType.ActingAs, PermissionOperate)
+		if err != nil {
+			return err
+		}
+		err = account.CaptureHold(commandType.HoldID, commandType.Amount)
+		if err != nil {
+			return err
+		}
+		err = cas.persistNewEvents(account)
+		if err != nil {
+			return err
+		}
+
+	case SetWithdrawalLimits:
+		account, err := cas.loadAccount(commandType.ID)
+		if err != nil {
+			return err
+		}
+		err = account.Authorize(commandType.ActingAs, PermissionChangeLimits)
+		if err != nil {
+			return err
+		}
+		err = account.SetWithdrawalLimits(commandType.PerTransactionLimit, commandType.DailyLimit, commandType.ActingAs)
+		if err != nil {
+			return err
+		}
+		err = cas.persistNewEvents(account)
+		if err != nil {
+			return err
+		}
+
+	case ExpireHolds:
+		account, err := cas.loadAccount(commandType.ID)
+		if err != nil {
+			return err
+		}
+		err = account.Authorize(commandType.ActingAs, PermissionOperate)
+		if err != nil {
+			return err
+		}
+		err = account.ExpireHolds()
+		if err != nil {
+			return err
+		}
+		err = cas.persistNewEvents(account)
+		if err != nil {
+			return err
+		}
+
+	case FreezeAccount:
+		account, err := cas.loadAccount(commandType.ID)
+		if err != nil {
+			return err
+		}
+		err = account.Authorize(commandType.ActingAs, PermissionOperate)
+		if err != nil {
+			return err
+		}
+		err = account.FreezeAccount(commandType.Scope, commandType.ReasonCode)
+		if err != nil {
+			return err
+		}
+		err = cas.persistNewEvents(account)
+		if err != nil {
+			return err
+		}
+
+	case UnfreezeAccount:
+		account, err := cas.loadAccount(commandType.ID)
+		if err != nil {
+			return err
+		}
+		err = account.Authorize(commandType.ActingAs, PermissionOperate)
+		if err != nil {
+			return err
+		}
+		err = account.UnfreezeAccount(commandType.ReasonCode)
+		if err != nil {
+			return err
+		}
+		err = cas.persistNewEvents(account)
+		if err != nil {
+			return err
+		}
+
+	case ChangeHolderName:
+		account, err := cas.loadAccount(commandType.ID)
+		if err != nil {
+			return err
+		}
+		err = account.Authorize(commandType.ActingAs, PermissionManageHolders)
+		if err != nil {
+			return err
+		}
+		err = account.ChangeHolderName(commandType.HolderID, commandType.Name, commandType.ActingAs)
+		if err != nil {
+			return err
+		}
+		err = cas.persistNewEvents(account)
+		if err != nil {
+			return err
+		}
+
+	case AddJointHolder:
+		account, err := cas.loadAccount(commandType.ID)
+		if err != nil {
+			return err
+		}
+		err = account.Authorize(commandType.ActingAs, PermissionManageHolders)
+		if err != nil {
+			return err
+		}
+		err = account.AddJointHolder(commandType.HolderID, commandType.Name, commandType.ActingAs)
+		if err != nil {
+			return err
+		}
+		err = cas.persistNewEvents(account)
+		if err != nil {
+			return err
+		}
+
+	case RemoveJointHolder:
+		account, err := cas.loadAccount(commandType.ID)
+		if err != nil {
+			return err
+		}
+		err = account.Authorize(commandType.ActingAs, PermissionManageHolders)
+		if err != nil {
+			return err
+		}
+		err = account.RemoveJointHolder(commandType.HolderID, commandType.ActingAs)
+		if err != nil {
+			return err
+		}
+		err = cas.persistNewEvents(account)
+		if err != nil {
+			return err
+		}
+
+	case AddAuthorizedSignatory:
+		account, err := cas.loadAccount(commandType.ID)
+		if err != nil {
+			return err
+		}
+		err = account.Authorize(commandType.ActingAs, PermissionManageHolders)
+		if err != nil {
+			return err
+		}
+		err = account.AddAuthorizedSignatory(commandType.SignatoryID, commandType.Name, commandType.Permissions, commandType.ActingAs)
+		if err != nil {
+			return err
+		}
+		err = cas.persistNewEvents(account)
+		if err != nil {
+			return err
+		}
+
+	case RemoveAuthorizedSignatory:
+		account, err := cas.loadAccount(commandType.ID)
+		if err != nil {
+			return err
+		}
+		err = account.Authorize(commandType.ActingAs, PermissionManageHolders)
+		if err != nil {
+			return err
+		}
+		err = account.RemoveAuthorizedSignatory(commandType.SignatoryID, commandType.ActingAs)
+		if err != nil {
+			return err
+		}
+		err = cas.persistNewEvents(account)
+		if err != nil {
+			return err
+		}
+
+	case CreateStandingOrder:
+		account, err := cas.loadAccount(commandType.ID)
+		if err != nil {
+			return err
+		}
+		err = account.Authorize(commandType.ActingAs, PermissionWithdraw)
+		if err != nil {
+			return err
+		}
+		err = cas.ensurePayeeCanBePaid(commandType.PayeeAccountID)
+		if err != nil {
+			return err
+		}
+		err = account.CreateStandingOrder(commandType.StandingOrderID, commandType.PayeeAccountID, commandType.Amount, commandType.Frequency, commandType.FirstPaymentDate, commandType.ActingAs)
+		if err != nil {
+			return err
+		}
+		err = cas.persistNewEvents(account)
+		if err != nil {
+			return err
+		}
+
+	case AmendStandingOrder:
+		account, err := cas.loadAccount(commandType.ID)
+		if err != nil {
+			return err
+		}
+		err = account.Authorize(commandType.ActingAs, PermissionWithdraw)
+		if err != nil {
+			return err
+		}
+		if commandType.PayeeAccountID != "" {
+			err = cas.ensurePayeeCanBePaid(commandType.PayeeAccountID)
+			if err != nil {
+				return err
+			}
+		}
+		err = account.AmendStandingOrder(commandType.StandingOrderID, commandType.PayeeAccountID, commandType.Amount, commandType.ActingAs)
+		if err != nil {
+			return err
+		}
+		err = cas.persistNewEvents(account)
+		if err != nil {
+			return err
+		}
+
+	case CancelStandingOrder:
+		account, err := cas.loadAccount(commandType.ID)
+		if err != nil {
+			return err
+		}
+		err = account.Authorize(commandType.ActingAs, PermissionWithdraw)
+		if err != nil {
+			return err
+		}
+		err = account.CancelStandingOrder(commandType.StandingOrderID, commandType.ActingAs)
+		if err != nil {
+			return err
+		}
+		err = cas.persistNewEvents(account)
+		if err != nil {
+			return err
+		}
+
+	case MakeStandingOrderPayment:
+		account, err := cas.loadAccount(commandType.ID)
+		if err != nil {
+			return err
+		}
+		err = account.Authorize(commandType.ActingAs, PermissionOperate)
+		if err != nil {
+			return err
+		}
+		err = account.MakeStandingOrderPayment(commandType.StandingOrderID, commandType.PaymentDate)
+		if err != nil {
+			return err
+		}
+		err = cas.persistNewEvents(account)
+		if err != nil {
+			return err
+		}
+
+	case SkipStandingOrderPayment:
+		account, err := cas.loadAccount(commandType.ID)
+		if err != nil {
+			return err
+		}
+		err = account.Authorize(commandType.ActingAs, PermissionOperate)
+		if err != nil {
+			return err
+		}
+		err = account.SkipStandingOrderPayment(commandType.StandingOrderID, commandType.PaymentDate)
+		if err != nil {
+			return err
+		}
+		err = cas.persistNewEvents(account)
+		if err != nil {
+			return err
+		}
+
+	case ReturnStandingOrderPayment:
+		account, err := cas.loadAccount(commandType.ID)
+		if err != nil {
+			return err
+		}
+		err = account.Authorize(commandType.ActingAs, PermissionOperate)
+		if err != nil {
+			return err
+		}
+		err = account.ReturnStandingOrderPayment(commandType.StandingOrderID, commandType.Amount, commandType.PaymentDate)
+		if err != nil {
+			return err
+		}
+		err = cas.persistNewEvents(account)
+		if err != nil {
+			return err
+		}
+
+	case ReopenAccount:
+		account, err := cas.loadAccount(commandType.ID)
+		if err != nil {
+			return err
+		}
+		err = account.Authorize(commandType.ActingAs, PermissionClose)
+		if err != nil {
+			return err
+		}
+		// Accounts opened before customers had to register have no customer to verify
+		if account.registeredHolder {
+			_, err = cas.customers.VerifiedName(account.primaryHolderID)
+			if err != nil {
+				return err
+			}
+		}
+		err = account.ReopenAccount(commandType.ActingAs)
+		if err != nil {
+			return err
+		}
+		err = cas.persistNewEvents(account)
+		if err != nil {
+			return err
+		}
+
+	case MarkAccountDormant:
+		account, err := cas.loadAccount(commandType.ID)
+		if err != nil {
+			return err
+		}
+		err = account.Authorize(commandType.ActingAs, PermissionOperate)
+		if err != nil {
+			return err
+		}
+		err = account.MarkDormant(commandType.InactivityPeriod)
+		if err != nil {
+			return err
+		}
+		err = cas.persistNewEvents(account)
 		if err != nil {
 			return err
 		}
@@ -117,6 +610,102 @@ func (cas *CheckingAccountService) HandleCommand(command Command) error {
 	return nil
 }
 
+type AccountSummary struct {
+	ID               string
+	Name             string
+	Holders          map[string]string
+	Status           AccountStatus
+	LedgerBalance    int
+	AvailableBalance int
+}
+
+// GetAccountSummary: the current state of an account, as seen by a principal with permission to view it
+func (cas *CheckingAccountService) GetAccountSummary(aggregateID string, actingAs string) (AccountSummary, error) {
+	account, err := cas.loadAccount(aggregateID)
+	if err != nil {
+		return AccountSummary{}, err
+	}
+	if account.Status() == AccountPending {
+		return AccountSummary{}, errors.New(fmt.Sprintf("account %s does not exist", aggregateID))
+	}
+	err = account.Authorize(actingAs, PermissionView)
+	if err != nil {
+		return AccountSummary{}, err
+	}
+
+	return summarise(account, cas.clock.Now().UnixNano()), nil
+}
+
+// summarise: an account's state, with holds judged as they stood at now
+func summarise(account *Account, now int64) AccountSummary {
+	return AccountSummary{
+		ID:               account.AggregateID(),
+		Name:             account.name,
+		Holders:          account.Holders(),
+		Status:           account.Status(),
+		LedgerBalance:    account.LedgerBalance(),
+		AvailableBalance: account.AvailableBalance(now),
+	}
+}
+
+// ensurePayeeCanBePaid: standing orders can only pay accounts at this bank that are able to accept deposits
+func (cas *CheckingAccountService) ensurePayeeCanBePaid(payeeAccountID string) error {
+	payee, err := cas.loadAccount(payeeAccountID)
+	if err != nil {
+		return err
+	}
+	return payee.ensureAllowed(actionDeposit)
+}
+
+// loadAccount: rebuild an account from its past events and configure it for its product
+func (cas *CheckingAccountService) loadAccount(aggregateID string) (*Account, error) {
+	events, err := cas.GetEventsByAggregateID(aggregateID)
+	if err != nil {
+		return nil, err
+	}
+	return cas.rebuildAccount(events)
+}
+
+// rebuildAccount: an account in the state the events leave it in, configured for its product
+func (cas *CheckingAccountService) rebuildAccount(events []Event) (*Account, error) {
+	account := Account{}
+	err := account.LoadFromEvents(events)
+	if err != nil {
+		return nil, err
+	}
+	if account.Status() != AccountPending {
+		product, err := cas.catalog.Get(account.Product(), ProductCatalog.KindChecking)
+		if err != nil {
+			return nil, err
+		}
+		account.SetFeeSchedule(product.FeeSchedule)
+	}
+	account.SetClock(cas.clock)
+	return &account, nil
+}
+
+// persistNewEvents: persist the events a command raised on the account together, so a command that raises several,
+// such as a withdrawal and its fee, never leaves only some of them behind. Fails with a Seacrest.VersionConflictError,
+// persisting nothing, if another command has changed the account since it was loaded.
+func (cas *CheckingAccountService) persistNewEvents(account *Account) error {
+	newEvents := account.GetNewEvents()
+	pending := make([]Seacrest.PendingEvent, 0, len(newEvents))
+	for _, event := range newEvents {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		pending = append(pending, Seacrest.PendingEvent{EventType: event.EventType(), Payload: payload})
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	loadedVersion := account.Version() - uint(len(newEvents))
+	return cas.eventStore.PersistEventsExpectingVersion(account.AggregateID(), loadedVersion, pending)
+}
+
+// PersistEvents: persist each event after its aggregate's latest, one at a time, e.g. to record an account's history.
+// An event that fails leaves the events before it persisted.
 func (cas *CheckingAccountService) PersistEvents(events ...Event) error {
 	for _, event := range events {
 		payload, err := json.Marshal(event)
@@ -148,7 +737,12 @@ func (cas *CheckingAccountService) GetAllEvents() ([]Event, error) {
 func (cas *CheckingAccountService) GetEventsByAggregateID(aggregateID string) ([]Event, error) {
 	envelopes := cas.eventStore.GetEventsByAggregateID(aggregateID)
 	var events []Event
-	for _, envelope := range envelopes {
+	// envelopes are keyed by version, iterate in version order rather than (random) map order
+	for version := uint(0); version < uint(len(envelopes)); version++ {
+		envelope, ok := envelopes[version]
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("missing event version %d for aggregate %s", version, aggregateID))
+		}
 		event, err := cas.TransformEnvelopeToEvent(envelope)
 		if err != nil {
 			return nil, err
@@ -159,28 +753,7 @@ func (cas *CheckingAccountService) GetEventsByAggregateID(aggregateID string) ([
 }
 
 func (cas *CheckingAccountService) TransformEnvelopeToEvent(envelope Seacrest.EventEnvelope) (Event, error) {
-	var event Event
-	switch envelope.EventType {
-	case TypeAccountWasOpened:
-		event = &AccountWasOpened{}
-	case TypeMoneyWasDeposited:
-		event = &MoneyWasDeposited{}
-	case TypeMoneyWasWithdrawn:
-		event = &MoneyWasWithdrawn{}
-	case TypeWithdrawFailedDueToInsufficientFunds:
-		event = &WithdrawFailedDueToInsufficientFunds{}
-	case TypeAccountWasClosed:
-		event = &AccountWasClosed{}
-	default:
-		return nil, errors.New(fmt.Sprintf("unknown event type in envelope %s", envelope.EventType))
-	}
-
-	err := json.Unmarshal(envelope.Payload, &event)
-	if err != nil {
-		return nil, err
-	}
-
-	return event, nil
+	return EventRegistry.Decode(envelope)
 }
 
 func (cas *CheckingAccountService) HydrateEvent(payload []byte, event Event) error {
@@ -208,144 +781,3 @@ func (cas *CheckingAccountService) LoadEventsFromFile(filename string) error {
 
 	return nil
 }
-
-// AutoGenerateEvents
-func (cas *CheckingAccountService) AutoGenerateEvents() error {
-	var allCustomerEvents []Event
-	customerCount := 100000
-	closeAccountChance := float32(0.1)
-	randSeed := int64(99)
-	rand.Seed(randSeed)
-	fake.Seed(randSeed)
-
-	for i := 0; i < customerCount; i++ {
-		closeAccount := false
-		if rand.Float32() >= closeAccountChance {
-			closeAccount = true
-		}
-		events, err := cas.GenerateFakeCustomerEvents(closeAccount)
-		if err != nil {
-			return err
-		}
-		allCustomerEvents = append(allCustomerEvents, events...)
-	}
-
-	// TODO Improve this by making the event envelope timestamps use a faked clock. Currently all events have faked
-	//  timestamps but the envelopes don't so all events are in the order in which they were generated (customer
-	//  events are grouped together despite having mixed event timestamps)
-	sort.Slice(allCustomerEvents, func(i, j int) bool {
-		return allCustomerEvents[i].EventTimestamp() < allCustomerEvents[j].EventTimestamp()
-	})
-	err := cas.PersistEvents(allCustomerEvents...)
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
-func (cas *CheckingAccountService) GenerateFakeCustomerEvents(closeAccount bool) ([]Event, error) {
-	var events []Event
-
-	startTime := time.Date(2020, time.January, 0, 0, 0, 0, 0, time.UTC)
-	month := rand.Intn(9)
-	day := rand.Intn(31)
-	hour := time.Hour * time.Duration(rand.Intn(24))
-	minute := time.Minute * time.Duration(rand.Intn(60))
-	second := time.Second * time.Duration(rand.Intn(60))
-	startTime = startTime.AddDate(0, month, day).Add(hour).Add(minute).Add(second)
-
-	// generate a UUID
-	UUID, err := uuid.NewV4()
-	if err != nil {
-		return nil, err
-	}
-	aggregateID := UUID.String()
-
-	// generate a fake name
-	fullName := fake.FullName()
-
-	// create AccountWasOpened event
-	events = append(events, AccountWasOpened{
-		ID:        aggregateID,
-		Name:      fullName,
-		Timestamp: startTime.UnixNano(),
-	})
-
-	// create random account deposit & withdraw events
-	depositMax := rand.Intn(100000)
-	balance := 0
-	var depositAmount, withdrawnAmount int
-	depositMin := 500
-	maxEventsCount := 10
-
-	for {
-		day := rand.Intn(4)
-		hour := time.Hour * time.Duration(rand.Intn(24))
-		minute := time.Minute * time.Duration(rand.Intn(60))
-		second := time.Second * time.Duration(rand.Intn(60))
-		startTime = startTime.AddDate(0, month, day).Add(hour).Add(minute).Add(second)
-
-		if depositMax == 0 {
-			if closeAccount {
-				if balance != 0 {
-					withdrawnAmount = balance
-					balance = 0
-					events = append(events, MoneyWasWithdrawn{
-						ID:        aggregateID,
-						Amount:    withdrawnAmount,
-						Balance:   balance,
-						Timestamp: startTime.UnixNano(),
-					})
-				}
-
-				events = append(events, AccountWasClosed{
-					ID:        aggregateID,
-					Timestamp: startTime.Add(time.Millisecond * 10).UnixNano(),
-				})
-			}
-			break
-		}
-
-		withdraw := false
-		withdrawChance := float32(0.9)
-		if rand.Float32() <= withdrawChance {
-			withdraw = true
-		}
-		// Must deposit if balance is 0
-		if balance == 0 {
-			withdraw = false
-		}
-
-		if withdraw {
-			withdrawnAmount = rand.Intn(balance)
-			balance -= withdrawnAmount
-			events = append(events, MoneyWasWithdrawn{
-				ID:        aggregateID,
-				Amount:    withdrawnAmount,
-				Balance:   balance,
-				Timestamp: startTime.UnixNano(),
-			})
-		} else {
-			if depositMax <= depositMin {
-				depositAmount = depositMax
-			} else {
-				// TODO Bug for future Alex: this can potentially generate a deposit amount of zero!
-				depositAmount = rand.Intn(depositMax)
-			}
-			depositMax -= depositAmount
-			balance += depositAmount
-			events = append(events, MoneyWasDeposited{
-				ID:        aggregateID,
-				Amount:    depositAmount,
-				Timestamp: startTime.UnixNano(),
-			})
-		}
-
-		if len(events) >= maxEventsCount {
-			break
-		}
-
-	}
-
-	return events, nil
-}
@@ -1,10 +1,15 @@
 package CheckingAccountService
 
 import (
+	"errors"
+	"fmt"
+	"github.com/agemmell/banking-cqrs-es-go/CustomerService"
+	"github.com/agemmell/banking-cqrs-es-go/ProductCatalog"
 	"github.com/agemmell/banking-cqrs-es-go/Seacrest"
 	uuid "github.com/nu7hatch/gouuid"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 func Test_NewServiceNoEvents(t *testing.T) {
@@ -21,6 +26,20 @@ type UnknownCommand struct{}
 
 func (uc UnknownCommand) isCommand() {}
 
+// verifiedCustomers: treats the customers in customerNames as having passed KYC so tests can open accounts without
+// registering them
+type verifiedCustomers struct{}
+
+var customerNames = map[string]string{"CUST1": "Alex Gemmell", "CUST2": "Sam Smith", "CUST3": "Jo Bloggs", "CUST5": "Sam Landlord"}
+
+func (vc verifiedCustomers) VerifiedName(customerID string) (string, error) {
+	name, ok := customerNames[customerID]
+	if !ok {
+		return "", &CustomerService.UnknownCustomerError{CustomerID: customerID}
+	}
+	return name, nil
+}
+
 func Test_Handle_Unknown_Command(t *testing.T) {
 	t.Parallel()
 
@@ -42,14 +61,14 @@ func Test_OpenAnAccount(t *testing.T) {
 
 	// Given
 	eventStore := Seacrest.NewEventStore()
-	checkingAccountService := New(eventStore)
+	checkingAccountService := New(eventStore, WithCustomerVerifier(verifiedCustomers{}))
 
 	// When
 	accountUUID, err := uuid.NewV4()
 	assert.Nil(t, err)
 	openAccount := OpenAccount{
-		ID:   accountUUID.String(),
-		Name: "Alex Gemmell",
+		ID:         accountUUID.String(),
+		CustomerID: "CUST1",
 	}
 	err = checkingAccountService.HandleCommand(openAccount)
 	assert.Nil(t, err)
@@ -62,7 +81,8 @@ func Test_OpenAnAccount(t *testing.T) {
 	eventType, ok := events[0].(*AccountWasOpened)
 	assert.True(t, ok)
 	assert.Equal(t, openAccount.ID, eventType.ID)
-	assert.Equal(t, openAccount.Name, eventType.Name)
+	assert.Equal(t, "Alex Gemmell", eventType.Name)
+	assert.Equal(t, "CUST1", eventType.HolderID)
 }
 
 func Test_DepositMoney(t *testing.T) {
@@ -70,7 +90,7 @@ func Test_DepositMoney(t *testing.T) {
 
 	// Given
 	eventStore := Seacrest.NewEventStore()
-	checkingAccountService := New(eventStore)
+	checkingAccountService := New(eventStore, WithCustomerVerifier(verifiedCustomers{}))
 	id := "ABCD"
 	name := "Alex Gemmell"
 	accountWasOpened := AccountWasOpened{
@@ -83,8 +103,9 @@ func Test_DepositMoney(t *testing.T) {
 
 	// When
 	depositMoney := DepositMoney{
-		ID:     id,
-		Amount: 1099,
+		ID:       id,
+		Amount:   1099,
+		ActingAs: SystemPrincipal,
 	}
 	err = checkingAccountService.HandleCommand(depositMoney)
 	assert.Nil(t, err)
@@ -105,7 +126,7 @@ func Test_WithdrawMoney(t *testing.T) {
 
 	// Given
 	eventStore := Seacrest.NewEventStore()
-	checkingAccountService := New(eventStore)
+	checkingAccountService := New(eventStore, WithCustomerVerifier(verifiedCustomers{}))
 	id := "ABCD"
 	name := "Alex Gemmell"
 	accountWasOpened := AccountWasOpened{
@@ -122,8 +143,9 @@ func Test_WithdrawMoney(t *testing.T) {
 
 	// When
 	withdrawMoney := WithdrawMoney{
-		ID:     id,
-		Amount: 199,
+		ID:       id,
+		Amount:   199,
+		ActingAs: SystemPrincipal,
 	}
 	err = checkingAccountService.HandleCommand(withdrawMoney)
 	assert.Nil(t, err)
@@ -145,7 +167,7 @@ func Test_WithdrawFailedDueToInsufficientFunds(t *testing.T) {
 
 	// Given
 	eventStore := Seacrest.NewEventStore()
-	checkingAccountService := New(eventStore)
+	checkingAccountService := New(eventStore, WithCustomerVerifier(verifiedCustomers{}))
 	id := "ABCD"
 	name := "Alex Gemmell"
 	accountWasOpened := AccountWasOpened{
@@ -166,8 +188,9 @@ func Test_WithdrawFailedDueToInsufficientFunds(t *testing.T) {
 
 	// When
 	withdrawMoney := WithdrawMoney{
-		ID:     id,
-		Amount: 1,
+		ID:       id,
+		Amount:   1,
+		ActingAs: SystemPrincipal,
 	}
 	err = checkingAccountService.HandleCommand(withdrawMoney)
 	assert.Nil(t, err)
@@ -189,7 +212,7 @@ func Test_CloseAccount(t *testing.T) {
 
 	// Given
 	eventStore := Seacrest.NewEventStore()
-	checkingAccountService := New(eventStore)
+	checkingAccountService := New(eventStore, WithCustomerVerifier(verifiedCustomers{}))
 	id := "ABCD"
 	name := "Alex Gemmell"
 	accountWasOpened := AccountWasOpened{
@@ -210,7 +233,8 @@ func Test_CloseAccount(t *testing.T) {
 
 	// When
 	closeAccount := CloseAccount{
-		ID: id,
+		ID:       id,
+		ActingAs: SystemPrincipal,
 	}
 	err = checkingAccountService.HandleCommand(closeAccount)
 	assert.Nil(t, err)
@@ -225,43 +249,414 @@ func Test_CloseAccount(t *testing.T) {
 	assert.Equal(t, closeAccount.ID, eventType.ID)
 }
 
-func Test_GenerateFakeCustomerEvents(t *testing.T) {
+func Test_ChargeAndRefundFee(t *testing.T) {
 	t.Parallel()
 
 	// Given
 	eventStore := Seacrest.NewEventStore()
-	checkingAccountService := New(eventStore)
+	checkingAccountService := New(eventStore, WithCustomerVerifier(verifiedCustomers{}))
+	err := checkingAccountService.SetFeeSchedule("Premium", FeeSchedule{MonthlyMaintenanceFee: 15})
+	assert.Nil(t, err)
+	id := "ABCD"
+	err = checkingAccountService.HandleCommand(OpenAccount{ID: id, CustomerID: "CUST1", Product: "Premium"})
+	assert.Nil(t, err)
+
+	// When
+	err = checkingAccountService.HandleCommand(ChargeFee{ID: id, FeeType: FeeTypeMonthlyMaintenance, ActingAs: SystemPrincipal})
+	assert.Nil(t, err)
+
+	// Then
+	events, err := checkingAccountService.GetAllEvents()
+	assert.Nil(t, err)
+	assert.Len(t, events, 2)
+	feeWasCharged, ok := events[1].(*FeeWasCharged)
+	assert.True(t, ok)
+	assert.Equal(t, 15, feeWasCharged.Amount)
+	assert.Equal(t, -15, feeWasCharged.Balance)
+
+	// When
+	err = checkingAccountService.HandleCommand(RefundFee{ID: id, FeeID: feeWasCharged.FeeID, ActingAs: SystemPrincipal})
+	assert.Nil(t, err)
+
+	// Then
+	events, err = checkingAccountService.GetAllEvents()
+	assert.Nil(t, err)
+	assert.Len(t, events, 3)
+	feeWasRefunded, ok := events[2].(*FeeWasRefunded)
+	assert.True(t, ok)
+	assert.Equal(t, feeWasCharged.FeeID, feeWasRefunded.FeeID)
+	assert.Equal(t, 0, feeWasRefunded.Balance)
+}
+
+func Test_PlaceAndCaptureHold(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	eventStore := Seacrest.NewEventStore()
+	checkingAccountService := New(eventStore, WithCustomerVerifier(verifiedCustomers{}))
+	id := "ABCD"
+	accountWasOpened := AccountWasOpened{
+		ID:   id,
+		Name: "Alex Gemmell",
+	}
+	moneyWasDeposited := MoneyWasDeposited{
+		ID:     id,
+		Amount: 1099,
+	}
+	historicalEvents := append([]Event{}, accountWasOpened, moneyWasDeposited)
+	err := checkingAccountService.PersistEvents(historicalEvents...)
+	assert.Nil(t, err)
+
+	// When
+	placeHold := PlaceHold{
+		ID:        id,
+		HoldID:    "HOLD1",
+		Amount:    1000,
+		ExpiresAt: time.Now().Add(time.Hour).UnixNano(),
+		ActingAs:  SystemPrincipal,
+	}
+	err = checkingAccountService.HandleCommand(placeHold)
+	assert.Nil(t, err)
+	err = checkingAccountService.HandleCommand(WithdrawMoney{ID: id, Amount: 100, ActingAs: SystemPrincipal})
+	assert.Nil(t, err)
+	err = checkingAccountService.HandleCommand(CaptureHold{ID: id, HoldID: "HOLD1", Amount: 1000, ActingAs: SystemPrincipal})
+	assert.Nil(t, err)
+
+	// Then
+	events, err := checkingAccountService.GetAllEvents()
+	assert.Nil(t, err)
+	assert.Len(t, events, 5)
+	assert.IsType(t, &HoldWasPlaced{}, events[2])
+	assert.IsType(t, &WithdrawFailedDueToInsufficientFunds{}, events[3])
+	holdWasCaptured, ok := events[4].(*HoldWasCaptured)
+	assert.True(t, ok)
+	assert.Equal(t, 99, holdWasCaptured.Balance)
+}
+
+func Test_FreezeAndUnfreezeAccount(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	eventStore := Seacrest.NewEventStore()
+	checkingAccountService := New(eventStore, WithCustomerVerifier(verifiedCustomers{}))
+	id := "ABCD"
+	accountWasOpened := AccountWasOpened{
+		ID:   id,
+		Name: "Alex Gemmell",
+	}
+	moneyWasDeposited := MoneyWasDeposited{
+		ID:     id,
+		Amount: 1099,
+	}
+	historicalEvents := append([]Event{}, accountWasOpened, moneyWasDeposited)
+	err := checkingAccountService.PersistEvents(historicalEvents...)
+	assert.Nil(t, err)
 
 	// When
-	closeAccount := false
-	events, err := checkingAccountService.GenerateFakeCustomerEvents(closeAccount)
+	err = checkingAccountService.HandleCommand(FreezeAccount{ID: id, Scope: FreezeDebits, ReasonCode: ReasonCourtOrder, ActingAs: SystemPrincipal})
+	assert.Nil(t, err)
+	withdrawErr := checkingAccountService.HandleCommand(WithdrawMoney{ID: id, Amount: 100, ActingAs: SystemPrincipal})
+	err = checkingAccountService.HandleCommand(UnfreezeAccount{ID: id, ReasonCode: ReasonReviewCompleted, ActingAs: SystemPrincipal})
+	assert.Nil(t, err)
+	err = checkingAccountService.HandleCommand(WithdrawMoney{ID: id, Amount: 100, ActingAs: SystemPrincipal})
+	assert.Nil(t, err)
+
+	// Then
+	assert.NotNil(t, withdrawErr)
+	events, err := checkingAccountService.GetAllEvents()
+	assert.Nil(t, err)
+	assert.Len(t, events, 5)
+	accountWasFrozen, ok := events[2].(*AccountWasFrozen)
+	assert.True(t, ok)
+	assert.Equal(t, FreezeDebits, accountWasFrozen.Scope)
+	assert.Equal(t, ReasonCourtOrder, accountWasFrozen.ReasonCode)
+	assert.IsType(t, &AccountWasUnfrozen{}, events[3])
+	assert.IsType(t, &MoneyWasWithdrawn{}, events[4])
+}
+
+func Test_CommandsCheckedAgainstActingPrincipal(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	eventStore := Seacrest.NewEventStore()
+	checkingAccountService := New(eventStore, WithCustomerVerifier(verifiedCustomers{}))
+	id := "ABCD"
+	err := checkingAccountService.HandleCommand(OpenAccount{ID: id, CustomerID: "CUST1"})
+	assert.Nil(t, err)
+	err = checkingAccountService.HandleCommand(DepositMoney{ID: id, Amount: 1000, ActingAs: SystemPrincipal})
+	assert.Nil(t, err)
+	addSignatory := AddAuthorizedSignatory{
+		ID:          id,
+		SignatoryID: "CUST3",
+		Name:        "Jo Bloggs",
+		Permissions: []string{PermissionView, PermissionWithdraw},
+		ActingAs:    "CUST1",
+	}
+	err = checkingAccountService.HandleCommand(addSignatory)
+	assert.Nil(t, err)
+
+	// When a signatory acts within their permissions
+	err = checkingAccountService.HandleCommand(WithdrawMoney{ID: id, Amount: 100, ActingAs: "CUST3"})
+	assert.Nil(t, err)
+	summary, err := checkingAccountService.GetAccountSummary(id, "CUST3")
+	assert.Nil(t, err)
+	assert.Equal(t, 900, summary.LedgerBalance)
+
+	// When a signatory or stranger acts beyond their permissions
+	assert.NotNil(t, checkingAccountService.HandleCommand(CloseAccount{ID: id, ActingAs: "CUST3"}))
+	assert.NotNil(t, checkingAccountService.HandleCommand(ChangeHolderName{ID: id, Name: "Jo Bloggs", ActingAs: "CUST3"}))
+	assert.NotNil(t, checkingAccountService.HandleCommand(WithdrawMoney{ID: id, Amount: 100, ActingAs: "CUST9"}))
+	_, err = checkingAccountService.GetAccountSummary(id, "CUST9")
+	assert.NotNil(t, err)
+
+	// When a holder changes their name
+	err = checkingAccountService.HandleCommand(ChangeHolderName{ID: id, Name: "Alexander Gemmell", ActingAs: "CUST1"})
 	assert.Nil(t, err)
 
 	// Then
-	accountWasOpened := false
-	moneyWasDeposited := 0
-	moneyWasWithdrawn := 0
-	accountWasClosed := false
+	summary, err = checkingAccountService.GetAccountSummary(id, "CUST1")
+	assert.Nil(t, err)
+	assert.Equal(t, "Alexander Gemmell", summary.Name)
+	events, err := checkingAccountService.GetAllEvents()
+	assert.Nil(t, err)
+	assert.Len(t, events, 5)
+}
+
+func Test_CommandsWithoutAPrincipalAreRejected(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	eventStore := Seacrest.NewEventStore()
+	checkingAccountService := New(eventStore, WithCustomerVerifier(verifiedCustomers{}))
+	id := "ABCD"
+	err := checkingAccountService.HandleCommand(OpenAccount{ID: id, CustomerID: "CUST1"})
+	assert.Nil(t, err)
+	expiresAt := time.Now().Add(time.Hour).UnixNano()
+
+	// When no principal is given
+	depositErr := checkingAccountService.HandleCommand(DepositMoney{ID: id, Amount: 1000})
+	holdErr := checkingAccountService.HandleCommand(PlaceHold{ID: id, HoldID: "HOLD1", Amount: 100, ExpiresAt: expiresAt})
+	feeErr := checkingAccountService.HandleCommand(ChargeFee{ID: id, FeeType: FeeTypeMonthlyMaintenance})
+	withdrawErr := checkingAccountService.HandleCommand(WithdrawMoney{ID: id, Amount: 100})
+
+	// When a holder does what only the bank may do
+	holderHoldErr := checkingAccountService.HandleCommand(PlaceHold{ID: id, HoldID: "HOLD1", Amount: 100, ExpiresAt: expiresAt, ActingAs: "CUST1"})
+	holderFeeErr := checkingAccountService.HandleCommand(ChargeFee{ID: id, FeeType: FeeTypeMonthlyMaintenance, ActingAs: "CUST1"})
+
+	// Then
+	assert.Equal(t, "no principal is acting on account ABCD [permission: Deposit]", depositErr.Error())
+	assert.Equal(t, "no principal is acting on account ABCD [permission: Operate]", holdErr.Error())
+	assert.Equal(t, "no principal is acting on account ABCD [permission: Operate]", feeErr.Error())
+	assert.Equal(t, "no principal is acting on account ABCD [permission: Withdraw]", withdrawErr.Error())
+	assert.Equal(t, "only the bank has the Operate permission on account ABCD", holderHoldErr.Error())
+	assert.Equal(t, "only the bank has the Operate permission on account ABCD", holderFeeErr.Error())
+	assert.Nil(t, checkingAccountService.HandleCommand(DepositMoney{ID: id, Amount: 1000, ActingAs: "CUST1"}))
+	events, err := checkingAccountService.GetAllEvents()
+	assert.Nil(t, err)
+	assert.Len(t, events, 2)
+}
+
+func Test_SetWithdrawalLimits(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	eventStore := Seacrest.NewEventStore()
+	clock := Seacrest.NewFakeClock(time.Date(2020, time.June, 30, 12, 0, 0, 0, time.UTC))
+	checkingAccountService := New(eventStore, WithClock(clock), WithCustomerVerifier(verifiedCustomers{}))
+	id := "ABCD"
+	err := checkingAccountService.HandleCommand(OpenAccount{ID: id, CustomerID: "CUST1"})
+	assert.Nil(t, err)
+	err = checkingAccountService.HandleCommand(DepositMoney{ID: id, Amount: 1000, ActingAs: SystemPrincipal})
+	assert.Nil(t, err)
+
+	// When
+	err = checkingAccountService.HandleCommand(SetWithdrawalLimits{ID: id, PerTransactionLimit: 200, DailyLimit: 300, ActingAs: "CUST1"})
+	assert.Nil(t, err)
+	err = checkingAccountService.HandleCommand(WithdrawMoney{ID: id, Amount: 200, ActingAs: SystemPrincipal})
+	assert.Nil(t, err)
+	err = checkingAccountService.HandleCommand(WithdrawMoney{ID: id, Amount: 200, ActingAs: SystemPrincipal})
+
+	// Then
+	assert.IsType(t, &WithdrawalLimitExceededError{}, err)
+	events, err := checkingAccountService.GetAllEvents()
+	assert.Nil(t, err)
+	assert.Len(t, events, 4)
 	for _, event := range events {
-		if _, ok := event.(AccountWasOpened); ok {
-			accountWasOpened = true
-			continue
-		}
-		if _, ok := event.(MoneyWasDeposited); ok {
-			moneyWasDeposited += 1
-			continue
-		}
-		if _, ok := event.(MoneyWasWithdrawn); ok {
-			moneyWasWithdrawn += 1
-			continue
-		}
-		if _, ok := event.(AccountWasClosed); ok {
-			accountWasClosed = true
-			continue
-		}
+		assert.Equal(t, clock.Now().UnixNano(), event.EventTimestamp())
 	}
-	assert.True(t, accountWasOpened, "AccountWasOpened event was not detected")
-	assert.Greater(t, moneyWasDeposited, 0, "No MoneyWasDeposited events detected")
-	assert.Greater(t, moneyWasWithdrawn, 0, "No MoneyWasWithdrawn events detected")
-	assert.False(t, accountWasClosed, "AccountWasClosed event was detected when it should not have been")
+}
+
+func Test_OpenAnAccountThatIsAlreadyOpen(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	eventStore := Seacrest.NewEventStore()
+	checkingAccountService := New(eventStore, WithCustomerVerifier(verifiedCustomers{}))
+	assert.Nil(t, checkingAccountService.HandleCommand(OpenAccount{ID: "ABCD", CustomerID: "CUST1"}))
+	assert.Nil(t, checkingAccountService.HandleCommand(DepositMoney{ID: "ABCD", Amount: 50, ActingAs: SystemPrincipal}))
+
+	// When
+	err := checkingAccountService.HandleCommand(OpenAccount{ID: "ABCD", CustomerID: "CUST1"})
+
+	// Then
+	assert.Contains(t, err.Error(), "cannot open an already open account")
+	assert.Len(t, eventStore.GetAllEvents(), 2)
+	summary, err := checkingAccountService.GetAccountSummary("ABCD", "CUST1")
+	assert.Nil(t, err)
+	assert.Equal(t, 50, summary.LedgerBalance)
+}
+
+func Test_OpenAccountWithUnknownProduct(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	eventStore := Seacrest.NewEventStore()
+	checkingAccountService := New(eventStore, WithCustomerVerifier(verifiedCustomers{}))
+
+	// When
+	err := checkingAccountService.HandleCommand(OpenAccount{ID: "ABCD", CustomerID: "CUST1", Product: "Gold"})
+
+	// Then
+	assert.Equal(t, "unknown product Gold", err.Error())
+}
+
+func Test_OpenAccountRequiresVerifiedCustomer(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	eventStore := Seacrest.NewEventStore()
+	checkingAccountService := New(eventStore)
+	customerService := CustomerService.New(eventStore)
+	address := CustomerService.Address{Line1: "1 High Street", City: "London", Postcode: "E1 6AN", Country: "GB"}
+	err := customerService.HandleCommand(CustomerService.RegisterCustomer{ID: "CUST1", Name: "Alex Gemmell", DateOfBirth: "1980-05-17", Address: address})
+	assert.Nil(t, err)
+
+	// When
+	unknownErr := checkingAccountService.HandleCommand(OpenAccount{ID: "ABCD", CustomerID: "CUST2"})
+	pendingErr := checkingAccountService.HandleCommand(OpenAccount{ID: "ABCD", CustomerID: "CUST1"})
+	assert.Nil(t, customerService.HandleCommand(CustomerService.VerifyKYC{ID: "CUST1", VerifiedBy: "compliance"}))
+	err = checkingAccountService.HandleCommand(OpenAccount{ID: "ABCD", CustomerID: "CUST1"})
+
+	// Then
+	assert.Equal(t, "customer CUST2 does not exist", unknownErr.Error())
+	assert.Equal(t, "customer CUST1 has not passed KYC [KYC: pending]", pendingErr.Error())
+	assert.Nil(t, err)
+	summary, err := checkingAccountService.GetAccountSummary("ABCD", "CUST1")
+	assert.Nil(t, err)
+	assert.Equal(t, "Alex Gemmell", summary.Name)
+	assert.Equal(t, map[string]string{"CUST1": "Alex Gemmell"}, summary.Holders)
+}
+
+func Test_ReopenAccountOnlyVerifiesRegisteredCustomers(t *testing.T) {
+	t.Parallel()
+
+	// Given an account opened for a customer who has since failed KYC, and one opened before customers registered
+	eventStore := Seacrest.NewEventStore()
+	checkingAccountService := New(eventStore)
+	customerService := CustomerService.New(eventStore)
+	address := CustomerService.Address{Line1: "1 High Street", City: "London", Postcode: "E1 6AN", Country: "GB"}
+	assert.Nil(t, customerService.HandleCommand(CustomerService.RegisterCustomer{ID: "CUST1", Name: "Alex Gemmell", DateOfBirth: "1980-05-17", Address: address}))
+	assert.Nil(t, customerService.HandleCommand(CustomerService.VerifyKYC{ID: "CUST1", VerifiedBy: "compliance"}))
+	assert.Nil(t, checkingAccountService.HandleCommand(OpenAccount{ID: "ABCD", CustomerID: "CUST1"}))
+	assert.Nil(t, checkingAccountService.PersistEvents(AccountWasOpened{ID: "LEGACY", Name: "Sam Smith", Product: ProductStandardChecking}))
+	for _, accountID := range []string{"ABCD", "LEGACY"} {
+		assert.Nil(t, checkingAccountService.HandleCommand(CloseAccount{ID: accountID, ActingAs: SystemPrincipal}))
+	}
+	assert.Nil(t, customerService.HandleCommand(CustomerService.RejectKYC{ID: "CUST1", ReasonCode: CustomerService.ReasonIdentityMismatch}))
+
+	// When
+	rejectedErr := checkingAccountService.HandleCommand(ReopenAccount{ID: "ABCD", ActingAs: "CUST1"})
+	legacyErr := checkingAccountService.HandleCommand(ReopenAccount{ID: "LEGACY", ActingAs: SystemPrincipal})
+
+	// Then
+	assert.Equal(t, "customer CUST1 has not passed KYC [KYC: rejected]", rejectedErr.Error())
+	assert.Nil(t, legacyErr)
+	summary, err := checkingAccountService.GetAccountSummary("LEGACY", SystemPrincipal)
+	assert.Nil(t, err)
+	assert.Equal(t, AccountActive, summary.Status)
+}
+
+// failingIDGenerator: gives out event IDs until it has given out `remaining`, then fails
+type failingIDGenerator struct {
+	remaining int
+}
+
+func (fig *failingIDGenerator) NewID() (string, error) {
+	if fig.remaining == 0 {
+		return "", errors.New("out of event IDs")
+	}
+	fig.remaining--
+	return fmt.Sprintf("event-%d", fig.remaining), nil
+}
+
+func Test_ReopenAccountRequiresItsRegisteredCustomerToExist(t *testing.T) {
+	t.Parallel()
+
+	// Given a closed account whose holder registered, but whose customer record is missing
+	eventStore := Seacrest.NewEventStore()
+	openedWithoutRecords := New(eventStore, WithCustomerVerifier(verifiedCustomers{}))
+	assert.Nil(t, openedWithoutRecords.HandleCommand(OpenAccount{ID: "ABCD", CustomerID: "CUST1"}))
+	assert.Nil(t, openedWithoutRecords.HandleCommand(CloseAccount{ID: "ABCD", ActingAs: "CUST1"}))
+	checkingAccountService := New(eventStore)
+
+	// When
+	err := checkingAccountService.HandleCommand(ReopenAccount{ID: "ABCD", ActingAs: "CUST1"})
+
+	// Then
+	assert.Equal(t, &CustomerService.UnknownCustomerError{CustomerID: "CUST1"}, err)
+	assert.Len(t, eventStore.GetAllEvents(), 2)
+}
+
+func Test_ACommandsEventsArePersistedTogetherOrNotAtAll(t *testing.T) {
+	t.Parallel()
+
+	// Given an account charging a transaction fee, whose event store fails after the withdrawal's event is given an ID
+	ids := &failingIDGenerator{remaining: 3}
+	eventStore := Seacrest.NewEventStore(Seacrest.WithIDGenerator(ids))
+	catalog := ProductCatalog.New(ProductCatalog.Product{
+		Code:        ProductCatalog.StandardChecking,
+		Kind:        ProductCatalog.KindChecking,
+		FeeSchedule: ProductCatalog.FeeSchedule{TransactionFee: 1},
+	})
+	checkingAccountService := New(eventStore, WithProductCatalog(catalog), WithCustomerVerifier(verifiedCustomers{}))
+	assert.Nil(t, checkingAccountService.HandleCommand(OpenAccount{ID: "ABCD", CustomerID: "CUST1"}))
+	assert.Nil(t, checkingAccountService.HandleCommand(DepositMoney{ID: "ABCD", Amount: 100, ActingAs: "CUST1"}))
+
+	// When the withdrawal raises its event and the fee's
+	err := checkingAccountService.HandleCommand(WithdrawMoney{ID: "ABCD", Amount: 40, ActingAs: "CUST1"})
+
+	// Then neither is persisted
+	assert.Equal(t, "out of event IDs", err.Error())
+	assert.Len(t, eventStore.GetAllEvents(), 2)
+	summary, err := checkingAccountService.GetAccountSummary("ABCD", "CUST1")
+	assert.Nil(t, err)
+	assert.Equal(t, 100, summary.LedgerBalance)
+}
+
+func Test_DeterministicEventsWithFakeClockAndIDs(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	start := time.Date(2021, time.January, 1, 9, 0, 0, 0, time.UTC)
+	clock := Seacrest.NewFakeClock(start)
+	eventStore := Seacrest.NewEventStore(Seacrest.WithClock(clock), Seacrest.WithIDGenerator(Seacrest.NewSequentialIDGenerator("event")))
+	checkingAccountService := New(eventStore, WithClock(clock), WithCustomerVerifier(verifiedCustomers{}))
+
+	// When
+	err := checkingAccountService.HandleCommand(OpenAccount{ID: "ABCD", CustomerID: "CUST1"})
+	assert.Nil(t, err)
+	clock.Advance(time.Minute)
+	err = checkingAccountService.HandleCommand(DepositMoney{ID: "ABCD", Amount: 100, ActingAs: SystemPrincipal})
+	assert.Nil(t, err)
+
+	// Then
+	envelopes := eventStore.GetAllEvents()
+	assert.Len(t, envelopes, 2)
+	assert.Equal(t, "event-1", envelopes[0].EventID)
+	assert.Equal(t, start.UnixNano(), envelopes[0].RecordedAt)
+	assert.Equal(t, "event-2", envelopes[1].EventID)
+	assert.Equal(t, start.Add(time.Minute).UnixNano(), envelopes[1].RecordedAt)
+	events, err := checkingAccountService.GetAllEvents()
+	assert.Nil(t, err)
+	assert.Equal(t, &MoneyWasDeposited{ID: "ABCD", Amount: 100, ChangedBy: SystemPrincipal, Timestamp: start.Add(time.Minute).UnixNano()}, events[1])
 }
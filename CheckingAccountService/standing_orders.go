@@ -0,0 +1,276 @@
+package CheckingAccountService
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const FrequencyWeekly = "Weekly"
+const FrequencyMonthly = "Monthly"
+
+// StandingOrder: a recurring payment out of an account
+type StandingOrder struct {
+	ID               string
+	PayeeAccountID   string
+	Amount           int
+	Frequency        string
+	FirstPaymentDate int64
+	PaymentsMade     int // payments that have been made or skipped
+	NextPaymentDate  int64
+	FailedAttempts   int // failed attempts at the next payment
+	LastAttemptAt    int64
+}
+
+// nthPaymentDate: the nominal date of a standing order's nth payment (counting from zero). Monthly payments keep to the
+// day of the month of the first payment, falling back to the last day of shorter months.
+func nthPaymentDate(firstPaymentDate int64, frequency string, n int) int64 {
+	first := time.Unix(0, firstPaymentDate).UTC()
+	if frequency == FrequencyWeekly {
+		return first.AddDate(0, 0, 7*n).UnixNano()
+	}
+
+	firstOfMonth := time.Date(first.Year(), first.Month()+time.Month(n), 1, first.Hour(), first.Minute(), first.Second(), first.Nanosecond(), time.UTC)
+	lastDay := firstOfMonth.AddDate(0, 1, -1).Day()
+	day := first.Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return firstOfMonth.AddDate(0, 0, day-1).UnixNano()
+}
+
+// StandingOrders: the account's active standing orders, in ID order
+func (a *Account) StandingOrders() []StandingOrder {
+	var standingOrders []StandingOrder
+	for _, standingOrder := range a.standingOrders {
+		standingOrders = append(standingOrders, standingOrder)
+	}
+	sort.Slice(standingOrders, func(i, j int) bool {
+		return standingOrders[i].ID < standingOrders[j].ID
+	})
+	return standingOrders
+}
+
+// CreateStandingOrder: set up a recurring payment to another account
+func (a *Account) CreateStandingOrder(standingOrderID string, payeeAccountID string, amount int, frequency string, firstPaymentDate int64, changedBy string) error {
+
+	err := a.ensureAllowed(actionManageStandingOrders)
+	if err != nil {
+		return err
+	}
+
+	if standingOrderID == "" {
+		return errors.New("a standing order requires an ID")
+	}
+
+	if _, ok := a.standingOrders[standingOrderID]; ok {
+		return errors.New(fmt.Sprintf("standing order %s already exists [account: %+v]", standingOrderID, a))
+	}
+
+	if payeeAccountID == "" || payeeAccountID == a.id {
+		return errors.New(fmt.Sprintf("standing order payee must be another account [Payee: %s]", payeeAccountID))
+	}
+
+	if amount <= 0 {
+		return errors.New(fmt.Sprintf("standing order Amount must be greater than 0 [Amount: %+v]", amount))
+	}
+
+	if frequency != FrequencyWeekly && frequency != FrequencyMonthly {
+		return errors.New(fmt.Sprintf("unknown standing order frequency %s", frequency))
+	}
+
+	if firstPaymentDate <= 0 {
+		return errors.New("a standing order requires a first payment date")
+	}
+
+	event := StandingOrderWasCreated{
+		ID:               a.id,
+		StandingOrderID:  standingOrderID,
+		PayeeAccountID:   payeeAccountID,
+		Amount:           amount,
+		Frequency:        frequency,
+		FirstPaymentDate: firstPaymentDate,
+		ChangedBy:        changedBy,
+		Timestamp:        a.now(),
+	}
+
+	return a.raiseEvent(&event)
+}
+
+// AmendStandingOrder: change who a standing order pays and how much
+func (a *Account) AmendStandingOrder(standingOrderID string, payeeAccountID string, amount int, changedBy string) error {
+
+	err := a.ensureAllowed(actionManageStandingOrders)
+	if err != nil {
+		return err
+	}
+
+	standingOrder, ok := a.standingOrders[standingOrderID]
+	if !ok {
+		return errors.New(fmt.Sprintf("standing order %s does not exist [account: %+v]", standingOrderID, a))
+	}
+
+	if payeeAccountID == "" {
+		payeeAccountID = standingOrder.PayeeAccountID
+	}
+	if payeeAccountID == a.id {
+		return errors.New(fmt.Sprintf("standing order payee must be another account [Payee: %s]", payeeAccountID))
+	}
+
+	if amount <= 0 {
+		return errors.New(fmt.Sprintf("standing order Amount must be greater than 0 [Amount: %+v]", amount))
+	}
+
+	event := StandingOrderWasAmended{
+		ID:              a.id,
+		StandingOrderID: standingOrderID,
+		PayeeAccountID:  payeeAccountID,
+		Amount:          amount,
+		ChangedBy:       changedBy,
+		Timestamp:       a.now(),
+	}
+
+	return a.raiseEvent(&event)
+}
+
+// CancelStandingOrder: stop a standing order; no further payments will be made
+func (a *Account) CancelStandingOrder(standingOrderID string, changedBy string) error {
+
+	err := a.ensureAllowed(actionCancelStandingOrder)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := a.standingOrders[standingOrderID]; !ok {
+		return errors.New(fmt.Sprintf("standing order %s does not exist [account: %+v]", standingOrderID, a))
+	}
+
+	event := StandingOrderWasCancelled{
+		ID:              a.id,
+		StandingOrderID: standingOrderID,
+		ChangedBy:       changedBy,
+		Timestamp:       a.now(),
+	}
+
+	return a.raiseEvent(&event)
+}
+
+// MakeStandingOrderPayment: pay the standing order's next payment if there are funds available. Standing orders are
+// pre-authorized by the customer so they are not subject to withdrawal limits.
+func (a *Account) MakeStandingOrderPayment(standingOrderID string, paymentDate int64) error {
+
+	err := a.ensureAllowed(actionWithdraw)
+	if err != nil {
+		return err
+	}
+
+	standingOrder, ok := a.standingOrders[standingOrderID]
+	if !ok {
+		return errors.New(fmt.Sprintf("standing order %s does not exist [account: %+v]", standingOrderID, a))
+	}
+
+	if paymentDate != standingOrder.NextPaymentDate {
+		return errors.New(fmt.Sprintf("standing order %s has no payment due on %d [next payment: %d]", standingOrderID, paymentDate, standingOrder.NextPaymentDate))
+	}
+
+	now := a.now()
+	if a.AvailableBalance(now) < standingOrder.Amount {
+		event := StandingOrderPaymentFailed{
+			ID:              a.id,
+			StandingOrderID: standingOrderID,
+			Amount:          standingOrder.Amount,
+			Balance:         a.balance,
+			PaymentDate:     paymentDate,
+			Attempt:         standingOrder.FailedAttempts + 1,
+			Timestamp:       now,
+		}
+		return a.raiseEvent(&event)
+	}
+
+	// the bank makes the payment, so it doesn't count as customer activity on a dormant account
+	moneyWasWithdrawn := MoneyWasWithdrawn{
+		ID:        a.id,
+		Amount:    standingOrder.Amount,
+		Balance:   a.balance - standingOrder.Amount,
+		ChangedBy: SystemPrincipal,
+		Timestamp: now,
+	}
+	err = a.raiseEvent(&moneyWasWithdrawn)
+	if err != nil {
+		return err
+	}
+
+	event := StandingOrderPaymentWasMade{
+		ID:              a.id,
+		StandingOrderID: standingOrderID,
+		PayeeAccountID:  standingOrder.PayeeAccountID,
+		Amount:          standingOrder.Amount,
+		PaymentDate:     paymentDate,
+		NextPaymentDate: nthPaymentDate(standingOrder.FirstPaymentDate, standingOrder.Frequency, standingOrder.PaymentsMade+1),
+		Timestamp:       now,
+	}
+
+	return a.raiseEvent(&event)
+}
+
+// SkipStandingOrderPayment: give up on a payment that could not be made and move on to the next one
+func (a *Account) SkipStandingOrderPayment(standingOrderID string, paymentDate int64) error {
+
+	err := a.ensureAllowed(actionSkipPayment)
+	if err != nil {
+		return err
+	}
+
+	standingOrder, ok := a.standingOrders[standingOrderID]
+	if !ok {
+		return errors.New(fmt.Sprintf("standing order %s does not exist [account: %+v]", standingOrderID, a))
+	}
+
+	if paymentDate != standingOrder.NextPaymentDate {
+		return errors.New(fmt.Sprintf("standing order %s has no payment due on %d [next payment: %d]", standingOrderID, paymentDate, standingOrder.NextPaymentDate))
+	}
+
+	event := StandingOrderPaymentWasSkipped{
+		ID:              a.id,
+		StandingOrderID: standingOrderID,
+		PaymentDate:     paymentDate,
+		FailedAttempts:  standingOrder.FailedAttempts,
+		NextPaymentDate: nthPaymentDate(standingOrder.FirstPaymentDate, standingOrder.Frequency, standingOrder.PaymentsMade+1),
+		Timestamp:       a.now(),
+	}
+
+	return a.raiseEvent(&event)
+}
+
+// ReturnStandingOrderPayment: give back a payment that was taken from the account but that its payee could not accept.
+// The payment counts as a failed attempt, so it is retried or skipped like any other. Returning the payment is not
+// customer activity, so it does not wake a dormant account.
+func (a *Account) ReturnStandingOrderPayment(standingOrderID string, amount int, paymentDate int64) error {
+
+	err := a.ensureAllowed(actionReturnPayment)
+	if err != nil {
+		return err
+	}
+
+	if amount <= 0 {
+		return errors.New(fmt.Sprintf("returned payment Amount must be greater than 0 [Amount: %+v]", amount))
+	}
+
+	attempt := 1
+	if standingOrder, ok := a.standingOrders[standingOrderID]; ok {
+		attempt = standingOrder.FailedAttempts + 1
+	}
+
+	event := StandingOrderPaymentWasReturned{
+		ID:              a.id,
+		StandingOrderID: standingOrderID,
+		Amount:          amount,
+		Balance:         a.balance + amount,
+		PaymentDate:     paymentDate,
+		Attempt:         attempt,
+		Timestamp:       a.now(),
+	}
+
+	return a.raiseEvent(&event)
+}
@@ -0,0 +1,99 @@
+package CheckingAccountService
+
+import (
+	"errors"
+	"fmt"
+)
+
+// AccountStatus: where an account is in its lifecycle
+type AccountStatus int
+
+const (
+	AccountPending AccountStatus = iota // not yet opened
+	AccountActive
+	AccountFrozen
+	AccountDormant
+	AccountClosed
+)
+
+func (s AccountStatus) String() string {
+	switch s {
+	case AccountPending:
+		return "pending"
+	case AccountActive:
+		return "active"
+	case AccountFrozen:
+		return "frozen"
+	case AccountDormant:
+		return "dormant"
+	case AccountClosed:
+		return "closed"
+	}
+	return fmt.Sprintf("AccountStatus(%d)", int(s))
+}
+
+// Freeze scopes are recorded on AccountWasFrozen events
+const FreezeDebits = "Debits" // withdrawals are blocked but deposits are still accepted
+const FreezeAll = "All"       // all customer transactions are blocked
+
+// Freeze reason codes
+const ReasonSuspectedFraud = "SuspectedFraud"
+const ReasonSanctionsScreening = "SanctionsScreening"
+const ReasonCourtOrder = "CourtOrder"
+const ReasonCustomerRequest = "CustomerRequest"
+const ReasonReviewCompleted = "ReviewCompleted"
+
+// accountAction: something a command handler wants to do to an account, checked against the account's status
+type accountAction string
+
+const (
+	actionDeposit              accountAction = "deposit money into"
+	actionWithdraw             accountAction = "withdraw money from"
+	actionClose                accountAction = "close"
+	actionChargeFee            accountAction = "charge a fee to"
+	actionRefundFee            accountAction = "refund a fee to"
+	actionPlaceHold            accountAction = "place a hold on"
+	actionReleaseHold          accountAction = "release a hold on"
+	actionCaptureHold          accountAction = "capture a hold on"
+	actionFreeze               accountAction = "freeze"
+	actionUnfreeze             accountAction = "unfreeze"
+	actionManageHolders        accountAction = "change the holders of"
+	actionChangeLimits         accountAction = "change the withdrawal limits of"
+	actionManageStandingOrders accountAction = "change the standing orders of"
+	actionCancelStandingOrder  accountAction = "cancel a standing order on"
+	actionSkipPayment          accountAction = "skip a standing order payment on"
+	actionReturnPayment        accountAction = "return a standing order payment to"
+	actionReopen               accountAction = "reopen"
+	actionMarkDormant          accountAction = "mark as dormant"
+)
+
+// ensureAllowed: the account lifecycle state machine; reject actions the account's current status does not permit
+func (a *Account) ensureAllowed(action accountAction) error {
+	allowed := false
+
+	switch a.status {
+	case AccountActive, AccountDormant:
+		allowed = action != actionUnfreeze && action != actionReopen
+	case AccountFrozen:
+		switch action {
+		case actionDeposit:
+			allowed = a.freezeScope == FreezeDebits
+		case actionFreeze:
+			// a freeze can be widened without lifting it first, so the account is never left unfrozen in between
+			allowed = a.freezeScope == FreezeDebits
+		case actionChargeFee, actionRefundFee, actionReleaseHold, actionUnfreeze, actionManageHolders,
+			actionCancelStandingOrder, actionSkipPayment, actionReturnPayment:
+			// the bank can still settle its own charges, let reserved funds go, keep holder details up to date and stop
+			// or unwind standing order payments while an account is frozen
+			allowed = true
+		}
+	case AccountClosed:
+		// a payment its payee could not accept is the payer's money, so it goes back even if the payer has since closed
+		allowed = action == actionReopen || action == actionReturnPayment
+	}
+
+	if !allowed {
+		return errors.New(fmt.Sprintf("cannot %s an account that is %s [account: %+v]", action, a.status, a))
+	}
+	return nil
+}
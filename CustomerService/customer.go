@@ -0,0 +1,260 @@
+package CustomerService
+
+import (
+	"errors"
+	"fmt"
+	"github.com/agemmell/banking-cqrs-es-go/EventRegistry"
+	"reflect"
+	"time"
+)
+
+type Event = EventRegistry.Event
+
+// KYCStatus: how far a customer has got through know-your-customer checks
+type KYCStatus int
+
+const (
+	KYCPending KYCStatus = iota // registered, identity not yet checked
+	KYCVerified
+	KYCRejected
+)
+
+func (s KYCStatus) String() string {
+	switch s {
+	case KYCPending:
+		return "pending"
+	case KYCVerified:
+		return "verified"
+	case KYCRejected:
+		return "rejected"
+	}
+	return fmt.Sprintf("KYCStatus(%d)", int(s))
+}
+
+// KYC rejection reason codes
+const ReasonDocumentsUnreadable = "DocumentsUnreadable"
+const ReasonIdentityMismatch = "IdentityMismatch"
+const ReasonSanctionsMatch = "SanctionsMatch"
+
+// dateOfBirthLayout: dates of birth are recorded as calendar dates without a time zone
+const dateOfBirthLayout = "2006-01-02"
+
+// minimumAge: customers must be adults to hold accounts
+const minimumAge = 18
+
+type Customer struct {
+	id          string
+	name        string
+	dateOfBirth string
+	address     Address
+	kycStatus   KYCStatus
+	version     uint
+	newEvents   []Event
+	clock       Clock
+}
+
+func (c *Customer) AggregateID() string {
+	return c.id
+}
+
+func (c *Customer) Version() uint {
+	return c.version
+}
+
+func (c *Customer) Name() string {
+	return c.name
+}
+
+func (c *Customer) KYCStatus() KYCStatus {
+	return c.kycStatus
+}
+
+// SetClock: the clock command handlers use to timestamp events and check ages
+func (c *Customer) SetClock(clock Clock) {
+	c.clock = clock
+}
+
+func (c *Customer) now() time.Time {
+	if c.clock == nil {
+		return time.Now()
+	}
+	return c.clock.Now()
+}
+
+func (c *Customer) raiseEvent(event Event) error {
+	err := c.ApplyEvent(event)
+	if err != nil {
+		return err
+	}
+
+	c.RecordNewEvent(event)
+	return nil
+}
+
+func (c *Customer) RecordNewEvent(event Event) {
+	c.newEvents = append(c.newEvents, event)
+}
+
+func (c *Customer) GetNewEvents() []Event {
+	return c.newEvents
+}
+
+// ApplyEvent: Change aggregate state according to event type
+func (c *Customer) ApplyEvent(event Event) error {
+	switch eventType := event.(type) {
+	case *CustomerWasRegistered:
+		c.id = eventType.ID
+		c.name = eventType.Name
+		c.dateOfBirth = eventType.DateOfBirth
+		c.address = eventType.Address
+		c.kycStatus = KYCPending
+	case *CustomerAddressWasChanged:
+		c.address = eventType.Address
+	case *CustomerKYCWasVerified:
+		c.kycStatus = KYCVerified
+	case *CustomerKYCWasRejected:
+		c.kycStatus = KYCRejected
+	case *CustomerKYCWasResubmitted:
+		c.kycStatus = KYCPending
+	default:
+		eventStruct := reflect.TypeOf(eventType).String()
+		return errors.New(fmt.Sprintf("unknown event %s", eventStruct))
+	}
+	c.version++
+	return nil
+}
+
+// LoadFromEvents: Return aggregate to state from past events without triggering side effects
+func (c *Customer) LoadFromEvents(events []Event) error {
+	for _, event := range events {
+		err := c.ApplyEvent(event)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Command Handlers: protect aggregate invariants before throwing an event
+
+// RegisterCustomer: record a new customer's personal details; their identity still has to be verified
+func (c *Customer) RegisterCustomer(id string, name string, dateOfBirth string, address Address) error {
+
+	if c.version > 0 {
+		return errors.New(fmt.Sprintf("customer %s is already registered", c.id))
+	}
+
+	if id == "" || name == "" {
+		return errors.New("a customer requires an ID and a name")
+	}
+
+	born, err := time.Parse(dateOfBirthLayout, dateOfBirth)
+	if err != nil {
+		return errors.New(fmt.Sprintf("date of birth must be formatted yyyy-mm-dd [DateOfBirth: %s]", dateOfBirth))
+	}
+
+	now := c.now()
+	if born.AddDate(minimumAge, 0, 0).After(now) {
+		return errors.New(fmt.Sprintf("customers must be at least %d years old [DateOfBirth: %s]", minimumAge, dateOfBirth))
+	}
+
+	err = ensureValidAddress(address)
+	if err != nil {
+		return err
+	}
+
+	event := CustomerWasRegistered{
+		ID:          id,
+		Name:        name,
+		DateOfBirth: dateOfBirth,
+		Address:     address,
+		Timestamp:   now.UnixNano(),
+	}
+
+	return c.raiseEvent(&event)
+}
+
+// ChangeAddress: record that the customer has moved
+func (c *Customer) ChangeAddress(address Address) error {
+
+	if c.version == 0 {
+		return errors.New("cannot change the address of an unregistered customer")
+	}
+
+	err := ensureValidAddress(address)
+	if err != nil {
+		return err
+	}
+
+	if address == c.address {
+		return errors.New(fmt.Sprintf("customer %s already lives at that address", c.id))
+	}
+
+	event := CustomerAddressWasChanged{
+		ID:              c.id,
+		Address:         address,
+		PreviousAddress: c.address,
+		Timestamp:       c.now().UnixNano(),
+	}
+
+	return c.raiseEvent(&event)
+}
+
+// VerifyKYC: the customer's identity has been checked and they may now open accounts
+func (c *Customer) VerifyKYC(verifiedBy string) error {
+
+	if c.version == 0 || c.kycStatus != KYCPending {
+		return errors.New(fmt.Sprintf("cannot verify a customer whose KYC is not pending [customer: %s, KYC: %s]", c.id, c.kycStatus))
+	}
+
+	event := CustomerKYCWasVerified{
+		ID:         c.id,
+		VerifiedBy: verifiedBy,
+		Timestamp:  c.now().UnixNano(),
+	}
+
+	return c.raiseEvent(&event)
+}
+
+// RejectKYC: the customer's identity could not be confirmed, or a verified customer failed a later check
+func (c *Customer) RejectKYC(reasonCode string, rejectedBy string) error {
+
+	if c.version == 0 || c.kycStatus == KYCRejected {
+		return errors.New(fmt.Sprintf("cannot reject a customer whose KYC is already rejected or who is not registered [customer: %s]", c.id))
+	}
+
+	if reasonCode == "" {
+		return errors.New("a KYC rejection requires a reason code")
+	}
+
+	event := CustomerKYCWasRejected{
+		ID:         c.id,
+		ReasonCode: reasonCode,
+		RejectedBy: rejectedBy,
+		Timestamp:  c.now().UnixNano(),
+	}
+
+	return c.raiseEvent(&event)
+}
+
+// ResubmitKYC: a rejected customer has provided new documents so their identity can be checked again
+func (c *Customer) ResubmitKYC() error {
+
+	if c.kycStatus != KYCRejected {
+		return errors.New(fmt.Sprintf("only rejected customers can resubmit KYC [customer: %s, KYC: %s]", c.id, c.kycStatus))
+	}
+
+	event := CustomerKYCWasResubmitted{
+		ID:        c.id,
+		Timestamp: c.now().UnixNano(),
+	}
+
+	return c.raiseEvent(&event)
+}
+
+func ensureValidAddress(address Address) error {
+	if address.Line1 == "" || address.City == "" || address.Postcode == "" || address.Country == "" {
+		return errors.New(fmt.Sprintf("an address requires a first line, city, postcode and country [Address: %+v]", address))
+	}
+	return nil
+}
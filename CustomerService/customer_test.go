@@ -0,0 +1,75 @@
+package CustomerService
+
+import (
+	"github.com/agemmell/banking-cqrs-es-go/Seacrest"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+var homeAddress = Address{Line1: "1 High Street", City: "London", Postcode: "E1 6AN", Country: "GB"}
+
+func TestCustomer_RegisterCustomer(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	customer := Customer{}
+	customer.SetClock(Seacrest.NewFakeClock(time.Date(2021, time.March, 1, 9, 0, 0, 0, time.UTC)))
+
+	// When
+	badDateErr := customer.RegisterCustomer("CUST1", "Alex Gemmell", "17/05/1980", homeAddress)
+	minorErr := customer.RegisterCustomer("CUST1", "Alex Gemmell", "2003-03-02", homeAddress)
+	noAddressErr := customer.RegisterCustomer("CUST1", "Alex Gemmell", "1980-05-17", Address{Line1: "1 High Street"})
+	err := customer.RegisterCustomer("CUST1", "Alex Gemmell", "2003-03-01", homeAddress)
+
+	// Then
+	assert.Equal(t, "date of birth must be formatted yyyy-mm-dd [DateOfBirth: 17/05/1980]", badDateErr.Error())
+	assert.Equal(t, "customers must be at least 18 years old [DateOfBirth: 2003-03-02]", minorErr.Error())
+	assert.NotNil(t, noAddressErr)
+	assert.Nil(t, err)
+	assert.Equal(t, KYCPending, customer.KYCStatus())
+	assert.NotNil(t, customer.RegisterCustomer("CUST1", "Alex Gemmell", "1980-05-17", homeAddress))
+}
+
+func TestCustomer_KYCTransitions(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	customer := Customer{}
+	assert.Nil(t, customer.RegisterCustomer("CUST1", "Alex Gemmell", "1980-05-17", homeAddress))
+
+	// When
+	noReasonErr := customer.RejectKYC("", "compliance")
+	assert.Nil(t, customer.RejectKYC(ReasonDocumentsUnreadable, "compliance"))
+	verifyRejectedErr := customer.VerifyKYC("compliance")
+	assert.Nil(t, customer.ResubmitKYC())
+	assert.Nil(t, customer.VerifyKYC("compliance"))
+	resubmitVerifiedErr := customer.ResubmitKYC()
+
+	// Then
+	assert.Equal(t, "a KYC rejection requires a reason code", noReasonErr.Error())
+	assert.Equal(t, "cannot verify a customer whose KYC is not pending [customer: CUST1, KYC: rejected]", verifyRejectedErr.Error())
+	assert.Equal(t, "only rejected customers can resubmit KYC [customer: CUST1, KYC: verified]", resubmitVerifiedErr.Error())
+	assert.Equal(t, KYCVerified, customer.KYCStatus())
+	assert.Len(t, customer.GetNewEvents(), 4)
+}
+
+func TestCustomer_ChangeAddress(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	customer := Customer{}
+	assert.Nil(t, customer.RegisterCustomer("CUST1", "Alex Gemmell", "1980-05-17", homeAddress))
+	newAddress := Address{Line1: "2 Low Road", City: "Leeds", Postcode: "LS1 1AA", Country: "GB"}
+
+	// When
+	sameErr := customer.ChangeAddress(homeAddress)
+	err := customer.ChangeAddress(newAddress)
+
+	// Then
+	assert.Equal(t, "customer CUST1 already lives at that address", sameErr.Error())
+	assert.Nil(t, err)
+	addressWasChanged := customer.GetNewEvents()[1].(*CustomerAddressWasChanged)
+	assert.Equal(t, homeAddress, addressWasChanged.PreviousAddress)
+	assert.Equal(t, newAddress, addressWasChanged.Address)
+}
@@ -0,0 +1,136 @@
+package CustomerService
+
+import "github.com/agemmell/banking-cqrs-es-go/EventRegistry"
+
+// Address: where a customer lives
+type Address struct {
+	Line1    string
+	Line2    string
+	City     string
+	Postcode string
+	Country  string
+}
+
+// Commands
+
+type RegisterCustomer struct {
+	ID          string
+	Name        string
+	DateOfBirth string // yyyy-mm-dd
+	Address     Address
+}
+type ChangeCustomerAddress struct {
+	ID      string
+	Address Address
+}
+type VerifyKYC struct {
+	ID         string
+	VerifiedBy string
+}
+type RejectKYC struct {
+	ID         string
+	ReasonCode string
+	RejectedBy string
+}
+type ResubmitKYC struct {
+	ID string
+}
+
+func (c RegisterCustomer) isCommand()      {}
+func (c ChangeCustomerAddress) isCommand() {}
+func (c VerifyKYC) isCommand()             {}
+func (c RejectKYC) isCommand()             {}
+func (c ResubmitKYC) isCommand()           {}
+
+// Events
+
+func init() {
+	EventRegistry.Register(func() EventRegistry.Event { return &CustomerWasRegistered{} })
+	EventRegistry.Register(func() EventRegistry.Event { return &CustomerAddressWasChanged{} })
+	EventRegistry.Register(func() EventRegistry.Event { return &CustomerKYCWasVerified{} })
+	EventRegistry.Register(func() EventRegistry.Event { return &CustomerKYCWasRejected{} })
+	EventRegistry.Register(func() EventRegistry.Event { return &CustomerKYCWasResubmitted{} })
+}
+
+type CustomerWasRegistered struct {
+	ID          string
+	Name        string
+	DateOfBirth string
+	Address     Address
+	Timestamp   int64
+}
+type CustomerAddressWasChanged struct {
+	ID              string
+	Address         Address
+	PreviousAddress Address
+	Timestamp       int64
+}
+type CustomerKYCWasVerified struct {
+	ID         string
+	VerifiedBy string
+	Timestamp  int64
+}
+type CustomerKYCWasRejected struct {
+	ID         string
+	ReasonCode string
+	RejectedBy string
+	Timestamp  int64
+}
+type CustomerKYCWasResubmitted struct {
+	ID        string
+	Timestamp int64
+}
+
+func (e CustomerWasRegistered) AggregateID() string {
+	return e.ID
+}
+func (e CustomerAddressWasChanged) AggregateID() string {
+	return e.ID
+}
+func (e CustomerKYCWasVerified) AggregateID() string {
+	return e.ID
+}
+func (e CustomerKYCWasRejected) AggregateID() string {
+	return e.ID
+}
+func (e CustomerKYCWasResubmitted) AggregateID() string {
+	return e.ID
+}
+
+const TypeCustomerWasRegistered = "CustomerWasRegistered"
+const TypeCustomerAddressWasChanged = "CustomerAddressWasChanged"
+const TypeCustomerKYCWasVerified = "CustomerKYCWasVerified"
+const TypeCustomerKYCWasRejected = "CustomerKYCWasRejected"
+const TypeCustomerKYCWasResubmitted = "CustomerKYCWasResubmitted"
+
+func (e CustomerWasRegistered) EventType() string {
+	return TypeCustomerWasRegistered
+}
+func (e CustomerAddressWasChanged) EventType() string {
+	return TypeCustomerAddressWasChanged
+}
+func (e CustomerKYCWasVerified) EventType() string {
+	return TypeCustomerKYCWasVerified
+}
+func (e CustomerKYCWasRejected) EventType() string {
+	return TypeCustomerKYCWasRejected
+}
+func (e CustomerKYCWasResubmitted) EventType() string {
+	return TypeCustomerKYCWasResubmitted
+}
+
+func (e CustomerWasRegistered) EventTimestamp() int64 {
+	return e.Timestamp
+}
+func (e CustomerAddressWasChanged) EventTimestamp() int64 {
+	return e.Timestamp
+}
+func (e CustomerKYCWasVerified) EventTimestamp() int64 {
+	return e.Timestamp
+}
+func (e CustomerKYCWasRejected) EventTimestamp() int64 {
+	return e.Timestamp
+}
+func (e CustomerKYCWasResubmitted) EventTimestamp() int64 {
+	return e.Timestamp
+}
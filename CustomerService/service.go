@@ -0,0 +1,194 @@
+package CustomerService
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/agemmell/banking-cqrs-es-go/EventRegistry"
+	"github.com/agemmell/banking-cqrs-es-go/Seacrest"
+	"reflect"
+)
+
+type Command interface {
+	isCommand()
+}
+
+// StoresEvents: the parts of the event store the customer service needs
+type StoresEvents interface {
+	GetEventsByAggregateID(aggregateID string) map[uint]Seacrest.EventEnvelope
+	PersistEvent(aggregateID string, eventType string, payload []byte) error
+	PersistEventsExpectingVersion(aggregateID string, expectedVersion uint, events []Seacrest.PendingEvent) error
+}
+
+// Clock: the source of the current time for command handlers
+type Clock = Seacrest.Clock
+
+type CustomerService struct {
+	eventStore StoresEvents
+	clock      Clock
+}
+
+// Option: configures optional CustomerService dependencies
+type Option func(cs *CustomerService)
+
+// WithClock: use the given clock instead of the system clock
+func WithClock(clock Clock) Option {
+	return func(cs *CustomerService) {
+		cs.clock = clock
+	}
+}
+
+func New(eventStore StoresEvents, options ...Option) CustomerService {
+	cs := CustomerService{eventStore, Seacrest.SystemClock{}}
+	for _, option := range options {
+		option(&cs)
+	}
+	return cs
+}
+
+// HandleCommand: Handles commands
+func (cs *CustomerService) HandleCommand(command Command) error {
+
+	var customer *Customer
+	var err error
+
+	switch commandType := command.(type) {
+	case RegisterCustomer:
+		customer, err = cs.loadCustomer(commandType.ID)
+		if err != nil {
+			return err
+		}
+		err = customer.RegisterCustomer(commandType.ID, commandType.Name, commandType.DateOfBirth, commandType.Address)
+
+	case ChangeCustomerAddress:
+		customer, err = cs.loadCustomer(commandType.ID)
+		if err != nil {
+			return err
+		}
+		err = customer.ChangeAddress(commandType.Address)
+
+	case VerifyKYC:
+		customer, err = cs.loadCustomer(commandType.ID)
+		if err != nil {
+			return err
+		}
+		err = customer.VerifyKYC(commandType.VerifiedBy)
+
+	case RejectKYC:
+		customer, err = cs.loadCustomer(commandType.ID)
+		if err != nil {
+			return err
+		}
+		err = customer.RejectKYC(commandType.ReasonCode, commandType.RejectedBy)
+
+	case ResubmitKYC:
+		customer, err = cs.loadCustomer(commandType.ID)
+		if err != nil {
+			return err
+		}
+		err = customer.ResubmitKYC()
+
+	default:
+		commandStruct := reflect.TypeOf(commandType).String()
+		return errors.New(fmt.Sprintf("unknown command %s", commandStruct))
+	}
+
+	if err != nil {
+		return err
+	}
+	return cs.persistNewEvents(customer)
+}
+
+// UnknownCustomerError: no customer has registered with the ID
+type UnknownCustomerError struct {
+	CustomerID string
+}
+
+func (e *UnknownCustomerError) Error() string {
+	return fmt.Sprintf("customer %s does not exist", e.CustomerID)
+}
+
+// VerifiedName: the registered name of a customer who has passed KYC. Accounts may only be opened for such customers,
+// and are held in the name they registered with.
+func (cs *CustomerService) VerifiedName(customerID string) (string, error) {
+	customer, err := cs.loadCustomer(customerID)
+	if err != nil {
+		return "", err
+	}
+	if customer.Version() == 0 {
+		return "", &UnknownCustomerError{CustomerID: customerID}
+	}
+	if customer.KYCStatus() != KYCVerified {
+		return "", errors.New(fmt.Sprintf("customer %s has not passed KYC [KYC: %s]", customerID, customer.KYCStatus()))
+	}
+	return customer.Name(), nil
+}
+
+// loadCustomer: rebuild a customer from their past events
+func (cs *CustomerService) loadCustomer(aggregateID string) (*Customer, error) {
+	events, err := cs.GetEventsByAggregateID(aggregateID)
+	if err != nil {
+		return nil, err
+	}
+	customer := Customer{}
+	err = customer.LoadFromEvents(events)
+	if err != nil {
+		return nil, err
+	}
+	customer.SetClock(cs.clock)
+	return &customer, nil
+}
+
+// persistNewEvents: persist the events a command raised on the customer together. Fails with a
+// Seacrest.VersionConflictError, persisting nothing, if another command has changed the customer since they were
+// loaded, so e.g. a KYC check can't be both verified and rejected.
+func (cs *CustomerService) persistNewEvents(customer *Customer) error {
+	newEvents := customer.GetNewEvents()
+	pending := make([]Seacrest.PendingEvent, 0, len(newEvents))
+	for _, event := range newEvents {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		pending = append(pending, Seacrest.PendingEvent{EventType: event.EventType(), Payload: payload})
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	loadedVersion := customer.Version() - uint(len(newEvents))
+	return cs.eventStore.PersistEventsExpectingVersion(customer.AggregateID(), loadedVersion, pending)
+}
+
+// PersistEvents: persist each event after its aggregate's latest, one at a time, e.g. to record a customer's history.
+// An event that fails leaves the events before it persisted.
+func (cs *CustomerService) PersistEvents(events ...Event) error {
+	for _, event := range events {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		err = cs.eventStore.PersistEvent(event.AggregateID(), event.EventType(), payload)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (cs *CustomerService) GetEventsByAggregateID(aggregateID string) ([]Event, error) {
+	envelopes := cs.eventStore.GetEventsByAggregateID(aggregateID)
+	var events []Event
+	for version := uint(0); version < uint(len(envelopes)); version++ {
+		envelope, ok := envelopes[version]
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("missing event version %d for aggregate %s", version, aggregateID))
+		}
+		event, err := EventRegistry.Decode(envelope)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
@@ -0,0 +1,71 @@
+package CustomerService
+
+import (
+	"github.com/agemmell/banking-cqrs-es-go/Seacrest"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// racingStore: an event store in which another writer's command is handled just before the next command persists
+type racingStore struct {
+	*Seacrest.EventStore
+	race func()
+}
+
+func (rs *racingStore) PersistEventsExpectingVersion(aggregateID string, expectedVersion uint, events []Seacrest.PendingEvent) error {
+	if rs.race != nil {
+		race := rs.race
+		rs.race = nil
+		race()
+	}
+	return rs.EventStore.PersistEventsExpectingVersion(aggregateID, expectedVersion, events)
+}
+
+func Test_VerifiedName(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	eventStore := Seacrest.NewEventStore()
+	customerService := New(eventStore)
+	err := customerService.HandleCommand(RegisterCustomer{ID: "CUST1", Name: "Alex Gemmell", DateOfBirth: "1980-05-17", Address: homeAddress})
+	assert.Nil(t, err)
+
+	// When
+	_, pendingErr := customerService.VerifiedName("CUST1")
+	assert.Nil(t, customerService.HandleCommand(RejectKYC{ID: "CUST1", ReasonCode: ReasonIdentityMismatch}))
+	_, rejectedErr := customerService.VerifiedName("CUST1")
+	assert.Nil(t, customerService.HandleCommand(ResubmitKYC{ID: "CUST1"}))
+	assert.Nil(t, customerService.HandleCommand(VerifyKYC{ID: "CUST1", VerifiedBy: "compliance"}))
+	name, err := customerService.VerifiedName("CUST1")
+	_, unknownErr := customerService.VerifiedName("CUST9")
+
+	// Then
+	assert.Equal(t, "customer CUST1 has not passed KYC [KYC: pending]", pendingErr.Error())
+	assert.Equal(t, "customer CUST1 has not passed KYC [KYC: rejected]", rejectedErr.Error())
+	assert.Nil(t, err)
+	assert.Equal(t, "Alex Gemmell", name)
+	assert.Equal(t, &UnknownCustomerError{CustomerID: "CUST9"}, unknownErr)
+	assert.Equal(t, "customer CUST9 does not exist", unknownErr.Error())
+	assert.Len(t, eventStore.GetAllEvents(), 4)
+}
+
+func Test_KYCCannotBeVerifiedAndRejectedConcurrently(t *testing.T) {
+	t.Parallel()
+
+	// Given a pending customer, whose KYC is verified after the rejection loads them
+	eventStore := &racingStore{EventStore: Seacrest.NewEventStore()}
+	customerService := New(eventStore)
+	assert.Nil(t, customerService.HandleCommand(RegisterCustomer{ID: "CUST1", Name: "Alex Gemmell", DateOfBirth: "1980-05-17", Address: homeAddress}))
+	eventStore.race = func() {
+		assert.Nil(t, customerService.HandleCommand(VerifyKYC{ID: "CUST1", VerifiedBy: "compliance"}))
+	}
+
+	// When
+	err := customerService.HandleCommand(RejectKYC{ID: "CUST1", ReasonCode: ReasonIdentityMismatch})
+
+	// Then
+	assert.Equal(t, Seacrest.VersionConflictError{AggregateID: "CUST1", ExpectedVersion: 1, ActualVersion: 2}, err)
+	name, err := customerService.VerifiedName("CUST1")
+	assert.Nil(t, err)
+	assert.Equal(t, "Alex Gemmell", name)
+}
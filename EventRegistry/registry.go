@@ -0,0 +1,91 @@
+package EventRegistry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/agemmell/banking-cqrs-es-go/Seacrest"
+	"sort"
+	"sync"
+)
+
+// Event: the domain events every aggregate records
+type Event interface {
+	AggregateID() string
+	EventType() string
+	EventTimestamp() int64
+}
+
+// Registry: maps the event types stored in event envelopes back to the domain events they were created from
+type Registry struct {
+	mutex     sync.RWMutex
+	factories map[string]func() Event // <event type> -> factory returning a pointer to an empty event
+}
+
+func New() *Registry {
+	return &Registry{factories: map[string]func() Event{}}
+}
+
+// Register: teach the registry how to decode an event type. The factory must return a pointer to an empty event.
+// Registering the same event type twice is a programming error.
+func (r *Registry) Register(factory func() Event) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	eventType := factory().EventType()
+	if _, ok := r.factories[eventType]; ok {
+		panic(fmt.Sprintf("event type %s is already registered", eventType))
+	}
+	r.factories[eventType] = factory
+}
+
+// Knows: has the event type been registered
+func (r *Registry) Knows(eventType string) bool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	_, ok := r.factories[eventType]
+	return ok
+}
+
+// EventTypes: every registered event type, in alphabetical order
+func (r *Registry) EventTypes() []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var eventTypes []string
+	for eventType := range r.factories {
+		eventTypes = append(eventTypes, eventType)
+	}
+	sort.Strings(eventTypes)
+	return eventTypes
+}
+
+// Decode: turn an event envelope back into the domain event it holds
+func (r *Registry) Decode(envelope Seacrest.EventEnvelope) (Event, error) {
+	r.mutex.RLock()
+	factory, ok := r.factories[envelope.EventType]
+	r.mutex.RUnlock()
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("unknown event type in envelope %s", envelope.EventType))
+	}
+
+	event := factory()
+	err := json.Unmarshal(envelope.Payload, event)
+	if err != nil {
+		return nil, err
+	}
+
+	return event, nil
+}
+
+// Default: the registry shared by every service, which registers its events when its package is initialised
+var Default = New()
+
+func Register(factory func() Event) {
+	Default.Register(factory)
+}
+
+func Decode(envelope Seacrest.EventEnvelope) (Event, error) {
+	return Default.Decode(envelope)
+}
@@ -0,0 +1,62 @@
+package EventRegistry
+
+import (
+	"github.com/agemmell/banking-cqrs-es-go/Seacrest"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type SomethingHappened struct {
+	ID        string
+	Value     int
+	Timestamp int64
+}
+
+func (e SomethingHappened) AggregateID() string   { return e.ID }
+func (e SomethingHappened) EventType() string     { return "SomethingHappened" }
+func (e SomethingHappened) EventTimestamp() int64 { return e.Timestamp }
+
+func Test_Registry_Decode(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	registry := New()
+	registry.Register(func() Event { return &SomethingHappened{} })
+	envelope := Seacrest.EventEnvelope{
+		EventType: "SomethingHappened",
+		Payload:   []byte(`{"ID":"ABCD","Value":42,"Timestamp":1234567890}`),
+	}
+
+	// When
+	event, err := registry.Decode(envelope)
+
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, &SomethingHappened{ID: "ABCD", Value: 42, Timestamp: 1234567890}, event)
+	assert.True(t, registry.Knows("SomethingHappened"))
+	assert.Equal(t, []string{"SomethingHappened"}, registry.EventTypes())
+}
+
+func Test_Registry_DecodeUnknownEventType(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	registry := New()
+
+	// When
+	_, err := registry.Decode(Seacrest.EventEnvelope{EventType: "SomethingElse"})
+
+	// Then
+	assert.Equal(t, "unknown event type in envelope SomethingElse", err.Error())
+}
+
+func Test_Registry_RegisterTwicePanics(t *testing.T) {
+	t.Parallel()
+
+	registry := New()
+	registry.Register(func() Event { return &SomethingHappened{} })
+
+	assert.Panics(t, func() {
+		registry.Register(func() Event { return &SomethingHappened{} })
+	})
+}
@@ -0,0 +1,118 @@
+package ProductCatalog
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Kinds of account a product can be
+const KindChecking = "Checking"
+const KindSavings = "Savings"
+
+// Product: an account type the bank offers and the rules its accounts follow. New products are added to a catalog,
+// the services that own each kind of account apply the rules.
+type Product struct {
+	Code        string
+	Name        string
+	Kind        string
+	FeeSchedule FeeSchedule
+	// Savings rules: zero disables the rule
+	MaxWithdrawalsPerMonth int
+	NoticePeriodDays       int
+}
+
+// Catalog: the products the bank offers, keyed by product code
+type Catalog struct {
+	mutex    sync.RWMutex
+	products map[string]Product
+}
+
+func New(products ...Product) *Catalog {
+	catalog := Catalog{products: map[string]Product{}}
+	for _, product := range products {
+		catalog.products[product.Code] = product
+	}
+	return &catalog
+}
+
+const StandardChecking = "StandardChecking"
+const EasyAccessSavings = "EasyAccessSavings"
+const NinetyDayNoticeSavings = "NinetyDayNoticeSavings"
+
+// Default: the products the bank offers out of the box
+func Default() *Catalog {
+	return New(
+		Product{
+			Code: StandardChecking,
+			Name: "Standard Checking",
+			Kind: KindChecking,
+			FeeSchedule: FeeSchedule{
+				MonthlyMaintenanceFee:   5,
+				TransactionFee:          0,
+				UnarrangedOverdraftFee:  20,
+				MinimumBalanceForWaiver: 1000,
+			},
+		},
+		Product{
+			Code:                   EasyAccessSavings,
+			Name:                   "Easy Access Savings",
+			Kind:                   KindSavings,
+			MaxWithdrawalsPerMonth: 3,
+		},
+		Product{
+			Code:             NinetyDayNoticeSavings,
+			Name:             "90 Day Notice Savings",
+			Kind:             KindSavings,
+			NoticePeriodDays: 90,
+		},
+	)
+}
+
+// Set: add a product to the catalog, or replace the rules of an existing product
+func (c *Catalog) Set(product Product) error {
+	if product.Code == "" {
+		return errors.New("a product requires a code")
+	}
+	if product.Kind != KindChecking && product.Kind != KindSavings {
+		return errors.New(fmt.Sprintf("unknown product kind %s", product.Kind))
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.products[product.Code] = product
+	return nil
+}
+
+// Get: the product with the given code, which must be of the given kind
+func (c *Catalog) Get(code string, kind string) (Product, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	product, ok := c.products[code]
+	if !ok {
+		return Product{}, errors.New(fmt.Sprintf("unknown product %s", code))
+	}
+	if product.Kind != kind {
+		return Product{}, errors.New(fmt.Sprintf("product %s is not a %s product", code, kind))
+	}
+	return product, nil
+}
+
+// Products: every product of the given kind, in code order
+func (c *Catalog) Products(kind string) []Product {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	var products []Product
+	for _, product := range c.products {
+		if product.Kind == kind {
+			products = append(products, product)
+		}
+	}
+	sort.Slice(products, func(i, j int) bool {
+		return products[i].Code < products[j].Code
+	})
+	return products
+}
@@ -0,0 +1,42 @@
+package ProductCatalog
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_GetProductOfKind(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	catalog := Default()
+
+	// When
+	product, err := catalog.Get(EasyAccessSavings, KindSavings)
+	_, wrongKindErr := catalog.Get(EasyAccessSavings, KindChecking)
+	_, unknownErr := catalog.Get("Gold", KindChecking)
+
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, 3, product.MaxWithdrawalsPerMonth)
+	assert.Equal(t, "product EasyAccessSavings is not a Checking product", wrongKindErr.Error())
+	assert.Equal(t, "unknown product Gold", unknownErr.Error())
+}
+
+func Test_SetProduct(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	catalog := New()
+
+	// When
+	err := catalog.Set(Product{Code: "PremierChecking", Kind: KindChecking, FeeSchedule: FeeSchedule{MonthlyMaintenanceFee: 15}})
+	invalidErr := catalog.Set(Product{Code: "Mortgage", Kind: "Loan"})
+
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, "unknown product kind Loan", invalidErr.Error())
+	assert.Len(t, catalog.Products(KindChecking), 1)
+	assert.Len(t, catalog.Products(KindSavings), 0)
+	assert.Equal(t, 15, catalog.Products(KindChecking)[0].FeeSchedule.FeeFor(FeeTypeMonthlyMaintenance))
+}
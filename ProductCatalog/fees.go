@@ -0,0 +1,33 @@
+package ProductCatalog
+
+// Fee types are recorded on fee events as the reason the fee was raised
+const FeeTypeMonthlyMaintenance = "MonthlyMaintenance"
+const FeeTypeTransaction = "Transaction"
+const FeeTypeUnarrangedOverdraft = "UnarrangedOverdraft"
+
+// FeeSchedule: the fees charged to accounts of a single product. A zero fee is never charged.
+type FeeSchedule struct {
+	MonthlyMaintenanceFee  int
+	TransactionFee         int
+	UnarrangedOverdraftFee int
+	// The monthly maintenance fee is waived when the balance is at least this amount (zero disables the waiver)
+	MinimumBalanceForWaiver int
+}
+
+// FeeFor: the amount charged for a fee type
+func (fs FeeSchedule) FeeFor(feeType string) int {
+	switch feeType {
+	case FeeTypeMonthlyMaintenance:
+		return fs.MonthlyMaintenanceFee
+	case FeeTypeTransaction:
+		return fs.TransactionFee
+	case FeeTypeUnarrangedOverdraft:
+		return fs.UnarrangedOverdraftFee
+	}
+	return 0
+}
+
+// WaivesMaintenanceFee: is the monthly maintenance fee waived for the given balance
+func (fs FeeSchedule) WaivesMaintenanceFee(balance int) bool {
+	return fs.MinimumBalanceForWaiver > 0 && balance >= fs.MinimumBalanceForWaiver
+}
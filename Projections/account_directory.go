@@ -0,0 +1,215 @@
+package Projections
+
+import (
+	"encoding/json"
+	"github.com/agemmell/banking-cqrs-es-go/CheckingAccountService"
+	"github.com/agemmell/banking-cqrs-es-go/ProductCatalog"
+	"github.com/agemmell/banking-cqrs-es-go/ReadModelStore"
+	"github.com/agemmell/banking-cqrs-es-go/SavingsAccountService"
+	"strings"
+)
+
+// AccountRecord: an account as the account directory lists it
+type AccountRecord struct {
+	ID       string
+	Kind     string // ProductCatalog.KindChecking or ProductCatalog.KindSavings
+	Name     string
+	HolderID string // the customer ID of the primary holder
+	Status   string // "active", "frozen", "dormant" or "closed", as CheckingAccountService.AccountStatus prints them
+	Balance  int
+	Held     int
+}
+
+// AccountDirectory: every account, kept in a read model store and found by name, status or balance without replaying
+// any events
+type AccountDirectory struct {
+	accounts *ReadModelStore.Table
+}
+
+// NewAccountDirectory: define the directory's table and indexes in the store
+func NewAccountDirectory(store *ReadModelStore.Store) (*AccountDirectory, error) {
+	accounts, err := store.DefineTable("accounts",
+		func(data []byte) (interface{}, error) {
+			record := AccountRecord{}
+			err := json.Unmarshal(data, &record)
+			return record, err
+		},
+		ReadModelStore.StringIndex("name", func(value interface{}) string {
+			return strings.ToLower(value.(AccountRecord).Name)
+		}),
+		ReadModelStore.StringIndex("status", func(value interface{}) string {
+			return value.(AccountRecord).Status
+		}),
+		ReadModelStore.NumberIndex("balance", func(value interface{}) int {
+			return value.(AccountRecord).Balance
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &AccountDirectory{accounts}, nil
+}
+
+func accountRecords(values []interface{}, err error) ([]AccountRecord, error) {
+	if err != nil {
+		return nil, err
+	}
+	records := make([]AccountRecord, 0, len(values))
+	for _, value := range values {
+		records = append(records, value.(AccountRecord))
+	}
+	return records, nil
+}
+
+// Account: the account with the ID
+func (ad *AccountDirectory) Account(accountID string) (AccountRecord, bool) {
+	record, ok := ad.accounts.Get(accountID)
+	if !ok {
+		return AccountRecord{}, false
+	}
+	return record.(AccountRecord), true
+}
+
+// Len: how many accounts there are
+func (ad *AccountDirectory) Len() int {
+	return ad.accounts.Len()
+}
+
+// NamesStartingWith: the accounts whose holder's name starts with the prefix, ignoring case, in name order
+func (ad *AccountDirectory) NamesStartingWith(prefix string) ([]AccountRecord, error) {
+	return accountRecords(ad.accounts.Prefix("name", strings.ToLower(prefix)))
+}
+
+// WithStatus: the accounts with the status, in ID order
+func (ad *AccountDirectory) WithStatus(status string) ([]AccountRecord, error) {
+	return accountRecords(ad.accounts.Equal("status", status))
+}
+
+// WithBalanceBetween: the accounts with a balance from min to max inclusive, lowest first
+func (ad *AccountDirectory) WithBalanceBetween(min int, max int) ([]AccountRecord, error) {
+	return accountRecords(ad.accounts.Between("balance", min, max))
+}
+
+// AccountDirectoryProjector: writes every account into an AccountDirectory. Its changes only become visible when they
+// are committed with its checkpoint, through a ReadModelCheckpointStore, or by committing its Transaction.
+type AccountDirectoryProjector struct {
+	directory   *AccountDirectory
+	transaction *ReadModelStore.Transaction
+}
+
+func NewAccountDirectoryProjector(directory *AccountDirectory) *AccountDirectoryProjector {
+	return &AccountDirectoryProjector{directory: directory}
+}
+
+func (adp *AccountDirectoryProjector) Name() string {
+	return "AccountDirectory"
+}
+
+func (adp *AccountDirectoryProjector) EventTypes() []string {
+	return []string{
+		CheckingAccountService.TypeAccountWasOpened,
+		CheckingAccountService.TypeAccountHolderWasRenamed,
+		CheckingAccountService.TypeAccountWasClosed,
+		CheckingAccountService.TypeAccountWasReopened,
+		CheckingAccountService.TypeAccountWasFrozen,
+		CheckingAccountService.TypeAccountWasUnfrozen,
+		CheckingAccountService.TypeAccountBecameDormant,
+		CheckingAccountService.TypeAccountWasReactivated,
+		CheckingAccountService.TypeMoneyWasDeposited,
+		CheckingAccountService.TypeMoneyWasWithdrawn,
+		CheckingAccountService.TypeFeeWasCharged,
+		CheckingAccountService.TypeFeeWasRefunded,
+		CheckingAccountService.TypeStandingOrderPaymentWasReturned,
+		CheckingAccountService.TypeHoldWasPlaced,
+		CheckingAccountService.TypeHoldWasReleased,
+		CheckingAccountService.TypeHoldWasCaptured,
+		SavingsAccountService.TypeSavingsAccountWasOpened,
+		SavingsAccountService.TypeSavingsMoneyWasDeposited,
+		SavingsAccountService.TypeSavingsMoneyWasWithdrawn,
+		SavingsAccountService.TypeSavingsAccountWasClosed,
+	}
+}
+
+// Transaction: the changes made since the last commit
+func (adp *AccountDirectoryProjector) Transaction() *ReadModelStore.Transaction {
+	if adp.transaction == nil || adp.transaction.Done() {
+		adp.transaction = adp.directory.accounts.Store().Begin()
+	}
+	return adp.transaction
+}
+
+func (adp *AccountDirectoryProjector) Handle(event Event) error {
+	accountID := event.AggregateID()
+	transaction := adp.Transaction()
+	record := AccountRecord{}
+	if row, ok := transaction.Get(adp.directory.accounts, accountID); ok {
+		record = row.(AccountRecord)
+	}
+
+	switch event := event.(type) {
+	case *CheckingAccountService.AccountWasOpened:
+		record = AccountRecord{ID: event.ID, Kind: ProductCatalog.KindChecking, Name: event.Name, HolderID: primaryHolderID(*event), Status: CheckingAccountService.AccountActive.String()}
+	case *CheckingAccountService.AccountHolderWasRenamed:
+		if record.HolderID != event.HolderID {
+			return nil
+		}
+		record.Name = event.Name
+	case *CheckingAccountService.AccountWasClosed, *SavingsAccountService.SavingsAccountWasClosed:
+		record.Status = CheckingAccountService.AccountClosed.String()
+	case *CheckingAccountService.AccountWasReopened, *CheckingAccountService.AccountWasUnfrozen, *CheckingAccountService.AccountWasReactivated:
+		record.Status = CheckingAccountService.AccountActive.String()
+	case *CheckingAccountService.AccountWasFrozen:
+		record.Status = CheckingAccountService.AccountFrozen.String()
+	case *CheckingAccountService.AccountBecameDormant:
+		record.Status = CheckingAccountService.AccountDormant.String()
+	case *CheckingAccountService.MoneyWasDeposited:
+		record.Balance += event.Amount
+	case *CheckingAccountService.MoneyWasWithdrawn:
+		record.Balance = event.Balance
+	case *CheckingAccountService.FeeWasCharged:
+		record.Balance = event.Balance
+	case *CheckingAccountService.FeeWasRefunded:
+		record.Balance = event.Balance
+	case *CheckingAccountService.StandingOrderPaymentWasReturned:
+		record.Balance = event.Balance
+	case *CheckingAccountService.HoldWasPlaced:
+		record.Held += event.Amount
+	case *CheckingAccountService.HoldWasReleased:
+		record.Held -= event.Amount
+	case *CheckingAccountService.HoldWasCaptured:
+		record.Balance = event.Balance
+		record.Held -= event.HeldAmount
+	case *SavingsAccountService.SavingsAccountWasOpened:
+		record = AccountRecord{ID: event.ID, Kind: ProductCatalog.KindSavings, Name: event.Name, HolderID: event.CustomerID, Status: CheckingAccountService.AccountActive.String()}
+	case *SavingsAccountService.SavingsMoneyWasDeposited:
+		record.Balance = event.Balance
+	case *SavingsAccountService.SavingsMoneyWasWithdrawn:
+		record.Balance = event.Balance
+	default:
+		return nil
+	}
+
+	return transaction.Put(adp.directory.accounts, accountID, record)
+}
+
+// Directory: the accounts as of the last commit
+func (adp *AccountDirectoryProjector) Directory() *AccountDirectory {
+	return adp.directory
+}
+
+func (adp *AccountDirectoryProjector) State() interface{} {
+	return adp.directory
+}
+
+// MarshalState: the directory itself is committed to the store with the checkpoint, so the checkpoint holds nothing
+func (adp *AccountDirectoryProjector) MarshalState() ([]byte, error) {
+	return []byte("{}"), nil
+}
+
+// RestoreState: the store already holds the directory as of the checkpoint, so only uncommitted changes are dropped
+func (adp *AccountDirectoryProjector) RestoreState(state []byte) error {
+	if adp.transaction != nil {
+		adp.transaction.Rollback()
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package Projections
+
+import (
+	"github.com/agemmell/banking-cqrs-es-go/CheckingAccountService"
+	"github.com/agemmell/banking-cqrs-es-go/ReadModelStore"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func accountIDs(records []AccountRecord) []string {
+	var found []string
+	for _, record := range records {
+		found = append(found, record.ID)
+	}
+	return found
+}
+
+func Test_AccountDirectoryFindsAccountsByNameStatusAndBalance(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	eventStore := newEventStore(t,
+		CheckingAccountService.OpenAccount{ID: "ACC1", CustomerID: "CUST1"},
+		CheckingAccountService.OpenAccount{ID: "ACC2", CustomerID: "CUST2"},
+		CheckingAccountService.OpenAccount{ID: "ACC3", CustomerID: "CUST4"},
+		CheckingAccountService.DepositMoney{ID: "ACC1", Amount: 100, ActingAs: CheckingAccountService.SystemPrincipal},
+		CheckingAccountService.DepositMoney{ID: "ACC2", Amount: 2500, ActingAs: CheckingAccountService.SystemPrincipal},
+		CheckingAccountService.WithdrawMoney{ID: "ACC2", Amount: 500, ActingAs: CheckingAccountService.SystemPrincipal},
+		CheckingAccountService.FreezeAccount{ID: "ACC2", Scope: CheckingAccountService.FreezeDebits, ReasonCode: CheckingAccountService.ReasonSuspectedFraud, ActingAs: CheckingAccountService.SystemPrincipal},
+		CheckingAccountService.CloseAccount{ID: "ACC3", ActingAs: CheckingAccountService.SystemPrincipal},
+	)
+	directory, err := NewAccountDirectory(ReadModelStore.NewMemoryStore())
+	assert.Nil(t, err)
+	projector := NewAccountDirectoryProjector(directory)
+
+	// When
+	err = NewRunner(projector).Run(eventStore)
+	uncommitted := directory.Len()
+	assert.Nil(t, projector.Transaction().Commit())
+
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, 0, uncommitted)
+	named, err := directory.NamesStartingWith("ALEX")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"ACC1", "ACC3"}, accountIDs(named))
+	frozen, err := directory.WithStatus(CheckingAccountService.AccountFrozen.String())
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"ACC2"}, accountIDs(frozen))
+	closed, err := directory.WithStatus(CheckingAccountService.AccountClosed.String())
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"ACC3"}, accountIDs(closed))
+	between, err := directory.WithBalanceBetween(1, 5000)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"ACC1", "ACC2"}, accountIDs(between))
+	account, found := directory.Account("ACC2")
+	assert.True(t, found)
+	assert.Equal(t, AccountRecord{ID: "ACC2", Kind: "Checking", Name: "Sam Smith", HolderID: "CUST2", Status: "frozen", Balance: 2000}, account)
+}
+
+func Test_AccountDirectoryIsCommittedWithItsCheckpoint(t *testing.T) {
+	t.Parallel()
+
+	// Given a directory checkpointed part way through, and changes after the checkpoint lost in a crash
+	dir, err := ioutil.TempDir("", "readmodels")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	eventStore := newEventStore(t,
+		CheckingAccountService.OpenAccount{ID: "ACC1", CustomerID: "CUST1"},
+		CheckingAccountService.DepositMoney{ID: "ACC1", Amount: 100, ActingAs: CheckingAccountService.SystemPrincipal},
+		CheckingAccountService.OpenAccount{ID: "ACC2", CustomerID: "CUST2"},
+		CheckingAccountService.DepositMoney{ID: "ACC1", Amount: 50, ActingAs: CheckingAccountService.SystemPrincipal},
+		CheckingAccountService.DepositMoney{ID: "ACC2", Amount: 75, ActingAs: CheckingAccountService.SystemPrincipal},
+	)
+	store, err := ReadModelStore.Open(dir)
+	assert.Nil(t, err)
+	directory, err := NewAccountDirectory(store)
+	assert.Nil(t, err)
+	projector := NewAccountDirectoryProjector(directory)
+	checkpoints, err := NewReadModelCheckpointStore(store, NewMemoryCheckpointStore(), projector)
+	assert.Nil(t, err)
+	crashed := NewRunner(projector)
+	assert.Nil(t, crashed.ResumeFrom(checkpoints, 2))
+	for _, envelope := range eventStore.GetAllEvents()[:3] {
+		assert.Nil(t, crashed.Apply(envelope))
+	}
+
+	// When
+	reopened, err := ReadModelStore.Open(dir)
+	assert.Nil(t, err)
+	restoredDirectory, err := NewAccountDirectory(reopened)
+	assert.Nil(t, err)
+	restored := NewAccountDirectoryProjector(restoredDirectory)
+	restoredCheckpoints, err := NewReadModelCheckpointStore(reopened, NewMemoryCheckpointStore(), restored)
+	assert.Nil(t, err)
+	runner := NewRunner(restored)
+	assert.Nil(t, runner.ResumeFrom(restoredCheckpoints, 0))
+	resumedFrom := runner.Position()
+	atCheckpoint := restoredDirectory.Len()
+	err = runner.Run(eventStore)
+
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, uint(2), resumedFrom)
+	assert.Equal(t, 1, atCheckpoint)
+	first, _ := restoredDirectory.Account("ACC1")
+	second, _ := restoredDirectory.Account("ACC2")
+	assert.Equal(t, 150, first.Balance)
+	assert.Equal(t, 75, second.Balance)
+	checkpoint, found, err := restoredCheckpoints.Load("AccountDirectory")
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, uint(5), checkpoint.Position)
+}
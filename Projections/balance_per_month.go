@@ -0,0 +1,165 @@
+package Projections
+
+import (
+	"encoding/json"
+	"github.com/agemmell/banking-cqrs-es-go/CheckingAccountService"
+	"github.com/agemmell/banking-cqrs-es-go/SavingsAccountService"
+	"time"
+)
+
+// MonthlyBalance: the bank's funds over a calendar month (UTC)
+type MonthlyBalance struct {
+	YearMonth string // yyyy-mm
+	Opening   int
+	Inflows   int // deposits and fee refunds
+	Outflows  int // withdrawals, fees and captured holds
+	Closing   int
+}
+
+// monthlyFlows: the money that moved in and out of the bank during a month
+type monthlyFlows struct {
+	Inflows  int
+	Outflows int
+}
+
+// BalancePerMonthProjector: bank total balance per month. Flows are totalled by the month the event happened in, so
+// the result does not depend on the order events are projected in.
+type BalancePerMonthProjector struct {
+	flowsPerMonth map[string]monthlyFlows // <year-month> -> flows during the month
+}
+
+func NewBalancePerMonthProjector() *BalancePerMonthProjector {
+	return &BalancePerMonthProjector{flowsPerMonth: map[string]monthlyFlows{}}
+}
+
+func (bpmp *BalancePerMonthProjector) Name() string {
+	return "TotalBalancePerMonth"
+}
+
+func (bpmp *BalancePerMonthProjector) EventTypes() []string {
+	return []string{
+		CheckingAccountService.TypeMoneyWasDeposited,
+		CheckingAccountService.TypeMoneyWasWithdrawn,
+		CheckingAccountService.TypeFeeWasCharged,
+		CheckingAccountService.TypeFeeWasRefunded,
+		CheckingAccountService.TypeStandingOrderPaymentWasReturned,
+		CheckingAccountService.TypeHoldWasCaptured,
+		SavingsAccountService.TypeSavingsMoneyWasDeposited,
+		SavingsAccountService.TypeSavingsMoneyWasWithdrawn,
+	}
+}
+
+func (bpmp *BalancePerMonthProjector) Handle(event Event) error {
+	yearMonth := yearMonthOf(event.EventTimestamp())
+	flows := bpmp.flowsPerMonth[yearMonth]
+
+	switch event := event.(type) {
+	case *CheckingAccountService.MoneyWasDeposited:
+		flows.Inflows += event.Amount
+	case *CheckingAccountService.MoneyWasWithdrawn:
+		flows.Outflows += event.Amount
+	case *CheckingAccountService.FeeWasCharged:
+		flows.Outflows += event.Amount
+	case *CheckingAccountService.FeeWasRefunded:
+		flows.Inflows += event.Amount
+	case *CheckingAccountService.StandingOrderPaymentWasReturned:
+		flows.Inflows += event.Amount
+	case *CheckingAccountService.HoldWasCaptured:
+		flows.Outflows += event.Amount
+	case *SavingsAccountService.SavingsMoneyWasDeposited:
+		flows.Inflows += event.Amount
+	case *SavingsAccountService.SavingsMoneyWasWithdrawn:
+		flows.Outflows += event.Amount
+	default:
+		return nil
+	}
+
+	bpmp.flowsPerMonth[yearMonth] = flows
+	return nil
+}
+
+// BalancePerMonth: the bank's balance for every month from the first month with a transaction to the last, earliest
+// first. Months without any transactions are included, with their opening balance carried through.
+func (bpmp *BalancePerMonthProjector) BalancePerMonth() []MonthlyBalance {
+	if len(bpmp.flowsPerMonth) == 0 {
+		return nil
+	}
+
+	first, last := "", ""
+	for yearMonth := range bpmp.flowsPerMonth {
+		if first == "" || yearMonth < first {
+			first = yearMonth
+		}
+		if yearMonth > last {
+			last = yearMonth
+		}
+	}
+
+	var balancePerMonth []MonthlyBalance
+	balance := 0
+	month, _ := time.Parse("2006-01", first)
+	for yearMonth := first; yearMonth <= last; yearMonth = month.Format("2006-01") {
+		flows := bpmp.flowsPerMonth[yearMonth]
+		monthlyBalance := MonthlyBalance{
+			YearMonth: yearMonth,
+			Opening:   balance,
+			Inflows:   flows.Inflows,
+			Outflows:  flows.Outflows,
+			Closing:   balance + flows.Inflows - flows.Outflows,
+		}
+		balancePerMonth = append(balancePerMonth, monthlyBalance)
+		balance = monthlyBalance.Closing
+		month = month.AddDate(0, 1, 0)
+	}
+	return balancePerMonth
+}
+
+func (bpmp *BalancePerMonthProjector) State() interface{} {
+	return bpmp.BalancePerMonth()
+}
+
+func (bpmp *BalancePerMonthProjector) MarshalState() ([]byte, error) {
+	return json.Marshal(bpmp.flowsPerMonth)
+}
+
+func (bpmp *BalancePerMonthProjector) RestoreState(state []byte) error {
+	restored := map[string]monthlyFlows{}
+	err := json.Unmarshal(state, &restored)
+	if err != nil {
+		return err
+	}
+	bpmp.flowsPerMonth = restored
+	return nil
+}
+
+// Version: 2 keeps each month's inflows and outflows rather than only its closing total
+func (bpmp *BalancePerMonthProjector) Version() uint {
+	return 2
+}
+
+func (bpmp *BalancePerMonthProjector) NewEmpty() Projector {
+	return NewBalancePerMonthProjector()
+}
+
+func (bpmp *BalancePerMonthProjector) NewPartition() Projector {
+	return NewBalancePerMonthProjector()
+}
+
+func (bpmp *BalancePerMonthProjector) Merge(partition Projector) error {
+	other, ok := partition.(*BalancePerMonthProjector)
+	if !ok {
+		return partitionMismatch(bpmp, partition)
+	}
+	for yearMonth, otherFlows := range other.flowsPerMonth {
+		flows := bpmp.flowsPerMonth[yearMonth]
+		flows.Inflows += otherFlows.Inflows
+		flows.Outflows += otherFlows.Outflows
+		bpmp.flowsPerMonth[yearMonth] = flows
+	}
+	return nil
+}
+
+// yearMonthOf: the calendar month (UTC) a timestamp falls in, as yyyy-mm
+func yearMonthOf(timestamp int64) string {
+	return time.Unix(0, timestamp).UTC().Format("2006-01")
+}
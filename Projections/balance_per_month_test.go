@@ -0,0 +1,83 @@
+package Projections
+
+import (
+	"github.com/agemmell/banking-cqrs-es-go/CheckingAccountService"
+	"github.com/agemmell/banking-cqrs-es-go/SavingsAccountService"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func at(year int, month time.Month, day int) int64 {
+	return time.Date(year, month, day, 12, 0, 0, 0, time.UTC).UnixNano()
+}
+
+func Test_BalancePerMonthIsOrderedWithGapsFilled(t *testing.T) {
+	t.Parallel()
+
+	// Given events from January and March, handled out of timestamp order
+	events := []Event{
+		&CheckingAccountService.MoneyWasDeposited{ID: "ACC1", Amount: 100, Timestamp: at(2021, time.March, 10)},
+		&CheckingAccountService.MoneyWasDeposited{ID: "ACC1", Amount: 500, Timestamp: at(2021, time.January, 5)},
+		&CheckingAccountService.FeeWasCharged{ID: "ACC1", Amount: 5, Timestamp: at(2021, time.March, 31)},
+		&CheckingAccountService.MoneyWasWithdrawn{ID: "ACC1", Amount: 200, Timestamp: at(2021, time.January, 20)},
+		&SavingsAccountService.SavingsMoneyWasDeposited{ID: "SAV1", Amount: 50, Timestamp: at(2021, time.March, 2)},
+		&CheckingAccountService.HoldWasCaptured{ID: "ACC1", Amount: 40, HeldAmount: 40, Timestamp: at(2021, time.January, 25)},
+	}
+	forwards := NewBalancePerMonthProjector()
+	backwards := NewBalancePerMonthProjector()
+
+	// When
+	for i := range events {
+		assert.Nil(t, forwards.Handle(events[i]))
+		assert.Nil(t, backwards.Handle(events[len(events)-1-i]))
+	}
+
+	// Then
+	expected := []MonthlyBalance{
+		{YearMonth: "2021-01", Opening: 0, Inflows: 500, Outflows: 240, Closing: 260},
+		{YearMonth: "2021-02", Opening: 260, Inflows: 0, Outflows: 0, Closing: 260},
+		{YearMonth: "2021-03", Opening: 260, Inflows: 150, Outflows: 5, Closing: 405},
+	}
+	assert.Equal(t, expected, forwards.BalancePerMonth())
+	assert.Equal(t, expected, backwards.BalancePerMonth())
+}
+
+func Test_BalancePerMonthClosesOnTheBanksFunds(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	eventStore := newEventStore(t,
+		CheckingAccountService.OpenAccount{ID: "ACC1", CustomerID: "CUST1"},
+		CheckingAccountService.DepositMoney{ID: "ACC1", Amount: 100, ActingAs: CheckingAccountService.SystemPrincipal},
+		CheckingAccountService.PlaceHold{ID: "ACC1", HoldID: "H1", Amount: 50, ExpiresAt: time.Date(2021, time.February, 1, 0, 0, 0, 0, time.UTC).UnixNano(), ActingAs: CheckingAccountService.SystemPrincipal},
+		CheckingAccountService.CaptureHold{ID: "ACC1", HoldID: "H1", Amount: 30, ActingAs: CheckingAccountService.SystemPrincipal},
+		CheckingAccountService.WithdrawMoney{ID: "ACC1", Amount: 20, ActingAs: CheckingAccountService.SystemPrincipal},
+	)
+	balancePerMonth := NewBalancePerMonthProjector()
+
+	// When
+	err := NewRunner(balancePerMonth).Run(eventStore)
+
+	// Then
+	assert.Nil(t, err)
+	bankFunds, err := BuildBankFunds(eventStore)
+	assert.Nil(t, err)
+	months := balancePerMonth.BalancePerMonth()
+	assert.Len(t, months, 1)
+	assert.Equal(t, bankFunds.Total, months[0].Closing)
+	assert.Equal(t, MonthlyBalance{YearMonth: "2021-01", Inflows: 100, Outflows: 50, Closing: 50}, months[0])
+}
+
+func Test_BalancePerMonthIsEmptyWithoutTransactions(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	balancePerMonth := NewBalancePerMonthProjector()
+
+	// When
+	months := balancePerMonth.BalancePerMonth()
+
+	// Then
+	assert.Empty(t, months)
+}
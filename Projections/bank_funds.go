@@ -2,72 +2,231 @@ package Projections
 
 import (
 	"encoding/json"
-	"fmt"
 	"github.com/agemmell/banking-cqrs-es-go/CheckingAccountService"
+	"github.com/agemmell/banking-cqrs-es-go/SavingsAccountService"
 	"github.com/agemmell/banking-cqrs-es-go/Seacrest"
-	"golang.org/x/text/language"
-	"golang.org/x/text/message"
-	"sort"
-	"time"
 )
 
-// Total bank funds (sum of all account balances)
-func TotalBankFunds(eventStore *Seacrest.EventStore) error {
-
-	totalBankFunds := 0
-
-	for _, envelope := range eventStore.GetAllEvents() {
-		switch envelope.EventType {
-		case "MoneyWasDeposited":
-			moneyWasDeposited := CheckingAccountService.MoneyWasDeposited{}
-			err := json.Unmarshal(envelope.Payload, &moneyWasDeposited)
-			if err != nil {
-				return err
-			}
-			totalBankFunds += moneyWasDeposited.Amount
-		case "MoneyWasWithdrawn":
-			moneyWasWithdrawn := CheckingAccountService.MoneyWasWithdrawn{}
-			err := json.Unmarshal(envelope.Payload, &moneyWasWithdrawn)
-			if err != nil {
-				return err
-			}
-			totalBankFunds -= moneyWasWithdrawn.Amount
-		}
+// BankFunds: the money the bank holds for its customers and how much of it is reserved by holds
+type BankFunds struct {
+	Total int
+	Held  int
+}
+
+// Available: the funds customers can still spend
+func (bf BankFunds) Available() int {
+	return bf.Total - bf.Held
+}
+
+// BankFundsProjector: total bank funds (sum of all account balances)
+type BankFundsProjector struct {
+	bankFunds BankFunds
+}
+
+func NewBankFundsProjector() *BankFundsProjector {
+	return &BankFundsProjector{}
+}
+
+func (bfp *BankFundsProjector) Name() string {
+	return "TotalBankFunds"
+}
+
+func (bfp *BankFundsProjector) EventTypes() []string {
+	return []string{
+		CheckingAccountService.TypeMoneyWasDeposited,
+		CheckingAccountService.TypeMoneyWasWithdrawn,
+		CheckingAccountService.TypeFeeWasCharged,
+		CheckingAccountService.TypeFeeWasRefunded,
+		CheckingAccountService.TypeStandingOrderPaymentWasReturned,
+		CheckingAccountService.TypeHoldWasPlaced,
+		CheckingAccountService.TypeHoldWasReleased,
+		CheckingAccountService.TypeHoldWasCaptured,
+		SavingsAccountService.TypeSavingsMoneyWasDeposited,
+		SavingsAccountService.TypeSavingsMoneyWasWithdrawn,
 	}
-	p := message.NewPrinter(language.English)
-	fmt.Printf("Total Banks Funds = $%s\n", p.Sprintf("%d", totalBankFunds))
+}
 
+func (bfp *BankFundsProjector) Handle(event Event) error {
+	switch event := event.(type) {
+	case *CheckingAccountService.MoneyWasDeposited:
+		bfp.bankFunds.Total += event.Amount
+	case *CheckingAccountService.MoneyWasWithdrawn:
+		bfp.bankFunds.Total -= event.Amount
+	case *CheckingAccountService.FeeWasCharged:
+		bfp.bankFunds.Total -= event.Amount
+	case *CheckingAccountService.FeeWasRefunded:
+		bfp.bankFunds.Total += event.Amount
+	case *CheckingAccountService.StandingOrderPaymentWasReturned:
+		bfp.bankFunds.Total += event.Amount
+	case *CheckingAccountService.HoldWasPlaced:
+		bfp.bankFunds.Held += event.Amount
+	case *CheckingAccountService.HoldWasReleased:
+		bfp.bankFunds.Held -= event.Amount
+	case *CheckingAccountService.HoldWasCaptured:
+		bfp.bankFunds.Total -= event.Amount
+		bfp.bankFunds.Held -= event.HeldAmount
+	case *SavingsAccountService.SavingsMoneyWasDeposited:
+		bfp.bankFunds.Total += event.Amount
+	case *SavingsAccountService.SavingsMoneyWasWithdrawn:
+		bfp.bankFunds.Total -= event.Amount
+	}
 	return nil
 }
 
-// Number of open & closed accounts
-func OpenClosedAccounts(eventStore *Seacrest.EventStore) error {
-
-	openAccounts, closedAccounts := 0, 0
-
-	for _, envelope := range eventStore.GetAllEvents() {
-		switch envelope.EventType {
-		case "AccountWasOpened":
-			accountWasOpened := CheckingAccountService.AccountWasOpened{}
-			err := json.Unmarshal(envelope.Payload, &accountWasOpened)
-			if err != nil {
-				return err
-			}
-			openAccounts += 1
-		case "AccountWasClosed":
-			accountWasClosed := CheckingAccountService.AccountWasClosed{}
-			err := json.Unmarshal(envelope.Payload, &accountWasClosed)
-			if err != nil {
-				return err
-			}
-			openAccounts -= 1
-			closedAccounts += 1
-		}
+func (bfp *BankFundsProjector) BankFunds() BankFunds {
+	return bfp.bankFunds
+}
+
+func (bfp *BankFundsProjector) State() interface{} {
+	return bfp.BankFunds()
+}
+
+func (bfp *BankFundsProjector) MarshalState() ([]byte, error) {
+	return json.Marshal(bfp.bankFunds)
+}
+
+func (bfp *BankFundsProjector) RestoreState(state []byte) error {
+	return json.Unmarshal(state, &bfp.bankFunds)
+}
+
+func (bfp *BankFundsProjector) Version() uint {
+	return 1
+}
+
+func (bfp *BankFundsProjector) NewEmpty() Projector {
+	return NewBankFundsProjector()
+}
+
+func (bfp *BankFundsProjector) NewPartition() Projector {
+	return NewBankFundsProjector()
+}
+
+func (bfp *BankFundsProjector) Merge(partition Projector) error {
+	other, ok := partition.(*BankFundsProjector)
+	if !ok {
+		return partitionMismatch(bfp, partition)
+	}
+	bfp.bankFunds.Total += other.bankFunds.Total
+	bfp.bankFunds.Held += other.bankFunds.Held
+	return nil
+}
+
+// BuildBankFunds: the bank's funds after every event in the store
+func BuildBankFunds(eventStore *Seacrest.EventStore) (BankFunds, error) {
+	projector := NewBankFundsProjector()
+	err := runProjector(eventStore, projector)
+	if err != nil {
+		return BankFunds{}, err
 	}
+	return projector.BankFunds(), nil
+}
 
-	fmt.Printf("Open Accounts = %d\n", openAccounts)
-	fmt.Printf("Closed Accounts = %d\n", closedAccounts)
+// AccountCounts: how many accounts are open and closed, and how many of the open accounts are dormant
+type AccountCounts struct {
+	Open    int
+	Closed  int
+	Dormant int
+}
+
+// AccountCountsProjector: number of open & closed accounts, and how many of the open accounts are dormant
+type AccountCountsProjector struct {
+	open    int
+	closed  int
+	dormant map[string]bool
+}
 
+func NewAccountCountsProjector() *AccountCountsProjector {
+	return &AccountCountsProjector{dormant: map[string]bool{}}
+}
+
+func (acp *AccountCountsProjector) Name() string {
+	return "OpenClosedAccounts"
+}
+
+func (acp *AccountCountsProjector) EventTypes() []string {
+	return []string{
+		CheckingAccountService.TypeAccountWasOpened,
+		CheckingAccountService.TypeAccountWasClosed,
+		CheckingAccountService.TypeAccountWasReopened,
+		CheckingAccountService.TypeAccountBecameDormant,
+		CheckingAccountService.TypeAccountWasReactivated,
+		SavingsAccountService.TypeSavingsAccountWasOpened,
+		SavingsAccountService.TypeSavingsAccountWasClosed,
+	}
+}
+
+func (acp *AccountCountsProjector) Handle(event Event) error {
+	switch event := event.(type) {
+	case *CheckingAccountService.AccountWasOpened, *SavingsAccountService.SavingsAccountWasOpened:
+		acp.open++
+	case *CheckingAccountService.AccountWasClosed, *SavingsAccountService.SavingsAccountWasClosed:
+		acp.open--
+		acp.closed++
+		// only open accounts are counted as dormant
+		delete(acp.dormant, event.AggregateID())
+	case *CheckingAccountService.AccountWasReopened:
+		acp.open++
+		acp.closed--
+		delete(acp.dormant, event.ID)
+	case *CheckingAccountService.AccountBecameDormant:
+		acp.dormant[event.ID] = true
+	case *CheckingAccountService.AccountWasReactivated:
+		delete(acp.dormant, event.ID)
+	}
+	return nil
+}
+
+func (acp *AccountCountsProjector) AccountCounts() AccountCounts {
+	return AccountCounts{Open: acp.open, Closed: acp.closed, Dormant: len(acp.dormant)}
+}
+
+func (acp *AccountCountsProjector) State() interface{} {
+	return acp.AccountCounts()
+}
+
+type accountCountsState struct {
+	Open    int
+	Closed  int
+	Dormant map[string]bool
+}
+
+func (acp *AccountCountsProjector) MarshalState() ([]byte, error) {
+	return json.Marshal(accountCountsState{Open: acp.open, Closed: acp.closed, Dormant: acp.dormant})
+}
+
+func (acp *AccountCountsProjector) RestoreState(state []byte) error {
+	restored := accountCountsState{Dormant: map[string]bool{}}
+	err := json.Unmarshal(state, &restored)
+	if err != nil {
+		return err
+	}
+	acp.open, acp.closed, acp.dormant = restored.Open, restored.Closed, restored.Dormant
+	return nil
+}
+
+// Version: 2 stops counting dormant accounts once they are closed or reopened
+func (acp *AccountCountsProjector) Version() uint {
+	return 2
+}
+
+func (acp *AccountCountsProjector) NewEmpty() Projector {
+	return NewAccountCountsProjector()
+}
+
+func (acp *AccountCountsProjector) NewPartition() Projector {
+	return NewAccountCountsProjector()
+}
+
+func (acp *AccountCountsProjector) Merge(partition Projector) error {
+	other, ok := partition.(*AccountCountsProjector)
+	if !ok {
+		return partitionMismatch(acp, partition)
+	}
+	acp.open += other.open
+	acp.closed += other.closed
+	for accountID := range other.dormant {
+		acp.dormant[accountID] = true
+	}
 	return nil
 }
 
@@ -75,140 +234,150 @@ type AccountBalance struct {
 	ID      string
 	Name    string
 	Balance int
+	Held    int
 }
 
-// Top 10 "highest balance" account owners
-func HighestBalanceOwners(eventStore *Seacrest.EventStore) error {
-
-	var accountBalances = map[string]AccountBalance{}
-	var topTen []AccountBalance
-
-	for _, envelope := range eventStore.GetAllEvents() {
-		switch envelope.EventType {
-		case "AccountWasOpened":
-			accountWasOpened := CheckingAccountService.AccountWasOpened{}
-			err := json.Unmarshal(envelope.Payload, &accountWasOpened)
-			if err != nil {
-				return err
-			}
-			accountBalance := AccountBalance{
-				ID:      accountWasOpened.ID,
-				Name:    accountWasOpened.Name,
-				Balance: 0,
-			}
-			accountBalances[accountWasOpened.ID] = accountBalance
-			topTen = sortTopTen(topTen, accountBalance)
-
-		case "MoneyWasDeposited":
-			moneyWasDeposited := CheckingAccountService.MoneyWasDeposited{}
-			err := json.Unmarshal(envelope.Payload, &moneyWasDeposited)
-			if err != nil {
-				return err
-			}
-			accountBalance := accountBalances[moneyWasDeposited.ID]
-			accountBalance.Balance += moneyWasDeposited.Amount
-			accountBalances[moneyWasDeposited.ID] = accountBalance
-			topTen = sortTopTen(topTen, accountBalance)
-
-		case "MoneyWasWithdrawn":
-			moneyWasWithdrawn := CheckingAccountService.MoneyWasWithdrawn{}
-			err := json.Unmarshal(envelope.Payload, &moneyWasWithdrawn)
-			if err != nil {
-				return err
-			}
-			accountBalance := accountBalances[moneyWasWithdrawn.ID]
-			accountBalance.Balance = moneyWasWithdrawn.Balance
-			accountBalances[moneyWasWithdrawn.ID] = accountBalance
-			topTen = sortTopTen(topTen, accountBalance)
-		}
-	}
+// Available: the ledger balance less funds reserved by outstanding holds
+func (ab AccountBalance) Available() int {
+	return ab.Balance - ab.Held
+}
 
-	fmt.Println("Top Ten Balances:")
-	p := message.NewPrinter(language.English)
-	for i, account := range topTen {
-		fmt.Printf("%d. %s (%s: %s)\n", i+1, p.Sprintf("%d", account.Balance), account.Name, account.ID)
+// AccountBalancesProjector: every account's balance. OnChange, if set, is called with an account's new balance each
+// time it changes.
+type AccountBalancesProjector struct {
+	OnChange        func(accountBalance AccountBalance)
+	accountBalances map[string]AccountBalance
+	primaryHolders  map[string]string // <account ID> -> <primary holder ID>
+}
+
+func NewAccountBalancesProjector() *AccountBalancesProjector {
+	return &AccountBalancesProjector{
+		accountBalances: map[string]AccountBalance{},
+		primaryHolders:  map[string]string{},
 	}
+}
 
-	return nil
+func (abp *AccountBalancesProjector) Name() string {
+	return "AccountBalances"
 }
 
-func sortTopTen(topTen []AccountBalance, accountBalance AccountBalance) []AccountBalance {
-	alreadyExists := false
-	for i, account := range topTen {
-		if account.ID == accountBalance.ID {
-			topTen[i] = accountBalance
-			alreadyExists = true
-			break
-		}
+func (abp *AccountBalancesProjector) EventTypes() []string {
+	return []string{
+		CheckingAccountService.TypeAccountWasOpened,
+		CheckingAccountService.TypeAccountHolderWasRenamed,
+		CheckingAccountService.TypeMoneyWasDeposited,
+		CheckingAccountService.TypeMoneyWasWithdrawn,
+		CheckingAccountService.TypeFeeWasCharged,
+		CheckingAccountService.TypeFeeWasRefunded,
+		CheckingAccountService.TypeStandingOrderPaymentWasReturned,
+		CheckingAccountService.TypeHoldWasPlaced,
+		CheckingAccountService.TypeHoldWasReleased,
+		CheckingAccountService.TypeHoldWasCaptured,
+		SavingsAccountService.TypeSavingsAccountWasOpened,
+		SavingsAccountService.TypeSavingsMoneyWasDeposited,
+		SavingsAccountService.TypeSavingsMoneyWasWithdrawn,
 	}
+}
 
-	if !alreadyExists {
-		topTen = append(topTen, accountBalance)
+func (abp *AccountBalancesProjector) Handle(event Event) error {
+	accountID := event.AggregateID()
+	accountBalance := abp.accountBalances[accountID]
+
+	switch event := event.(type) {
+	case *CheckingAccountService.AccountWasOpened:
+		accountBalance = AccountBalance{ID: event.ID, Name: event.Name}
+		abp.primaryHolders[event.ID] = primaryHolderID(*event)
+	case *CheckingAccountService.AccountHolderWasRenamed:
+		if abp.primaryHolders[event.ID] != event.HolderID {
+			return nil
+		}
+		accountBalance.Name = event.Name
+	case *CheckingAccountService.MoneyWasDeposited:
+		accountBalance.Balance += event.Amount
+	case *CheckingAccountService.MoneyWasWithdrawn:
+		accountBalance.Balance = event.Balance
+	case *CheckingAccountService.FeeWasCharged:
+		accountBalance.Balance = event.Balance
+	case *CheckingAccountService.FeeWasRefunded:
+		accountBalance.Balance = event.Balance
+	case *CheckingAccountService.StandingOrderPaymentWasReturned:
+		accountBalance.Balance = event.Balance
+	case *CheckingAccountService.HoldWasPlaced:
+		accountBalance.Held += event.Amount
+	case *CheckingAccountService.HoldWasReleased:
+		accountBalance.Held -= event.Amount
+	case *CheckingAccountService.HoldWasCaptured:
+		accountBalance.Balance = event.Balance
+		accountBalance.Held -= event.HeldAmount
+	case *SavingsAccountService.SavingsAccountWasOpened:
+		accountBalance = AccountBalance{ID: event.ID, Name: event.Name}
+	case *SavingsAccountService.SavingsMoneyWasDeposited:
+		accountBalance.Balance = event.Balance
+	case *SavingsAccountService.SavingsMoneyWasWithdrawn:
+		accountBalance.Balance = event.Balance
+	default:
+		return nil
 	}
 
-	sort.Slice(topTen, func(i, j int) bool {
-		return topTen[i].Balance > topTen[j].Balance
-	})
-
-	l := len(topTen)
-	if l > 10 {
-		l = 10
+	abp.accountBalances[accountID] = accountBalance
+	if abp.OnChange != nil {
+		abp.OnChange(accountBalance)
 	}
-	return topTen[0:l]
+	return nil
 }
 
-// Bank total balance per month
-func TotalBalancePerMonth(eventStore *Seacrest.EventStore) error {
+// AccountBalances: every account's balance, keyed by account ID
+func (abp *AccountBalancesProjector) AccountBalances() map[string]AccountBalance {
+	return abp.accountBalances
+}
 
-	var totalBankFundsPerMonth = map[string]int{}
-	var yearMonths []string
-	var yearMonth string
+func (abp *AccountBalancesProjector) State() interface{} {
+	return abp.AccountBalances()
+}
 
-	for _, envelope := range eventStore.GetAllEvents() {
-		switch envelope.EventType {
-		case "MoneyWasDeposited":
-			moneyWasDeposited := CheckingAccountService.MoneyWasDeposited{}
-			err := json.Unmarshal(envelope.Payload, &moneyWasDeposited)
-			if err != nil {
-				return err
-			}
+type accountBalancesState struct {
+	AccountBalances map[string]AccountBalance
+	PrimaryHolders  map[string]string
+}
 
-			yearMonth = time.Unix(0, moneyWasDeposited.Timestamp).Format("2006-01")
+func (abp *AccountBalancesProjector) MarshalState() ([]byte, error) {
+	return json.Marshal(accountBalancesState{AccountBalances: abp.accountBalances, PrimaryHolders: abp.primaryHolders})
+}
 
-			if _, ok := totalBankFundsPerMonth[yearMonth]; ok {
-				totalBankFundsPerMonth[yearMonth] += moneyWasDeposited.Amount
-			} else {
-				totalBankFundsPerMonth[yearMonth] = moneyWasDeposited.Amount
-				yearMonths = append(yearMonths, yearMonth)
-			}
+func (abp *AccountBalancesProjector) RestoreState(state []byte) error {
+	restored := accountBalancesState{AccountBalances: map[string]AccountBalance{}, PrimaryHolders: map[string]string{}}
+	err := json.Unmarshal(state, &restored)
+	if err != nil {
+		return err
+	}
+	abp.accountBalances, abp.primaryHolders = restored.AccountBalances, restored.PrimaryHolders
+	return nil
+}
 
-		case "MoneyWasWithdrawn":
-			moneyWasWithdrawn := CheckingAccountService.MoneyWasWithdrawn{}
-			err := json.Unmarshal(envelope.Payload, &moneyWasWithdrawn)
-			if err != nil {
-				return err
-			}
+func (abp *AccountBalancesProjector) Version() uint {
+	return 1
+}
 
-			yearMonth = time.Unix(0, moneyWasWithdrawn.Timestamp).Format("2006-01")
+func (abp *AccountBalancesProjector) NewEmpty() Projector {
+	return NewAccountBalancesProjector()
+}
 
-			if _, ok := totalBankFundsPerMonth[yearMonth]; ok {
-				totalBankFundsPerMonth[yearMonth] -= moneyWasWithdrawn.Amount
-			} else {
-				totalBankFundsPerMonth[yearMonth] = -moneyWasWithdrawn.Amount
-				yearMonths = append(yearMonths, yearMonth)
-			}
-		}
+// BuildAccountBalances: every account's balance after every event in the store. onChange is called with an
+// account's new balance each time it changes.
+func BuildAccountBalances(eventStore *Seacrest.EventStore, onChange func(accountBalance AccountBalance)) (map[string]AccountBalance, error) {
+	projector := NewAccountBalancesProjector()
+	projector.OnChange = onChange
+	err := runProjector(eventStore, projector)
+	if err != nil {
+		return nil, err
 	}
+	return projector.AccountBalances(), nil
+}
 
-	// TODO totalBankFundsPerMonth map needs to be sorted by date key and missing year-month keys need filling in because
-	//  they will be missing if no events happened during that month.
-	//  Also, more importantly, the events are not in actual event timestamp order so this algorithm isn't going to wo
-
-	p := message.NewPrinter(language.English)
-	fmt.Println("Total Banks Funds Per Month:")
-	for date, balance := range totalBankFundsPerMonth {
-		fmt.Printf("%s: %s\n", date, p.Sprintf("%d", balance))
+// primaryHolderID: accounts opened before holders had their own IDs are held by a principal with the account's ID
+func primaryHolderID(accountWasOpened CheckingAccountService.AccountWasOpened) string {
+	if accountWasOpened.HolderID == "" {
+		return accountWasOpened.ID
 	}
-
-	return nil
+	return accountWasOpened.HolderID
 }
@@ -0,0 +1,167 @@
+package Projections
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// bankFundsAt: the bank's funds just after an event that changed them
+type bankFundsAt struct {
+	position  uint
+	timestamp int64
+	bankFunds BankFunds
+}
+
+// BankFundsHistoryProjector: an index of the bank's funds after every event that changed them, so the funds at any
+// point in history can be found without replaying events. The index is checkpointed, so it only ever has to be built
+// once.
+type BankFundsHistoryProjector struct {
+	current     *BankFundsProjector
+	history     []bankFundsAt // in position order
+	byTimestamp []bankFundsAt // in timestamp order, each with the funds after every event that happened by then
+}
+
+func NewBankFundsHistoryProjector() *BankFundsHistoryProjector {
+	return &BankFundsHistoryProjector{current: NewBankFundsProjector()}
+}
+
+func (bfhp *BankFundsHistoryProjector) Name() string {
+	return "BankFundsHistory"
+}
+
+func (bfhp *BankFundsHistoryProjector) EventTypes() []string {
+	return bfhp.current.EventTypes()
+}
+
+func (bfhp *BankFundsHistoryProjector) Handle(event Event) error {
+	return errors.New("the bank funds history needs to know where each event was persisted")
+}
+
+func (bfhp *BankFundsHistoryProjector) HandleAt(event Event, position uint) error {
+	before := bfhp.current.BankFunds()
+	err := bfhp.current.Handle(event)
+	if err != nil {
+		return err
+	}
+
+	entry := bankFundsAt{position: position, timestamp: event.EventTimestamp(), bankFunds: bfhp.current.BankFunds()}
+	bfhp.history = append(bfhp.history, entry)
+	bfhp.indexByTimestamp(entry, BankFunds{Total: entry.bankFunds.Total - before.Total, Held: entry.bankFunds.Held - before.Held})
+	return nil
+}
+
+// indexByTimestamp: insert an event's change to the funds after every event that happened no later than it, and add
+// the change to the funds of every event that happened after it. Events are normally persisted in the order they
+// happen, so the change is almost always appended.
+func (bfhp *BankFundsHistoryProjector) indexByTimestamp(entry bankFundsAt, change BankFunds) {
+	after := sort.Search(len(bfhp.byTimestamp), func(i int) bool {
+		return bfhp.byTimestamp[i].timestamp > entry.timestamp
+	})
+	entry.bankFunds = change
+	if after > 0 {
+		entry.bankFunds.Total += bfhp.byTimestamp[after-1].bankFunds.Total
+		entry.bankFunds.Held += bfhp.byTimestamp[after-1].bankFunds.Held
+	}
+
+	bfhp.byTimestamp = append(bfhp.byTimestamp, bankFundsAt{})
+	copy(bfhp.byTimestamp[after+1:], bfhp.byTimestamp[after:])
+	bfhp.byTimestamp[after] = entry
+	for i := after + 1; i < len(bfhp.byTimestamp); i++ {
+		bfhp.byTimestamp[i].bankFunds.Total += change.Total
+		bfhp.byTimestamp[i].bankFunds.Held += change.Held
+	}
+}
+
+// AsOfPosition: the bank's funds after every event persisted at or before the position
+func (bfhp *BankFundsHistoryProjector) AsOfPosition(position uint) BankFunds {
+	after := sort.Search(len(bfhp.history), func(i int) bool {
+		return bfhp.history[i].position > position
+	})
+	if after == 0 {
+		return BankFunds{}
+	}
+	return bfhp.history[after-1].bankFunds
+}
+
+// AsOfTimestamp: the bank's funds after every event that happened at or before the timestamp, whatever order the
+// events were persisted in
+func (bfhp *BankFundsHistoryProjector) AsOfTimestamp(timestamp int64) BankFunds {
+	after := sort.Search(len(bfhp.byTimestamp), func(i int) bool {
+		return bfhp.byTimestamp[i].timestamp > timestamp
+	})
+	if after == 0 {
+		return BankFunds{}
+	}
+	return bfhp.byTimestamp[after-1].bankFunds
+}
+
+func (bfhp *BankFundsHistoryProjector) State() interface{} {
+	return bfhp.current.BankFunds()
+}
+
+// bankFundsHistoryState: the history in columns, each entry stored as its change from the entry before, which keeps
+// the checkpoint of a long history small
+type bankFundsHistoryState struct {
+	Positions  []uint
+	Timestamps []int64
+	Totals     []int
+	Held       []int
+}
+
+func (bfhp *BankFundsHistoryProjector) MarshalState() ([]byte, error) {
+	state := bankFundsHistoryState{
+		Positions:  make([]uint, len(bfhp.history)),
+		Timestamps: make([]int64, len(bfhp.history)),
+		Totals:     make([]int, len(bfhp.history)),
+		Held:       make([]int, len(bfhp.history)),
+	}
+	previous := bankFundsAt{}
+	for i, entry := range bfhp.history {
+		state.Positions[i] = entry.position - previous.position
+		state.Timestamps[i] = entry.timestamp - previous.timestamp
+		state.Totals[i] = entry.bankFunds.Total - previous.bankFunds.Total
+		state.Held[i] = entry.bankFunds.Held - previous.bankFunds.Held
+		previous = entry
+	}
+	return json.Marshal(state)
+}
+
+func (bfhp *BankFundsHistoryProjector) RestoreState(state []byte) error {
+	restored := bankFundsHistoryState{}
+	err := json.Unmarshal(state, &restored)
+	if err != nil {
+		return err
+	}
+	entries := len(restored.Positions)
+	if len(restored.Timestamps) != entries || len(restored.Totals) != entries || len(restored.Held) != entries {
+		return errors.New(fmt.Sprintf("the checkpoint's columns have different lengths [Positions: %d, Timestamps: %d, Totals: %d, Held: %d]", entries, len(restored.Timestamps), len(restored.Totals), len(restored.Held)))
+	}
+
+	bfhp.history = make([]bankFundsAt, 0, entries)
+	bfhp.byTimestamp = make([]bankFundsAt, 0, entries)
+	previous := bankFundsAt{}
+	for i := 0; i < entries; i++ {
+		entry := bankFundsAt{
+			position:  previous.position + restored.Positions[i],
+			timestamp: previous.timestamp + restored.Timestamps[i],
+			bankFunds: BankFunds{Total: previous.bankFunds.Total + restored.Totals[i], Held: previous.bankFunds.Held + restored.Held[i]},
+		}
+		bfhp.history = append(bfhp.history, entry)
+		bfhp.indexByTimestamp(entry, BankFunds{Total: restored.Totals[i], Held: restored.Held[i]})
+		previous = entry
+	}
+
+	bfhp.current = NewBankFundsProjector()
+	bfhp.current.bankFunds = previous.bankFunds
+	return nil
+}
+
+func (bfhp *BankFundsHistoryProjector) Version() uint {
+	return 1
+}
+
+func (bfhp *BankFundsHistoryProjector) NewEmpty() Projector {
+	return NewBankFundsHistoryProjector()
+}
@@ -0,0 +1,96 @@
+package Projections
+
+import (
+	"github.com/agemmell/banking-cqrs-es-go/CheckingAccountService"
+	"github.com/agemmell/banking-cqrs-es-go/EventRegistry"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_BankFundsHistoryAnswersAsOfAnyPoint(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	eventStore := newEventStore(t,
+		CheckingAccountService.OpenAccount{ID: "ACC1", CustomerID: "CUST1"},
+		CheckingAccountService.DepositMoney{ID: "ACC1", Amount: 100, ActingAs: CheckingAccountService.SystemPrincipal},
+		CheckingAccountService.PlaceHold{ID: "ACC1", HoldID: "H1", Amount: 40, ExpiresAt: time.Date(2021, time.February, 1, 0, 0, 0, 0, time.UTC).UnixNano(), ActingAs: CheckingAccountService.SystemPrincipal},
+		CheckingAccountService.WithdrawMoney{ID: "ACC1", Amount: 25, ActingAs: CheckingAccountService.SystemPrincipal},
+	)
+	history := NewBankFundsHistoryProjector()
+
+	// When
+	err := NewRunner(history).Run(eventStore)
+
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, BankFunds{}, history.AsOfPosition(1))
+	assert.Equal(t, BankFunds{Total: 100}, history.AsOfPosition(2))
+	assert.Equal(t, BankFunds{Total: 100, Held: 40}, history.AsOfPosition(3))
+	assert.Equal(t, BankFunds{Total: 75, Held: 40}, history.AsOfPosition(99))
+	assert.Equal(t, BankFunds{Total: 75, Held: 40}, history.State())
+
+	envelopes := eventStore.GetAllEvents()
+	deposit, err := EventRegistry.Decode(envelopes[1])
+	assert.Nil(t, err)
+	withdrawal, err := EventRegistry.Decode(envelopes[3])
+	assert.Nil(t, err)
+	assert.Equal(t, BankFunds{}, history.AsOfTimestamp(deposit.EventTimestamp()-1))
+	assert.Equal(t, BankFunds{Total: 100}, history.AsOfTimestamp(deposit.EventTimestamp()))
+	assert.Equal(t, BankFunds{Total: 100, Held: 40}, history.AsOfTimestamp(withdrawal.EventTimestamp()-1))
+	assert.Equal(t, BankFunds{Total: 75, Held: 40}, history.AsOfTimestamp(withdrawal.EventTimestamp()))
+}
+
+func Test_BankFundsHistoryHandlesEventsOutOfTimestampOrder(t *testing.T) {
+	t.Parallel()
+
+	// Given a deposit persisted after a later withdrawal, then one that happened before everything else
+	history := NewBankFundsHistoryProjector()
+	assert.Nil(t, history.HandleAt(&CheckingAccountService.MoneyWasDeposited{ID: "ACC1", Amount: 500, Timestamp: at(2021, time.January, 1)}, 1))
+	assert.Nil(t, history.HandleAt(&CheckingAccountService.MoneyWasWithdrawn{ID: "ACC1", Amount: 200, Timestamp: at(2021, time.March, 1)}, 2))
+	assert.Nil(t, history.HandleAt(&CheckingAccountService.MoneyWasDeposited{ID: "ACC2", Amount: 50, Timestamp: at(2021, time.February, 1)}, 3))
+	assert.Nil(t, history.HandleAt(&CheckingAccountService.MoneyWasDeposited{ID: "ACC3", Amount: 10, Timestamp: at(2020, time.December, 31)}, 4))
+
+	// When
+	endOfDecember := history.AsOfTimestamp(at(2020, time.December, 31))
+	endOfJanuary := history.AsOfTimestamp(at(2021, time.January, 31))
+	endOfFebruary := history.AsOfTimestamp(at(2021, time.February, 28))
+	endOfMarch := history.AsOfTimestamp(at(2021, time.March, 31))
+
+	// Then
+	assert.Equal(t, BankFunds{}, history.AsOfTimestamp(at(2020, time.December, 30)))
+	assert.Equal(t, BankFunds{Total: 10}, endOfDecember)
+	assert.Equal(t, BankFunds{Total: 510}, endOfJanuary)
+	assert.Equal(t, BankFunds{Total: 560}, endOfFebruary)
+	assert.Equal(t, BankFunds{Total: 360}, endOfMarch)
+	assert.Equal(t, BankFunds{Total: 300}, history.AsOfPosition(2))
+}
+
+func Test_BankFundsHistoryResumesFromItsCheckpoint(t *testing.T) {
+	t.Parallel()
+
+	// Given a history with a deposit persisted after a later withdrawal, checkpointed
+	original := NewBankFundsHistoryProjector()
+	assert.Nil(t, original.HandleAt(&CheckingAccountService.MoneyWasDeposited{ID: "ACC1", Amount: 500, Timestamp: at(2021, time.January, 1)}, 1))
+	assert.Nil(t, original.HandleAt(&CheckingAccountService.MoneyWasWithdrawn{ID: "ACC1", Amount: 200, Timestamp: at(2021, time.March, 1)}, 3))
+	assert.Nil(t, original.HandleAt(&CheckingAccountService.MoneyWasDeposited{ID: "ACC2", Amount: 50, Timestamp: at(2021, time.February, 1)}, 4))
+	state, err := original.MarshalState()
+	assert.Nil(t, err)
+
+	// When
+	restored := NewBankFundsHistoryProjector()
+	err = restored.RestoreState(state)
+	assert.Nil(t, err)
+	assert.Nil(t, restored.HandleAt(&CheckingAccountService.MoneyWasDeposited{ID: "ACC2", Amount: 25, Timestamp: at(2021, time.April, 1)}, 6))
+	mismatchedErr := NewBankFundsHistoryProjector().RestoreState([]byte(`{"Positions": [1], "Timestamps": [], "Totals": [1], "Held": [0]}`))
+
+	// Then
+	assert.Equal(t, BankFunds{}, restored.AsOfPosition(0))
+	assert.Equal(t, BankFunds{Total: 500}, restored.AsOfPosition(2))
+	assert.Equal(t, BankFunds{Total: 300}, restored.AsOfPosition(3))
+	assert.Equal(t, BankFunds{Total: 375}, restored.AsOfPosition(6))
+	assert.Equal(t, BankFunds{Total: 550}, restored.AsOfTimestamp(at(2021, time.February, 28)))
+	assert.Equal(t, BankFunds{Total: 375}, restored.State())
+	assert.Equal(t, "the checkpoint's columns have different lengths [Positions: 1, Timestamps: 0, Totals: 1, Held: 1]", mismatchedErr.Error())
+}
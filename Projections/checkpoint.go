@@ -0,0 +1,150 @@
+package Projections
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Checkpoint: a projector's state after it had seen every event up to and including Position. The state and position
+// are always saved together, so a restored projector never applies an event twice or misses one.
+type Checkpoint struct {
+	Projector string
+	Version   uint // the version of the projector that saved the state
+	Position  uint // the global Order of the last event the state includes
+	State     json.RawMessage
+}
+
+// version: the version of the projector that saved the checkpoint. Checkpoints saved before projectors had versions
+// were all saved by version 1.
+func (c Checkpoint) version() uint {
+	if c.Version == 0 {
+		return 1
+	}
+	return c.Version
+}
+
+// CheckpointStore: where projectors' checkpoints are kept between runs
+type CheckpointStore interface {
+	// Load: the projector's latest checkpoint, or false if it has never been checkpointed
+	Load(projector string) (Checkpoint, bool, error)
+	// Save: replace the projector's checkpoint; a failed save leaves the previous checkpoint in place
+	Save(checkpoint Checkpoint) error
+}
+
+// FileCheckpointStore: one JSON file per projector in a directory
+type FileCheckpointStore struct {
+	dir string
+}
+
+func NewFileCheckpointStore(dir string) (*FileCheckpointStore, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	return &FileCheckpointStore{dir}, nil
+}
+
+func (fcs *FileCheckpointStore) filename(projector string) (string, error) {
+	if projector == "" || filepath.Base(projector) != projector {
+		return "", errors.New(fmt.Sprintf("projector name %q cannot be used as a checkpoint file name", projector))
+	}
+	return filepath.Join(fcs.dir, projector+".json"), nil
+}
+
+func (fcs *FileCheckpointStore) Load(projector string) (Checkpoint, bool, error) {
+	filename, err := fcs.filename(projector)
+	if err != nil {
+		return Checkpoint{}, false, err
+	}
+
+	contents, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return Checkpoint{}, false, nil
+	}
+	if err != nil {
+		return Checkpoint{}, false, err
+	}
+
+	checkpoint := Checkpoint{}
+	err = json.Unmarshal(contents, &checkpoint)
+	if err != nil {
+		return Checkpoint{}, false, errors.New(fmt.Sprintf("checkpoint %s is corrupt: %s", filename, err))
+	}
+	return checkpoint, true, nil
+}
+
+// Save: write the checkpoint to a temporary file and rename it over the old one. Renames within a directory are
+// atomic, so a crash leaves either the old checkpoint or the new one, never a partial file.
+func (fcs *FileCheckpointStore) Save(checkpoint Checkpoint) error {
+	filename, err := fcs.filename(checkpoint.Projector)
+	if err != nil {
+		return err
+	}
+	contents, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	temp, err := ioutil.TempFile(fcs.dir, checkpoint.Projector+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name()) // fails harmlessly once the file has been renamed
+
+	_, err = temp.Write(contents)
+	if err == nil {
+		err = temp.Sync()
+	}
+	closeErr := temp.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(temp.Name(), filename)
+	if err != nil {
+		return err
+	}
+	return syncDir(fcs.dir)
+}
+
+// syncDir: make a rename in the directory durable
+func syncDir(dir string) error {
+	directory, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer directory.Close()
+	return directory.Sync()
+}
+
+// MemoryCheckpointStore: checkpoints kept in memory, for tests and for runs that don't need to survive a restart
+type MemoryCheckpointStore struct {
+	mutex       sync.Mutex
+	checkpoints map[string]Checkpoint
+}
+
+func NewMemoryCheckpointStore() *MemoryCheckpointStore {
+	return &MemoryCheckpointStore{checkpoints: map[string]Checkpoint{}}
+}
+
+func (mcs *MemoryCheckpointStore) Load(projector string) (Checkpoint, bool, error) {
+	mcs.mutex.Lock()
+	defer mcs.mutex.Unlock()
+	checkpoint, ok := mcs.checkpoints[projector]
+	return checkpoint, ok, nil
+}
+
+func (mcs *MemoryCheckpointStore) Save(checkpoint Checkpoint) error {
+	mcs.mutex.Lock()
+	defer mcs.mutex.Unlock()
+	mcs.checkpoints[checkpoint.Projector] = checkpoint
+	return nil
+}
@@ -0,0 +1,275 @@
+package Projections
+
+import (
+	"encoding/json"
+	"github.com/agemmell/banking-cqrs-es-go/CheckingAccountService"
+	"github.com/agemmell/banking-cqrs-es-go/Seacrest"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// countingProjector: counts the deposits it is given, and checkpoints the count
+type countingProjector struct {
+	deposits int
+}
+
+func (cp *countingProjector) Name() string {
+	return "counting"
+}
+
+func (cp *countingProjector) EventTypes() []string {
+	return []string{CheckingAccountService.TypeMoneyWasDeposited}
+}
+
+func (cp *countingProjector) State() interface{} {
+	return cp.deposits
+}
+
+func (cp *countingProjector) Handle(event Event) error {
+	cp.deposits++
+	return nil
+}
+
+func (cp *countingProjector) MarshalState() ([]byte, error) {
+	return json.Marshal(cp.deposits)
+}
+
+func (cp *countingProjector) RestoreState(state []byte) error {
+	return json.Unmarshal(state, &cp.deposits)
+}
+
+// handleCommands: handle more commands against an event store, two weeks after newEventStore's commands
+func handleCommands(t *testing.T, eventStore *Seacrest.EventStore, commands ...CheckingAccountService.Command) {
+	clock := Seacrest.NewFakeClock(time.Date(2021, time.January, 18, 9, 0, 0, 0, time.UTC))
+	clock.AutoAdvance(time.Minute)
+	cas := CheckingAccountService.New(eventStore, CheckingAccountService.WithClock(clock), CheckingAccountService.WithCustomerVerifier(anyCustomer{}))
+	for _, command := range commands {
+		assert.Nil(t, cas.HandleCommand(command))
+	}
+}
+
+func allProjectors() []Projector {
+	return []Projector{
+		NewBankFundsProjector(),
+		NewAccountCountsProjector(),
+		NewHighestBalancesProjector(),
+		NewBalancePerMonthProjector(),
+		NewFeeIncomeProjector(),
+		NewFrozenAccountsProjector(),
+		NewCustomerAccountsProjector(),
+	}
+}
+
+func Test_RunnerResumesFromItsCheckpoint(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	eventStore := newEventStore(t,
+		CheckingAccountService.OpenAccount{ID: "ACC1", CustomerID: "CUST1"},
+		CheckingAccountService.DepositMoney{ID: "ACC1", Amount: 100, ActingAs: CheckingAccountService.SystemPrincipal},
+		CheckingAccountService.DepositMoney{ID: "ACC1", Amount: 50, ActingAs: CheckingAccountService.SystemPrincipal},
+	)
+	checkpoints := NewMemoryCheckpointStore()
+	first := NewRunner(&countingProjector{})
+	assert.Nil(t, first.ResumeFrom(checkpoints, 0))
+	assert.Nil(t, first.Run(eventStore))
+	handleCommands(t, eventStore, CheckingAccountService.DepositMoney{ID: "ACC1", Amount: 25, ActingAs: CheckingAccountService.SystemPrincipal})
+
+	// When
+	counting := &countingProjector{}
+	second := NewRunner(counting)
+	assert.Nil(t, second.ResumeFrom(checkpoints, 0))
+	resumedFrom := second.Position()
+	err := second.Run(eventStore)
+
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, uint(3), resumedFrom)
+	assert.Equal(t, uint(4), second.Position())
+	assert.Equal(t, 3, counting.deposits)
+	checkpoint, found, err := checkpoints.Load("counting")
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, uint(4), checkpoint.Position)
+	assert.Equal(t, "3", string(checkpoint.State))
+}
+
+func Test_RestoredProjectorsMatchAFullRebuild(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	eventStore := newEventStore(t,
+		CheckingAccountService.OpenAccount{ID: "ACC1", CustomerID: "CUST1"},
+		CheckingAccountService.OpenAccount{ID: "ACC2", CustomerID: "CUST2"},
+		CheckingAccountService.DepositMoney{ID: "ACC1", Amount: 100, ActingAs: CheckingAccountService.SystemPrincipal},
+		CheckingAccountService.DepositMoney{ID: "ACC2", Amount: 250, ActingAs: CheckingAccountService.SystemPrincipal},
+		CheckingAccountService.PlaceHold{ID: "ACC2", HoldID: "H1", Amount: 50, ExpiresAt: time.Date(2021, time.February, 1, 0, 0, 0, 0, time.UTC).UnixNano(), ActingAs: CheckingAccountService.SystemPrincipal},
+		CheckingAccountService.FreezeAccount{ID: "ACC1", Scope: CheckingAccountService.FreezeDebits, ReasonCode: CheckingAccountService.ReasonSuspectedFraud, ActingAs: CheckingAccountService.SystemPrincipal},
+		CheckingAccountService.AddJointHolder{ID: "ACC2", HolderID: "CUST3", Name: "Jo Bloggs", ActingAs: CheckingAccountService.SystemPrincipal},
+		CheckingAccountService.WithdrawMoney{ID: "ACC2", Amount: 75, ActingAs: CheckingAccountService.SystemPrincipal},
+		CheckingAccountService.CaptureHold{ID: "ACC2", HoldID: "H1", Amount: 40, ActingAs: CheckingAccountService.SystemPrincipal},
+		CheckingAccountService.OpenAccount{ID: "ACC3", CustomerID: "CUST3"},
+		CheckingAccountService.CloseAccount{ID: "ACC3", ActingAs: CheckingAccountService.SystemPrincipal},
+	)
+	rebuilt := allProjectors()
+	assert.Nil(t, NewRunner(rebuilt...).Run(eventStore))
+
+	checkpoints := NewMemoryCheckpointStore()
+	halfway := NewRunner(allProjectors()...)
+	assert.Nil(t, halfway.ResumeFrom(checkpoints, 0))
+	for _, envelope := range eventStore.GetAllEvents()[:5] {
+		assert.Nil(t, halfway.Apply(envelope))
+	}
+	assert.Nil(t, halfway.Checkpoint())
+
+	// When
+	resumed := allProjectors()
+	runner := NewRunner(resumed...)
+	assert.Nil(t, runner.ResumeFrom(checkpoints, 0))
+	err := runner.Run(eventStore)
+
+	// Then
+	assert.Nil(t, err)
+	for i := range rebuilt {
+		assert.Equal(t, rebuilt[i].State(), resumed[i].State(), rebuilt[i].Name())
+	}
+}
+
+func Test_CrashBetweenCheckpointsDoesNotDoubleCount(t *testing.T) {
+	t.Parallel()
+
+	// Given a runner that checkpoints every 3 events and crashes after applying the 5th
+	dir, err := ioutil.TempDir("", "checkpoints")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	checkpoints, err := NewFileCheckpointStore(dir)
+	assert.Nil(t, err)
+
+	eventStore := newEventStore(t,
+		CheckingAccountService.OpenAccount{ID: "ACC1", CustomerID: "CUST1"},
+		CheckingAccountService.DepositMoney{ID: "ACC1", Amount: 100, ActingAs: CheckingAccountService.SystemPrincipal},
+		CheckingAccountService.DepositMoney{ID: "ACC1", Amount: 200, ActingAs: CheckingAccountService.SystemPrincipal},
+		CheckingAccountService.DepositMoney{ID: "ACC1", Amount: 300, ActingAs: CheckingAccountService.SystemPrincipal},
+		CheckingAccountService.DepositMoney{ID: "ACC1", Amount: 400, ActingAs: CheckingAccountService.SystemPrincipal},
+		CheckingAccountService.WithdrawMoney{ID: "ACC1", Amount: 500, ActingAs: CheckingAccountService.SystemPrincipal},
+	)
+	crashing := NewRunner(NewBankFundsProjector())
+	assert.Nil(t, crashing.ResumeFrom(checkpoints, 3))
+	for _, envelope := range eventStore.GetAllEvents()[:5] {
+		assert.Nil(t, crashing.Apply(envelope))
+	}
+
+	// When
+	bankFunds := NewBankFundsProjector()
+	restarted := NewRunner(bankFunds)
+	assert.Nil(t, restarted.ResumeFrom(checkpoints, 3))
+	resumedFrom := restarted.Position()
+	err = restarted.Run(eventStore)
+
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, uint(3), resumedFrom)
+	assert.Equal(t, BankFunds{Total: 500}, bankFunds.BankFunds())
+}
+
+func Test_ResumeRejectsACheckpointAheadOfTheEventStore(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	checkpoints := NewMemoryCheckpointStore()
+	assert.Nil(t, checkpoints.Save(Checkpoint{Projector: "counting", Position: 10, State: []byte("7")}))
+	eventStore := newEventStore(t,
+		CheckingAccountService.OpenAccount{ID: "ACC1", CustomerID: "CUST1"},
+	)
+	runner := NewRunner(&countingProjector{})
+	assert.Nil(t, runner.ResumeFrom(checkpoints, 0))
+
+	// When
+	err := runner.Run(eventStore)
+
+	// Then
+	assert.Equal(t, "the checkpoint for projection counting is at event 10 but the event store ends at event 1", err.Error())
+}
+
+func Test_ResumeRequiresSnapshotters(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	runner := NewRunner(&recordingProjector{name: "recording"})
+
+	// When
+	err := runner.ResumeFrom(NewMemoryCheckpointStore(), 0)
+
+	// Then
+	assert.Equal(t, "projection recording cannot be checkpointed", err.Error())
+}
+
+func Test_FileCheckpointStoreReplacesCheckpointsWhole(t *testing.T) {
+	t.Parallel()
+
+	// Given a saved checkpoint, and a temporary file left behind by a save that crashed part way through
+	dir, err := ioutil.TempDir("", "checkpoints")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	checkpoints, err := NewFileCheckpointStore(dir)
+	assert.Nil(t, err)
+	assert.Nil(t, checkpoints.Save(Checkpoint{Projector: "counting", Position: 1, State: []byte("1")}))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "counting.123.tmp"), []byte(`{"Projector":"counting","Posi`), 0644))
+
+	// When
+	assert.Nil(t, checkpoints.Save(Checkpoint{Projector: "counting", Position: 2, State: []byte("2")}))
+	checkpoint, found, err := checkpoints.Load("counting")
+	_, missingFound, missingErr := checkpoints.Load("missing")
+	badNameErr := checkpoints.Save(Checkpoint{Projector: "../counting"})
+
+	// Then
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, Checkpoint{Projector: "counting", Position: 2, State: []byte("2")}, checkpoint)
+	assert.Nil(t, missingErr)
+	assert.False(t, missingFound)
+	assert.Equal(t, `projector name "../counting" cannot be used as a checkpoint file name`, badNameErr.Error())
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "counting.123.tmp"), filepath.Join(dir, "counting.json")}, files)
+}
+
+func Test_RunnerRebuildsProjectionsCheckpointedByAnotherVersion(t *testing.T) {
+	t.Parallel()
+
+	// Given a monthly balance checkpoint saved before versions were recorded, in the format it had then
+	eventStore := newEventStore(t,
+		CheckingAccountService.OpenAccount{ID: "ACC1", CustomerID: "CUST1"},
+		CheckingAccountService.DepositMoney{ID: "ACC1", Amount: 100, ActingAs: CheckingAccountService.SystemPrincipal},
+		CheckingAccountService.WithdrawMoney{ID: "ACC1", Amount: 30, ActingAs: CheckingAccountService.SystemPrincipal},
+	)
+	checkpoints := NewMemoryCheckpointStore()
+	first := NewRunner(NewBankFundsProjector())
+	assert.Nil(t, first.ResumeFrom(checkpoints, 0))
+	assert.Nil(t, first.Run(eventStore))
+	balancePerMonth := NewBalancePerMonthProjector()
+	assert.Nil(t, checkpoints.Save(Checkpoint{Projector: balancePerMonth.Name(), Position: 3, State: json.RawMessage(`{"2021-01": 70}`)}))
+	rebuilt := NewBalancePerMonthProjector()
+	assert.Nil(t, runProjector(eventStore, rebuilt))
+
+	// When
+	runner := NewRunner(NewBankFundsProjector(), balancePerMonth)
+	resumeErr := runner.ResumeFrom(checkpoints, 0)
+	resumedFrom := runner.Position()
+	runErr := runner.Run(eventStore)
+
+	// Then
+	assert.Nil(t, resumeErr)
+	assert.Nil(t, runErr)
+	assert.Equal(t, []string{balancePerMonth.Name()}, runner.Stale())
+	assert.Equal(t, uint(0), resumedFrom)
+	assert.Equal(t, rebuilt.BalancePerMonth(), balancePerMonth.BalancePerMonth())
+	checkpoint, _, err := checkpoints.Load(balancePerMonth.Name())
+	assert.Nil(t, err)
+	assert.Equal(t, uint(2), checkpoint.Version)
+	assert.Equal(t, uint(3), checkpoint.Position)
+}
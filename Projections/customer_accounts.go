@@ -0,0 +1,209 @@
+package Projections
+
+import (
+	"encoding/json"
+	"github.com/agemmell/banking-cqrs-es-go/CheckingAccountService"
+	"github.com/agemmell/banking-cqrs-es-go/CustomerService"
+	"github.com/agemmell/banking-cqrs-es-go/ProductCatalog"
+	"github.com/agemmell/banking-cqrs-es-go/SavingsAccountService"
+	"github.com/agemmell/banking-cqrs-es-go/Seacrest"
+	"sort"
+)
+
+// Roles a customer can have on an account
+const RolePrimaryHolder = "Primary"
+const RoleJointHolder = "Joint"
+
+type CustomerAccount struct {
+	ID     string
+	Kind   string // ProductCatalog.KindChecking or ProductCatalog.KindSavings
+	Role   string
+	Closed bool
+}
+
+type CustomerAccounts struct {
+	CustomerID string
+	Name       string
+	Accounts   []CustomerAccount // in the order the customer became a holder
+}
+
+// OpenAccounts: how many of the customer's accounts are open
+func (ca *CustomerAccounts) OpenAccounts() int {
+	openAccounts := 0
+	for _, account := range ca.Accounts {
+		if !account.Closed {
+			openAccounts++
+		}
+	}
+	return openAccounts
+}
+
+// CustomerAccountsProjector: every account each customer holds
+type CustomerAccountsProjector struct {
+	customers      map[string]*CustomerAccounts
+	accountHolders map[string][]string // <account ID> -> <customer IDs holding the account>
+}
+
+func NewCustomerAccountsProjector() *CustomerAccountsProjector {
+	return &CustomerAccountsProjector{
+		customers:      map[string]*CustomerAccounts{},
+		accountHolders: map[string][]string{},
+	}
+}
+
+func (cp *CustomerAccountsProjector) Name() string {
+	return "AccountsPerCustomer"
+}
+
+func (cp *CustomerAccountsProjector) EventTypes() []string {
+	return []string{
+		CustomerService.TypeCustomerWasRegistered,
+		CheckingAccountService.TypeAccountWasOpened,
+		CheckingAccountService.TypeJointHolderWasAdded,
+		CheckingAccountService.TypeJointHolderWasRemoved,
+		CheckingAccountService.TypeAccountWasClosed,
+		CheckingAccountService.TypeAccountWasReopened,
+		SavingsAccountService.TypeSavingsAccountWasOpened,
+		SavingsAccountService.TypeSavingsAccountWasClosed,
+	}
+}
+
+func (cp *CustomerAccountsProjector) customer(customerID string) *CustomerAccounts {
+	if _, ok := cp.customers[customerID]; !ok {
+		cp.customers[customerID] = &CustomerAccounts{CustomerID: customerID}
+	}
+	return cp.customers[customerID]
+}
+
+func (cp *CustomerAccountsProjector) addAccount(customerID string, account CustomerAccount) {
+	customer := cp.customer(customerID)
+	customer.Accounts = append(customer.Accounts, account)
+	cp.accountHolders[account.ID] = append(cp.accountHolders[account.ID], customerID)
+}
+
+func (cp *CustomerAccountsProjector) setClosed(accountID string, closed bool) {
+	for _, customerID := range cp.accountHolders[accountID] {
+		for i, account := range cp.customers[customerID].Accounts {
+			if account.ID == accountID {
+				cp.customers[customerID].Accounts[i].Closed = closed
+			}
+		}
+	}
+}
+
+func (cp *CustomerAccountsProjector) Handle(event Event) error {
+	switch event := event.(type) {
+	case *CustomerService.CustomerWasRegistered:
+		cp.customer(event.ID).Name = event.Name
+
+	case *CheckingAccountService.AccountWasOpened:
+		cp.addAccount(primaryHolderID(*event), CustomerAccount{ID: event.ID, Kind: ProductCatalog.KindChecking, Role: RolePrimaryHolder})
+
+	case *CheckingAccountService.JointHolderWasAdded:
+		cp.addAccount(event.HolderID, CustomerAccount{ID: event.ID, Kind: ProductCatalog.KindChecking, Role: RoleJointHolder})
+
+	case *CheckingAccountService.JointHolderWasRemoved:
+		holder := cp.customer(event.HolderID)
+		for i, account := range holder.Accounts {
+			if account.ID == event.ID {
+				holder.Accounts = append(holder.Accounts[:i], holder.Accounts[i+1:]...)
+				break
+			}
+		}
+		holders := cp.accountHolders[event.ID]
+		for i, customerID := range holders {
+			if customerID == event.HolderID {
+				cp.accountHolders[event.ID] = append(holders[:i], holders[i+1:]...)
+				break
+			}
+		}
+
+	case *CheckingAccountService.AccountWasClosed:
+		cp.setClosed(event.ID, true)
+
+	case *CheckingAccountService.AccountWasReopened:
+		cp.setClosed(event.ID, false)
+
+	case *SavingsAccountService.SavingsAccountWasOpened:
+		cp.addAccount(event.CustomerID, CustomerAccount{ID: event.ID, Kind: ProductCatalog.KindSavings, Role: RolePrimaryHolder})
+
+	case *SavingsAccountService.SavingsAccountWasClosed:
+		cp.setClosed(event.ID, true)
+	}
+	return nil
+}
+
+// CustomerAccounts: every account each customer holds, keyed by customer ID
+func (cp *CustomerAccountsProjector) CustomerAccounts() map[string]*CustomerAccounts {
+	return cp.customers
+}
+
+func (cp *CustomerAccountsProjector) State() interface{} {
+	return cp.CustomerAccounts()
+}
+
+type customerAccountsState struct {
+	Customers      map[string]*CustomerAccounts
+	AccountHolders map[string][]string
+}
+
+func (cp *CustomerAccountsProjector) MarshalState() ([]byte, error) {
+	return json.Marshal(customerAccountsState{Customers: cp.customers, AccountHolders: cp.accountHolders})
+}
+
+func (cp *CustomerAccountsProjector) RestoreState(state []byte) error {
+	restored := customerAccountsState{Customers: map[string]*CustomerAccounts{}, AccountHolders: map[string][]string{}}
+	err := json.Unmarshal(state, &restored)
+	if err != nil {
+		return err
+	}
+	cp.customers, cp.accountHolders = restored.Customers, restored.AccountHolders
+	return nil
+}
+
+func (cp *CustomerAccountsProjector) Version() uint {
+	return 1
+}
+
+func (cp *CustomerAccountsProjector) NewEmpty() Projector {
+	return NewCustomerAccountsProjector()
+}
+
+// BuildCustomerAccounts: every account each customer holds, keyed by customer ID
+func BuildCustomerAccounts(eventStore *Seacrest.EventStore) (map[string]*CustomerAccounts, error) {
+	projector := NewCustomerAccountsProjector()
+	err := runProjector(eventStore, projector)
+	if err != nil {
+		return nil, err
+	}
+	return projector.CustomerAccounts(), nil
+}
+
+// CustomerAccountsSummary: how many accounts customers hold
+type CustomerAccountsSummary struct {
+	Customers         int
+	OpenAccountCounts map[int]int         // <open accounts held> -> customers
+	MostAccounts      []*CustomerAccounts // the ten customers who have held the most accounts, most first
+}
+
+// SummariseCustomerAccounts: accounts held by each customer
+func SummariseCustomerAccounts(customers map[string]*CustomerAccounts) CustomerAccountsSummary {
+	summary := CustomerAccountsSummary{Customers: len(customers), OpenAccountCounts: map[int]int{}}
+
+	for _, customer := range customers {
+		summary.OpenAccountCounts[customer.OpenAccounts()]++
+		summary.MostAccounts = append(summary.MostAccounts, customer)
+	}
+
+	sort.Slice(summary.MostAccounts, func(i, j int) bool {
+		if len(summary.MostAccounts[i].Accounts) != len(summary.MostAccounts[j].Accounts) {
+			return len(summary.MostAccounts[i].Accounts) > len(summary.MostAccounts[j].Accounts)
+		}
+		return summary.MostAccounts[i].CustomerID < summary.MostAccounts[j].CustomerID
+	})
+	if len(summary.MostAccounts) > 10 {
+		summary.MostAccounts = summary.MostAccounts[:10]
+	}
+
+	return summary
+}
@@ -0,0 +1,80 @@
+package Projections
+
+import (
+	"github.com/agemmell/banking-cqrs-es-go/CheckingAccountService"
+	"github.com/agemmell/banking-cqrs-es-go/CustomerService"
+	"github.com/agemmell/banking-cqrs-es-go/ProductCatalog"
+	"github.com/agemmell/banking-cqrs-es-go/SavingsAccountService"
+	"github.com/agemmell/banking-cqrs-es-go/Seacrest"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_CustomerAccountsFollowsEveryAccountACustomerHolds(t *testing.T) {
+	t.Parallel()
+
+	// Given two verified customers who hold checking and savings accounts, alone and jointly
+	eventStore := Seacrest.NewEventStore()
+	customerService := CustomerService.New(eventStore)
+	address := CustomerService.Address{Line1: "1 High Street", City: "London", Postcode: "E1 6AN", Country: "GB"}
+	for customerID, name := range map[string]string{"CUST1": "Alex Gemmell", "CUST2": "Sam Smith"} {
+		assert.Nil(t, customerService.HandleCommand(CustomerService.RegisterCustomer{ID: customerID, Name: name, DateOfBirth: "1980-05-17", Address: address}))
+		assert.Nil(t, customerService.HandleCommand(CustomerService.VerifyKYC{ID: customerID, VerifiedBy: "compliance"}))
+	}
+	checkingAccountService := CheckingAccountService.New(eventStore)
+	savingsAccountService := SavingsAccountService.New(eventStore)
+	for _, command := range []CheckingAccountService.Command{
+		CheckingAccountService.OpenAccount{ID: "ACC1", CustomerID: "CUST1"},
+		CheckingAccountService.OpenAccount{ID: "ACC2", CustomerID: "CUST2"},
+		CheckingAccountService.AddJointHolder{ID: "ACC1", HolderID: "CUST2", Name: "Sam Smith", ActingAs: "CUST1"},
+		CheckingAccountService.AddJointHolder{ID: "ACC2", HolderID: "CUST1", Name: "Alex Gemmell", ActingAs: "CUST2"},
+		CheckingAccountService.RemoveJointHolder{ID: "ACC2", HolderID: "CUST1", ActingAs: "CUST2"},
+		CheckingAccountService.CloseAccount{ID: "ACC1", ActingAs: "CUST1"},
+		CheckingAccountService.CloseAccount{ID: "ACC2", ActingAs: "CUST2"},
+		CheckingAccountService.ReopenAccount{ID: "ACC2", ActingAs: "CUST2"},
+	} {
+		assert.Nil(t, checkingAccountService.HandleCommand(command))
+	}
+	assert.Nil(t, savingsAccountService.HandleCommand(SavingsAccountService.OpenAccount{ID: "SAV1", CustomerID: "CUST1", Product: ProductCatalog.NinetyDayNoticeSavings}))
+	projector := NewCustomerAccountsProjector()
+
+	// When
+	err := NewRunner(projector).Run(eventStore)
+
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]*CustomerAccounts{
+		"CUST1": {CustomerID: "CUST1", Name: "Alex Gemmell", Accounts: []CustomerAccount{
+			{ID: "ACC1", Kind: ProductCatalog.KindChecking, Role: RolePrimaryHolder, Closed: true},
+			{ID: "SAV1", Kind: ProductCatalog.KindSavings, Role: RolePrimaryHolder},
+		}},
+		"CUST2": {CustomerID: "CUST2", Name: "Sam Smith", Accounts: []CustomerAccount{
+			{ID: "ACC2", Kind: ProductCatalog.KindChecking, Role: RolePrimaryHolder},
+			{ID: "ACC1", Kind: ProductCatalog.KindChecking, Role: RoleJointHolder, Closed: true},
+		}},
+	}, projector.CustomerAccounts())
+}
+
+func Test_SummariseCustomerAccounts(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	customers := map[string]*CustomerAccounts{
+		"CUST1": {CustomerID: "CUST1", Accounts: []CustomerAccount{{ID: "ACC1"}, {ID: "ACC2", Closed: true}}},
+		"CUST2": {CustomerID: "CUST2", Accounts: []CustomerAccount{{ID: "ACC3"}, {ID: "ACC4"}, {ID: "ACC5", Closed: true}}},
+		"CUST3": {CustomerID: "CUST3", Accounts: []CustomerAccount{{ID: "ACC6", Closed: true}}},
+		"CUST4": {CustomerID: "CUST4", Accounts: []CustomerAccount{{ID: "ACC7"}, {ID: "ACC8"}}},
+	}
+
+	// When
+	summary := SummariseCustomerAccounts(customers)
+
+	// Then
+	assert.Equal(t, 4, summary.Customers)
+	assert.Equal(t, map[int]int{0: 1, 1: 1, 2: 2}, summary.OpenAccountCounts)
+	var mostAccounts []string
+	for _, customer := range summary.MostAccounts {
+		mostAccounts = append(mostAccounts, customer.CustomerID)
+	}
+	assert.Equal(t, []string{"CUST2", "CUST1", "CUST4", "CUST3"}, mostAccounts)
+}
@@ -0,0 +1,115 @@
+package Projections
+
+import (
+	"encoding/json"
+	"github.com/agemmell/banking-cqrs-es-go/CheckingAccountService"
+	"sort"
+)
+
+// MonthlyFeeIncome: fees charged less fees refunded during a month
+type MonthlyFeeIncome struct {
+	YearMonth string         // yyyy-mm
+	Total     int            // income from every fee type
+	ByFeeType map[string]int // <fee type> -> income
+}
+
+// FeeIncomeProjector: fee income per month and fee type (fees charged less fees refunded)
+type FeeIncomeProjector struct {
+	feeIncomePerMonth map[string]map[string]int // <year-month> -> <fee type> -> income
+}
+
+func NewFeeIncomeProjector() *FeeIncomeProjector {
+	return &FeeIncomeProjector{feeIncomePerMonth: map[string]map[string]int{}}
+}
+
+func (fip *FeeIncomeProjector) Name() string {
+	return "FeeIncome"
+}
+
+func (fip *FeeIncomeProjector) EventTypes() []string {
+	return []string{
+		CheckingAccountService.TypeFeeWasCharged,
+		CheckingAccountService.TypeFeeWasRefunded,
+	}
+}
+
+func (fip *FeeIncomeProjector) addFeeIncome(timestamp int64, feeType string, amount int) {
+	yearMonth := yearMonthOf(timestamp)
+	if _, ok := fip.feeIncomePerMonth[yearMonth]; !ok {
+		fip.feeIncomePerMonth[yearMonth] = map[string]int{}
+	}
+	fip.feeIncomePerMonth[yearMonth][feeType] += amount
+}
+
+func (fip *FeeIncomeProjector) Handle(event Event) error {
+	switch event := event.(type) {
+	case *CheckingAccountService.FeeWasCharged:
+		fip.addFeeIncome(event.Timestamp, event.FeeType, event.Amount)
+	case *CheckingAccountService.FeeWasRefunded:
+		fip.addFeeIncome(event.Timestamp, event.FeeType, -event.Amount)
+	}
+	return nil
+}
+
+// FeeIncome: fee income for every month fees were charged or refunded in, earliest first
+func (fip *FeeIncomeProjector) FeeIncome() []MonthlyFeeIncome {
+	var feeIncome []MonthlyFeeIncome
+	for yearMonth, byFeeType := range fip.feeIncomePerMonth {
+		monthlyFeeIncome := MonthlyFeeIncome{YearMonth: yearMonth, ByFeeType: map[string]int{}}
+		for feeType, income := range byFeeType {
+			monthlyFeeIncome.ByFeeType[feeType] = income
+			monthlyFeeIncome.Total += income
+		}
+		feeIncome = append(feeIncome, monthlyFeeIncome)
+	}
+	sort.Slice(feeIncome, func(i, j int) bool {
+		return feeIncome[i].YearMonth < feeIncome[j].YearMonth
+	})
+	return feeIncome
+}
+
+func (fip *FeeIncomeProjector) State() interface{} {
+	return fip.FeeIncome()
+}
+
+func (fip *FeeIncomeProjector) MarshalState() ([]byte, error) {
+	return json.Marshal(fip.feeIncomePerMonth)
+}
+
+func (fip *FeeIncomeProjector) RestoreState(state []byte) error {
+	restored := map[string]map[string]int{}
+	err := json.Unmarshal(state, &restored)
+	if err != nil {
+		return err
+	}
+	fip.feeIncomePerMonth = restored
+	return nil
+}
+
+func (fip *FeeIncomeProjector) Version() uint {
+	return 1
+}
+
+func (fip *FeeIncomeProjector) NewEmpty() Projector {
+	return NewFeeIncomeProjector()
+}
+
+func (fip *FeeIncomeProjector) NewPartition() Projector {
+	return NewFeeIncomeProjector()
+}
+
+func (fip *FeeIncomeProjector) Merge(partition Projector) error {
+	other, ok := partition.(*FeeIncomeProjector)
+	if !ok {
+		return partitionMismatch(fip, partition)
+	}
+	for yearMonth, byFeeType := range other.feeIncomePerMonth {
+		if _, ok := fip.feeIncomePerMonth[yearMonth]; !ok {
+			fip.feeIncomePerMonth[yearMonth] = map[string]int{}
+		}
+		for feeType, income := range byFeeType {
+			fip.feeIncomePerMonth[yearMonth][feeType] += income
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package Projections
+
+import (
+	"encoding/json"
+	"github.com/agemmell/banking-cqrs-es-go/CheckingAccountService"
+	"sort"
+)
+
+type FrozenAccount struct {
+	ID          string
+	Name        string
+	Scope       string
+	ReasonCode  string
+	FrozenSince int64
+}
+
+// FrozenAccountsProjector: accounts that are currently frozen
+type FrozenAccountsProjector struct {
+	accountNames   map[string]string
+	primaryHolders map[string]string // <account ID> -> <primary holder ID>
+	frozenAccounts map[string]FrozenAccount
+}
+
+func NewFrozenAccountsProjector() *FrozenAccountsProjector {
+	return &FrozenAccountsProjector{
+		accountNames:   map[string]string{},
+		primaryHolders: map[string]string{},
+		frozenAccounts: map[string]FrozenAccount{},
+	}
+}
+
+func (fap *FrozenAccountsProjector) Name() string {
+	return "FrozenAccounts"
+}
+
+func (fap *FrozenAccountsProjector) EventTypes() []string {
+	return []string{
+		CheckingAccountService.TypeAccountWasOpened,
+		CheckingAccountService.TypeAccountHolderWasRenamed,
+		CheckingAccountService.TypeAccountWasFrozen,
+		CheckingAccountService.TypeAccountWasUnfrozen,
+	}
+}
+
+func (fap *FrozenAccountsProjector) Handle(event Event) error {
+	switch event := event.(type) {
+	case *CheckingAccountService.AccountWasOpened:
+		fap.accountNames[event.ID] = event.Name
+		fap.primaryHolders[event.ID] = primaryHolderID(*event)
+	case *CheckingAccountService.AccountHolderWasRenamed:
+		if fap.primaryHolders[event.ID] != event.HolderID {
+			return nil
+		}
+		fap.accountNames[event.ID] = event.Name
+		if frozenAccount, ok := fap.frozenAccounts[event.ID]; ok {
+			frozenAccount.Name = event.Name
+			fap.frozenAccounts[event.ID] = frozenAccount
+		}
+	case *CheckingAccountService.AccountWasFrozen:
+		frozenSince := event.Timestamp
+		// a widened freeze has been in place since the account was first frozen
+		if frozenAccount, ok := fap.frozenAccounts[event.ID]; ok {
+			frozenSince = frozenAccount.FrozenSince
+		}
+		fap.frozenAccounts[event.ID] = FrozenAccount{
+			ID:          event.ID,
+			Name:        fap.accountNames[event.ID],
+			Scope:       event.Scope,
+			ReasonCode:  event.ReasonCode,
+			FrozenSince: frozenSince,
+		}
+	case *CheckingAccountService.AccountWasUnfrozen:
+		delete(fap.frozenAccounts, event.ID)
+	}
+	return nil
+}
+
+// FrozenAccounts: the accounts that are frozen, longest frozen first
+func (fap *FrozenAccountsProjector) FrozenAccounts() []FrozenAccount {
+	var frozen []FrozenAccount
+	for _, frozenAccount := range fap.frozenAccounts {
+		frozen = append(frozen, frozenAccount)
+	}
+	sort.Slice(frozen, func(i, j int) bool {
+		return frozen[i].FrozenSince < frozen[j].FrozenSince
+	})
+	return frozen
+}
+
+func (fap *FrozenAccountsProjector) State() interface{} {
+	return fap.FrozenAccounts()
+}
+
+type frozenAccountsState struct {
+	AccountNames   map[string]string
+	PrimaryHolders map[string]string
+	FrozenAccounts map[string]FrozenAccount
+}
+
+func (fap *FrozenAccountsProjector) MarshalState() ([]byte, error) {
+	return json.Marshal(frozenAccountsState{AccountNames: fap.accountNames, PrimaryHolders: fap.primaryHolders, FrozenAccounts: fap.frozenAccounts})
+}
+
+func (fap *FrozenAccountsProjector) RestoreState(state []byte) error {
+	restored := frozenAccountsState{AccountNames: map[string]string{}, PrimaryHolders: map[string]string{}, FrozenAccounts: map[string]FrozenAccount{}}
+	err := json.Unmarshal(state, &restored)
+	if err != nil {
+		return err
+	}
+	fap.accountNames, fap.primaryHolders, fap.frozenAccounts = restored.AccountNames, restored.PrimaryHolders, restored.FrozenAccounts
+	return nil
+}
+
+func (fap *FrozenAccountsProjector) Version() uint {
+	return 1
+}
+
+func (fap *FrozenAccountsProjector) NewEmpty() Projector {
+	return NewFrozenAccountsProjector()
+}
+
+func (fap *FrozenAccountsProjector) NewPartition() Projector {
+	return NewFrozenAccountsProjector()
+}
+
+func (fap *FrozenAccountsProjector) Merge(partition Projector) error {
+	other, ok := partition.(*FrozenAccountsProjector)
+	if !ok {
+		return partitionMismatch(fap, partition)
+	}
+	for accountID, name := range other.accountNames {
+		fap.accountNames[accountID] = name
+	}
+	for accountID, holderID := range other.primaryHolders {
+		fap.primaryHolders[accountID] = holderID
+	}
+	for accountID, frozenAccount := range other.frozenAccounts {
+		fap.frozenAccounts[accountID] = frozenAccount
+	}
+	return nil
+}
@@ -0,0 +1,360 @@
+package Projections
+
+import (
+	"errors"
+	"fmt"
+	"github.com/agemmell/banking-cqrs-es-go/Seacrest"
+	"sync"
+	"time"
+)
+
+// ProjectionHost: keeps projectors current by subscribing each of them to the event store, so read models follow
+// commands as they are handled rather than only after a batch run. Each projection follows the stream independently,
+// so a slow projection falls behind without holding the others up.
+type ProjectionHost struct {
+	eventStore  *Seacrest.EventStore
+	projections map[string]*hostedProjection
+	order       []string // projection names in the order they were given
+	wg          sync.WaitGroup
+}
+
+// hostedProjection: the projector's state is guarded separately from its progress, so the lag of a projection that is
+// busy applying an event can still be read
+type hostedProjection struct {
+	state        sync.RWMutex // held while events are applied to the projector or its read model is viewed
+	runner       *Runner
+	projector    Projector // nil while a projection with no usable read model is rebuilt
+	subscription Seacrest.Subscription
+
+	// A projection whose checkpoint is stale is rebuilt into shadow, which nothing reads until it is swapped in, and
+	// checkpointed to checkpoints from then on
+	shadow      *Runner
+	checkpoints CheckpointStore
+	every       uint
+
+	mutex      sync.Mutex // guards the projection's progress below
+	position   uint
+	rebuilding bool
+	err        error         // why the projection stopped following the stream, if it failed
+	stopped    bool          // set once the projection no longer follows the stream
+	advanced   chan struct{} // closed and replaced whenever the position changes or the projection stops
+}
+
+func NewProjectionHost(eventStore *Seacrest.EventStore, projectors ...Projector) (*ProjectionHost, error) {
+	ph := &ProjectionHost{eventStore: eventStore, projections: map[string]*hostedProjection{}}
+	for _, projector := range projectors {
+		if _, ok := ph.projections[projector.Name()]; ok {
+			return nil, errors.New(fmt.Sprintf("projection %s is already hosted", projector.Name()))
+		}
+		ph.projections[projector.Name()] = &hostedProjection{
+			runner:    NewRunner(projector),
+			projector: projector,
+			advanced:  make(chan struct{}),
+		}
+		ph.order = append(ph.order, projector.Name())
+	}
+	return ph, nil
+}
+
+// ResumeFrom: before Start, restore every projection from its checkpoint in the store, and save checkpoints there
+// every `every` events and when the host stops. A Versioned projection whose checkpoint was saved by another version,
+// or cannot be restored, is rebuilt from the first event into an empty copy in the background. Until the copy catches
+// up with the event store the projection keeps serving the stale read model, if it could be restored, and then the
+// copy is swapped in whole. Views of a projection that has been swapped see the copy rather than the projector given
+// to the host.
+func (ph *ProjectionHost) ResumeFrom(checkpoints CheckpointStore, every uint) error {
+	for _, name := range ph.order {
+		projection := ph.projections[name]
+		checkpoint, found, err := checkpoints.Load(name)
+		if err != nil {
+			return err
+		}
+
+		versioned, ok := projection.projector.(Versioned)
+		if !found || !ok || checkpoint.version() == versioned.Version() {
+			err = projection.runner.ResumeFrom(checkpoints, every)
+			if err == nil {
+				projection.position = projection.runner.Position()
+				continue
+			}
+			if !ok {
+				return err
+			}
+		}
+
+		projection.shadow = NewRunner(versioned.NewEmpty())
+		projection.checkpoints = checkpoints
+		projection.every = every
+		projection.rebuilding = true
+		projection.projector = nil
+		projection.position = 0
+		if versioned.RestoreState(checkpoint.State) == nil {
+			projection.projector = versioned
+			projection.position = checkpoint.Position
+		}
+	}
+	return nil
+}
+
+// Start: subscribe every projection to the event store. Projections catch up on the events already persisted and then
+// follow new ones.
+func (ph *ProjectionHost) Start() {
+	for _, name := range ph.order {
+		projection := ph.projections[name]
+		from := projection.runner.Position()
+		if projection.shadow != nil {
+			from = projection.shadow.Position()
+		}
+		projection.subscription = ph.eventStore.Subscribe(from)
+		ph.wg.Add(1)
+		go ph.follow(projection)
+	}
+}
+
+// Stop: unsubscribe every projection and wait for them to finish the event they are applying, then checkpoint the
+// projections that resumed from a checkpoint store. The read models keep the state they had reached, and a rebuild
+// that had not caught up is abandoned. Returns the first projection failure, if any.
+func (ph *ProjectionHost) Stop() error {
+	for _, name := range ph.order {
+		if ph.projections[name].subscription != nil {
+			ph.projections[name].subscription.Close()
+		}
+	}
+	ph.wg.Wait()
+
+	for _, name := range ph.order {
+		err := ph.Err(name)
+		if err != nil {
+			return err
+		}
+	}
+	for _, name := range ph.order {
+		projection := ph.projections[name]
+		if projection.shadow != nil || projection.runner.checkpoints == nil {
+			continue
+		}
+		err := projection.runner.Checkpoint()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// follow: apply the projection's events until its subscription is closed or the projector fails
+func (ph *ProjectionHost) follow(projection *hostedProjection) {
+	defer ph.wg.Done()
+	defer func() {
+		projection.mutex.Lock()
+		projection.stopped = true
+		projection.notify()
+		projection.mutex.Unlock()
+	}()
+
+	// A rebuild of an empty event store has already caught up
+	err := ph.swapIfCaughtUp(projection)
+	if err == nil {
+		for envelope := range projection.subscription.Events() {
+			err = ph.apply(projection, envelope)
+			if err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		projection.mutex.Lock()
+		projection.err = err
+		projection.mutex.Unlock()
+		projection.subscription.Close()
+	}
+}
+
+// apply: apply one event to the projection, or to its rebuild if it is being rebuilt
+func (ph *ProjectionHost) apply(projection *hostedProjection, envelope Seacrest.EventEnvelope) error {
+	if projection.shadow != nil {
+		err := projection.shadow.Apply(envelope)
+		if err != nil {
+			return err
+		}
+		return ph.swapIfCaughtUp(projection)
+	}
+
+	projection.state.Lock()
+	err := projection.runner.Apply(envelope)
+	position := projection.runner.Position()
+	projection.state.Unlock()
+	if err != nil {
+		return err
+	}
+
+	projection.mutex.Lock()
+	projection.position = position
+	projection.notify()
+	projection.mutex.Unlock()
+	return nil
+}
+
+// swapIfCaughtUp: once a rebuild has applied every event persisted so far, replace the projection's projector with it
+// and checkpoint it. Views wait for the swap, so they see either the stale read model or the rebuilt one.
+func (ph *ProjectionHost) swapIfCaughtUp(projection *hostedProjection) error {
+	if projection.shadow == nil || projection.shadow.Position() < ph.eventStore.GlobalOrder() {
+		return nil
+	}
+
+	projection.state.Lock()
+	projection.runner = projection.shadow
+	projection.projector = projection.shadow.Projectors()[0]
+	projection.shadow = nil
+	// The rebuild only checkpoints once it is swapped in, so a restart never resumes from a half-built read model
+	projection.runner.checkpoints = projection.checkpoints
+	projection.runner.every = projection.every
+	err := projection.runner.Checkpoint()
+	position := projection.runner.Position()
+	projection.state.Unlock()
+	if err != nil {
+		return err
+	}
+
+	projection.mutex.Lock()
+	projection.position = position
+	projection.rebuilding = false
+	projection.notify()
+	projection.mutex.Unlock()
+	return nil
+}
+
+// notify: wake everyone waiting on the projection; the caller holds the lock
+func (hp *hostedProjection) notify() {
+	close(hp.advanced)
+	hp.advanced = make(chan struct{})
+}
+
+func (ph *ProjectionHost) projection(name string) (*hostedProjection, error) {
+	projection, ok := ph.projections[name]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("projection %s is not hosted", name))
+	}
+	return projection, nil
+}
+
+// Names: the hosted projections, in the order they were given
+func (ph *ProjectionHost) Names() []string {
+	return ph.order
+}
+
+// Position: the Order of the last event the projection has applied
+func (ph *ProjectionHost) Position(name string) (uint, error) {
+	projection, err := ph.projection(name)
+	if err != nil {
+		return 0, err
+	}
+	projection.mutex.Lock()
+	defer projection.mutex.Unlock()
+	return projection.position, nil
+}
+
+// Lag: how many events the projection is behind the head of the event store
+func (ph *ProjectionHost) Lag(name string) (uint, error) {
+	head := ph.eventStore.GlobalOrder()
+	position, err := ph.Position(name)
+	if err != nil {
+		return 0, err
+	}
+	if position > head {
+		return 0, nil
+	}
+	return head - position, nil
+}
+
+// Lags: every projection's lag, keyed by projection name
+func (ph *ProjectionHost) Lags() map[string]uint {
+	lags := map[string]uint{}
+	for _, name := range ph.order {
+		lags[name], _ = ph.Lag(name)
+	}
+	return lags
+}
+
+// Rebuilding: whether the projection is being rebuilt because its checkpoint was stale. Its position stays where the
+// stale read model was until the rebuild is swapped in.
+func (ph *ProjectionHost) Rebuilding(name string) (bool, error) {
+	projection, err := ph.projection(name)
+	if err != nil {
+		return false, err
+	}
+	projection.mutex.Lock()
+	defer projection.mutex.Unlock()
+	return projection.rebuilding, nil
+}
+
+// Err: why the projection stopped following the event stream, or nil if it hasn't failed
+func (ph *ProjectionHost) Err(name string) error {
+	projection, err := ph.projection(name)
+	if err != nil {
+		return err
+	}
+	projection.mutex.Lock()
+	defer projection.mutex.Unlock()
+	return projection.err
+}
+
+// WaitForPosition: block until the projection has applied every event up to and including position, so a caller can
+// read its own writes. Pass the event store's GlobalOrder after handling a command to wait for that command's events.
+// A projection being rebuilt also waits for the rebuild to be swapped in, as the stale read model it serves until then
+// belongs to another version of the projector. Fails if the projection has failed or stopped first, or if the timeout
+// passes.
+func (ph *ProjectionHost) WaitForPosition(name string, position uint, timeout time.Duration) error {
+	projection, err := ph.projection(name)
+	if err != nil {
+		return err
+	}
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		projection.mutex.Lock()
+		reached := projection.position
+		rebuilding := projection.rebuilding
+		failure := projection.err
+		stopped := projection.stopped
+		advanced := projection.advanced
+		projection.mutex.Unlock()
+
+		switch {
+		case reached >= position && !rebuilding:
+			return nil
+		case failure != nil:
+			return failure
+		case stopped && rebuilding:
+			return errors.New(fmt.Sprintf("projection %s stopped before its rebuild reached event %d", name, position))
+		case stopped:
+			return errors.New(fmt.Sprintf("projection %s stopped at event %d before reaching event %d", name, reached, position))
+		}
+
+		select {
+		case <-advanced:
+		case <-deadline.C:
+			if rebuilding {
+				return errors.New(fmt.Sprintf("projection %s was still being rebuilt after %s", name, timeout))
+			}
+			return errors.New(fmt.Sprintf("projection %s only reached event %d of %d within %s", name, reached, position, timeout))
+		}
+	}
+}
+
+// View: call view with the projection's projector while no events are being applied to it, so the read model can be
+// read consistently. view must not keep references to the projector's state after it returns. Fails if the projection
+// is being rebuilt and had no read model to serve in the meantime.
+func (ph *ProjectionHost) View(name string, view func(projector Projector)) error {
+	projection, err := ph.projection(name)
+	if err != nil {
+		return err
+	}
+	projection.state.RLock()
+	defer projection.state.RUnlock()
+	if projection.projector == nil {
+		return errors.New(fmt.Sprintf("projection %s is being rebuilt and has no read model yet", name))
+	}
+	view(projection.projector)
+	return nil
+}
@@ -0,0 +1,320 @@
+package Projections
+
+import (
+	"encoding/json"
+	"github.com/agemmell/banking-cqrs-es-go/CheckingAccountService"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// blockingProjector: handles each deposit only once it is released
+type blockingProjector struct {
+	release chan struct{}
+}
+
+func (bp *blockingProjector) Name() string {
+	return "blocking"
+}
+
+func (bp *blockingProjector) EventTypes() []string {
+	return []string{CheckingAccountService.TypeMoneyWasDeposited}
+}
+
+func (bp *blockingProjector) State() interface{} {
+	return nil
+}
+
+func (bp *blockingProjector) Handle(event Event) error {
+	<-bp.release
+	return nil
+}
+
+// versionedCountingProjector: a countingProjector at a version, whose deposits are only counted once it is released
+type versionedCountingProjector struct {
+	countingProjector
+	version uint
+	release chan struct{}
+}
+
+func (vcp *versionedCountingProjector) Handle(event Event) error {
+	<-vcp.release
+	return vcp.countingProjector.Handle(event)
+}
+
+func (vcp *versionedCountingProjector) Version() uint {
+	return vcp.version
+}
+
+func (vcp *versionedCountingProjector) NewEmpty() Projector {
+	return &versionedCountingProjector{version: vcp.version, release: vcp.release}
+}
+
+// viewDeposits: the deposits the hosted projection has counted
+func viewDeposits(host *ProjectionHost) (int, error) {
+	deposits := 0
+	err := host.View("counting", func(projector Projector) {
+		deposits = projector.State().(int)
+	})
+	return deposits, err
+}
+
+func Test_HostKeepsReadModelsCurrent(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	eventStore := newEventStore(t,
+		CheckingAccountService.OpenAccount{ID: "ACC1", CustomerID: "CUST1"},
+		CheckingAccountService.DepositMoney{ID: "ACC1", Amount: 100, ActingAs: CheckingAccountService.SystemPrincipal},
+	)
+	host, err := NewProjectionHost(eventStore,
+		NewBankFundsProjector(),
+		NewAccountCountsProjector(),
+		NewHighestBalancesProjector(),
+		NewBalancePerMonthProjector(),
+	)
+	assert.Nil(t, err)
+	host.Start()
+	defer host.Stop()
+
+	// When commands are handled after the host has caught up
+	handleCommands(t, eventStore,
+		CheckingAccountService.OpenAccount{ID: "ACC2", CustomerID: "CUST2"},
+		CheckingAccountService.DepositMoney{ID: "ACC2", Amount: 250, ActingAs: CheckingAccountService.SystemPrincipal},
+		CheckingAccountService.WithdrawMoney{ID: "ACC1", Amount: 30, ActingAs: CheckingAccountService.SystemPrincipal},
+	)
+	for _, name := range host.Names() {
+		assert.Nil(t, host.WaitForPosition(name, eventStore.GlobalOrder(), time.Second))
+	}
+
+	// Then
+	assert.Equal(t, map[string]uint{"TotalBankFunds": 0, "OpenClosedAccounts": 0, "HighestBalanceOwners": 0, "TotalBalancePerMonth": 0}, host.Lags())
+	assert.Nil(t, host.View("TotalBankFunds", func(projector Projector) {
+		assert.Equal(t, BankFunds{Total: 320}, projector.State())
+	}))
+	assert.Nil(t, host.View("OpenClosedAccounts", func(projector Projector) {
+		assert.Equal(t, AccountCounts{Open: 2}, projector.State())
+	}))
+	assert.Nil(t, host.View("HighestBalanceOwners", func(projector Projector) {
+		assert.Equal(t, []AccountBalance{
+			{ID: "ACC2", Name: "Sam Smith", Balance: 250},
+			{ID: "ACC1", Name: "Alex Gemmell", Balance: 70},
+		}, projector.State())
+	}))
+	assert.Nil(t, host.View("TotalBalancePerMonth", func(projector Projector) {
+		assert.Equal(t, []MonthlyBalance{{YearMonth: "2021-01", Inflows: 350, Outflows: 30, Closing: 320}}, projector.State())
+	}))
+}
+
+func Test_HostTracksLagOfASlowProjection(t *testing.T) {
+	t.Parallel()
+
+	// Given a projection stuck on the first deposit
+	eventStore := newEventStore(t,
+		CheckingAccountService.OpenAccount{ID: "ACC1", CustomerID: "CUST1"},
+		CheckingAccountService.DepositMoney{ID: "ACC1", Amount: 100, ActingAs: CheckingAccountService.SystemPrincipal},
+		CheckingAccountService.DepositMoney{ID: "ACC1", Amount: 50, ActingAs: CheckingAccountService.SystemPrincipal},
+	)
+	blocking := &blockingProjector{release: make(chan struct{})}
+	host, err := NewProjectionHost(eventStore, blocking, NewBankFundsProjector())
+	assert.Nil(t, err)
+	host.Start()
+
+	// When
+	waitErr := host.WaitForPosition("blocking", 3, 20*time.Millisecond)
+	assert.Nil(t, host.WaitForPosition("TotalBankFunds", 3, time.Second))
+	lags := host.Lags()
+	close(blocking.release)
+	caughtUpErr := host.WaitForPosition("blocking", 3, time.Second)
+
+	// Then
+	assert.Equal(t, "projection blocking only reached event 1 of 3 within 20ms", waitErr.Error())
+	assert.Equal(t, map[string]uint{"blocking": 2, "TotalBankFunds": 0}, lags)
+	assert.Nil(t, caughtUpErr)
+	assert.Nil(t, host.Stop())
+}
+
+func Test_HostStopsAFailingProjection(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	eventStore := newEventStore(t,
+		CheckingAccountService.OpenAccount{ID: "ACC1", CustomerID: "CUST1"},
+		CheckingAccountService.DepositMoney{ID: "ACC1", Amount: 100, ActingAs: CheckingAccountService.SystemPrincipal},
+	)
+	failing := &recordingProjector{name: "failing", eventTypes: []string{CheckingAccountService.TypeMoneyWasDeposited}, failOn: CheckingAccountService.TypeMoneyWasDeposited}
+	host, err := NewProjectionHost(eventStore, failing)
+	assert.Nil(t, err)
+	host.Start()
+
+	// When
+	waitErr := host.WaitForPosition("failing", 2, time.Second)
+	handleCommands(t, eventStore, CheckingAccountService.DepositMoney{ID: "ACC1", Amount: 50, ActingAs: CheckingAccountService.SystemPrincipal})
+	lag, lagErr := host.Lag("failing")
+	stopErr := host.Stop()
+
+	// Then
+	assert.Equal(t, "projection failing failed on event 2 (MoneyWasDeposited): projector failed", waitErr.Error())
+	assert.Nil(t, lagErr)
+	assert.Equal(t, uint(2), lag)
+	assert.Equal(t, waitErr, stopErr)
+}
+
+func Test_HostRejectsUnknownAndDuplicateProjections(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	eventStore := newEventStore(t)
+
+	// When
+	_, duplicateErr := NewProjectionHost(eventStore, NewBankFundsProjector(), NewBankFundsProjector())
+	host, err := NewProjectionHost(eventStore, NewBankFundsProjector())
+	assert.Nil(t, err)
+	unknownErr := host.WaitForPosition("missing", 1, time.Second)
+
+	// Then
+	assert.Equal(t, "projection TotalBankFunds is already hosted", duplicateErr.Error())
+	assert.Equal(t, "projection missing is not hosted", unknownErr.Error())
+}
+
+func Test_HostServesTheStaleReadModelUntilItsRebuildCatchesUp(t *testing.T) {
+	t.Parallel()
+
+	// Given a checkpoint saved by version 1 of a projection that is now at version 2
+	eventStore := newEventStore(t,
+		CheckingAccountService.OpenAccount{ID: "ACC1", CustomerID: "CUST1"},
+		CheckingAccountService.DepositMoney{ID: "ACC1", Amount: 100, ActingAs: CheckingAccountService.SystemPrincipal},
+		CheckingAccountService.DepositMoney{ID: "ACC1", Amount: 50, ActingAs: CheckingAccountService.SystemPrincipal},
+	)
+	checkpoints := NewMemoryCheckpointStore()
+	assert.Nil(t, checkpoints.Save(Checkpoint{Projector: "counting", Version: 1, Position: 2, State: json.RawMessage("1")}))
+	projector := &versionedCountingProjector{version: 2, release: make(chan struct{})}
+	host, err := NewProjectionHost(eventStore, projector)
+	assert.Nil(t, err)
+	assert.Nil(t, host.ResumeFrom(checkpoints, 0))
+	host.Start()
+
+	// When
+	rebuilding, _ := host.Rebuilding("counting")
+	position, _ := host.Position("counting")
+	staleDeposits, staleErr := viewDeposits(host)
+	close(projector.release)
+	caughtUpErr := host.WaitForPosition("counting", 3, time.Second)
+	rebuiltDeposits, rebuiltErr := viewDeposits(host)
+	stillRebuilding, _ := host.Rebuilding("counting")
+
+	// Then
+	assert.True(t, rebuilding)
+	assert.Equal(t, uint(2), position)
+	assert.Nil(t, staleErr)
+	assert.Equal(t, 1, staleDeposits)
+	assert.Nil(t, caughtUpErr)
+	assert.Nil(t, rebuiltErr)
+	assert.Equal(t, 2, rebuiltDeposits)
+	assert.False(t, stillRebuilding)
+	checkpoint, _, err := checkpoints.Load("counting")
+	assert.Nil(t, err)
+	assert.Equal(t, Checkpoint{Projector: "counting", Version: 2, Position: 3, State: json.RawMessage("2")}, checkpoint)
+	assert.Nil(t, host.Stop())
+}
+
+func Test_HostWaitsForARebuildBeforeReportingAPositionReached(t *testing.T) {
+	t.Parallel()
+
+	// Given a projection being rebuilt while it serves a stale read model of the first two events
+	eventStore := newEventStore(t,
+		CheckingAccountService.OpenAccount{ID: "ACC1", CustomerID: "CUST1"},
+		CheckingAccountService.DepositMoney{ID: "ACC1", Amount: 100, ActingAs: CheckingAccountService.SystemPrincipal},
+		CheckingAccountService.DepositMoney{ID: "ACC1", Amount: 50, ActingAs: CheckingAccountService.SystemPrincipal},
+	)
+	checkpoints := NewMemoryCheckpointStore()
+	assert.Nil(t, checkpoints.Save(Checkpoint{Projector: "counting", Version: 1, Position: 2, State: json.RawMessage("1")}))
+	projector := &versionedCountingProjector{version: 2, release: make(chan struct{})}
+	host, err := NewProjectionHost(eventStore, projector)
+	assert.Nil(t, err)
+	assert.Nil(t, host.ResumeFrom(checkpoints, 0))
+	host.Start()
+
+	// When a caller waits for a position the stale read model has already reached
+	timeoutErr := host.WaitForPosition("counting", 2, 10*time.Millisecond)
+	waited := make(chan error)
+	go func() {
+		waited <- host.WaitForPosition("counting", 2, time.Second)
+	}()
+	close(projector.release)
+	waitedErr := <-waited
+	stillRebuilding, _ := host.Rebuilding("counting")
+	deposits, viewErr := viewDeposits(host)
+
+	// Then the wait only succeeds once the rebuild has been swapped in
+	assert.Equal(t, "projection counting was still being rebuilt after 10ms", timeoutErr.Error())
+	assert.Nil(t, waitedErr)
+	assert.False(t, stillRebuilding)
+	assert.Nil(t, viewErr)
+	assert.Equal(t, 2, deposits)
+	assert.Nil(t, host.Stop())
+}
+
+func Test_HostRebuildsAProjectionWhoseCheckpointCannotBeRestored(t *testing.T) {
+	t.Parallel()
+
+	// Given a checkpoint in a format the projection no longer reads
+	eventStore := newEventStore(t,
+		CheckingAccountService.OpenAccount{ID: "ACC1", CustomerID: "CUST1"},
+		CheckingAccountService.DepositMoney{ID: "ACC1", Amount: 100, ActingAs: CheckingAccountService.SystemPrincipal},
+	)
+	checkpoints := NewMemoryCheckpointStore()
+	assert.Nil(t, checkpoints.Save(Checkpoint{Projector: "counting", Version: 2, Position: 2, State: json.RawMessage(`{"Deposits": 1}`)}))
+	projector := &versionedCountingProjector{version: 2, release: make(chan struct{})}
+	host, err := NewProjectionHost(eventStore, projector)
+	assert.Nil(t, err)
+	assert.Nil(t, host.ResumeFrom(checkpoints, 0))
+	host.Start()
+
+	// When
+	_, rebuildingErr := viewDeposits(host)
+	close(projector.release)
+	caughtUpErr := host.WaitForPosition("counting", 2, time.Second)
+	deposits, rebuiltErr := viewDeposits(host)
+
+	// Then
+	assert.Equal(t, "projection counting is being rebuilt and has no read model yet", rebuildingErr.Error())
+	assert.Nil(t, caughtUpErr)
+	assert.Nil(t, rebuiltErr)
+	assert.Equal(t, 1, deposits)
+	assert.Nil(t, host.Stop())
+}
+
+func Test_HostCheckpointsWhenItStops(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	eventStore := newEventStore(t,
+		CheckingAccountService.OpenAccount{ID: "ACC1", CustomerID: "CUST1"},
+		CheckingAccountService.DepositMoney{ID: "ACC1", Amount: 100, ActingAs: CheckingAccountService.SystemPrincipal},
+	)
+	checkpoints := NewMemoryCheckpointStore()
+	first, err := NewProjectionHost(eventStore, &countingProjector{})
+	assert.Nil(t, err)
+	assert.Nil(t, first.ResumeFrom(checkpoints, 0))
+	first.Start()
+	assert.Nil(t, first.WaitForPosition("counting", 2, time.Second))
+	assert.Nil(t, first.Stop())
+	handleCommands(t, eventStore, CheckingAccountService.DepositMoney{ID: "ACC1", Amount: 50, ActingAs: CheckingAccountService.SystemPrincipal})
+
+	// When
+	second, err := NewProjectionHost(eventStore, &countingProjector{})
+	assert.Nil(t, err)
+	assert.Nil(t, second.ResumeFrom(checkpoints, 0))
+	resumedFrom, _ := second.Position("counting")
+	second.Start()
+	caughtUpErr := second.WaitForPosition("counting", 3, time.Second)
+	deposits, viewErr := viewDeposits(second)
+
+	// Then
+	assert.Equal(t, uint(2), resumedFrom)
+	assert.Nil(t, caughtUpErr)
+	assert.Nil(t, viewErr)
+	assert.Equal(t, 2, deposits)
+	assert.Nil(t, second.Stop())
+}
@@ -0,0 +1,425 @@
+package Projections
+
+import (
+	"container/heap"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/agemmell/banking-cqrs-es-go/CheckingAccountService"
+	"github.com/agemmell/banking-cqrs-es-go/SavingsAccountService"
+)
+
+// RankingKey: what a leaderboard ranks accounts by
+type RankingKey string
+
+const RankByBalance RankingKey = "Balance"
+const RankByDepositVolume RankingKey = "DepositVolume"       // the total of every deposit into the account
+const RankByTransactionCount RankingKey = "TransactionCount" // deposits, withdrawals and captured holds
+
+// RankingOrder: which end of a ranking a leaderboard lists
+type RankingOrder int
+
+const (
+	HighestFirst RankingOrder = iota
+	LowestFirst
+)
+
+// Ranked: an account's place on a leaderboard
+type Ranked struct {
+	Rank      int
+	AccountID string
+	Name      string
+	Score     int
+}
+
+// rankedAccount: an account's score, the balance and name a leaderboard reports with it, and where it currently sits in
+// the ranking heap
+type rankedAccount struct {
+	accountID string
+	name      string
+	holderID  string // the primary holder, whose renames rename the account
+	balance   int
+	held      int
+	score     int
+	index     int
+}
+
+// rankingHeap: every account, with the account that ranks first at the root. Accounts track their own index so a
+// changed score can be fixed in place in logarithmic time. Events go through push and fix, which sift the accounts
+// directly rather than through container/heap's interface calls.
+type rankingHeap struct {
+	accounts []*rankedAccount
+	order    RankingOrder
+}
+
+// ahead: whether first ranks ahead of second; ties go to the lower account ID so rankings are repeatable
+func (rh *rankingHeap) ahead(first *rankedAccount, second *rankedAccount) bool {
+	if first.score != second.score {
+		if rh.order == LowestFirst {
+			return first.score < second.score
+		}
+		return first.score > second.score
+	}
+	return first.accountID < second.accountID
+}
+
+func (rh *rankingHeap) Len() int { return len(rh.accounts) }
+
+func (rh *rankingHeap) Less(i, j int) bool { return rh.ahead(rh.accounts[i], rh.accounts[j]) }
+
+func (rh *rankingHeap) Swap(i, j int) {
+	rh.accounts[i], rh.accounts[j] = rh.accounts[j], rh.accounts[i]
+	rh.accounts[i].index = i
+	rh.accounts[j].index = j
+}
+
+func (rh *rankingHeap) Push(account interface{}) {
+	ranked := account.(*rankedAccount)
+	ranked.index = len(rh.accounts)
+	rh.accounts = append(rh.accounts, ranked)
+}
+
+func (rh *rankingHeap) Pop() interface{} {
+	last := rh.accounts[len(rh.accounts)-1]
+	rh.accounts = rh.accounts[:len(rh.accounts)-1]
+	return last
+}
+
+// push: add an account to the ranking
+func (rh *rankingHeap) push(account *rankedAccount) {
+	rh.Push(account)
+	rh.up(account.index)
+}
+
+// fix: move the account at the position to its place after its score has changed
+func (rh *rankingHeap) fix(position int) {
+	if !rh.down(position) {
+		rh.up(position)
+	}
+}
+
+func (rh *rankingHeap) up(position int) {
+	for position > 0 {
+		parent := (position - 1) / 2
+		if !rh.ahead(rh.accounts[position], rh.accounts[parent]) {
+			return
+		}
+		rh.Swap(parent, position)
+		position = parent
+	}
+}
+
+// down: whether the account at the position had to move down
+func (rh *rankingHeap) down(position int) bool {
+	start := position
+	for {
+		child := 2*position + 1
+		if child >= len(rh.accounts) {
+			break
+		}
+		if sibling := child + 1; sibling < len(rh.accounts) && rh.ahead(rh.accounts[sibling], rh.accounts[child]) {
+			child = sibling
+		}
+		if !rh.ahead(rh.accounts[child], rh.accounts[position]) {
+			break
+		}
+		rh.Swap(position, child)
+		position = child
+	}
+	return position > start
+}
+
+// frontier: heap positions still to be visited when walking the ranking heap in rank order
+type frontier struct {
+	ranking   *rankingHeap
+	positions []int
+}
+
+func (f *frontier) Len() int { return len(f.positions) }
+
+func (f *frontier) Less(i, j int) bool {
+	return f.ranking.ahead(f.ranking.accounts[f.positions[i]], f.ranking.accounts[f.positions[j]])
+}
+
+func (f *frontier) Swap(i, j int) { f.positions[i], f.positions[j] = f.positions[j], f.positions[i] }
+
+func (f *frontier) Push(position interface{}) { f.positions = append(f.positions, position.(int)) }
+
+func (f *frontier) Pop() interface{} {
+	last := f.positions[len(f.positions)-1]
+	f.positions = f.positions[:len(f.positions)-1]
+	return last
+}
+
+// LeaderboardProjector: the first N accounts ranked by a key. Every account stays in the ranking, so an account that
+// drops out of the top N and later climbs back is ranked correctly. Each event costs one lookup of its account and,
+// if the account's score changed, O(log accounts) to move it.
+type LeaderboardProjector struct {
+	key      RankingKey
+	size     int
+	accounts map[string]*rankedAccount
+	ranking  *rankingHeap
+}
+
+func NewLeaderboardProjector(key RankingKey, size int, order RankingOrder) (*LeaderboardProjector, error) {
+	switch key {
+	case RankByBalance, RankByDepositVolume, RankByTransactionCount:
+	default:
+		return nil, errors.New(fmt.Sprintf("cannot rank accounts by %s", key))
+	}
+	if size < 1 {
+		return nil, errors.New(fmt.Sprintf("a leaderboard must list at least one account [size: %d]", size))
+	}
+	return &LeaderboardProjector{
+		key:      key,
+		size:     size,
+		accounts: map[string]*rankedAccount{},
+		ranking:  &rankingHeap{order: order},
+	}, nil
+}
+
+func (lp *LeaderboardProjector) Name() string {
+	if lp.ranking.order == LowestFirst {
+		return fmt.Sprintf("Bottom%d%s", lp.size, lp.key)
+	}
+	return fmt.Sprintf("Top%d%s", lp.size, lp.key)
+}
+
+func (lp *LeaderboardProjector) EventTypes() []string {
+	return []string{
+		CheckingAccountService.TypeAccountWasOpened,
+		CheckingAccountService.TypeAccountHolderWasRenamed,
+		CheckingAccountService.TypeMoneyWasDeposited,
+		CheckingAccountService.TypeMoneyWasWithdrawn,
+		CheckingAccountService.TypeFeeWasCharged,
+		CheckingAccountService.TypeFeeWasRefunded,
+		CheckingAccountService.TypeStandingOrderPaymentWasReturned,
+		CheckingAccountService.TypeHoldWasPlaced,
+		CheckingAccountService.TypeHoldWasReleased,
+		CheckingAccountService.TypeHoldWasCaptured,
+		SavingsAccountService.TypeSavingsAccountWasOpened,
+		SavingsAccountService.TypeSavingsMoneyWasDeposited,
+		SavingsAccountService.TypeSavingsMoneyWasWithdrawn,
+	}
+}
+
+func (lp *LeaderboardProjector) Handle(event Event) error {
+	switch event := event.(type) {
+	case *CheckingAccountService.AccountWasOpened:
+		lp.open(event.ID, event.Name, primaryHolderID(*event))
+		return nil
+	case *SavingsAccountService.SavingsAccountWasOpened:
+		lp.open(event.ID, event.Name, event.CustomerID)
+		return nil
+	}
+
+	account, ok := lp.accounts[event.AggregateID()]
+	if !ok {
+		return nil
+	}
+
+	deposited, transacted := 0, false
+	switch event := event.(type) {
+	case *CheckingAccountService.AccountHolderWasRenamed:
+		if account.holderID == event.HolderID {
+			account.name = event.Name
+		}
+		return nil
+	case *CheckingAccountService.MoneyWasDeposited:
+		account.balance += event.Amount
+		deposited, transacted = event.Amount, true
+	case *CheckingAccountService.MoneyWasWithdrawn:
+		account.balance = event.Balance
+		transacted = true
+	case *CheckingAccountService.FeeWasCharged:
+		account.balance = event.Balance
+	case *CheckingAccountService.FeeWasRefunded:
+		account.balance = event.Balance
+	case *CheckingAccountService.StandingOrderPaymentWasReturned:
+		account.balance = event.Balance
+	case *CheckingAccountService.HoldWasPlaced:
+		account.held += event.Amount
+	case *CheckingAccountService.HoldWasReleased:
+		account.held -= event.Amount
+	case *CheckingAccountService.HoldWasCaptured:
+		account.balance = event.Balance
+		account.held -= event.HeldAmount
+		transacted = true
+	case *SavingsAccountService.SavingsMoneyWasDeposited:
+		account.balance = event.Balance
+		deposited, transacted = event.Amount, true
+	case *SavingsAccountService.SavingsMoneyWasWithdrawn:
+		account.balance = event.Balance
+		transacted = true
+	}
+
+	score := account.score
+	switch lp.key {
+	case RankByBalance:
+		score = account.balance
+	case RankByDepositVolume:
+		score += deposited
+	case RankByTransactionCount:
+		if transacted {
+			score++
+		}
+	}
+	if score != account.score {
+		account.score = score
+		lp.ranking.fix(account.index)
+	}
+	return nil
+}
+
+// open: add a newly opened account to the ranking with nothing in it
+func (lp *LeaderboardProjector) open(accountID string, name string, holderID string) {
+	if account, ok := lp.accounts[accountID]; ok {
+		account.name, account.holderID = name, holderID
+		return
+	}
+	account := &rankedAccount{accountID: accountID, name: name, holderID: holderID}
+	lp.accounts[accountID] = account
+	lp.ranking.push(account)
+}
+
+// leaders: the first N accounts in rank order. The heap is walked best first, so this costs O(N log N) however many
+// accounts there are.
+func (lp *LeaderboardProjector) leaders() []*rankedAccount {
+	var leaders []*rankedAccount
+	if lp.ranking.Len() == 0 {
+		return leaders
+	}
+
+	next := &frontier{ranking: lp.ranking, positions: []int{0}}
+	for next.Len() > 0 && len(leaders) < lp.size {
+		position := heap.Pop(next).(int)
+		leaders = append(leaders, lp.ranking.accounts[position])
+		for _, child := range []int{2*position + 1, 2*position + 2} {
+			if child < lp.ranking.Len() {
+				heap.Push(next, child)
+			}
+		}
+	}
+	return leaders
+}
+
+// Leaders: the first N accounts in rank order
+func (lp *LeaderboardProjector) Leaders() []Ranked {
+	var leaders []Ranked
+	for _, account := range lp.leaders() {
+		leaders = append(leaders, Ranked{Rank: len(leaders) + 1, AccountID: account.accountID, Name: account.name, Score: account.score})
+	}
+	return leaders
+}
+
+func (lp *LeaderboardProjector) State() interface{} {
+	return lp.Leaders()
+}
+
+type leaderboardAccount struct {
+	Name     string
+	HolderID string
+	Balance  int
+	Held     int
+	Score    int
+}
+
+type leaderboardState struct {
+	Accounts map[string]leaderboardAccount
+}
+
+func (lp *LeaderboardProjector) MarshalState() ([]byte, error) {
+	accounts := make(map[string]leaderboardAccount, len(lp.accounts))
+	for accountID, account := range lp.accounts {
+		accounts[accountID] = leaderboardAccount{Name: account.name, HolderID: account.holderID, Balance: account.balance, Held: account.held, Score: account.score}
+	}
+	return json.Marshal(leaderboardState{Accounts: accounts})
+}
+
+func (lp *LeaderboardProjector) RestoreState(state []byte) error {
+	restored := leaderboardState{}
+	err := json.Unmarshal(state, &restored)
+	if err != nil {
+		return err
+	}
+	if restored.Accounts == nil {
+		return errors.New("the checkpoint has no accounts")
+	}
+
+	lp.accounts = make(map[string]*rankedAccount, len(restored.Accounts))
+	lp.ranking.accounts = make([]*rankedAccount, 0, len(restored.Accounts))
+	for accountID, saved := range restored.Accounts {
+		account := &rankedAccount{accountID: accountID, name: saved.Name, holderID: saved.HolderID, balance: saved.Balance, held: saved.Held, score: saved.Score, index: len(lp.ranking.accounts)}
+		lp.accounts[accountID] = account
+		lp.ranking.accounts = append(lp.ranking.accounts, account)
+	}
+	heap.Init(lp.ranking)
+	return nil
+}
+
+// Version: 3 keeps each account's name and balance alongside its score rather than in a separate account balances
+// projection
+func (lp *LeaderboardProjector) Version() uint {
+	return 3
+}
+
+func (lp *LeaderboardProjector) NewEmpty() Projector {
+	empty, _ := NewLeaderboardProjector(lp.key, lp.size, lp.ranking.order)
+	return empty
+}
+
+func (lp *LeaderboardProjector) NewPartition() Projector {
+	partition, _ := NewLeaderboardProjector(lp.key, lp.size, lp.ranking.order)
+	return partition
+}
+
+func (lp *LeaderboardProjector) Merge(partition Projector) error {
+	other, ok := partition.(*LeaderboardProjector)
+	if !ok {
+		return partitionMismatch(lp, partition)
+	}
+	for accountID, theirs := range other.accounts {
+		if ours, ok := lp.accounts[accountID]; ok {
+			index := ours.index
+			*ours = *theirs
+			ours.index = index
+			lp.ranking.fix(index)
+			continue
+		}
+		account := *theirs
+		lp.accounts[accountID] = &account
+		lp.ranking.push(&account)
+	}
+	return nil
+}
+
+// HighestBalancesProjector: top 10 "highest balance" account owners
+type HighestBalancesProjector struct {
+	*LeaderboardProjector
+}
+
+func NewHighestBalancesProjector() *HighestBalancesProjector {
+	leaderboard, _ := NewLeaderboardProjector(RankByBalance, 10, HighestFirst)
+	return &HighestBalancesProjector{leaderboard}
+}
+
+func (hbp *HighestBalancesProjector) Name() string {
+	return "HighestBalanceOwners"
+}
+
+// HighestBalances: the ten accounts with the highest balances, highest first
+func (hbp *HighestBalancesProjector) HighestBalances() []AccountBalance {
+	var highestBalances []AccountBalance
+	for _, account := range hbp.leaders() {
+		highestBalances = append(highestBalances, AccountBalance{ID: account.accountID, Name: account.name, Balance: account.balance, Held: account.held})
+	}
+	return highestBalances
+}
+
+func (hbp *HighestBalancesProjector) State() interface{} {
+	return hbp.HighestBalances()
+}
+
+func (hbp *HighestBalancesProjector) NewEmpty() Projector {
+	return NewHighestBalancesProjector()
+}
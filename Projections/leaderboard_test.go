@@ -0,0 +1,316 @@
+package Projections
+
+import (
+	"fmt"
+	"github.com/agemmell/banking-cqrs-es-go/CheckingAccountService"
+	"github.com/agemmell/banking-cqrs-es-go/EventRegistry"
+	"github.com/agemmell/banking-cqrs-es-go/Seacrest"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func openAccount(t *testing.T, projector Projector, accountID string) {
+	assert.Nil(t, projector.Handle(&CheckingAccountService.AccountWasOpened{ID: accountID, Name: "Holder of " + accountID}))
+}
+
+func deposit(t *testing.T, projector Projector, accountID string, amount int) {
+	assert.Nil(t, projector.Handle(&CheckingAccountService.MoneyWasDeposited{ID: accountID, Amount: amount}))
+}
+
+func withdraw(t *testing.T, projector Projector, accountID string, amount int, balance int) {
+	assert.Nil(t, projector.Handle(&CheckingAccountService.MoneyWasWithdrawn{ID: accountID, Amount: amount, Balance: balance}))
+}
+
+func leaderIDs(leaders []Ranked) []string {
+	var accountIDs []string
+	for _, leader := range leaders {
+		accountIDs = append(accountIDs, leader.AccountID)
+	}
+	return accountIDs
+}
+
+func Test_LeaderboardRanksAccountsThatFallAndRecover(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	leaderboard, err := NewLeaderboardProjector(RankByBalance, 3, HighestFirst)
+	assert.Nil(t, err)
+	for i, accountID := range []string{"ACC1", "ACC2", "ACC3", "ACC4"} {
+		openAccount(t, leaderboard, accountID)
+		deposit(t, leaderboard, accountID, 100*(i+1))
+	}
+
+	// When the richest account empties out of the top three and then becomes the richest again
+	withdraw(t, leaderboard, "ACC4", 400, 0)
+	fallen := leaderIDs(leaderboard.Leaders())
+	deposit(t, leaderboard, "ACC4", 1000)
+
+	// Then
+	assert.Equal(t, []string{"ACC3", "ACC2", "ACC1"}, fallen)
+	assert.Equal(t, []Ranked{
+		{Rank: 1, AccountID: "ACC4", Name: "Holder of ACC4", Score: 1000},
+		{Rank: 2, AccountID: "ACC3", Name: "Holder of ACC3", Score: 300},
+		{Rank: 3, AccountID: "ACC2", Name: "Holder of ACC2", Score: 200},
+	}, leaderboard.Leaders())
+}
+
+func Test_LeaderboardKeysAndOrders(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	volume, err := NewLeaderboardProjector(RankByDepositVolume, 2, HighestFirst)
+	assert.Nil(t, err)
+	count, err := NewLeaderboardProjector(RankByTransactionCount, 2, HighestFirst)
+	assert.Nil(t, err)
+	lowest, err := NewLeaderboardProjector(RankByBalance, 2, LowestFirst)
+	assert.Nil(t, err)
+
+	// When
+	for _, leaderboard := range []Projector{volume, count, lowest} {
+		openAccount(t, leaderboard, "ACC1")
+		openAccount(t, leaderboard, "ACC2")
+		openAccount(t, leaderboard, "ACC3")
+		deposit(t, leaderboard, "ACC1", 500)
+		withdraw(t, leaderboard, "ACC1", 450, 50)
+		deposit(t, leaderboard, "ACC2", 10)
+		deposit(t, leaderboard, "ACC2", 10)
+		deposit(t, leaderboard, "ACC2", 10)
+		deposit(t, leaderboard, "ACC3", 100)
+	}
+
+	// Then
+	assert.Equal(t, "Top2DepositVolume", volume.Name())
+	assert.Equal(t, []string{"ACC1", "ACC3"}, leaderIDs(volume.Leaders()))
+	assert.Equal(t, 500, volume.Leaders()[0].Score)
+	assert.Equal(t, "Top2TransactionCount", count.Name())
+	assert.Equal(t, []string{"ACC2", "ACC1"}, leaderIDs(count.Leaders()))
+	assert.Equal(t, 3, count.Leaders()[0].Score)
+	assert.Equal(t, "Bottom2Balance", lowest.Name())
+	assert.Equal(t, []string{"ACC2", "ACC1"}, leaderIDs(lowest.Leaders()))
+}
+
+func Test_LeaderboardMatchesAFullSort(t *testing.T) {
+	t.Parallel()
+
+	// Given random deposits and withdrawals across many accounts
+	random := rand.New(rand.NewSource(42))
+	leaderboard, err := NewLeaderboardProjector(RankByBalance, 7, HighestFirst)
+	assert.Nil(t, err)
+	balances := map[string]int{}
+	for i := 0; i < 50; i++ {
+		accountID := fmt.Sprintf("ACC%02d", i)
+		openAccount(t, leaderboard, accountID)
+		balances[accountID] = 0
+	}
+
+	for step := 0; step < 2000; step++ {
+		// When
+		accountID := fmt.Sprintf("ACC%02d", random.Intn(50))
+		amount := random.Intn(500) + 1
+		if random.Intn(2) == 0 || balances[accountID] < amount {
+			deposit(t, leaderboard, accountID, amount)
+			balances[accountID] += amount
+		} else {
+			balances[accountID] -= amount
+			withdraw(t, leaderboard, accountID, amount, balances[accountID])
+		}
+
+		// Then
+		var expected []string
+		for accountID := range balances {
+			expected = append(expected, accountID)
+		}
+		sort.Slice(expected, func(i, j int) bool {
+			if balances[expected[i]] != balances[expected[j]] {
+				return balances[expected[i]] > balances[expected[j]]
+			}
+			return expected[i] < expected[j]
+		})
+		if !assert.Equal(t, expected[:7], leaderIDs(leaderboard.Leaders()), "step %d", step) {
+			return
+		}
+	}
+}
+
+func Test_LeaderboardRestoresFromItsCheckpoint(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	original, err := NewLeaderboardProjector(RankByTransactionCount, 2, HighestFirst)
+	assert.Nil(t, err)
+	openAccount(t, original, "ACC1")
+	openAccount(t, original, "ACC2")
+	deposit(t, original, "ACC1", 10)
+	deposit(t, original, "ACC2", 10)
+	deposit(t, original, "ACC2", 10)
+	state, err := original.MarshalState()
+	assert.Nil(t, err)
+
+	// When
+	restored, err := NewLeaderboardProjector(RankByTransactionCount, 2, HighestFirst)
+	assert.Nil(t, err)
+	assert.Nil(t, restored.RestoreState(state))
+	deposit(t, restored, "ACC1", 10)
+	deposit(t, restored, "ACC1", 10)
+	legacyErr := restored.RestoreState([]byte(`{"AccountBalances": {}, "Scores": {}}`))
+
+	// Then
+	assert.Equal(t, []Ranked{
+		{Rank: 1, AccountID: "ACC1", Name: "Holder of ACC1", Score: 3},
+		{Rank: 2, AccountID: "ACC2", Name: "Holder of ACC2", Score: 2},
+	}, restored.Leaders())
+	assert.Equal(t, "the checkpoint has no accounts", legacyErr.Error())
+}
+
+func Test_LeaderboardRejectsBadConfiguration(t *testing.T) {
+	t.Parallel()
+
+	// When
+	_, keyErr := NewLeaderboardProjector("Age", 10, HighestFirst)
+	_, sizeErr := NewLeaderboardProjector(RankByBalance, 0, HighestFirst)
+
+	// Then
+	assert.Equal(t, "cannot rank accounts by Age", keyErr.Error())
+	assert.Equal(t, "a leaderboard must list at least one account [size: 0]", sizeErr.Error())
+}
+
+// sortTopTen: how the highest balances used to be kept, to benchmark against. It re-sorts the top ten on every change,
+// and forgets an account once it has been pushed out.
+func sortTopTen(topTen []AccountBalance, accountBalance AccountBalance) []AccountBalance {
+	alreadyExists := false
+	for i, account := range topTen {
+		if account.ID == accountBalance.ID {
+			topTen[i] = accountBalance
+			alreadyExists = true
+			break
+		}
+	}
+	if !alreadyExists {
+		topTen = append(topTen, accountBalance)
+	}
+	sort.Slice(topTen, func(i, j int) bool {
+		return topTen[i].Balance > topTen[j].Balance
+	})
+	if len(topTen) > 10 {
+		topTen = topTen[:10]
+	}
+	return topTen
+}
+
+// everyCustomer: treats every customer as having passed KYC, so the benchmark can open as many accounts as it needs
+type everyCustomer struct{}
+
+func (ec everyCustomer) VerifiedName(customerID string) (string, error) {
+	return "Holder of " + customerID, nil
+}
+
+var benchmarkEvents = struct {
+	mutex     sync.Mutex
+	byAccount map[int][]Event
+}{byAccount: map[int][]Event{}}
+
+// generateBenchmarkEvents: the decoded balance events of a bank with the given number of accounts, each opened and
+// then given ten random deposits and withdrawals in a random order. The fake clock and sequential IDs make every run of
+// the benchmark replay exactly the same events.
+func generateBenchmarkEvents(b *testing.B, accounts int) []Event {
+	benchmarkEvents.mutex.Lock()
+	defer benchmarkEvents.mutex.Unlock()
+	if events, ok := benchmarkEvents.byAccount[accounts]; ok {
+		return events
+	}
+
+	clock := Seacrest.NewFakeClock(time.Date(2021, time.January, 4, 9, 0, 0, 0, time.UTC))
+	clock.AutoAdvance(time.Second)
+	eventStore := Seacrest.NewEventStore(Seacrest.WithClock(clock), Seacrest.WithIDGenerator(Seacrest.NewSequentialIDGenerator("event")))
+	checkingAccountService := CheckingAccountService.New(eventStore,
+		CheckingAccountService.WithClock(clock),
+		CheckingAccountService.WithIDGenerator(Seacrest.NewSequentialIDGenerator("id")),
+		CheckingAccountService.WithCustomerVerifier(everyCustomer{}),
+	)
+	handle := func(command CheckingAccountService.Command) {
+		err := checkingAccountService.HandleCommand(command)
+		if err != nil {
+			b.Fatalf("generating benchmark events: %s", err)
+		}
+	}
+
+	random := rand.New(rand.NewSource(47))
+	balances := make([]int, accounts)
+	for account := range balances {
+		handle(CheckingAccountService.OpenAccount{ID: fmt.Sprintf("ACC%d", account), CustomerID: fmt.Sprintf("CUST%d", account)})
+	}
+	for transaction := 0; transaction < 10*accounts; transaction++ {
+		account := random.Intn(accounts)
+		accountID := fmt.Sprintf("ACC%d", account)
+		amount := random.Intn(10000) + 1
+		if random.Intn(3) == 0 && balances[account] > 0 {
+			amount = amount%balances[account] + 1
+			handle(CheckingAccountService.WithdrawMoney{ID: accountID, Amount: amount, ActingAs: CheckingAccountService.SystemPrincipal})
+			balances[account] -= amount
+		} else {
+			handle(CheckingAccountService.DepositMoney{ID: accountID, Amount: amount, ActingAs: CheckingAccountService.SystemPrincipal})
+			balances[account] += amount
+		}
+	}
+
+	handles := map[string]bool{}
+	for _, eventType := range NewAccountBalancesProjector().EventTypes() {
+		handles[eventType] = true
+	}
+	var events []Event
+	for _, envelope := range eventStore.GetAllEvents() {
+		if !handles[envelope.EventType] {
+			continue
+		}
+		event, err := EventRegistry.Decode(envelope)
+		if err != nil {
+			b.Fatal(err)
+		}
+		events = append(events, event)
+	}
+	benchmarkEvents.byAccount[accounts] = events
+	return events
+}
+
+// BenchmarkHighestBalances: keep the ten highest balances while a bank's events are replayed, reading them every
+// hundred events as a live dashboard would. SortTopTen is how they used to be kept, re-sorting the top ten on every
+// change (and wrong once a balance falls); Leaderboard keeps every account in a heap and only walks the top of it.
+func BenchmarkHighestBalances(b *testing.B) {
+	const readEvery = 100
+
+	for _, accounts := range []int{1000, 100000} {
+		events := generateBenchmarkEvents(b, accounts)
+
+		b.Run(fmt.Sprintf("SortTopTen/%dAccounts", accounts), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				var topTen []AccountBalance
+				projector := NewAccountBalancesProjector()
+				projector.OnChange = func(accountBalance AccountBalance) {
+					topTen = sortTopTen(topTen, accountBalance)
+				}
+				for handled, event := range events {
+					_ = projector.Handle(event)
+					if handled%readEvery == 0 {
+						_ = append([]AccountBalance{}, topTen...)
+					}
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("Leaderboard/%dAccounts", accounts), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				projector := NewHighestBalancesProjector()
+				for handled, event := range events {
+					_ = projector.Handle(event)
+					if handled%readEvery == 0 {
+						projector.HighestBalances()
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package Projections
+
+import (
+	"errors"
+	"fmt"
+	"github.com/agemmell/banking-cqrs-es-go/Seacrest"
+	"hash/fnv"
+	"sync"
+)
+
+// Partitioned: a projector whose read model can be built in parallel. Events are split between partitions by aggregate
+// ID, each partition builds its own copy of the read model from its share of the events, and the copies are merged.
+// Only projectors where one aggregate's events never change how another aggregate's events are projected can be
+// partitioned; merging must then give exactly the read model a single pass over every event would.
+type Partitioned interface {
+	Projector
+	NewPartition() Projector         // an empty copy of the projector, configured the same way
+	Merge(partition Projector) error // add a partition built by NewPartition to this read model
+}
+
+// partitionBatchSize: how many events are handed to a worker at once
+const partitionBatchSize = 1024
+
+// RunParallel: apply every event in the store after the runner's position, like Run, with the work spread over
+// `workers` goroutines. Events are partitioned by a hash of their aggregate ID, so each aggregate's events are still
+// projected in order. Partitioned projectors that are building from the first event get a copy per worker, merged in
+// partition order once every event has been applied. Every other projector is fed every event in order by one more
+// goroutine. The read models are the same as Run builds. Checkpoints are only saved at the end.
+func (r *Runner) RunParallel(eventStore *Seacrest.EventStore, workers int) error {
+	if workers < 1 {
+		return errors.New(fmt.Sprintf("a parallel run needs at least one worker [workers: %d]", workers))
+	}
+	envelopes := eventStore.GetAllEvents()
+	err := r.checkRestoredWithin(envelopes)
+	if err != nil {
+		return err
+	}
+
+	// Projectors carrying on from earlier events would have their read models merged with themselves
+	sequential := &Runner{byEventType: map[string][]Projector{}, restored: r.restored, position: r.position}
+	var partitioned []Partitioned
+	for _, projector := range r.projectors {
+		partitionable, ok := projector.(Partitioned)
+		if ok && r.position == 0 && r.restored[projector.Name()] == 0 {
+			partitioned = append(partitioned, partitionable)
+		} else {
+			sequential.Register(projector)
+		}
+	}
+	partitions := make([]*Runner, workers)
+	for i := range partitions {
+		partitions[i] = NewRunner()
+		for _, projector := range partitioned {
+			partitions[i].Register(projector.NewPartition())
+		}
+	}
+
+	runners := append([]*Runner{sequential}, partitions...)
+	batches := make([]chan []Seacrest.EventEnvelope, len(runners))
+	failures := make([]error, len(runners))
+	var wait sync.WaitGroup
+	for i, runner := range runners {
+		batches[i] = make(chan []Seacrest.EventEnvelope, 4)
+		wait.Add(1)
+		go func(i int, runner *Runner) {
+			defer wait.Done()
+			for batch := range batches[i] {
+				// keep draining after a failure so the events can still be handed out
+				for _, envelope := range batch {
+					if failures[i] == nil {
+						failures[i] = runner.Apply(envelope)
+					}
+				}
+			}
+		}(i, runner)
+	}
+
+	pending := make([][]Seacrest.EventEnvelope, len(runners))
+	hand := func(i int, envelope Seacrest.EventEnvelope) {
+		pending[i] = append(pending[i], envelope)
+		if len(pending[i]) == partitionBatchSize {
+			batches[i] <- pending[i]
+			pending[i] = nil
+		}
+	}
+	for _, envelope := range envelopes {
+		if envelope.Order <= r.position {
+			continue
+		}
+		if len(sequential.byEventType[envelope.EventType]) > 0 {
+			hand(0, envelope)
+		}
+		if len(partitioned) > 0 && len(partitions[0].byEventType[envelope.EventType]) > 0 {
+			hand(1+partitionOf(envelope.AggregateID, workers), envelope)
+		}
+	}
+	for i := range runners {
+		if len(pending[i]) > 0 {
+			batches[i] <- pending[i]
+		}
+		close(batches[i])
+	}
+	wait.Wait()
+
+	for _, failure := range failures {
+		if failure != nil {
+			return failure
+		}
+	}
+	for i, projector := range partitioned {
+		for _, partition := range partitions {
+			err = projector.Merge(partition.projectors[i])
+			if err != nil {
+				return errors.New(fmt.Sprintf("cannot merge a partition of projection %s: %s", projector.Name(), err))
+			}
+		}
+	}
+
+	if len(envelopes) > 0 && envelopes[len(envelopes)-1].Order > r.position {
+		r.position = envelopes[len(envelopes)-1].Order
+	}
+	if r.checkpoints == nil {
+		return nil
+	}
+	return r.Checkpoint()
+}
+
+// partitionOf: the partition an aggregate's events belong to
+func partitionOf(aggregateID string, partitions int) int {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(aggregateID))
+	return int(hash.Sum32() % uint32(partitions))
+}
+
+// partitionMismatch: the error for merging a partition that was not built by the projector's NewPartition
+func partitionMismatch(projector Projector, partition Projector) error {
+	return errors.New(fmt.Sprintf("projection %s cannot merge a %T", projector.Name(), partition))
+}
@@ -0,0 +1,133 @@
+package Projections
+
+import (
+	"github.com/agemmell/banking-cqrs-es-go/CheckingAccountService"
+	"github.com/agemmell/banking-cqrs-es-go/Seacrest"
+	"github.com/agemmell/banking-cqrs-es-go/Simulator"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// partitionedProjectors: every projector, including ones that cannot be partitioned
+func partitionedProjectors() []Projector {
+	mostActive, _ := NewLeaderboardProjector(RankByTransactionCount, 5, HighestFirst)
+	lowestBalances, _ := NewLeaderboardProjector(RankByBalance, 5, LowestFirst)
+	return append(allProjectors(), NewStatementsProjector(), mostActive, lowestBalances)
+}
+
+func simulatedEventStore(t *testing.T) *Seacrest.EventStore {
+	eventStore, _, err := Simulator.Run(Simulator.Config{
+		Customers:           300,
+		Seed:                11,
+		Start:               time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
+		End:                 time.Date(2020, time.July, 1, 0, 0, 0, 0, time.UTC),
+		ArrivalsPerDay:      4,
+		DepositsPerMonth:    2,
+		WithdrawalsPerMonth: 3,
+		DepositSize:         Simulator.Distribution{Median: 200, Spread: 1, Min: 1, Max: 5000},
+		WithdrawalSize:      Simulator.Distribution{Median: 150, Spread: 1, Min: 1, Max: 5000},
+		Salary:              Simulator.Recurring{Chance: 0.5, Amount: Simulator.Distribution{Median: 2500, Spread: 0.3, Min: 1000, Max: 9000}, DayOfMonth: 25},
+		Rent:                Simulator.Recurring{Chance: 0.5, Amount: Simulator.Distribution{Median: 1200, Spread: 0.3, Min: 500, Max: 4000}},
+		CloseChance:         0.2,
+	})
+	assert.Nil(t, err)
+	return eventStore
+}
+
+func Test_RunParallelMatchesASequentialRun(t *testing.T) {
+	t.Parallel()
+
+	eventStores := map[string]*Seacrest.EventStore{
+		"simulated": simulatedEventStore(t),
+		"every event type": newEventStore(t,
+			CheckingAccountService.OpenAccount{ID: "ACC1", CustomerID: "CUST1"},
+			CheckingAccountService.OpenAccount{ID: "ACC2", CustomerID: "CUST2"},
+			CheckingAccountService.OpenAccount{ID: "ACC3", CustomerID: "CUST1"},
+			CheckingAccountService.DepositMoney{ID: "ACC1", Amount: 100, ActingAs: CheckingAccountService.SystemPrincipal},
+			CheckingAccountService.DepositMoney{ID: "ACC2", Amount: 250, ActingAs: CheckingAccountService.SystemPrincipal},
+			CheckingAccountService.PlaceHold{ID: "ACC2", HoldID: "H1", Amount: 50, ExpiresAt: time.Date(2021, time.February, 1, 0, 0, 0, 0, time.UTC).UnixNano(), ActingAs: CheckingAccountService.SystemPrincipal},
+			CheckingAccountService.FreezeAccount{ID: "ACC1", Scope: CheckingAccountService.FreezeDebits, ReasonCode: CheckingAccountService.ReasonSuspectedFraud, ActingAs: CheckingAccountService.SystemPrincipal},
+			CheckingAccountService.AddJointHolder{ID: "ACC2", HolderID: "CUST3", Name: "Jo Bloggs", ActingAs: CheckingAccountService.SystemPrincipal},
+			CheckingAccountService.ChargeFee{ID: "ACC2", FeeType: CheckingAccountService.FeeTypeMonthlyMaintenance, ActingAs: CheckingAccountService.SystemPrincipal},
+			CheckingAccountService.WithdrawMoney{ID: "ACC2", Amount: 75, ActingAs: CheckingAccountService.SystemPrincipal},
+			CheckingAccountService.CaptureHold{ID: "ACC2", HoldID: "H1", Amount: 40, ActingAs: CheckingAccountService.SystemPrincipal},
+			CheckingAccountService.CloseAccount{ID: "ACC3", ActingAs: CheckingAccountService.SystemPrincipal},
+		),
+	}
+
+	for dataset, eventStore := range eventStores {
+		// Given
+		sequential := partitionedProjectors()
+		assert.Nil(t, NewRunner(sequential...).Run(eventStore))
+
+		for _, workers := range []int{1, 3, 8} {
+			// When
+			parallel := partitionedProjectors()
+			runner := NewRunner(parallel...)
+			err := runner.RunParallel(eventStore, workers)
+
+			// Then
+			assert.Nil(t, err)
+			assert.Equal(t, eventStore.GlobalOrder(), runner.Position())
+			for i := range sequential {
+				assert.Equal(t, sequential[i].State(), parallel[i].State(), "%s with %d workers: %s", dataset, workers, sequential[i].Name())
+			}
+		}
+	}
+}
+
+func Test_RunParallelCarriesOnFromCheckpoints(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	eventStore := simulatedEventStore(t)
+	rebuilt := partitionedProjectors()
+	assert.Nil(t, NewRunner(rebuilt...).Run(eventStore))
+
+	checkpoints := NewMemoryCheckpointStore()
+	halfway := NewRunner(partitionedProjectors()...)
+	assert.Nil(t, halfway.ResumeFrom(checkpoints, 0))
+	for _, envelope := range eventStore.GetAllEvents()[:eventStore.GlobalOrder()/2] {
+		assert.Nil(t, halfway.Apply(envelope))
+	}
+	assert.Nil(t, halfway.Checkpoint())
+
+	// When
+	resumed := partitionedProjectors()
+	runner := NewRunner(resumed...)
+	assert.Nil(t, runner.ResumeFrom(checkpoints, 0))
+	err := runner.RunParallel(eventStore, 4)
+
+	// Then
+	assert.Nil(t, err)
+	for i := range rebuilt {
+		assert.Equal(t, rebuilt[i].State(), resumed[i].State(), rebuilt[i].Name())
+	}
+	checkpoint, found, err := checkpoints.Load("TotalBankFunds")
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, eventStore.GlobalOrder(), checkpoint.Position)
+}
+
+func Test_RunParallelReportsFailures(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	eventStore := newEventStore(t,
+		CheckingAccountService.OpenAccount{ID: "ACC1", CustomerID: "CUST1"},
+		CheckingAccountService.DepositMoney{ID: "ACC1", Amount: 100, ActingAs: CheckingAccountService.SystemPrincipal},
+	)
+	failing := &recordingProjector{name: "failing", eventTypes: []string{CheckingAccountService.TypeMoneyWasDeposited}, failOn: CheckingAccountService.TypeMoneyWasDeposited}
+	runner := NewRunner(NewBankFundsProjector(), failing)
+
+	// When
+	noWorkersErr := runner.RunParallel(eventStore, 0)
+	err := runner.RunParallel(eventStore, 2)
+	mergeErr := NewBankFundsProjector().Merge(NewFeeIncomeProjector())
+
+	// Then
+	assert.Equal(t, "a parallel run needs at least one worker [workers: 0]", noWorkersErr.Error())
+	assert.Equal(t, "projection failing failed on event 2 (MoneyWasDeposited): projector failed", err.Error())
+	assert.Equal(t, "projection TotalBankFunds cannot merge a *Projections.FeeIncomeProjector", mergeErr.Error())
+}
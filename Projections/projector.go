@@ -0,0 +1,238 @@
+package Projections
+
+import (
+	"errors"
+	"fmt"
+	"github.com/agemmell/banking-cqrs-es-go/EventRegistry"
+	"github.com/agemmell/banking-cqrs-es-go/Seacrest"
+)
+
+type Event = EventRegistry.Event
+
+// Projector: builds a read model from events. Handle is only called with events of the types EventTypes returns, in
+// the order they were persisted.
+type Projector interface {
+	Name() string
+	EventTypes() []string
+	Handle(event Event) error
+	State() interface{} // the read model built so far
+}
+
+// PositionedProjector: a projector that needs to know the global position each event it handles was persisted at. The
+// runner calls HandleAt instead of Handle.
+type PositionedProjector interface {
+	Projector
+	HandleAt(event Event, position uint) error
+}
+
+// Snapshotter: a projector whose state can be saved to a checkpoint and restored from it, so it can carry on from
+// where it left off instead of rebuilding from the first event
+type Snapshotter interface {
+	Projector
+	MarshalState() ([]byte, error)
+	RestoreState(state []byte) error
+}
+
+// Versioned: a Snapshotter that records which version of its logic built each checkpoint. Bump the version whenever a
+// change means read models built before it are wrong, or their checkpoints can no longer be restored; checkpoints of
+// any other version are then rebuilt from the first event instead of restored. Projectors that aren't Versioned are at
+// version 1.
+type Versioned interface {
+	Snapshotter
+	Version() uint
+	NewEmpty() Projector // a projector like this one that has not seen any events, to rebuild into
+}
+
+// versionOf: the version of the projector's logic
+func versionOf(projector Projector) uint {
+	if versioned, ok := projector.(Versioned); ok {
+		return versioned.Version()
+	}
+	return 1
+}
+
+// Runner: feeds events to projectors. Each event is decoded once, however many projectors handle it, and events no
+// projector handles are not decoded at all. Events at or before the runner's position have already been applied and
+// are skipped.
+type Runner struct {
+	projectors  []Projector
+	byEventType map[string][]Projector // <event type> -> projectors that handle it, in the order they were registered
+	position    uint                   // the Order of the last event every projector has seen
+	restored    map[string]uint        // <projector name> -> the Order its restored checkpoint had reached
+	stale       []string               // projectors whose checkpoints were saved by another version
+	checkpoints CheckpointStore
+	every       uint // checkpoint after every this many events, or only at the end of a Run if 0
+}
+
+func NewRunner(projectors ...Projector) *Runner {
+	runner := &Runner{byEventType: map[string][]Projector{}, restored: map[string]uint{}}
+	for _, projector := range projectors {
+		runner.Register(projector)
+	}
+	return runner
+}
+
+// Register: add a projector; it will see events applied from now on
+func (r *Runner) Register(projector Projector) {
+	r.projectors = append(r.projectors, projector)
+	for _, eventType := range projector.EventTypes() {
+		r.byEventType[eventType] = append(r.byEventType[eventType], projector)
+	}
+}
+
+// Projectors: the registered projectors, in the order they were registered
+func (r *Runner) Projectors() []Projector {
+	return r.projectors
+}
+
+// Position: the Order of the last event every projector has seen
+func (r *Runner) Position() uint {
+	return r.position
+}
+
+// ResumeFrom: restore every projector from its checkpoint in the store, and save checkpoints there every `every`
+// events and at the end of each Run. Projectors that have never been checkpointed, or whose checkpoint was saved by
+// another version of the projector, start from the first event. Every projector must be a Snapshotter.
+func (r *Runner) ResumeFrom(checkpoints CheckpointStore, every uint) error {
+	position := uint(0)
+	for i, projector := range r.projectors {
+		snapshotter, ok := projector.(Snapshotter)
+		if !ok {
+			return errors.New(fmt.Sprintf("projection %s cannot be checkpointed", projector.Name()))
+		}
+
+		checkpoint, found, err := checkpoints.Load(projector.Name())
+		if err != nil {
+			return err
+		}
+		if found && checkpoint.version() != versionOf(projector) {
+			r.stale = append(r.stale, projector.Name())
+			found = false
+		}
+		if found {
+			err = snapshotter.RestoreState(checkpoint.State)
+			if err != nil {
+				return errors.New(fmt.Sprintf("cannot restore projection %s: %s", projector.Name(), err))
+			}
+			r.restored[projector.Name()] = checkpoint.Position
+		}
+
+		if i == 0 || r.restored[projector.Name()] < position {
+			position = r.restored[projector.Name()]
+		}
+	}
+
+	r.position = position
+	r.checkpoints = checkpoints
+	r.every = every
+	return nil
+}
+
+// Stale: the projectors ResumeFrom found checkpoints of another version for, which are being rebuilt from the first
+// event
+func (r *Runner) Stale() []string {
+	return r.stale
+}
+
+// Checkpoint: save every projector's state together with the position it has reached
+func (r *Runner) Checkpoint() error {
+	if r.checkpoints == nil {
+		return errors.New("the runner has no checkpoint store")
+	}
+	for _, projector := range r.projectors {
+		state, err := projector.(Snapshotter).MarshalState()
+		if err != nil {
+			return errors.New(fmt.Sprintf("cannot checkpoint projection %s: %s", projector.Name(), err))
+		}
+
+		position := r.position
+		if r.restored[projector.Name()] > position {
+			position = r.restored[projector.Name()]
+		}
+
+		err = r.checkpoints.Save(Checkpoint{Projector: projector.Name(), Version: versionOf(projector), Position: position, State: state})
+		if err != nil {
+			return errors.New(fmt.Sprintf("cannot checkpoint projection %s: %s", projector.Name(), err))
+		}
+	}
+	return nil
+}
+
+// Run: apply every event in the store after the runner's position in a single pass, then checkpoint if the runner
+// resumed from a checkpoint store
+func (r *Runner) Run(eventStore *Seacrest.EventStore) error {
+	envelopes := eventStore.GetAllEvents()
+	err := r.checkRestoredWithin(envelopes)
+	if err != nil {
+		return err
+	}
+
+	for _, envelope := range envelopes {
+		err := r.Apply(envelope)
+		if err != nil {
+			return err
+		}
+	}
+
+	if r.checkpoints == nil {
+		return nil
+	}
+	return r.Checkpoint()
+}
+
+// checkRestoredWithin: fail if any projector was restored from a checkpoint beyond the last of the events
+func (r *Runner) checkRestoredWithin(envelopes []Seacrest.EventEnvelope) error {
+	last := uint(0)
+	if len(envelopes) > 0 {
+		last = envelopes[len(envelopes)-1].Order
+	}
+	for _, projector := range r.projectors {
+		if r.restored[projector.Name()] > last {
+			return errors.New(fmt.Sprintf("the checkpoint for projection %s is at event %d but the event store ends at event %d", projector.Name(), r.restored[projector.Name()], last))
+		}
+	}
+	return nil
+}
+
+// Apply: decode one event and hand it to every projector that handles its type and has not already seen it
+func (r *Runner) Apply(envelope Seacrest.EventEnvelope) error {
+	if envelope.Order <= r.position {
+		return nil
+	}
+
+	var event Event
+	for _, projector := range r.byEventType[envelope.EventType] {
+		if envelope.Order <= r.restored[projector.Name()] {
+			continue
+		}
+
+		if event == nil {
+			var err error
+			event, err = EventRegistry.Decode(envelope)
+			if err != nil {
+				return errors.New(fmt.Sprintf("cannot decode event %d (%s): %s", envelope.Order, envelope.EventType, err))
+			}
+		}
+
+		var err error
+		if positioned, ok := projector.(PositionedProjector); ok {
+			err = positioned.HandleAt(event, envelope.Order)
+		} else {
+			err = projector.Handle(event)
+		}
+		if err != nil {
+			return errors.New(fmt.Sprintf("projection %s failed on event %d (%s): %s", projector.Name(), envelope.Order, envelope.EventType, err))
+		}
+	}
+	r.position = envelope.Order
+
+	if r.checkpoints != nil && r.every > 0 && r.position%r.every == 0 {
+		return r.Checkpoint()
+	}
+	return nil
+}
+
+// runProjector: build a single projector's read model from every event in the store
+func runProjector(eventStore *Seacrest.EventStore, projector Projector) error {
+	return NewRunner(projector).Run(eventStore)
+}
@@ -0,0 +1,186 @@
+package Projections
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/agemmell/banking-cqrs-es-go/CheckingAccountService"
+	"github.com/agemmell/banking-cqrs-es-go/CustomerService"
+	"github.com/agemmell/banking-cqrs-es-go/Seacrest"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// anyCustomer: treats the customers in customerNames as having passed KYC
+type anyCustomer struct{}
+
+var customerNames = map[string]string{"CUST1": "Alex Gemmell", "CUST2": "Sam Smith", "CUST3": "Jo Bloggs", "CUST4": "alexa Jones"}
+
+func (ac anyCustomer) VerifiedName(customerID string) (string, error) {
+	name, ok := customerNames[customerID]
+	if !ok {
+		return "", &CustomerService.UnknownCustomerError{CustomerID: customerID}
+	}
+	return name, nil
+}
+
+// recordingProjector: remembers the events it is given and fails on the event types it is told to
+type recordingProjector struct {
+	name       string
+	eventTypes []string
+	failOn     string
+	handled    []Event
+}
+
+func (rp *recordingProjector) Name() string         { return rp.name }
+func (rp *recordingProjector) EventTypes() []string { return rp.eventTypes }
+func (rp *recordingProjector) State() interface{}   { return rp.handled }
+
+func (rp *recordingProjector) Handle(event Event) error {
+	if event.EventType() == rp.failOn {
+		return errors.New("projector failed")
+	}
+	rp.handled = append(rp.handled, event)
+	return nil
+}
+
+func newEventStore(t *testing.T, commands ...CheckingAccountService.Command) *Seacrest.EventStore {
+	clock := Seacrest.NewFakeClock(time.Date(2021, time.January, 4, 9, 0, 0, 0, time.UTC))
+	clock.AutoAdvance(time.Minute)
+	eventStore := Seacrest.NewEventStore(Seacrest.WithClock(clock))
+	cas := CheckingAccountService.New(eventStore, CheckingAccountService.WithClock(clock), CheckingAccountService.WithCustomerVerifier(anyCustomer{}))
+	for _, command := range commands {
+		assert.Nil(t, cas.HandleCommand(command))
+	}
+	return eventStore
+}
+
+func Test_RunnerFansEventsOutInASinglePass(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	eventStore := newEventStore(t,
+		CheckingAccountService.OpenAccount{ID: "ACC1", CustomerID: "CUST1"},
+		CheckingAccountService.DepositMoney{ID: "ACC1", Amount: 100, ActingAs: CheckingAccountService.SystemPrincipal},
+		CheckingAccountService.WithdrawMoney{ID: "ACC1", Amount: 30, ActingAs: CheckingAccountService.SystemPrincipal},
+	)
+	deposits := &recordingProjector{name: "deposits", eventTypes: []string{CheckingAccountService.TypeMoneyWasDeposited}}
+	transactions := &recordingProjector{name: "transactions", eventTypes: []string{CheckingAccountService.TypeMoneyWasDeposited, CheckingAccountService.TypeMoneyWasWithdrawn}}
+
+	// When
+	err := NewRunner(deposits, transactions).Run(eventStore)
+
+	// Then
+	assert.Nil(t, err)
+	assert.Len(t, deposits.handled, 1)
+	assert.Len(t, transactions.handled, 2)
+	assert.Same(t, deposits.handled[0], transactions.handled[0], "the deposit should have been decoded once and shared")
+	assert.Equal(t, 70, transactions.handled[1].(*CheckingAccountService.MoneyWasWithdrawn).Balance)
+}
+
+func Test_RunnerReportsTheFailingProjectorAndEvent(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	eventStore := newEventStore(t,
+		CheckingAccountService.OpenAccount{ID: "ACC1", CustomerID: "CUST1"},
+		CheckingAccountService.DepositMoney{ID: "ACC1", Amount: 100, ActingAs: CheckingAccountService.SystemPrincipal},
+	)
+	failing := &recordingProjector{name: "failing", eventTypes: []string{CheckingAccountService.TypeMoneyWasDeposited}, failOn: CheckingAccountService.TypeMoneyWasDeposited}
+
+	// When
+	err := NewRunner(failing).Run(eventStore)
+
+	// Then
+	assert.Equal(t, "projection failing failed on event 2 (MoneyWasDeposited): projector failed", err.Error())
+}
+
+func Test_ProjectorsReturnTypedResults(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	eventStore := newEventStore(t,
+		CheckingAccountService.OpenAccount{ID: "ACC1", CustomerID: "CUST1"},
+		CheckingAccountService.OpenAccount{ID: "ACC2", CustomerID: "CUST2"},
+		CheckingAccountService.OpenAccount{ID: "ACC3", CustomerID: "CUST3"},
+		CheckingAccountService.DepositMoney{ID: "ACC1", Amount: 100, ActingAs: CheckingAccountService.SystemPrincipal},
+		CheckingAccountService.DepositMoney{ID: "ACC2", Amount: 250, ActingAs: CheckingAccountService.SystemPrincipal},
+		CheckingAccountService.PlaceHold{ID: "ACC2", HoldID: "H1", Amount: 50, ExpiresAt: time.Date(2021, time.February, 1, 0, 0, 0, 0, time.UTC).UnixNano(), ActingAs: CheckingAccountService.SystemPrincipal},
+		CheckingAccountService.FreezeAccount{ID: "ACC1", Scope: CheckingAccountService.FreezeDebits, ReasonCode: CheckingAccountService.ReasonSuspectedFraud, ActingAs: CheckingAccountService.SystemPrincipal},
+		CheckingAccountService.CloseAccount{ID: "ACC3", ActingAs: CheckingAccountService.SystemPrincipal},
+	)
+	bankFunds := NewBankFundsProjector()
+	accountCounts := NewAccountCountsProjector()
+	highestBalances := NewHighestBalancesProjector()
+	frozenAccounts := NewFrozenAccountsProjector()
+	customerAccounts := NewCustomerAccountsProjector()
+
+	// When
+	err := NewRunner(bankFunds, accountCounts, highestBalances, frozenAccounts, customerAccounts).Run(eventStore)
+
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, BankFunds{Total: 350, Held: 50}, bankFunds.State())
+	assert.Equal(t, AccountCounts{Open: 2, Closed: 1}, accountCounts.State())
+	assert.Equal(t, []AccountBalance{
+		{ID: "ACC2", Name: "Sam Smith", Balance: 250, Held: 50},
+		{ID: "ACC1", Name: "Alex Gemmell", Balance: 100},
+		{ID: "ACC3", Name: "Jo Bloggs"},
+	}, highestBalances.HighestBalances())
+	assert.Len(t, frozenAccounts.FrozenAccounts(), 1)
+	assert.Equal(t, "Alex Gemmell", frozenAccounts.FrozenAccounts()[0].Name)
+	summary := SummariseCustomerAccounts(customerAccounts.CustomerAccounts())
+	assert.Equal(t, 3, summary.Customers)
+	assert.Equal(t, map[int]int{0: 1, 1: 2}, summary.OpenAccountCounts)
+}
+
+func Test_FrozenAccountsKeepWhenAWidenedFreezeBegan(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	eventStore := newEventStore(t,
+		CheckingAccountService.OpenAccount{ID: "ACC1", CustomerID: "CUST1"},
+		CheckingAccountService.FreezeAccount{ID: "ACC1", Scope: CheckingAccountService.FreezeDebits, ReasonCode: CheckingAccountService.ReasonSuspectedFraud, ActingAs: CheckingAccountService.SystemPrincipal},
+		CheckingAccountService.FreezeAccount{ID: "ACC1", Scope: CheckingAccountService.FreezeAll, ReasonCode: CheckingAccountService.ReasonCourtOrder, ActingAs: CheckingAccountService.SystemPrincipal},
+	)
+	frozenAccounts := NewFrozenAccountsProjector()
+
+	// When
+	err := NewRunner(frozenAccounts).Run(eventStore)
+
+	// Then
+	assert.Nil(t, err)
+	firstFreeze := CheckingAccountService.AccountWasFrozen{}
+	assert.Nil(t, json.Unmarshal(eventStore.GetAllEvents()[1].Payload, &firstFreeze))
+	assert.Equal(t, []FrozenAccount{
+		{ID: "ACC1", Name: "Alex Gemmell", Scope: CheckingAccountService.FreezeAll, ReasonCode: CheckingAccountService.ReasonCourtOrder, FrozenSince: firstFreeze.Timestamp},
+	}, frozenAccounts.FrozenAccounts())
+}
+
+func Test_AccountCountsOnlyCountOpenAccountsAsDormant(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	eventStore := newEventStore(t,
+		CheckingAccountService.OpenAccount{ID: "ACC1", CustomerID: "CUST1"},
+		CheckingAccountService.OpenAccount{ID: "ACC2", CustomerID: "CUST2"},
+		CheckingAccountService.MarkAccountDormant{ID: "ACC1", InactivityPeriod: time.Minute, ActingAs: CheckingAccountService.SystemPrincipal},
+		CheckingAccountService.MarkAccountDormant{ID: "ACC2", InactivityPeriod: time.Minute, ActingAs: CheckingAccountService.SystemPrincipal},
+	)
+	accountCounts := NewAccountCountsProjector()
+	assert.Nil(t, runProjector(eventStore, accountCounts))
+	bothDormant := accountCounts.AccountCounts()
+
+	// When
+	handleCommands(t, eventStore, CheckingAccountService.CloseAccount{ID: "ACC1", ActingAs: CheckingAccountService.SystemPrincipal}, CheckingAccountService.CloseAccount{ID: "ACC2", ActingAs: CheckingAccountService.SystemPrincipal})
+	closed := NewAccountCountsProjector()
+	assert.Nil(t, runProjector(eventStore, closed))
+	handleCommands(t, eventStore, CheckingAccountService.ReopenAccount{ID: "ACC2", ActingAs: CheckingAccountService.SystemPrincipal})
+	reopened := NewAccountCountsProjector()
+	assert.Nil(t, runProjector(eventStore, reopened))
+
+	// Then
+	assert.Equal(t, AccountCounts{Open: 2, Dormant: 2}, bothDormant)
+	assert.Equal(t, AccountCounts{Closed: 2}, closed.AccountCounts())
+	assert.Equal(t, AccountCounts{Open: 1, Closed: 1}, reopened.AccountCounts())
+}
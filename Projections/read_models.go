@@ -0,0 +1,64 @@
+package Projections
+
+import (
+	"encoding/json"
+	"github.com/agemmell/banking-cqrs-es-go/ReadModelStore"
+)
+
+// ReadModelWriter: a projector that writes its read model into a ReadModelStore. Its changes are staged in a
+// transaction that is committed together with its checkpoint, so the rows in the store always match the position the
+// checkpoint records.
+type ReadModelWriter interface {
+	Snapshotter
+	Transaction() *ReadModelStore.Transaction // the changes made since the last commit
+}
+
+// ReadModelCheckpointStore: keeps read model writers' checkpoints in the read model store, each committed in the same
+// transaction as the writer's changes. Other projectors' checkpoints are kept in another CheckpointStore.
+type ReadModelCheckpointStore struct {
+	checkpoints *ReadModelStore.Table
+	writers     map[string]ReadModelWriter
+	others      CheckpointStore
+}
+
+func NewReadModelCheckpointStore(store *ReadModelStore.Store, others CheckpointStore, writers ...ReadModelWriter) (*ReadModelCheckpointStore, error) {
+	checkpoints, err := store.DefineTable("checkpoints", func(data []byte) (interface{}, error) {
+		checkpoint := Checkpoint{}
+		err := json.Unmarshal(data, &checkpoint)
+		return checkpoint, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rmcs := &ReadModelCheckpointStore{checkpoints: checkpoints, writers: map[string]ReadModelWriter{}, others: others}
+	for _, writer := range writers {
+		rmcs.writers[writer.Name()] = writer
+	}
+	return rmcs, nil
+}
+
+func (rmcs *ReadModelCheckpointStore) Load(projector string) (Checkpoint, bool, error) {
+	if _, ok := rmcs.writers[projector]; !ok {
+		return rmcs.others.Load(projector)
+	}
+	checkpoint, found := rmcs.checkpoints.Get(projector)
+	if !found {
+		return Checkpoint{}, false, nil
+	}
+	return checkpoint.(Checkpoint), true, nil
+}
+
+// Save: commit a read model writer's changes along with its checkpoint
+func (rmcs *ReadModelCheckpointStore) Save(checkpoint Checkpoint) error {
+	writer, ok := rmcs.writers[checkpoint.Projector]
+	if !ok {
+		return rmcs.others.Save(checkpoint)
+	}
+	transaction := writer.Transaction()
+	err := transaction.Put(rmcs.checkpoints, checkpoint.Projector, checkpoint)
+	if err != nil {
+		return err
+	}
+	return transaction.Commit()
+}
@@ -0,0 +1,214 @@
+package Projections
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/agemmell/banking-cqrs-es-go/CheckingAccountService"
+	"github.com/agemmell/banking-cqrs-es-go/SavingsAccountService"
+	"sort"
+)
+
+// StatementLine: one transaction on an account statement
+type StatementLine struct {
+	Date        int64 // when the transaction happened (unix nanoseconds)
+	Description string
+	Debit       int
+	Credit      int
+	Balance     int // the account's balance after the transaction
+}
+
+// StatementQuery: which of an account's transactions to list. Zero values mean no limit.
+type StatementQuery struct {
+	From     int64 // only transactions at or after this time
+	To       int64 // only transactions before this time
+	Page     int   // the page to list, starting at 1
+	PageSize int   // transactions per page, or every transaction on one page if 0
+}
+
+// Statement: an account's transactions over a period, a page at a time
+type Statement struct {
+	AccountID      string
+	OpeningBalance int // the balance before the first transaction in the period
+	ClosingBalance int // the balance after the last transaction in the period
+	Transactions   int // how many transactions there are in the period, across every page
+	Page           int
+	Pages          int
+	Lines          []StatementLine
+}
+
+// StatementsProjector: every transaction on every account, with a running balance
+type StatementsProjector struct {
+	lines map[string][]StatementLine // <account ID> -> transactions in the order they happened
+}
+
+func NewStatementsProjector() *StatementsProjector {
+	return &StatementsProjector{lines: map[string][]StatementLine{}}
+}
+
+func (sp *StatementsProjector) Name() string {
+	return "AccountStatements"
+}
+
+func (sp *StatementsProjector) EventTypes() []string {
+	return []string{
+		CheckingAccountService.TypeAccountWasOpened,
+		CheckingAccountService.TypeMoneyWasDeposited,
+		CheckingAccountService.TypeMoneyWasWithdrawn,
+		CheckingAccountService.TypeFeeWasCharged,
+		CheckingAccountService.TypeFeeWasRefunded,
+		CheckingAccountService.TypeStandingOrderPaymentWasReturned,
+		CheckingAccountService.TypeHoldWasCaptured,
+		CheckingAccountService.TypeStandingOrderPaymentWasMade,
+		SavingsAccountService.TypeSavingsAccountWasOpened,
+		SavingsAccountService.TypeSavingsMoneyWasDeposited,
+		SavingsAccountService.TypeSavingsMoneyWasWithdrawn,
+	}
+}
+
+// balance: the account's balance after its latest transaction
+func (sp *StatementsProjector) balance(accountID string) int {
+	lines := sp.lines[accountID]
+	if len(lines) == 0 {
+		return 0
+	}
+	return lines[len(lines)-1].Balance
+}
+
+func (sp *StatementsProjector) addLine(accountID string, line StatementLine) {
+	sp.lines[accountID] = append(sp.lines[accountID], line)
+}
+
+func (sp *StatementsProjector) Handle(event Event) error {
+	accountID := event.AggregateID()
+
+	switch event := event.(type) {
+	case *CheckingAccountService.AccountWasOpened, *SavingsAccountService.SavingsAccountWasOpened:
+		if _, ok := sp.lines[accountID]; !ok {
+			sp.lines[accountID] = []StatementLine{}
+		}
+	case *CheckingAccountService.MoneyWasDeposited:
+		sp.addLine(accountID, StatementLine{Date: event.Timestamp, Description: "Deposit", Credit: event.Amount, Balance: sp.balance(accountID) + event.Amount})
+	case *CheckingAccountService.MoneyWasWithdrawn:
+		sp.addLine(accountID, StatementLine{Date: event.Timestamp, Description: "Withdrawal", Debit: event.Amount, Balance: event.Balance})
+	case *CheckingAccountService.FeeWasCharged:
+		sp.addLine(accountID, StatementLine{Date: event.Timestamp, Description: "Fee: " + event.FeeType, Debit: event.Amount, Balance: event.Balance})
+	case *CheckingAccountService.FeeWasRefunded:
+		sp.addLine(accountID, StatementLine{Date: event.Timestamp, Description: "Fee refund: " + event.FeeType, Credit: event.Amount, Balance: event.Balance})
+	case *CheckingAccountService.StandingOrderPaymentWasReturned:
+		sp.addLine(accountID, StatementLine{Date: event.Timestamp, Description: "Standing order returned: " + event.StandingOrderID, Credit: event.Amount, Balance: event.Balance})
+	case *CheckingAccountService.HoldWasCaptured:
+		sp.addLine(accountID, StatementLine{Date: event.Timestamp, Description: "Payment (hold " + event.HoldID + ")", Debit: event.Amount, Balance: event.Balance})
+	case *CheckingAccountService.StandingOrderPaymentWasMade:
+		// the payment is recorded straight after the withdrawal that paid it
+		lines := sp.lines[accountID]
+		if len(lines) > 0 && lines[len(lines)-1].Debit == event.Amount && lines[len(lines)-1].Date == event.Timestamp {
+			lines[len(lines)-1].Description = "Standing order to " + event.PayeeAccountID
+		}
+	case *SavingsAccountService.SavingsMoneyWasDeposited:
+		sp.addLine(accountID, StatementLine{Date: event.Timestamp, Description: "Deposit", Credit: event.Amount, Balance: event.Balance})
+	case *SavingsAccountService.SavingsMoneyWasWithdrawn:
+		sp.addLine(accountID, StatementLine{Date: event.Timestamp, Description: "Withdrawal", Debit: event.Amount, Balance: event.Balance})
+	}
+	return nil
+}
+
+// Statement: the account's transactions that match the query, with the balances either side of the period
+func (sp *StatementsProjector) Statement(accountID string, query StatementQuery) (Statement, error) {
+	lines, ok := sp.lines[accountID]
+	if !ok {
+		return Statement{}, errors.New(fmt.Sprintf("there is no statement for account %s", accountID))
+	}
+	if query.Page < 0 || query.PageSize < 0 {
+		return Statement{}, errors.New(fmt.Sprintf("page and page size cannot be negative [Page: %d, PageSize: %d]", query.Page, query.PageSize))
+	}
+
+	first := 0
+	if query.From != 0 {
+		first = sort.Search(len(lines), func(i int) bool {
+			return lines[i].Date >= query.From
+		})
+	}
+	end := len(lines)
+	if query.To != 0 {
+		end = sort.Search(len(lines), func(i int) bool {
+			return lines[i].Date >= query.To
+		})
+	}
+	if end < first {
+		end = first
+	}
+	period := lines[first:end]
+
+	statement := Statement{AccountID: accountID, Transactions: len(period), Page: query.Page, Pages: 1}
+	if first > 0 {
+		statement.OpeningBalance = lines[first-1].Balance
+	}
+	statement.ClosingBalance = statement.OpeningBalance
+	if len(period) > 0 {
+		statement.ClosingBalance = period[len(period)-1].Balance
+	}
+
+	if statement.Page == 0 {
+		statement.Page = 1
+	}
+	if query.PageSize == 0 {
+		statement.Lines = append([]StatementLine{}, period...)
+		return statement, nil
+	}
+
+	statement.Pages = (len(period) + query.PageSize - 1) / query.PageSize
+	if statement.Pages == 0 {
+		statement.Pages = 1
+	}
+	pageStart := (statement.Page - 1) * query.PageSize
+	if pageStart < len(period) {
+		pageEnd := pageStart + query.PageSize
+		if pageEnd > len(period) {
+			pageEnd = len(period)
+		}
+		statement.Lines = append([]StatementLine{}, period[pageStart:pageEnd]...)
+	}
+	return statement, nil
+}
+
+func (sp *StatementsProjector) State() interface{} {
+	return sp.lines
+}
+
+func (sp *StatementsProjector) MarshalState() ([]byte, error) {
+	return json.Marshal(sp.lines)
+}
+
+func (sp *StatementsProjector) RestoreState(state []byte) error {
+	restored := map[string][]StatementLine{}
+	err := json.Unmarshal(state, &restored)
+	if err != nil {
+		return err
+	}
+	sp.lines = restored
+	return nil
+}
+
+func (sp *StatementsProjector) Version() uint {
+	return 1
+}
+
+func (sp *StatementsProjector) NewEmpty() Projector {
+	return NewStatementsProjector()
+}
+
+func (sp *StatementsProjector) NewPartition() Projector {
+	return NewStatementsProjector()
+}
+
+func (sp *StatementsProjector) Merge(partition Projector) error {
+	other, ok := partition.(*StatementsProjector)
+	if !ok {
+		return partitionMismatch(sp, partition)
+	}
+	for accountID, lines := range other.lines {
+		sp.lines[accountID] = lines
+	}
+	return nil
+}
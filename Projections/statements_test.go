@@ -0,0 +1,121 @@
+package Projections
+
+import (
+	"github.com/agemmell/banking-cqrs-es-go/CheckingAccountService"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func newStatements(t *testing.T) *StatementsProjector {
+	eventStore := newEventStore(t,
+		CheckingAccountService.OpenAccount{ID: "ACC1", CustomerID: "CUST1"},
+		CheckingAccountService.OpenAccount{ID: "ACC2", CustomerID: "CUST2"},
+		CheckingAccountService.DepositMoney{ID: "ACC1", Amount: 1000, ActingAs: CheckingAccountService.SystemPrincipal},
+		CheckingAccountService.WithdrawMoney{ID: "ACC1", Amount: 200, ActingAs: CheckingAccountService.SystemPrincipal},
+		CheckingAccountService.ChargeFee{ID: "ACC1", FeeType: CheckingAccountService.FeeTypeMonthlyMaintenance, ActingAs: CheckingAccountService.SystemPrincipal},
+		CheckingAccountService.PlaceHold{ID: "ACC1", HoldID: "H1", Amount: 100, ExpiresAt: time.Date(2021, time.February, 1, 0, 0, 0, 0, time.UTC).UnixNano(), ActingAs: CheckingAccountService.SystemPrincipal},
+		CheckingAccountService.CaptureHold{ID: "ACC1", HoldID: "H1", Amount: 60, ActingAs: CheckingAccountService.SystemPrincipal},
+		CheckingAccountService.DepositMoney{ID: "ACC2", Amount: 75, ActingAs: CheckingAccountService.SystemPrincipal},
+		CheckingAccountService.DepositMoney{ID: "ACC1", Amount: 40, ActingAs: CheckingAccountService.SystemPrincipal},
+	)
+	statements := NewStatementsProjector()
+	assert.Nil(t, NewRunner(statements).Run(eventStore))
+	return statements
+}
+
+func Test_StatementListsEveryTransactionWithARunningBalance(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	statements := newStatements(t)
+
+	// When
+	statement, err := statements.Statement("ACC1", StatementQuery{})
+
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, 0, statement.OpeningBalance)
+	assert.Equal(t, 775, statement.ClosingBalance)
+	assert.Equal(t, 5, statement.Transactions)
+	assert.Equal(t, 1, statement.Pages)
+	var descriptions []string
+	var balances []int
+	for _, line := range statement.Lines {
+		descriptions = append(descriptions, line.Description)
+		balances = append(balances, line.Balance)
+		assert.Equal(t, 2021, time.Unix(0, line.Date).UTC().Year())
+	}
+	assert.Equal(t, []string{"Deposit", "Withdrawal", "Fee: MonthlyMaintenance", "Payment (hold H1)", "Deposit"}, descriptions)
+	assert.Equal(t, []int{1000, 800, 795, 735, 775}, balances)
+	assert.Equal(t, StatementLine{Date: statement.Lines[1].Date, Description: "Withdrawal", Debit: 200, Balance: 800}, statement.Lines[1])
+	assert.Equal(t, 40, statement.Lines[4].Credit)
+}
+
+func Test_StatementForAPeriod(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	statements := newStatements(t)
+	all, err := statements.Statement("ACC1", StatementQuery{})
+	assert.Nil(t, err)
+
+	// When the period covers the withdrawal and the fee
+	period, err := statements.Statement("ACC1", StatementQuery{From: all.Lines[1].Date, To: all.Lines[3].Date})
+	after, afterErr := statements.Statement("ACC1", StatementQuery{From: all.Lines[4].Date + 1})
+
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, 1000, period.OpeningBalance)
+	assert.Equal(t, 795, period.ClosingBalance)
+	assert.Equal(t, all.Lines[1:3], period.Lines)
+	assert.Nil(t, afterErr)
+	assert.Equal(t, 775, after.OpeningBalance)
+	assert.Equal(t, 775, after.ClosingBalance)
+	assert.Empty(t, after.Lines)
+}
+
+func Test_StatementPages(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	statements := newStatements(t)
+	all, err := statements.Statement("ACC1", StatementQuery{})
+	assert.Nil(t, err)
+
+	// When
+	first, firstErr := statements.Statement("ACC1", StatementQuery{PageSize: 2})
+	last, lastErr := statements.Statement("ACC1", StatementQuery{Page: 3, PageSize: 2})
+	beyond, beyondErr := statements.Statement("ACC1", StatementQuery{Page: 4, PageSize: 2})
+	_, negativeErr := statements.Statement("ACC1", StatementQuery{Page: -1})
+	_, unknownErr := statements.Statement("ACC9", StatementQuery{})
+
+	// Then
+	assert.Nil(t, firstErr)
+	assert.Equal(t, Statement{AccountID: "ACC1", ClosingBalance: 775, Transactions: 5, Page: 1, Pages: 3, Lines: all.Lines[0:2]}, first)
+	assert.Nil(t, lastErr)
+	assert.Equal(t, all.Lines[4:], last.Lines)
+	assert.Nil(t, beyondErr)
+	assert.Empty(t, beyond.Lines)
+	assert.Equal(t, "page and page size cannot be negative [Page: -1, PageSize: 0]", negativeErr.Error())
+	assert.Equal(t, "there is no statement for account ACC9", unknownErr.Error())
+}
+
+func Test_StatementNamesStandingOrderPayments(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	statements := NewStatementsProjector()
+	paidAt := at(2021, time.March, 1)
+	assert.Nil(t, statements.Handle(&CheckingAccountService.AccountWasOpened{ID: "ACC1", Name: "Alex Gemmell"}))
+	assert.Nil(t, statements.Handle(&CheckingAccountService.MoneyWasDeposited{ID: "ACC1", Amount: 500, Timestamp: at(2021, time.February, 1)}))
+
+	// When
+	assert.Nil(t, statements.Handle(&CheckingAccountService.MoneyWasWithdrawn{ID: "ACC1", Amount: 120, Balance: 380, Timestamp: paidAt}))
+	assert.Nil(t, statements.Handle(&CheckingAccountService.StandingOrderPaymentWasMade{ID: "ACC1", StandingOrderID: "SO1", PayeeAccountID: "ACC2", Amount: 120, Timestamp: paidAt}))
+
+	// Then
+	statement, err := statements.Statement("ACC1", StatementQuery{})
+	assert.Nil(t, err)
+	assert.Equal(t, StatementLine{Date: paidAt, Description: "Standing order to ACC2", Debit: 120, Balance: 380}, statement.Lines[1])
+}
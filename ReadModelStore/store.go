@@ -0,0 +1,261 @@
+package ReadModelStore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+const manifestFile = "manifest.json"
+
+// storeFile: the names the store gives its files, <table>.<generation>.json for a table's rows and <file>.<random>.tmp
+// while a file is being written. Opening the store only ever removes files named like these.
+var storeFile = regexp.MustCompile(`^(.+\.[0-9]+\.json|.+\.json\.[0-9]+\.tmp)$`)
+
+// manifest: which file holds each table's rows. Rewriting it is what commits a transaction to disk.
+type manifest struct {
+	Generation uint64            // how many transactions have been committed
+	Tables     map[string]string // <table name> -> the file holding its rows
+}
+
+// Store: an embedded store for read models. Projectors write rows into its tables in transactions, and queries read
+// them back by key or through the tables' secondary indexes without replaying any events. Everything is held in
+// memory; a store opened on a directory also persists every committed transaction there.
+type Store struct {
+	mutex      sync.RWMutex // guards the tables' rows and indexes
+	committing sync.Mutex   // transactions are committed one at a time
+	dir        string       // where the store is persisted, or "" if it is only kept in memory
+	generation uint64
+	files      map[string]string // <table name> -> the file holding its rows
+	tables     map[string]*Table
+}
+
+func NewMemoryStore() *Store {
+	return &Store{files: map[string]string{}, tables: map[string]*Table{}}
+}
+
+// Open: the store persisted in a directory, which is created if it does not exist. Rows of tables that have not been
+// defined yet are kept as they are until they are.
+func Open(dir string) (*Store, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	store := NewMemoryStore()
+	store.dir = dir
+
+	contents, err := ioutil.ReadFile(filepath.Join(dir, manifestFile))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		committed := manifest{}
+		err = json.Unmarshal(contents, &committed)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("the read model store manifest in %s is corrupt: %s", dir, err))
+		}
+		store.generation = committed.Generation
+		for name, file := range committed.Tables {
+			contents, err := ioutil.ReadFile(filepath.Join(dir, file))
+			if err != nil {
+				return nil, err
+			}
+			encoded := map[string]json.RawMessage{}
+			err = json.Unmarshal(contents, &encoded)
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("read model table %s is corrupt: %s", name, err))
+			}
+			store.files[name] = file
+			store.tables[name] = &Table{store: store, name: name, encoded: encoded}
+		}
+	}
+
+	// Files a crash left behind before their transaction's manifest was written. Anything else in the directory is
+	// left alone, in case the store was opened on a directory it shares.
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	current := map[string]bool{manifestFile: true}
+	for _, file := range store.files {
+		current[file] = true
+	}
+	for _, entry := range entries {
+		if !current[entry.Name()] && !entry.IsDir() && storeFile.MatchString(entry.Name()) {
+			_ = os.Remove(filepath.Join(dir, entry.Name()))
+		}
+	}
+	return store, nil
+}
+
+// DefineTable: the table with the name, decoding any rows it already has and building its indexes. Each table can
+// be defined once per store, and must be defined before it is used.
+func (s *Store) DefineTable(name string, decode Decoder, indexes ...Index) (*Table, error) {
+	if name == "" || filepath.Base(name) != name || name == manifestFile {
+		return nil, errors.New(fmt.Sprintf("table name %q cannot be used as a file name", name))
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	table, ok := s.tables[name]
+	if ok && table.decode != nil {
+		return nil, errors.New(fmt.Sprintf("table %s is already defined", name))
+	}
+	if !ok {
+		table = &Table{store: s, name: name, encoded: map[string]json.RawMessage{}}
+	}
+
+	rows := make(map[string]interface{}, len(table.encoded))
+	for key, encoded := range table.encoded {
+		value, err := decode(encoded)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("cannot decode row %s of table %s: %s", key, name, err))
+		}
+		rows[key] = value
+	}
+
+	definedIndexes := map[string]*index{}
+	for _, definition := range indexes {
+		if _, ok := definedIndexes[definition.name]; ok {
+			return nil, errors.New(fmt.Sprintf("table %s has two indexes called %s", name, definition.name))
+		}
+		defined := &index{Index: definition}
+		for key, value := range rows {
+			defined.entries = append(defined.entries, defined.entry(key, value))
+		}
+		sort.Slice(defined.entries, func(i, j int) bool {
+			return defined.entries[i].before(defined.entries[j])
+		})
+		definedIndexes[definition.name] = defined
+	}
+
+	table.decode = decode
+	table.rows = rows
+	table.indexes = definedIndexes
+	s.tables[name] = table
+	return table, nil
+}
+
+// Begin: start a transaction
+func (s *Store) Begin() *Transaction {
+	return &Transaction{store: s, changes: map[*Table]*tableChanges{}}
+}
+
+func (s *Store) commit(changes map[*Table]*tableChanges) error {
+	s.committing.Lock()
+	defer s.committing.Unlock()
+
+	encoded := map[*Table]map[string]json.RawMessage{}
+	for table, tableChanges := range changes {
+		encoded[table] = make(map[string]json.RawMessage, len(tableChanges.put))
+		for key, value := range tableChanges.put {
+			row, err := json.Marshal(value)
+			if err != nil {
+				return errors.New(fmt.Sprintf("cannot encode row %s of table %s: %s", key, table.name, err))
+			}
+			encoded[table][key] = row
+		}
+	}
+
+	if s.dir != "" {
+		err := s.persist(changes, encoded)
+		if err != nil {
+			return err
+		}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for table, tableChanges := range changes {
+		table.apply(tableChanges, encoded[table])
+	}
+	return nil
+}
+
+// persist: write a new file for each changed table, then a manifest naming them. Until the manifest has replaced the
+// old one, opening the store finds the tables as they were before the transaction.
+func (s *Store) persist(changes map[*Table]*tableChanges, encoded map[*Table]map[string]json.RawMessage) error {
+	generation := s.generation + 1
+	files := make(map[string]string, len(s.files)+len(changes))
+	for name, file := range s.files {
+		files[name] = file
+	}
+
+	// Only commits change the tables' encoded rows, so they can be read without the store's lock
+	for table, tableChanges := range changes {
+		rows := make(map[string]json.RawMessage, len(table.encoded)+len(encoded[table]))
+		for key, row := range table.encoded {
+			if !tableChanges.deleted[key] {
+				rows[key] = row
+			}
+		}
+		for key, row := range encoded[table] {
+			rows[key] = row
+		}
+		contents, err := json.Marshal(rows)
+		if err != nil {
+			return err
+		}
+		files[table.name] = fmt.Sprintf("%s.%d.json", table.name, generation)
+		err = writeFile(s.dir, files[table.name], contents)
+		if err != nil {
+			return err
+		}
+	}
+
+	contents, err := json.Marshal(manifest{Generation: generation, Tables: files})
+	if err != nil {
+		return err
+	}
+	err = writeFile(s.dir, manifestFile, contents)
+	if err != nil {
+		return err
+	}
+
+	for name, file := range s.files {
+		if files[name] != file {
+			_ = os.Remove(filepath.Join(s.dir, file))
+		}
+	}
+	s.generation = generation
+	s.files = files
+	return nil
+}
+
+// writeFile: write the file in full or not at all, by writing a temporary file and renaming it into place
+func writeFile(dir string, name string, contents []byte) error {
+	temp, err := ioutil.TempFile(dir, name+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name()) // fails harmlessly once the file has been renamed
+
+	_, err = temp.Write(contents)
+	if err == nil {
+		err = temp.Sync()
+	}
+	closeErr := temp.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(temp.Name(), filepath.Join(dir, name))
+	if err != nil {
+		return err
+	}
+	directory, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer directory.Close()
+	return directory.Sync()
+}
@@ -0,0 +1,249 @@
+package ReadModelStore
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type account struct {
+	ID      string
+	Name    string
+	Status  string
+	Balance int
+}
+
+func decodeAccount(data []byte) (interface{}, error) {
+	decoded := account{}
+	err := json.Unmarshal(data, &decoded)
+	return decoded, err
+}
+
+func defineAccounts(t *testing.T, store *Store) *Table {
+	accounts, err := store.DefineTable("accounts", decodeAccount,
+		StringIndex("name", func(value interface{}) string { return value.(account).Name }),
+		StringIndex("status", func(value interface{}) string { return value.(account).Status }),
+		NumberIndex("balance", func(value interface{}) int { return value.(account).Balance }),
+	)
+	assert.Nil(t, err)
+	return accounts
+}
+
+func putAccounts(t *testing.T, store *Store, accounts *Table, rows ...account) {
+	transaction := store.Begin()
+	for _, row := range rows {
+		assert.Nil(t, transaction.Put(accounts, row.ID, row))
+	}
+	assert.Nil(t, transaction.Commit())
+}
+
+func ids(values []interface{}) []string {
+	var found []string
+	for _, value := range values {
+		found = append(found, value.(account).ID)
+	}
+	return found
+}
+
+func Test_IndexesFollowCommittedChanges(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	store := NewMemoryStore()
+	accounts := defineAccounts(t, store)
+	putAccounts(t, store, accounts,
+		account{ID: "ACC1", Name: "alex gemmell", Status: "active", Balance: 100},
+		account{ID: "ACC2", Name: "sam smith", Status: "active", Balance: 2500},
+		account{ID: "ACC3", Name: "alexa jones", Status: "closed", Balance: 0},
+		account{ID: "ACC4", Name: "jo bloggs", Status: "dormant", Balance: 700},
+	)
+
+	// When
+	transaction := store.Begin()
+	assert.Nil(t, transaction.Put(accounts, "ACC2", account{ID: "ACC2", Name: "sam smith", Status: "frozen", Balance: 50}))
+	assert.Nil(t, transaction.Delete(accounts, "ACC4"))
+	assert.Nil(t, transaction.Commit())
+
+	// Then
+	named, err := accounts.Prefix("name", "alex")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"ACC1", "ACC3"}, ids(named))
+	active, err := accounts.Equal("status", "active")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"ACC1"}, ids(active))
+	between, err := accounts.Between("balance", 0, 100)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"ACC3", "ACC2", "ACC1"}, ids(between))
+	dormant, err := accounts.Equal("status", "dormant")
+	assert.Nil(t, err)
+	assert.Empty(t, dormant)
+	assert.Equal(t, 3, accounts.Len())
+	_, found := accounts.Get("ACC4")
+	assert.False(t, found)
+}
+
+func Test_TransactionsAreInvisibleUntilCommitted(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	store := NewMemoryStore()
+	accounts := defineAccounts(t, store)
+	putAccounts(t, store, accounts, account{ID: "ACC1", Name: "alex gemmell", Status: "active", Balance: 100})
+
+	// When
+	transaction := store.Begin()
+	assert.Nil(t, transaction.Put(accounts, "ACC1", account{ID: "ACC1", Name: "alex gemmell", Status: "active", Balance: 90}))
+	assert.Nil(t, transaction.Put(accounts, "ACC2", account{ID: "ACC2", Name: "sam smith", Status: "active"}))
+	ownView, _ := transaction.Get(accounts, "ACC1")
+	committedView, _ := accounts.Get("ACC1")
+	_, newRowVisible := accounts.Get("ACC2")
+	transaction.Rollback()
+	afterRollback, _ := accounts.Get("ACC1")
+	finishedErr := transaction.Put(accounts, "ACC3", account{ID: "ACC3"})
+
+	// Then
+	assert.Equal(t, 90, ownView.(account).Balance)
+	assert.Equal(t, 100, committedView.(account).Balance)
+	assert.False(t, newRowVisible)
+	assert.Equal(t, 100, afterRollback.(account).Balance)
+	assert.True(t, transaction.Done())
+	assert.Equal(t, "the transaction has already been committed or rolled back", finishedErr.Error())
+}
+
+func Test_CommittedTransactionsSurviveReopening(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	dir, err := ioutil.TempDir("", "readmodels")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	store, err := Open(dir)
+	assert.Nil(t, err)
+	accounts := defineAccounts(t, store)
+	notes, err := store.DefineTable("notes", func(data []byte) (interface{}, error) {
+		var note string
+		err := json.Unmarshal(data, &note)
+		return note, err
+	})
+	assert.Nil(t, err)
+	putAccounts(t, store, accounts,
+		account{ID: "ACC1", Name: "alex gemmell", Status: "active", Balance: 100},
+		account{ID: "ACC2", Name: "sam smith", Status: "active", Balance: 2500},
+	)
+	transaction := store.Begin()
+	assert.Nil(t, transaction.Put(notes, "ACC1", "called about fees"))
+	assert.Nil(t, transaction.Commit())
+
+	// When the store is reopened without defining the notes, and the accounts change again
+	reopened, err := Open(dir)
+	assert.Nil(t, err)
+	reopenedAccounts := defineAccounts(t, reopened)
+	putAccounts(t, reopened, reopenedAccounts, account{ID: "ACC3", Name: "jo bloggs", Status: "closed"})
+	again, err := Open(dir)
+	assert.Nil(t, err)
+	againAccounts := defineAccounts(t, again)
+	againNotes, err := again.DefineTable("notes", func(data []byte) (interface{}, error) {
+		var note string
+		err := json.Unmarshal(data, &note)
+		return note, err
+	})
+	assert.Nil(t, err)
+
+	// Then
+	rich, err := againAccounts.Between("balance", 1000, 5000)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"ACC2"}, ids(rich))
+	assert.Equal(t, 3, againAccounts.Len())
+	note, found := againNotes.Get("ACC1")
+	assert.True(t, found)
+	assert.Equal(t, "called about fees", note)
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "accounts.3.json"), filepath.Join(dir, "manifest.json"), filepath.Join(dir, "notes.2.json")}, files)
+}
+
+func Test_ACrashBeforeTheManifestIsWrittenLosesOnlyThatTransaction(t *testing.T) {
+	t.Parallel()
+
+	// Given a committed transaction, and the files of one that crashed before its manifest was written
+	dir, err := ioutil.TempDir("", "readmodels")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	store, err := Open(dir)
+	assert.Nil(t, err)
+	putAccounts(t, store, defineAccounts(t, store), account{ID: "ACC1", Name: "alex gemmell", Status: "active", Balance: 100})
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "accounts.2.json"), []byte(`{"ACC1": {"ID": "ACC1", "Balance": 5}}`), 0644))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "manifest.json.123.tmp"), []byte(`{"Generation": 2, "Tab`), 0644))
+
+	// When
+	reopened, err := Open(dir)
+	assert.Nil(t, err)
+	accounts := defineAccounts(t, reopened)
+
+	// Then
+	row, found := accounts.Get("ACC1")
+	assert.True(t, found)
+	assert.Equal(t, 100, row.(account).Balance)
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "accounts.1.json"), filepath.Join(dir, "manifest.json")}, files)
+}
+
+func Test_OpeningAStoreOnlyRemovesItsOwnFiles(t *testing.T) {
+	t.Parallel()
+
+	// Given a store sharing its directory with files it did not write
+	dir, err := ioutil.TempDir("", "readmodels")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	store, err := Open(dir)
+	assert.Nil(t, err)
+	putAccounts(t, store, defineAccounts(t, store), account{ID: "ACC1", Name: "alex gemmell", Status: "active", Balance: 100})
+	for _, name := range []string{"notes.txt", "accounts.json", "report.2021.csv", "backup.tmp"} {
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, name), []byte("keep me"), 0644))
+	}
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "accounts.2.json.456.tmp"), []byte(`{"ACC1": {`), 0644))
+
+	// When
+	_, err = Open(dir)
+
+	// Then
+	assert.Nil(t, err)
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	assert.Nil(t, err)
+	var names []string
+	for _, file := range files {
+		names = append(names, filepath.Base(file))
+	}
+	assert.Equal(t, []string{"accounts.1.json", "accounts.json", "backup.tmp", "manifest.json", "notes.txt", "report.2021.csv"}, names)
+}
+
+func Test_TablesAndIndexesMustBeDefined(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	store := NewMemoryStore()
+	accounts := defineAccounts(t, store)
+	other := defineAccounts(t, NewMemoryStore())
+
+	// When
+	_, definedTwiceErr := store.DefineTable("accounts", decodeAccount)
+	_, badNameErr := store.DefineTable("../accounts", decodeAccount)
+	_, twoIndexesErr := store.DefineTable("holders", decodeAccount, NumberIndex("age", nil), NumberIndex("age", nil))
+	_, unknownIndexErr := accounts.Equal("postcode", "EH1")
+	_, stringIndexErr := accounts.Between("name", 0, 10)
+	_, numberIndexErr := accounts.Prefix("balance", "1")
+	otherStoreErr := store.Begin().Put(other, "ACC1", account{})
+
+	// Then
+	assert.Equal(t, "table accounts is already defined", definedTwiceErr.Error())
+	assert.Equal(t, `table name "../accounts" cannot be used as a file name`, badNameErr.Error())
+	assert.Equal(t, "table holders has two indexes called age", twoIndexesErr.Error())
+	assert.Equal(t, "table accounts has no index postcode", unknownIndexErr.Error())
+	assert.Equal(t, "index name on table accounts is not a number index", stringIndexErr.Error())
+	assert.Equal(t, "index balance on table accounts is not a string index", numberIndexErr.Error())
+	assert.Equal(t, "table accounts belongs to another store", otherStoreErr.Error())
+}
@@ -0,0 +1,202 @@
+package ReadModelStore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Decoder: turns a row read back from disk into the value that was put
+type Decoder func(data []byte) (interface{}, error)
+
+// Index: a secondary index on a table. A string index finds rows by an exact value or a prefix, and a number index
+// finds rows in a range.
+type Index struct {
+	name   string
+	text   func(value interface{}) string // set for string indexes
+	number func(value interface{}) int    // set for number indexes
+}
+
+func StringIndex(name string, key func(value interface{}) string) Index {
+	return Index{name: name, text: key}
+}
+
+func NumberIndex(name string, key func(value interface{}) int) Index {
+	return Index{name: name, number: key}
+}
+
+// indexEntry: one row's key in an index
+type indexEntry struct {
+	text   string
+	number int
+	rowKey string
+}
+
+func (ie indexEntry) before(other indexEntry) bool {
+	if ie.text != other.text {
+		return ie.text < other.text
+	}
+	if ie.number != other.number {
+		return ie.number < other.number
+	}
+	return ie.rowKey < other.rowKey
+}
+
+// index: an Index's entries for every row, in order
+type index struct {
+	Index
+	entries []indexEntry
+}
+
+func (i *index) entry(rowKey string, value interface{}) indexEntry {
+	if i.text != nil {
+		return indexEntry{text: i.text(value), rowKey: rowKey}
+	}
+	return indexEntry{number: i.number(value), rowKey: rowKey}
+}
+
+// update: replace the entries of changed rows. Rows that are no longer in the table have no value. Costs
+// O(rows + changes log changes), so committing a handful of changes to a large table stays cheap.
+func (i *index) update(changed map[string]interface{}, deleted map[string]bool) {
+	var added []indexEntry
+	for rowKey, value := range changed {
+		added = append(added, i.entry(rowKey, value))
+	}
+	sort.Slice(added, func(first, second int) bool {
+		return added[first].before(added[second])
+	})
+
+	merged := make([]indexEntry, 0, len(i.entries)+len(added))
+	next := 0
+	for _, existing := range i.entries {
+		if _, ok := changed[existing.rowKey]; ok || deleted[existing.rowKey] {
+			continue
+		}
+		for next < len(added) && added[next].before(existing) {
+			merged = append(merged, added[next])
+			next++
+		}
+		merged = append(merged, existing)
+	}
+	i.entries = append(merged, added[next:]...)
+}
+
+// Table: rows of one kind of value, each with a unique key, and the table's secondary indexes. Rows change only when
+// a Transaction is committed; reads see every committed change and nothing else.
+type Table struct {
+	store   *Store
+	name    string
+	decode  Decoder
+	rows    map[string]interface{}
+	encoded map[string]json.RawMessage // the rows as they are persisted
+	indexes map[string]*index
+}
+
+func (t *Table) Name() string {
+	return t.name
+}
+
+// Store: the store the table belongs to
+func (t *Table) Store() *Store {
+	return t.store
+}
+
+// Get: the row with the key
+func (t *Table) Get(key string) (interface{}, bool) {
+	t.store.mutex.RLock()
+	defer t.store.mutex.RUnlock()
+	value, ok := t.rows[key]
+	return value, ok
+}
+
+// Len: how many rows the table has
+func (t *Table) Len() int {
+	t.store.mutex.RLock()
+	defer t.store.mutex.RUnlock()
+	return len(t.rows)
+}
+
+func (t *Table) index(name string, text bool) (*index, error) {
+	found, ok := t.indexes[name]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("table %s has no index %s", t.name, name))
+	}
+	if text && found.text == nil {
+		return nil, errors.New(fmt.Sprintf("index %s on table %s is not a string index", name, t.name))
+	}
+	if !text && found.number == nil {
+		return nil, errors.New(fmt.Sprintf("index %s on table %s is not a number index", name, t.name))
+	}
+	return found, nil
+}
+
+// scan: the rows of the entries from the first one at or after `from` while they match, in index order
+func (t *Table) scan(found *index, from indexEntry, matches func(entry indexEntry) bool) []interface{} {
+	first := sort.Search(len(found.entries), func(i int) bool {
+		return !found.entries[i].before(from)
+	})
+	var values []interface{}
+	for _, entry := range found.entries[first:] {
+		if !matches(entry) {
+			break
+		}
+		values = append(values, t.rows[entry.rowKey])
+	}
+	return values
+}
+
+// Equal: the rows whose string index value is exactly `text`, in key order
+func (t *Table) Equal(indexName string, text string) ([]interface{}, error) {
+	t.store.mutex.RLock()
+	defer t.store.mutex.RUnlock()
+	found, err := t.index(indexName, true)
+	if err != nil {
+		return nil, err
+	}
+	return t.scan(found, indexEntry{text: text}, func(entry indexEntry) bool {
+		return entry.text == text
+	}), nil
+}
+
+// Prefix: the rows whose string index value starts with `prefix`, in index order
+func (t *Table) Prefix(indexName string, prefix string) ([]interface{}, error) {
+	t.store.mutex.RLock()
+	defer t.store.mutex.RUnlock()
+	found, err := t.index(indexName, true)
+	if err != nil {
+		return nil, err
+	}
+	return t.scan(found, indexEntry{text: prefix}, func(entry indexEntry) bool {
+		return strings.HasPrefix(entry.text, prefix)
+	}), nil
+}
+
+// Between: the rows whose number index value is from `min` to `max` inclusive, lowest first
+func (t *Table) Between(indexName string, min int, max int) ([]interface{}, error) {
+	t.store.mutex.RLock()
+	defer t.store.mutex.RUnlock()
+	found, err := t.index(indexName, false)
+	if err != nil {
+		return nil, err
+	}
+	return t.scan(found, indexEntry{number: min}, func(entry indexEntry) bool {
+		return entry.number <= max
+	}), nil
+}
+
+// apply: make a committed transaction's changes to the table. The store's write lock must be held.
+func (t *Table) apply(changes *tableChanges, encoded map[string]json.RawMessage) {
+	for rowKey := range changes.deleted {
+		delete(t.rows, rowKey)
+		delete(t.encoded, rowKey)
+	}
+	for rowKey, value := range changes.put {
+		t.rows[rowKey] = value
+		t.encoded[rowKey] = encoded[rowKey]
+	}
+	for _, changedIndex := range t.indexes {
+		changedIndex.update(changes.put, changes.deleted)
+	}
+}
@@ -0,0 +1,98 @@
+package ReadModelStore
+
+import (
+	"errors"
+	"fmt"
+)
+
+// tableChanges: the rows a transaction puts into and deletes from one table. A row is never in both.
+type tableChanges struct {
+	put     map[string]interface{}
+	deleted map[string]bool
+}
+
+// Transaction: changes to any of a store's tables that become visible, and are persisted, together when committed.
+// Nothing is visible to readers of the tables until then, but the transaction's own Get sees its changes. A
+// transaction is not safe to use from several goroutines.
+type Transaction struct {
+	store   *Store
+	changes map[*Table]*tableChanges
+	done    bool
+}
+
+func (t *Transaction) tableChanges(table *Table) (*tableChanges, error) {
+	if t.done {
+		return nil, errors.New("the transaction has already been committed or rolled back")
+	}
+	if table.store != t.store {
+		return nil, errors.New(fmt.Sprintf("table %s belongs to another store", table.name))
+	}
+	if table.decode == nil {
+		return nil, errors.New(fmt.Sprintf("table %s has not been defined", table.name))
+	}
+	changes, ok := t.changes[table]
+	if !ok {
+		changes = &tableChanges{put: map[string]interface{}{}, deleted: map[string]bool{}}
+		t.changes[table] = changes
+	}
+	return changes, nil
+}
+
+// Put: add the row, or replace it if the table already has a row with the key
+func (t *Transaction) Put(table *Table, key string, value interface{}) error {
+	changes, err := t.tableChanges(table)
+	if err != nil {
+		return err
+	}
+	delete(changes.deleted, key)
+	changes.put[key] = value
+	return nil
+}
+
+// Delete: remove the row with the key, if there is one
+func (t *Transaction) Delete(table *Table, key string) error {
+	changes, err := t.tableChanges(table)
+	if err != nil {
+		return err
+	}
+	delete(changes.put, key)
+	changes.deleted[key] = true
+	return nil
+}
+
+// Get: the row with the key, including the transaction's own changes
+func (t *Transaction) Get(table *Table, key string) (interface{}, bool) {
+	if changes, ok := t.changes[table]; ok {
+		if value, ok := changes.put[key]; ok {
+			return value, true
+		}
+		if changes.deleted[key] {
+			return nil, false
+		}
+	}
+	return table.Get(key)
+}
+
+// Done: whether the transaction has been committed or rolled back
+func (t *Transaction) Done() bool {
+	return t.done
+}
+
+// Commit: persist the changes and then make them visible. If they cannot be persisted the tables are left as they
+// were. Either way the transaction is finished.
+func (t *Transaction) Commit() error {
+	if t.done {
+		return errors.New("the transaction has already been committed or rolled back")
+	}
+	t.done = true
+	if len(t.changes) == 0 {
+		return nil
+	}
+	return t.store.commit(t.changes)
+}
+
+// Rollback: discard the changes
+func (t *Transaction) Rollback() {
+	t.done = true
+	t.changes = nil
+}
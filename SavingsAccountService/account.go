@@ -0,0 +1,286 @@
+package SavingsAccountService
+
+import (
+	"errors"
+	"fmt"
+	"github.com/agemmell/banking-cqrs-es-go/EventRegistry"
+	"github.com/agemmell/banking-cqrs-es-go/ProductCatalog"
+	"reflect"
+	"time"
+)
+
+type Event = EventRegistry.Event
+
+// SystemPrincipal: the bank itself, acting on its own behalf. It is the same principal checking accounts know as
+// CheckingAccountService.SystemPrincipal.
+const SystemPrincipal = "system"
+
+// notice: a promise to withdraw money once the product's notice period has passed
+type notice struct {
+	amount        int
+	availableFrom int64
+}
+
+type Account struct {
+	id                 string
+	customerID         string
+	name               string
+	product            string
+	balance            int
+	version            uint
+	newEvents          []Event
+	open               bool
+	rules              ProductCatalog.Product
+	withdrawalsByMonth map[string]int    // <year-month> -> withdrawals made
+	notices            map[string]notice // <notice ID> -> notice
+	clock              Clock
+}
+
+func (a *Account) AggregateID() string {
+	return a.id
+}
+
+func (a *Account) Version() uint {
+	return a.version
+}
+
+func (a *Account) Product() string {
+	return a.product
+}
+
+func (a *Account) Balance() int {
+	return a.balance
+}
+
+// Authorize: check that a principal may act on the account. Every command must name the principal it is acting as;
+// only the account's holder and the bank, acting as SystemPrincipal, may act on it.
+func (a *Account) Authorize(principal string) error {
+	if principal == "" {
+		return errors.New(fmt.Sprintf("no principal is acting on savings account %s", a.id))
+	}
+	if principal == SystemPrincipal || principal == a.customerID {
+		return nil
+	}
+	return errors.New(fmt.Sprintf("%s is not the holder of savings account %s", principal, a.id))
+}
+
+// SetRules: the savings rules of the account's product
+func (a *Account) SetRules(rules ProductCatalog.Product) {
+	a.rules = rules
+}
+
+// SetClock: the clock command handlers use to timestamp events and evaluate time-dependent rules
+func (a *Account) SetClock(clock Clock) {
+	a.clock = clock
+}
+
+func (a *Account) now() int64 {
+	if a.clock == nil {
+		return time.Now().UnixNano()
+	}
+	return a.clock.Now().UnixNano()
+}
+
+func (a *Account) raiseEvent(event Event) error {
+	err := a.ApplyEvent(event)
+	if err != nil {
+		return err
+	}
+
+	a.RecordNewEvent(event)
+	return nil
+}
+
+func (a *Account) RecordNewEvent(event Event) {
+	a.newEvents = append(a.newEvents, event)
+}
+
+func (a *Account) GetNewEvents() []Event {
+	return a.newEvents
+}
+
+// ApplyEvent: Change aggregate state according to event type
+func (a *Account) ApplyEvent(event Event) error {
+	switch eventType := event.(type) {
+	case *SavingsAccountWasOpened:
+		a.id = eventType.ID
+		a.customerID = eventType.CustomerID
+		a.name = eventType.Name
+		a.product = eventType.Product
+		a.open = true
+		a.balance = 0
+		a.withdrawalsByMonth = map[string]int{}
+		a.notices = map[string]notice{}
+	case *SavingsMoneyWasDeposited:
+		a.balance += eventType.Amount
+	case *SavingsWithdrawalNoticeWasGiven:
+		a.notices[eventType.NoticeID] = notice{amount: eventType.Amount, availableFrom: eventType.AvailableFrom}
+	case *SavingsMoneyWasWithdrawn:
+		a.balance -= eventType.Amount
+		a.withdrawalsByMonth[yearMonth(eventType.Timestamp)]++
+		delete(a.notices, eventType.NoticeID)
+	case *SavingsAccountWasClosed:
+		a.open = false
+	default:
+		eventStruct := reflect.TypeOf(eventType).String()
+		return errors.New(fmt.Sprintf("unknown event %s", eventStruct))
+	}
+	a.version++
+	return nil
+}
+
+// LoadFromEvents: Return aggregate to state from past events without triggering side effects
+func (a *Account) LoadFromEvents(events []Event) error {
+	for _, event := range events {
+		err := a.ApplyEvent(event)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Command Handlers: protect aggregate invariants before throwing an event
+
+// OpenAccount: open a new savings account held by a customer
+func (a *Account) OpenAccount(id string, customerID string, name string, product string) error {
+
+	if a.version > 0 {
+		return errors.New(fmt.Sprintf("cannot open an already open account [account: %+v]", a))
+	}
+
+	if customerID == SystemPrincipal {
+		return errors.New(fmt.Sprintf("the bank cannot be the holder of savings account %s", id))
+	}
+
+	event := SavingsAccountWasOpened{
+		ID:         id,
+		CustomerID: customerID,
+		Name:       name,
+		Product:    product,
+		Timestamp:  a.now(),
+	}
+
+	return a.raiseEvent(&event)
+}
+
+// DepositMoney: deposit money into a savings account
+func (a *Account) DepositMoney(amount int) error {
+
+	if a.open == false {
+		return errors.New(fmt.Sprintf("cannot deposit money into an unopened account [account: %+v]", a))
+	}
+
+	if amount <= 0 {
+		return errors.New(fmt.Sprintf("deposit Amount must be greater than 0 [Amount: %+v]", amount))
+	}
+
+	event := SavingsMoneyWasDeposited{
+		ID:        a.id,
+		Amount:    amount,
+		Balance:   a.balance + amount,
+		Timestamp: a.now(),
+	}
+
+	return a.raiseEvent(&event)
+}
+
+// GiveWithdrawalNotice: start the notice period before money can be withdrawn from a notice account
+func (a *Account) GiveWithdrawalNotice(noticeID string, amount int) error {
+
+	if a.open == false {
+		return errors.New(fmt.Sprintf("cannot give notice on an unopened account [account: %+v]", a))
+	}
+
+	if a.rules.NoticePeriodDays == 0 {
+		return errors.New(fmt.Sprintf("the %s product does not require notice of withdrawals", a.product))
+	}
+
+	if _, ok := a.notices[noticeID]; ok || noticeID == "" {
+		return errors.New(fmt.Sprintf("notice ID must be new and non-empty [NoticeID: %s]", noticeID))
+	}
+
+	if amount <= 0 || amount > a.balance {
+		return errors.New(fmt.Sprintf("notice Amount must be between 1 and the balance [Amount: %d, Balance: %d]", amount, a.balance))
+	}
+
+	now := a.now()
+	event := SavingsWithdrawalNoticeWasGiven{
+		ID:            a.id,
+		NoticeID:      noticeID,
+		Amount:        amount,
+		AvailableFrom: time.Unix(0, now).AddDate(0, 0, a.rules.NoticePeriodDays).UnixNano(),
+		Timestamp:     now,
+	}
+
+	return a.raiseEvent(&event)
+}
+
+// WithdrawMoney: withdraw money, subject to the product's monthly withdrawal allowance and notice period
+func (a *Account) WithdrawMoney(amount int, noticeID string) error {
+
+	if a.open == false {
+		return errors.New(fmt.Sprintf("cannot withdraw money from an unopened account [account: %+v]", a))
+	}
+
+	if amount <= 0 {
+		return errors.New(fmt.Sprintf("withdrawal Amount must be greater than 0 [Amount: %+v]", amount))
+	}
+
+	now := a.now()
+	if a.rules.MaxWithdrawalsPerMonth > 0 && a.withdrawalsByMonth[yearMonth(now)] >= a.rules.MaxWithdrawalsPerMonth {
+		return errors.New(fmt.Sprintf("the %s product allows %d withdrawals a month [account: %+v]", a.product, a.rules.MaxWithdrawalsPerMonth, a))
+	}
+
+	if a.rules.NoticePeriodDays > 0 {
+		notice, ok := a.notices[noticeID]
+		if !ok {
+			return errors.New(fmt.Sprintf("the %s product requires %d days notice of withdrawals", a.product, a.rules.NoticePeriodDays))
+		}
+		if now < notice.availableFrom {
+			return errors.New(fmt.Sprintf("notice %s has not yet served its notice period [AvailableFrom: %d]", noticeID, notice.availableFrom))
+		}
+		if amount > notice.amount {
+			return errors.New(fmt.Sprintf("cannot withdraw more than notice %s was given for [Amount: %d, Notice: %d]", noticeID, amount, notice.amount))
+		}
+	} else {
+		noticeID = ""
+	}
+
+	if amount > a.balance {
+		return errors.New(fmt.Sprintf("insufficient funds [Amount: %d, Balance: %d]", amount, a.balance))
+	}
+
+	event := SavingsMoneyWasWithdrawn{
+		ID:        a.id,
+		Amount:    amount,
+		Balance:   a.balance - amount,
+		NoticeID:  noticeID,
+		Timestamp: now,
+	}
+
+	return a.raiseEvent(&event)
+}
+
+// CloseAccount: close the savings account once it has been emptied
+func (a *Account) CloseAccount() error {
+
+	if a.open == false {
+		return errors.New(fmt.Sprintf("cannot close a closed account [account: %+v]", a))
+	}
+
+	if a.balance != 0 {
+		return errors.New(fmt.Sprintf("cannot close an account with a balance [account: %+v]", a))
+	}
+
+	event := SavingsAccountWasClosed{
+		ID:        a.id,
+		Timestamp: a.now(),
+	}
+
+	return a.raiseEvent(&event)
+}
+
+func yearMonth(timestamp int64) string {
+	return time.Unix(0, timestamp).UTC().Format("2006-01")
+}
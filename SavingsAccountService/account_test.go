@@ -0,0 +1,107 @@
+package SavingsAccountService
+
+import (
+	"github.com/agemmell/banking-cqrs-es-go/ProductCatalog"
+	"github.com/agemmell/banking-cqrs-es-go/Scenario"
+	"github.com/agemmell/banking-cqrs-es-go/Seacrest"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func openedAccount(t *testing.T, product string, clock Clock) *Account {
+	rules, err := ProductCatalog.Default().Get(product, ProductCatalog.KindSavings)
+	assert.Nil(t, err)
+
+	account := Account{}
+	account.SetClock(clock)
+	account.SetRules(rules)
+	assert.Nil(t, account.OpenAccount("ABCD", "CUST1", "Alex Gemmell", product))
+	assert.Nil(t, account.DepositMoney(1000))
+	return &account
+}
+
+func TestAccount_EasyAccessWithdrawalsPerMonth(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	clock := Seacrest.NewFakeClock(time.Date(2021, time.March, 1, 9, 0, 0, 0, time.UTC))
+	account := openedAccount(t, ProductCatalog.EasyAccessSavings, clock)
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, account.WithdrawMoney(10, ""))
+	}
+
+	// When
+	err := account.WithdrawMoney(10, "")
+	clock.Set(clock.Now().AddDate(0, 1, 0))
+	nextMonthErr := account.WithdrawMoney(10, "")
+
+	// Then
+	assert.Contains(t, err.Error(), "the EasyAccessSavings product allows 3 withdrawals a month")
+	assert.Nil(t, nextMonthErr)
+	assert.Equal(t, 960, account.Balance())
+}
+
+func TestAccount_NoticeWithdrawals(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	clock := Seacrest.NewFakeClock(time.Date(2021, time.March, 1, 9, 0, 0, 0, time.UTC))
+	account := openedAccount(t, ProductCatalog.NinetyDayNoticeSavings, clock)
+
+	// When
+	withoutNoticeErr := account.WithdrawMoney(100, "")
+	noticeErr := account.GiveWithdrawalNotice("N1", 100)
+	clock.Set(clock.Now().AddDate(0, 0, 89))
+	tooSoonErr := account.WithdrawMoney(100, "N1")
+	clock.Set(clock.Now().AddDate(0, 0, 1))
+	tooMuchErr := account.WithdrawMoney(101, "N1")
+	err := account.WithdrawMoney(100, "N1")
+	reusedErr := account.WithdrawMoney(100, "N1")
+
+	// Then
+	assert.Equal(t, "the NinetyDayNoticeSavings product requires 90 days notice of withdrawals", withoutNoticeErr.Error())
+	assert.Nil(t, noticeErr)
+	assert.Contains(t, tooSoonErr.Error(), "notice N1 has not yet served its notice period")
+	assert.Equal(t, "cannot withdraw more than notice N1 was given for [Amount: 101, Notice: 100]", tooMuchErr.Error())
+	assert.Nil(t, err)
+	assert.NotNil(t, reusedErr)
+	assert.Equal(t, 900, account.Balance())
+}
+
+func TestAccount_SavingsInsufficientFundsAndClose(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	clock := Seacrest.NewFakeClock(time.Date(2021, time.March, 1, 9, 0, 0, 0, time.UTC))
+	account := openedAccount(t, ProductCatalog.EasyAccessSavings, clock)
+
+	// When
+	insufficientErr := account.WithdrawMoney(1001, "")
+	withBalanceErr := account.CloseAccount()
+	assert.Nil(t, account.WithdrawMoney(1000, ""))
+	err := account.CloseAccount()
+
+	// Then
+	assert.Equal(t, "insufficient funds [Amount: 1001, Balance: 1000]", insufficientErr.Error())
+	assert.NotNil(t, withBalanceErr)
+	assert.Nil(t, err)
+	assert.NotNil(t, account.DepositMoney(10))
+}
+
+func TestScenario_NoticeMustBeGivenBeforeWithdrawal(t *testing.T) {
+	t.Parallel()
+
+	rules, err := ProductCatalog.Default().Get(ProductCatalog.NinetyDayNoticeSavings, ProductCatalog.KindSavings)
+	assert.Nil(t, err)
+	account := &Account{}
+	account.SetRules(rules)
+
+	Scenario.ForAggregate(t, account).
+		Given(
+			&SavingsAccountWasOpened{ID: "ABCD", CustomerID: "CUST1", Name: "Alex Gemmell", Product: ProductCatalog.NinetyDayNoticeSavings},
+			&SavingsMoneyWasDeposited{ID: "ABCD", Amount: 500, Balance: 500},
+		).
+		When(func() error { return account.WithdrawMoney(100, "") }).
+		ThenError("the NinetyDayNoticeSavings product requires 90 days notice of withdrawals")
+}
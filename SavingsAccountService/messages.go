@@ -0,0 +1,134 @@
+package SavingsAccountService
+
+import "github.com/agemmell/banking-cqrs-es-go/EventRegistry"
+
+// Commands
+
+type OpenAccount struct {
+	ID         string
+	CustomerID string // the verified customer who will hold the account, in their registered name
+	Product    string
+}
+type DepositMoney struct {
+	ID       string
+	Amount   int
+	ActingAs string
+}
+type GiveWithdrawalNotice struct {
+	ID       string
+	NoticeID string
+	Amount   int
+	ActingAs string
+}
+type WithdrawMoney struct {
+	ID       string
+	Amount   int
+	NoticeID string
+	ActingAs string
+}
+type CloseAccount struct {
+	ID       string
+	ActingAs string
+}
+
+func (c OpenAccount) isCommand()          {}
+func (c DepositMoney) isCommand()         {}
+func (c GiveWithdrawalNotice) isCommand() {}
+func (c WithdrawMoney) isCommand()        {}
+func (c CloseAccount) isCommand()         {}
+
+// Events
+
+func init() {
+	EventRegistry.Register(func() EventRegistry.Event { return &SavingsAccountWasOpened{} })
+	EventRegistry.Register(func() EventRegistry.Event { return &SavingsMoneyWasDeposited{} })
+	EventRegistry.Register(func() EventRegistry.Event { return &SavingsWithdrawalNoticeWasGiven{} })
+	EventRegistry.Register(func() EventRegistry.Event { return &SavingsMoneyWasWithdrawn{} })
+	EventRegistry.Register(func() EventRegistry.Event { return &SavingsAccountWasClosed{} })
+}
+
+type SavingsAccountWasOpened struct {
+	ID         string
+	CustomerID string
+	Name       string
+	Product    string
+	Timestamp  int64
+}
+type SavingsMoneyWasDeposited struct {
+	ID        string
+	Amount    int
+	Balance   int
+	Timestamp int64
+}
+type SavingsWithdrawalNoticeWasGiven struct {
+	ID            string
+	NoticeID      string
+	Amount        int
+	AvailableFrom int64
+	Timestamp     int64
+}
+type SavingsMoneyWasWithdrawn struct {
+	ID        string
+	Amount    int
+	Balance   int
+	NoticeID  string
+	Timestamp int64
+}
+type SavingsAccountWasClosed struct {
+	ID        string
+	Timestamp int64
+}
+
+func (e SavingsAccountWasOpened) AggregateID() string {
+	return e.ID
+}
+func (e SavingsMoneyWasDeposited) AggregateID() string {
+	return e.ID
+}
+func (e SavingsWithdrawalNoticeWasGiven) AggregateID() string {
+	return e.ID
+}
+func (e SavingsMoneyWasWithdrawn) AggregateID() string {
+	return e.ID
+}
+func (e SavingsAccountWasClosed) AggregateID() string {
+	return e.ID
+}
+
+const TypeSavingsAccountWasOpened = "SavingsAccountWasOpened"
+const TypeSavingsMoneyWasDeposited = "SavingsMoneyWasDeposited"
+const TypeSavingsWithdrawalNoticeWasGiven = "SavingsWithdrawalNoticeWasGiven"
+const TypeSavingsMoneyWasWithdrawn = "SavingsMoneyWasWithdrawn"
+const TypeSavingsAccountWasClosed = "SavingsAccountWasClosed"
+
+func (e SavingsAccountWasOpened) EventType() string {
+	return TypeSavingsAccountWasOpened
+}
+func (e SavingsMoneyWasDeposited) EventType() string {
+	return TypeSavingsMoneyWasDeposited
+}
+func (e SavingsWithdrawalNoticeWasGiven) EventType() string {
+	return TypeSavingsWithdrawalNoticeWasGiven
+}
+func (e SavingsMoneyWasWithdrawn) EventType() string {
+	return TypeSavingsMoneyWasWithdrawn
+}
+func (e SavingsAccountWasClosed) EventType() string {
+	return TypeSavingsAccountWasClosed
+}
+
+func (e SavingsAccountWasOpened) EventTimestamp() int64 {
+	return e.Timestamp
+}
+func (e SavingsMoneyWasDeposited) EventTimestamp() int64 {
+	return e.Timestamp
+}
+func (e SavingsWithdrawalNoticeWasGiven) EventTimestamp() int64 {
+	return e.Timestamp
+}
+func (e SavingsMoneyWasWithdrawn) EventTimestamp() int64 {
+	return e.Timestamp
+}
+func (e SavingsAccountWasClosed) EventTimestamp() int64 {
+	return e.Timestamp
+}
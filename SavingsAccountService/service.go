@@ -0,0 +1,227 @@
+package SavingsAccountService
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/agemmell/banking-cqrs-es-go/CustomerService"
+	"github.com/agemmell/banking-cqrs-es-go/EventRegistry"
+	"github.com/agemmell/banking-cqrs-es-go/ProductCatalog"
+	"github.com/agemmell/banking-cqrs-es-go/Seacrest"
+	"reflect"
+)
+
+type Command interface {
+	isCommand()
+}
+
+// StoresEvents: the parts of the event store the savings service needs
+type StoresEvents interface {
+	GetEventsByAggregateID(aggregateID string) map[uint]Seacrest.EventEnvelope
+	PersistEvent(aggregateID string, eventType string, payload []byte) error
+	PersistEventsExpectingVersion(aggregateID string, expectedVersion uint, events []Seacrest.PendingEvent) error
+}
+
+// Clock: the source of the current time for command handlers
+type Clock = Seacrest.Clock
+
+// VerifiesCustomers: checks a customer has passed KYC before an account is opened for them, and gives the name they
+// registered with, which the account is held in
+type VerifiesCustomers interface {
+	VerifiedName(customerID string) (string, error)
+}
+
+type SavingsAccountService struct {
+	eventStore StoresEvents
+	catalog    *ProductCatalog.Catalog
+	clock      Clock
+	customers  VerifiesCustomers
+}
+
+// Option: configures optional SavingsAccountService dependencies
+type Option func(sas *SavingsAccountService)
+
+// WithProductCatalog: offer the products in the given catalog instead of the default products
+func WithProductCatalog(catalog *ProductCatalog.Catalog) Option {
+	return func(sas *SavingsAccountService) {
+		sas.catalog = catalog
+	}
+}
+
+// WithClock: use the given clock instead of the system clock
+func WithClock(clock Clock) Option {
+	return func(sas *SavingsAccountService) {
+		sas.clock = clock
+	}
+}
+
+// WithCustomerVerifier: check customers with the given verifier instead of the customers in the service's event store
+func WithCustomerVerifier(customers VerifiesCustomers) Option {
+	return func(sas *SavingsAccountService) {
+		sas.customers = customers
+	}
+}
+
+func New(eventStore StoresEvents, options ...Option) SavingsAccountService {
+	customers := CustomerService.New(eventStore)
+	sas := SavingsAccountService{eventStore, ProductCatalog.Default(), Seacrest.SystemClock{}, &customers}
+	for _, option := range options {
+		option(&sas)
+	}
+	return sas
+}
+
+// HandleCommand: Handles commands. Every command on an existing account must be acting as the account's holder or as
+// SystemPrincipal; OpenAccount is authorized by the customer having passed KYC.
+func (sas *SavingsAccountService) HandleCommand(command Command) error {
+
+	var account *Account
+	var err error
+
+	switch commandType := command.(type) {
+	case OpenAccount:
+		_, err = sas.catalog.Get(commandType.Product, ProductCatalog.KindSavings)
+		if err != nil {
+			return err
+		}
+		var name string
+		name, err = sas.customers.VerifiedName(commandType.CustomerID)
+		if err != nil {
+			return err
+		}
+		account, err = sas.loadAccount(commandType.ID)
+		if err != nil {
+			return err
+		}
+		err = account.OpenAccount(commandType.ID, commandType.CustomerID, name, commandType.Product)
+
+	case DepositMoney:
+		account, err = sas.loadAccount(commandType.ID)
+		if err != nil {
+			return err
+		}
+		err = account.Authorize(commandType.ActingAs)
+		if err != nil {
+			return err
+		}
+		err = account.DepositMoney(commandType.Amount)
+
+	case GiveWithdrawalNotice:
+		account, err = sas.loadAccount(commandType.ID)
+		if err != nil {
+			return err
+		}
+		err = account.Authorize(commandType.ActingAs)
+		if err != nil {
+			return err
+		}
+		err = account.GiveWithdrawalNotice(commandType.NoticeID, commandType.Amount)
+
+	case WithdrawMoney:
+		account, err = sas.loadAccount(commandType.ID)
+		if err != nil {
+			return err
+		}
+		err = account.Authorize(commandType.ActingAs)
+		if err != nil {
+			return err
+		}
+		err = account.WithdrawMoney(commandType.Amount, commandType.NoticeID)
+
+	case CloseAccount:
+		account, err = sas.loadAccount(commandType.ID)
+		if err != nil {
+			return err
+		}
+		err = account.Authorize(commandType.ActingAs)
+		if err != nil {
+			return err
+		}
+		err = account.CloseAccount()
+
+	default:
+		commandStruct := reflect.TypeOf(commandType).String()
+		return errors.New(fmt.Sprintf("unknown command %s", commandStruct))
+	}
+
+	if err != nil {
+		return err
+	}
+	return sas.persistNewEvents(account)
+}
+
+// loadAccount: rebuild a savings account from its past events and apply its product's rules
+func (sas *SavingsAccountService) loadAccount(aggregateID string) (*Account, error) {
+	events, err := sas.GetEventsByAggregateID(aggregateID)
+	if err != nil {
+		return nil, err
+	}
+	account := Account{}
+	err = account.LoadFromEvents(events)
+	if err != nil {
+		return nil, err
+	}
+	if account.Version() > 0 {
+		rules, err := sas.catalog.Get(account.Product(), ProductCatalog.KindSavings)
+		if err != nil {
+			return nil, err
+		}
+		account.SetRules(rules)
+	}
+	account.SetClock(sas.clock)
+	return &account, nil
+}
+
+// persistNewEvents: persist the events a command raised on the account together. Fails with a
+// Seacrest.VersionConflictError, persisting nothing, if another command has changed the account since it was loaded, so
+// two withdrawals can't both be checked against the same balance.
+func (sas *SavingsAccountService) persistNewEvents(account *Account) error {
+	newEvents := account.GetNewEvents()
+	pending := make([]Seacrest.PendingEvent, 0, len(newEvents))
+	for _, event := range newEvents {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		pending = append(pending, Seacrest.PendingEvent{EventType: event.EventType(), Payload: payload})
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	loadedVersion := account.Version() - uint(len(newEvents))
+	return sas.eventStore.PersistEventsExpectingVersion(account.AggregateID(), loadedVersion, pending)
+}
+
+// PersistEvents: persist each event after its aggregate's latest, one at a time, e.g. to record an account's history.
+// An event that fails leaves the events before it persisted.
+func (sas *SavingsAccountService) PersistEvents(events ...Event) error {
+	for _, event := range events {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		err = sas.eventStore.PersistEvent(event.AggregateID(), event.EventType(), payload)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (sas *SavingsAccountService) GetEventsByAggregateID(aggregateID string) ([]Event, error) {
+	envelopes := sas.eventStore.GetEventsByAggregateID(aggregateID)
+	var events []Event
+	for version := uint(0); version < uint(len(envelopes)); version++ {
+		envelope, ok := envelopes[version]
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("missing event version %d for aggregate %s", version, aggregateID))
+		}
+		event, err := EventRegistry.Decode(envelope)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
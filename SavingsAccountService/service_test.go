@@ -0,0 +1,124 @@
+package SavingsAccountService
+
+import (
+	"github.com/agemmell/banking-cqrs-es-go/ProductCatalog"
+	"github.com/agemmell/banking-cqrs-es-go/Seacrest"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// verifiedCustomers: treats every customer as having passed KYC so tests can open accounts without registering them
+type verifiedCustomers struct{}
+
+func (vc verifiedCustomers) VerifiedName(customerID string) (string, error) {
+	return "Alex Gemmell", nil
+}
+
+// racingStore: an event store in which another writer's command is handled just before the next command persists
+type racingStore struct {
+	*Seacrest.EventStore
+	race func()
+}
+
+func (rs *racingStore) PersistEventsExpectingVersion(aggregateID string, expectedVersion uint, events []Seacrest.PendingEvent) error {
+	if rs.race != nil {
+		race := rs.race
+		rs.race = nil
+		race()
+	}
+	return rs.EventStore.PersistEventsExpectingVersion(aggregateID, expectedVersion, events)
+}
+
+func Test_SavingsAccountLifecycle(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	eventStore := Seacrest.NewEventStore()
+	clock := Seacrest.NewFakeClock(time.Date(2021, time.March, 1, 9, 0, 0, 0, time.UTC))
+	savingsAccountService := New(eventStore, WithClock(clock), WithCustomerVerifier(verifiedCustomers{}))
+
+	// When
+	err := savingsAccountService.HandleCommand(OpenAccount{ID: "ABCD", CustomerID: "CUST1", Product: ProductCatalog.NinetyDayNoticeSavings})
+	assert.Nil(t, err)
+	assert.Nil(t, savingsAccountService.HandleCommand(DepositMoney{ID: "ABCD", Amount: 500, ActingAs: "CUST1"}))
+	assert.Nil(t, savingsAccountService.HandleCommand(GiveWithdrawalNotice{ID: "ABCD", NoticeID: "N1", Amount: 500, ActingAs: "CUST1"}))
+	clock.Set(clock.Now().AddDate(0, 0, 90))
+	assert.Nil(t, savingsAccountService.HandleCommand(WithdrawMoney{ID: "ABCD", Amount: 500, NoticeID: "N1", ActingAs: "CUST1"}))
+	err = savingsAccountService.HandleCommand(CloseAccount{ID: "ABCD", ActingAs: SystemPrincipal})
+
+	// Then
+	assert.Nil(t, err)
+	events, err := savingsAccountService.GetEventsByAggregateID("ABCD")
+	assert.Nil(t, err)
+	assert.Len(t, events, 5)
+	assert.Equal(t, TypeSavingsWithdrawalNoticeWasGiven, events[2].EventType())
+	withdrawn := events[3].(*SavingsMoneyWasWithdrawn)
+	assert.Equal(t, "N1", withdrawn.NoticeID)
+	assert.Equal(t, 0, withdrawn.Balance)
+}
+
+func Test_OpenSavingsAccountWithCheckingProduct(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	eventStore := Seacrest.NewEventStore()
+	savingsAccountService := New(eventStore)
+
+	// When
+	err := savingsAccountService.HandleCommand(OpenAccount{ID: "ABCD", CustomerID: "CUST1", Product: ProductCatalog.StandardChecking})
+
+	// Then
+	assert.Equal(t, "product StandardChecking is not a Savings product", err.Error())
+	assert.Len(t, eventStore.GetAllEvents(), 0)
+}
+
+func Test_SavingsCommandsMustBeActingAsTheHolderOrTheBank(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	eventStore := Seacrest.NewEventStore()
+	savingsAccountService := New(eventStore, WithCustomerVerifier(verifiedCustomers{}))
+	assert.Nil(t, savingsAccountService.HandleCommand(OpenAccount{ID: "ABCD", CustomerID: "CUST1", Product: ProductCatalog.EasyAccessSavings}))
+
+	// When
+	anonymousErr := savingsAccountService.HandleCommand(DepositMoney{ID: "ABCD", Amount: 500})
+	strangerErr := savingsAccountService.HandleCommand(WithdrawMoney{ID: "ABCD", Amount: 100, ActingAs: "CUST2"})
+	closeErr := savingsAccountService.HandleCommand(CloseAccount{ID: "ABCD", ActingAs: "CUST2"})
+	bankErr := savingsAccountService.HandleCommand(DepositMoney{ID: "ABCD", Amount: 500, ActingAs: SystemPrincipal})
+	holderErr := savingsAccountService.HandleCommand(WithdrawMoney{ID: "ABCD", Amount: 100, ActingAs: "CUST1"})
+	bankHolderErr := savingsAccountService.HandleCommand(OpenAccount{ID: "EFGH", CustomerID: SystemPrincipal, Product: ProductCatalog.EasyAccessSavings})
+
+	// Then
+	assert.Equal(t, "no principal is acting on savings account ABCD", anonymousErr.Error())
+	assert.Equal(t, "CUST2 is not the holder of savings account ABCD", strangerErr.Error())
+	assert.Equal(t, "CUST2 is not the holder of savings account ABCD", closeErr.Error())
+	assert.Nil(t, bankErr)
+	assert.Nil(t, holderErr)
+	assert.Equal(t, "the bank cannot be the holder of savings account EFGH", bankHolderErr.Error())
+	events, err := savingsAccountService.GetEventsByAggregateID("ABCD")
+	assert.Nil(t, err)
+	assert.Len(t, events, 3)
+}
+
+func Test_ConcurrentWithdrawalsCannotOverdrawASavingsAccount(t *testing.T) {
+	t.Parallel()
+
+	// Given an account holding 500, and another withdrawal of 400 made after this one loads the account
+	eventStore := &racingStore{EventStore: Seacrest.NewEventStore()}
+	savingsAccountService := New(eventStore, WithCustomerVerifier(verifiedCustomers{}))
+	assert.Nil(t, savingsAccountService.HandleCommand(OpenAccount{ID: "ABCD", CustomerID: "CUST1", Product: ProductCatalog.EasyAccessSavings}))
+	assert.Nil(t, savingsAccountService.HandleCommand(DepositMoney{ID: "ABCD", Amount: 500, ActingAs: "CUST1"}))
+	eventStore.race = func() {
+		assert.Nil(t, savingsAccountService.HandleCommand(WithdrawMoney{ID: "ABCD", Amount: 400, ActingAs: "CUST1"}))
+	}
+
+	// When
+	err := savingsAccountService.HandleCommand(WithdrawMoney{ID: "ABCD", Amount: 400, ActingAs: "CUST1"})
+
+	// Then
+	assert.Equal(t, Seacrest.VersionConflictError{AggregateID: "ABCD", ExpectedVersion: 2, ActualVersion: 3}, err)
+	account, err := savingsAccountService.loadAccount("ABCD")
+	assert.Nil(t, err)
+	assert.Equal(t, 100, account.Balance())
+}
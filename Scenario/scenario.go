@@ -0,0 +1,174 @@
+package Scenario
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/agemmell/banking-cqrs-es-go/EventRegistry"
+	"github.com/agemmell/banking-cqrs-es-go/Seacrest"
+	"github.com/stretchr/testify/assert"
+	"reflect"
+)
+
+type Event = EventRegistry.Event
+
+// T: the parts of *testing.T a scenario reports failures through
+type T interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// Aggregate: anything that can be rebuilt from past events and records the new events its command handlers raise
+type Aggregate interface {
+	LoadFromEvents(events []Event) error
+	GetNewEvents() []Event
+}
+
+// Scenario: a given/when/then test of an aggregate or of a service writing to an event store. Given sets up history,
+// When runs the code under test, then Then or ThenError checks what happened.
+type Scenario struct {
+	t                T
+	given            []Event
+	load             func(events []Event) error // puts the given events in place
+	recorded         func() ([]Event, error)    // the events raised since the given events were put in place
+	ignoreTimestamps bool
+	whenErr          error
+	whenEvents       []Event
+	ran              bool
+}
+
+// ForAggregate: test an aggregate's command handlers directly. Pass a pointer to an empty aggregate and call its
+// command handlers from When.
+func ForAggregate(t T, aggregate Aggregate) *Scenario {
+	return &Scenario{
+		t:    t,
+		load: aggregate.LoadFromEvents,
+		recorded: func() ([]Event, error) {
+			return aggregate.GetNewEvents(), nil
+		},
+	}
+}
+
+// ForEventStore: test a service end to end. Given events are persisted to the store and the events the service
+// persists from When are decoded through the event registry.
+func ForEventStore(t T, eventStore *Seacrest.EventStore) *Scenario {
+	givenCount := 0
+	return &Scenario{
+		t: t,
+		load: func(events []Event) error {
+			for _, event := range events {
+				payload, err := json.Marshal(event)
+				if err != nil {
+					return err
+				}
+				err = eventStore.PersistEvent(event.AggregateID(), event.EventType(), payload)
+				if err != nil {
+					return err
+				}
+			}
+			givenCount = len(eventStore.GetAllEvents())
+			return nil
+		},
+		recorded: func() ([]Event, error) {
+			var events []Event
+			for _, envelope := range eventStore.GetAllEvents()[givenCount:] {
+				event, err := EventRegistry.Decode(envelope)
+				if err != nil {
+					return nil, err
+				}
+				events = append(events, event)
+			}
+			return events, nil
+		},
+	}
+}
+
+// IgnoringTimestamps: compare events without their Timestamp fields, for code that doesn't use a fake clock
+func (s *Scenario) IgnoringTimestamps() *Scenario {
+	s.ignoreTimestamps = true
+	return s
+}
+
+// Given: the events that have already happened
+func (s *Scenario) Given(events ...Event) *Scenario {
+	s.given = append(s.given, events...)
+	return s
+}
+
+// When: run the code under test, e.g. a command handler, after putting the given events in place
+func (s *Scenario) When(when func() error) *Scenario {
+	s.t.Helper()
+
+	err := s.load(s.given)
+	if err != nil {
+		s.t.Errorf("could not apply the given events: %s", err)
+		return s
+	}
+
+	s.whenErr = when()
+	s.whenEvents, err = s.recorded()
+	if err != nil {
+		s.t.Errorf("could not read the events raised: %s", err)
+	}
+	s.ran = true
+	return s
+}
+
+// Then: expect exactly these events, in order, and no error
+func (s *Scenario) Then(expected ...Event) {
+	s.t.Helper()
+	if !s.ensureRan() {
+		return
+	}
+
+	if s.whenErr != nil {
+		s.t.Errorf("expected %d events but got error: %s", len(expected), s.whenErr)
+		return
+	}
+	assert.Equal(s.t, s.describe(expected), s.describe(s.whenEvents), "unexpected events")
+}
+
+// ThenError: expect the given error message and no events
+func (s *Scenario) ThenError(expected string) {
+	s.t.Helper()
+	if !s.ensureRan() {
+		return
+	}
+
+	if s.whenErr == nil {
+		s.t.Errorf("expected error %q but got none", expected)
+	} else {
+		assert.Equal(s.t, expected, s.whenErr.Error(), "unexpected error")
+	}
+	assert.Equal(s.t, "", s.describe(s.whenEvents), "expected no events alongside the error")
+}
+
+func (s *Scenario) ensureRan() bool {
+	s.t.Helper()
+	if !s.ran {
+		s.t.Errorf("When must be called before Then")
+	}
+	return s.ran
+}
+
+// describe: one line per event with its type and fields, so mismatches show as a readable line diff. Events are
+// compared by value whether they are passed as pointers or structs.
+func (s *Scenario) describe(events []Event) string {
+	description := ""
+	for _, event := range events {
+		value := reflect.Indirect(reflect.ValueOf(event))
+		if s.ignoreTimestamps && value.Kind() == reflect.Struct {
+			copied := reflect.New(value.Type()).Elem()
+			copied.Set(value)
+			if timestamp := copied.FieldByName("Timestamp"); timestamp.IsValid() && timestamp.CanSet() {
+				timestamp.Set(reflect.Zero(timestamp.Type()))
+			}
+			value = copied
+		}
+		fields, err := json.Marshal(value.Interface())
+		if err != nil {
+			fields = []byte(fmt.Sprintf("%+v", value.Interface()))
+		}
+		description += fmt.Sprintf("%s %s\n", event.EventType(), fields)
+	}
+	return description
+}
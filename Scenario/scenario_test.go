@@ -0,0 +1,131 @@
+package Scenario
+
+import (
+	"errors"
+	"fmt"
+	"github.com/agemmell/banking-cqrs-es-go/Seacrest"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// recordingT: collects the failures a scenario reports so the harness itself can be tested
+type recordingT struct {
+	failures []string
+}
+
+func (rt *recordingT) Helper() {}
+func (rt *recordingT) Errorf(format string, args ...interface{}) {
+	rt.failures = append(rt.failures, fmt.Sprintf(format, args...))
+}
+
+type CounterWasIncremented struct {
+	ID        string
+	By        int
+	Timestamp int64
+}
+
+func (e CounterWasIncremented) AggregateID() string   { return e.ID }
+func (e CounterWasIncremented) EventType() string     { return "CounterWasIncremented" }
+func (e CounterWasIncremented) EventTimestamp() int64 { return e.Timestamp }
+
+// counter: the smallest aggregate that can be given history and raise events
+type counter struct {
+	total     int
+	newEvents []Event
+}
+
+func (c *counter) LoadFromEvents(events []Event) error {
+	for _, event := range events {
+		c.total += event.(*CounterWasIncremented).By
+	}
+	return nil
+}
+
+func (c *counter) GetNewEvents() []Event {
+	return c.newEvents
+}
+
+func (c *counter) Increment(by int, timestamp int64) error {
+	if c.total+by > 10 {
+		return errors.New("counter cannot go above 10")
+	}
+	c.total += by
+	c.newEvents = append(c.newEvents, &CounterWasIncremented{ID: "C", By: by, Timestamp: timestamp})
+	return nil
+}
+
+func Test_ThenPassesOnMatchingEvents(t *testing.T) {
+	t.Parallel()
+
+	recorder := &recordingT{}
+	aggregate := &counter{}
+
+	ForAggregate(recorder, aggregate).
+		Given(&CounterWasIncremented{ID: "C", By: 5}).
+		When(func() error { return aggregate.Increment(3, 1234) }).
+		Then(CounterWasIncremented{ID: "C", By: 3, Timestamp: 1234})
+
+	assert.Empty(t, recorder.failures)
+}
+
+func Test_ThenReportsMismatchedEvents(t *testing.T) {
+	t.Parallel()
+
+	recorder := &recordingT{}
+	aggregate := &counter{}
+
+	ForAggregate(recorder, aggregate).
+		When(func() error { return aggregate.Increment(3, 1234) }).
+		Then(&CounterWasIncremented{ID: "C", By: 4, Timestamp: 1234})
+
+	assert.Len(t, recorder.failures, 1)
+	assert.Contains(t, recorder.failures[0], `-CounterWasIncremented {"ID":"C","By":4,"Timestamp":1234}`)
+	assert.Contains(t, recorder.failures[0], `+CounterWasIncremented {"ID":"C","By":3,"Timestamp":1234}`)
+}
+
+func Test_IgnoringTimestamps(t *testing.T) {
+	t.Parallel()
+
+	recorder := &recordingT{}
+	aggregate := &counter{}
+
+	ForAggregate(recorder, aggregate).
+		IgnoringTimestamps().
+		When(func() error { return aggregate.Increment(3, 1234) }).
+		Then(&CounterWasIncremented{ID: "C", By: 3})
+
+	assert.Empty(t, recorder.failures)
+}
+
+func Test_ThenError(t *testing.T) {
+	t.Parallel()
+
+	recorder := &recordingT{}
+	aggregate := &counter{}
+
+	ForAggregate(recorder, aggregate).
+		Given(&CounterWasIncremented{ID: "C", By: 9}).
+		When(func() error { return aggregate.Increment(3, 1234) }).
+		ThenError("counter cannot go above 10")
+	assert.Empty(t, recorder.failures)
+
+	ForAggregate(recorder, &counter{}).
+		When(func() error { return nil }).
+		ThenError("counter cannot go above 10")
+	assert.Equal(t, []string{`expected error "counter cannot go above 10" but got none`}, recorder.failures)
+}
+
+func Test_ForEventStoreOnlyReportsNewEvents(t *testing.T) {
+	t.Parallel()
+
+	recorder := &recordingT{}
+	eventStore := Seacrest.NewEventStore()
+
+	ForEventStore(recorder, eventStore).
+		Given(&CounterWasIncremented{ID: "C", By: 9}).
+		When(func() error { return eventStore.PersistEvent("C", "UnregisteredEvent", []byte(`{}`)) }).
+		Then()
+
+	assert.Equal(t, []string{"could not read the events raised: unknown event type in envelope UnregisteredEvent"}, recorder.failures)
+	assert.Len(t, eventStore.GetAllEvents(), 2)
+}
@@ -0,0 +1,344 @@
+package Conformance
+
+import (
+	"errors"
+	"fmt"
+	"github.com/agemmell/banking-cqrs-es-go/Seacrest"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// EventStore: everything an event store must do to stand in for Seacrest.EventStore
+type EventStore interface {
+	GetAllEvents() []Seacrest.EventEnvelope
+	GetEventsByAggregateID(aggregateID string) map[uint]Seacrest.EventEnvelope
+	PersistEvent(aggregateID string, eventType string, payload []byte) error
+	PersistEventExpectingVersion(aggregateID string, expectedVersion uint, eventType string, payload []byte) error
+	PersistEventsExpectingVersion(aggregateID string, expectedVersion uint, events []Seacrest.PendingEvent) error
+	Subscribe(afterOrder uint) Seacrest.Subscription
+	WriteEventsToFile(filename string) error
+	LoadEventsFromFile(filename string) error
+}
+
+// deliveryTimeout: how long a subscription has to deliver an event before the store is considered broken
+const deliveryTimeout = 5 * time.Second
+
+// Run: prove an event store implementation behaves like every other. newStore must return a new, empty store each
+// time it is called.
+func Run(t *testing.T, newStore func() EventStore) {
+	t.Run("GlobalOrder", func(t *testing.T) { testGlobalOrder(t, newStore()) })
+	t.Run("AggregateVersions", func(t *testing.T) { testAggregateVersions(t, newStore()) })
+	t.Run("ExpectedVersion", func(t *testing.T) { testExpectedVersion(t, newStore()) })
+	t.Run("ConcurrentWrites", func(t *testing.T) { testConcurrentWrites(t, newStore()) })
+	t.Run("ConcurrentExpectedVersion", func(t *testing.T) { testConcurrentExpectedVersion(t, newStore()) })
+	t.Run("AppendManyEvents", func(t *testing.T) { testAppendManyEvents(t, newStore()) })
+	t.Run("RoundTripThroughFile", func(t *testing.T) { testRoundTripThroughFile(t, newStore(), newStore()) })
+	t.Run("SubscriptionCatchesUp", func(t *testing.T) { testSubscriptionCatchesUp(t, newStore()) })
+	t.Run("SubscriptionFollowsWrites", func(t *testing.T) { testSubscriptionFollowsWrites(t, newStore()) })
+	t.Run("SubscriptionClose", func(t *testing.T) { testSubscriptionClose(t, newStore()) })
+}
+
+func persist(t *testing.T, eventStore EventStore, aggregateID string, eventType string) {
+	t.Helper()
+	payload := []byte(fmt.Sprintf(`{"ID":"%s","Type":"%s"}`, aggregateID, eventType))
+	err := eventStore.PersistEvent(aggregateID, eventType, payload)
+	if err != nil {
+		t.Fatalf("persisting %s for %s: %s", eventType, aggregateID, err)
+	}
+}
+
+func receive(t *testing.T, subscription Seacrest.Subscription) Seacrest.EventEnvelope {
+	t.Helper()
+	select {
+	case envelope, ok := <-subscription.Events():
+		if !ok {
+			t.Fatal("subscription closed before the event was delivered")
+		}
+		return envelope
+	case <-time.After(deliveryTimeout):
+		t.Fatalf("no event delivered within %s", deliveryTimeout)
+	}
+	return Seacrest.EventEnvelope{}
+}
+
+func testGlobalOrder(t *testing.T, eventStore EventStore) {
+	t.Parallel()
+
+	// Given
+	assert.Len(t, eventStore.GetAllEvents(), 0)
+
+	// When
+	persist(t, eventStore, "A", "event1")
+	persist(t, eventStore, "B", "event2")
+	persist(t, eventStore, "A", "event3")
+
+	// Then
+	envelopes := eventStore.GetAllEvents()
+	assert.Len(t, envelopes, 3)
+	seenIDs := map[string]bool{}
+	for i, envelope := range envelopes {
+		assert.Equal(t, uint(i+1), envelope.Order)
+		assert.Equal(t, fmt.Sprintf("event%d", i+1), envelope.EventType)
+		assert.NotEmpty(t, envelope.EventID)
+		assert.False(t, seenIDs[envelope.EventID], "event ID %s was reused", envelope.EventID)
+		seenIDs[envelope.EventID] = true
+		if i > 0 {
+			assert.GreaterOrEqual(t, envelope.RecordedAt, envelopes[i-1].RecordedAt)
+		}
+	}
+}
+
+func testAggregateVersions(t *testing.T, eventStore EventStore) {
+	t.Parallel()
+
+	// Given
+	persist(t, eventStore, "A", "event1")
+	persist(t, eventStore, "B", "event2")
+	persist(t, eventStore, "A", "event3")
+	persist(t, eventStore, "A", "event4")
+
+	// When
+	aggregateA := eventStore.GetEventsByAggregateID("A")
+	aggregateB := eventStore.GetEventsByAggregateID("B")
+	unknown := eventStore.GetEventsByAggregateID("C")
+
+	// Then
+	assert.Len(t, aggregateA, 3)
+	assert.Equal(t, "event1", aggregateA[0].EventType)
+	assert.Equal(t, "event3", aggregateA[1].EventType)
+	assert.Equal(t, "event4", aggregateA[2].EventType)
+	assert.Len(t, aggregateB, 1)
+	assert.Equal(t, "event2", aggregateB[0].EventType)
+	assert.Len(t, unknown, 0)
+}
+
+func testExpectedVersion(t *testing.T, eventStore EventStore) {
+	t.Parallel()
+
+	// Given
+	persist(t, eventStore, "A", "event1")
+
+	// When
+	staleErr := eventStore.PersistEventExpectingVersion("A", 0, "stale", []byte(`{}`))
+	aheadErr := eventStore.PersistEventExpectingVersion("A", 2, "ahead", []byte(`{}`))
+	err := eventStore.PersistEventExpectingVersion("A", 1, "event2", []byte(`{}`))
+	newErr := eventStore.PersistEventExpectingVersion("B", 0, "event3", []byte(`{}`))
+
+	// Then
+	var conflict Seacrest.VersionConflictError
+	assert.True(t, errors.As(staleErr, &conflict), "expected a VersionConflictError, got %v", staleErr)
+	assert.Equal(t, Seacrest.VersionConflictError{AggregateID: "A", ExpectedVersion: 0, ActualVersion: 1}, conflict)
+	assert.True(t, errors.As(aheadErr, &conflict), "expected a VersionConflictError, got %v", aheadErr)
+	assert.Equal(t, Seacrest.VersionConflictError{AggregateID: "A", ExpectedVersion: 2, ActualVersion: 1}, conflict)
+	assert.Nil(t, err)
+	assert.Nil(t, newErr)
+	assert.Len(t, eventStore.GetAllEvents(), 3)
+	assert.Len(t, eventStore.GetEventsByAggregateID("A"), 2)
+	assert.Equal(t, "event2", eventStore.GetEventsByAggregateID("A")[1].EventType)
+}
+
+func testAppendManyEvents(t *testing.T, eventStore EventStore) {
+	t.Parallel()
+
+	// Given
+	persist(t, eventStore, "A", "event1")
+	events := []Seacrest.PendingEvent{{EventType: "event2", Payload: []byte(`{}`)}, {EventType: "event3", Payload: []byte(`{}`)}}
+
+	// When
+	staleErr := eventStore.PersistEventsExpectingVersion("A", 0, events)
+	err := eventStore.PersistEventsExpectingVersion("A", 1, events)
+
+	// Then nothing is persisted by the stale append, and the other's events follow each other in order
+	var conflict Seacrest.VersionConflictError
+	assert.True(t, errors.As(staleErr, &conflict), "expected a VersionConflictError, got %v", staleErr)
+	assert.Nil(t, err)
+	envelopes := eventStore.GetAllEvents()
+	assert.Len(t, envelopes, 3)
+	for i, envelope := range envelopes {
+		assert.Equal(t, uint(i+1), envelope.Order)
+		assert.Equal(t, fmt.Sprintf("event%d", i+1), envelope.EventType)
+	}
+	assert.Equal(t, "event3", eventStore.GetEventsByAggregateID("A")[2].EventType)
+}
+
+func testConcurrentWrites(t *testing.T, eventStore EventStore) {
+	t.Parallel()
+
+	// Given
+	writers := 8
+	eventsPerWriter := 50
+	wg := sync.WaitGroup{}
+
+	// When every writer persists to its own aggregate and to one they all share
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < eventsPerWriter; i++ {
+				aggregateID := fmt.Sprintf("writer-%d", w)
+				if i%2 == 0 {
+					aggregateID = "shared"
+				}
+				err := eventStore.PersistEvent(aggregateID, fmt.Sprintf("event-%d-%d", w, i), []byte(`{}`))
+				assert.Nil(t, err)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	// Then
+	envelopes := eventStore.GetAllEvents()
+	assert.Len(t, envelopes, writers*eventsPerWriter)
+	for i, envelope := range envelopes {
+		assert.Equal(t, uint(i+1), envelope.Order)
+	}
+	shared := eventStore.GetEventsByAggregateID("shared")
+	assert.Len(t, shared, writers*eventsPerWriter/2)
+	for version := uint(0); version < uint(len(shared)); version++ {
+		_, ok := shared[version]
+		assert.True(t, ok, "shared aggregate is missing version %d", version)
+	}
+	for w := 0; w < writers; w++ {
+		assert.Len(t, eventStore.GetEventsByAggregateID(fmt.Sprintf("writer-%d", w)), eventsPerWriter/2)
+	}
+}
+
+func testConcurrentExpectedVersion(t *testing.T, eventStore EventStore) {
+	t.Parallel()
+
+	// Given
+	persist(t, eventStore, "A", "event1")
+	writers := 8
+	results := make(chan error, writers)
+
+	// When every writer tries to append the aggregate's second event
+	for w := 0; w < writers; w++ {
+		go func(w int) {
+			results <- eventStore.PersistEventExpectingVersion("A", 1, fmt.Sprintf("writer-%d", w), []byte(`{}`))
+		}(w)
+	}
+
+	// Then exactly one of them wins
+	succeeded := 0
+	for w := 0; w < writers; w++ {
+		err := <-results
+		if err == nil {
+			succeeded++
+			continue
+		}
+		var conflict Seacrest.VersionConflictError
+		assert.True(t, errors.As(err, &conflict), "expected a VersionConflictError, got %v", err)
+	}
+	assert.Equal(t, 1, succeeded)
+	assert.Len(t, eventStore.GetEventsByAggregateID("A"), 2)
+	assert.Len(t, eventStore.GetAllEvents(), 2)
+}
+
+func testRoundTripThroughFile(t *testing.T, eventStore EventStore, loadedStore EventStore) {
+	t.Parallel()
+
+	// Given
+	persist(t, eventStore, "A", "event1")
+	persist(t, eventStore, "B", "event2")
+	persist(t, eventStore, "A", "event3")
+	dir, err := ioutil.TempDir("", "conformance")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "events.txt")
+
+	// When
+	err = eventStore.WriteEventsToFile(filename)
+	assert.Nil(t, err)
+	err = loadedStore.LoadEventsFromFile(filename)
+	assert.Nil(t, err)
+
+	// Then
+	assert.Equal(t, eventStore.GetAllEvents(), loadedStore.GetAllEvents())
+	assert.Equal(t, eventStore.GetEventsByAggregateID("A"), loadedStore.GetEventsByAggregateID("A"))
+	assert.Equal(t, eventStore.GetEventsByAggregateID("B"), loadedStore.GetEventsByAggregateID("B"))
+	persist(t, loadedStore, "B", "event4")
+	assert.Equal(t, uint(4), loadedStore.GetAllEvents()[3].Order)
+	assert.Len(t, loadedStore.GetEventsByAggregateID("B"), 2)
+}
+
+func testSubscriptionCatchesUp(t *testing.T, eventStore EventStore) {
+	t.Parallel()
+
+	// Given
+	persist(t, eventStore, "A", "event1")
+	persist(t, eventStore, "B", "event2")
+	persist(t, eventStore, "A", "event3")
+
+	// When
+	everything := eventStore.Subscribe(0)
+	defer everything.Close()
+	afterFirst := eventStore.Subscribe(1)
+	defer afterFirst.Close()
+
+	// Then
+	for order := uint(1); order <= 3; order++ {
+		assert.Equal(t, order, receive(t, everything).Order)
+	}
+	for order := uint(2); order <= 3; order++ {
+		assert.Equal(t, order, receive(t, afterFirst).Order)
+	}
+}
+
+func testSubscriptionFollowsWrites(t *testing.T, eventStore EventStore) {
+	t.Parallel()
+
+	// Given
+	persist(t, eventStore, "A", "event1")
+	subscription := eventStore.Subscribe(0)
+	defer subscription.Close()
+	assert.Equal(t, "event1", receive(t, subscription).EventType)
+	writers := 4
+	eventsPerWriter := 25
+
+	// When
+	for w := 0; w < writers; w++ {
+		go func(w int) {
+			for i := 0; i < eventsPerWriter; i++ {
+				assert.Nil(t, eventStore.PersistEvent(fmt.Sprintf("writer-%d", w), "event", []byte(`{}`)))
+			}
+		}(w)
+	}
+
+	// Then every event arrives once, in global order
+	for order := uint(2); order <= uint(1+writers*eventsPerWriter); order++ {
+		assert.Equal(t, order, receive(t, subscription).Order)
+	}
+}
+
+func testSubscriptionClose(t *testing.T, eventStore EventStore) {
+	t.Parallel()
+
+	// Given
+	persist(t, eventStore, "A", "event1")
+	persist(t, eventStore, "A", "event2")
+	subscription := eventStore.Subscribe(0)
+	assert.Equal(t, "event1", receive(t, subscription).EventType)
+
+	// When
+	subscription.Close()
+	subscription.Close()
+
+	// Then the channel is closed, possibly after the event that was already on its way
+	deadline := time.After(deliveryTimeout)
+	for {
+		select {
+		case _, ok := <-subscription.Events():
+			if !ok {
+				persist(t, eventStore, "A", "event3")
+				return
+			}
+		case <-deadline:
+			t.Fatalf("subscription still open %s after it was closed", deliveryTimeout)
+		}
+	}
+}
@@ -0,0 +1,12 @@
+package Conformance
+
+import (
+	"github.com/agemmell/banking-cqrs-es-go/Seacrest"
+	"testing"
+)
+
+func Test_SeacrestEventStoreConforms(t *testing.T) {
+	Run(t, func() EventStore {
+		return Seacrest.NewEventStore()
+	})
+}
@@ -0,0 +1,98 @@
+package Seacrest
+
+import (
+	"fmt"
+	uuid "github.com/nu7hatch/gouuid"
+	"sync"
+	"time"
+)
+
+// Clock: the source of the current time, so recorded times can be controlled in tests and simulations
+type Clock interface {
+	Now() time.Time
+}
+
+// IDGenerator: the source of unique IDs, so generated IDs can be controlled in tests and simulations
+type IDGenerator interface {
+	NewID() (string, error)
+}
+
+// SystemClock: the wall clock
+type SystemClock struct{}
+
+func (sc SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// UUIDGenerator: random version 4 UUIDs
+type UUIDGenerator struct{}
+
+func (ug UUIDGenerator) NewID() (string, error) {
+	UUID, err := uuid.NewV4()
+	if err != nil {
+		return "", err
+	}
+	return UUID.String(), nil
+}
+
+// FakeClock: a clock that only moves when told to. It is safe to share between goroutines.
+type FakeClock struct {
+	mutex sync.Mutex
+	now   time.Time
+	step  time.Duration
+}
+
+func NewFakeClock(start time.Time) *FakeClock {
+	return &FakeClock{now: start}
+}
+
+// Now: the fake time, which then moves on by the auto advance step (if any)
+func (fc *FakeClock) Now() time.Time {
+	fc.mutex.Lock()
+	defer fc.mutex.Unlock()
+
+	now := fc.now
+	fc.now = fc.now.Add(fc.step)
+	return now
+}
+
+// Set: move the clock to the given time
+func (fc *FakeClock) Set(now time.Time) {
+	fc.mutex.Lock()
+	defer fc.mutex.Unlock()
+	fc.now = now
+}
+
+// Advance: move the clock on by the given duration
+func (fc *FakeClock) Advance(duration time.Duration) {
+	fc.mutex.Lock()
+	defer fc.mutex.Unlock()
+	fc.now = fc.now.Add(duration)
+}
+
+// AutoAdvance: move the clock on by step every time it is read, so every reading is distinct and ordered
+func (fc *FakeClock) AutoAdvance(step time.Duration) {
+	fc.mutex.Lock()
+	defer fc.mutex.Unlock()
+	fc.step = step
+}
+
+// SequentialIDGenerator: predictable IDs made of a prefix and a counter, e.g. "event-1", "event-2", ...
+type SequentialIDGenerator struct {
+	mutex  sync.Mutex
+	prefix string
+	next   uint64
+}
+
+func NewSequentialIDGenerator(prefix string) *SequentialIDGenerator {
+	return &SequentialIDGenerator{prefix: prefix, next: 1}
+}
+
+func (sig *SequentialIDGenerator) NewID() (string, error) {
+	sig.mutex.Lock()
+	defer sig.mutex.Unlock()
+
+	id := fmt.Sprintf("%s-%d", sig.prefix, sig.next)
+	sig.next++
+	return id, nil
+}
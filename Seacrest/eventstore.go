@@ -2,13 +2,13 @@ package Seacrest
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
-	uuid "github.com/nu7hatch/gouuid"
-	"log"
+	"io"
 	"os"
-	"time"
+	"sync"
 )
 
 type EventEnvelope struct {
@@ -20,43 +20,155 @@ type EventEnvelope struct {
 	RecordedAt  int64
 }
 
+// EventStore: an in-memory event store. It is safe to use from multiple goroutines.
 type EventStore struct {
+	mutex         sync.RWMutex
 	orderedEvents []EventEnvelope                   // <global order> -> EventEnvelope
 	eventsByID    map[string]map[uint]EventEnvelope // <aggregateID> -> <version> -> EventEnvelope
 	globalOrder   uint
+	clock         Clock
+	idGenerator   IDGenerator
+	persisted     chan struct{} // closed and replaced whenever events are persisted, to wake subscriptions
 }
 
-func NewEventStore() *EventStore {
+// VersionConflictError: an event was persisted expecting the aggregate to be at a version it is no longer at
+type VersionConflictError struct {
+	AggregateID     string
+	ExpectedVersion uint
+	ActualVersion   uint
+}
+
+func (vce VersionConflictError) Error() string {
+	return fmt.Sprintf("aggregate %s is at version %d, not the expected version %d", vce.AggregateID, vce.ActualVersion, vce.ExpectedVersion)
+}
+
+// Option: configures optional EventStore dependencies
+type Option func(es *EventStore)
+
+// WithClock: record events at the times given by the clock instead of the system clock
+func WithClock(clock Clock) Option {
+	return func(es *EventStore) {
+		es.clock = clock
+	}
+}
+
+// WithIDGenerator: give events IDs from the generator instead of random UUIDs
+func WithIDGenerator(idGenerator IDGenerator) Option {
+	return func(es *EventStore) {
+		es.idGenerator = idGenerator
+	}
+}
+
+func NewEventStore(options ...Option) *EventStore {
 	eventsByID := make(map[string]map[uint]EventEnvelope, 0)
 	orderedEvents := make([]EventEnvelope, 0)
-	es := EventStore{orderedEvents, eventsByID, 0}
+	es := EventStore{
+		orderedEvents: orderedEvents,
+		eventsByID:    eventsByID,
+		clock:         SystemClock{},
+		idGenerator:   UUIDGenerator{},
+		persisted:     make(chan struct{}),
+	}
+	for _, option := range options {
+		option(&es)
+	}
 	return &es
 }
 
 func (es *EventStore) GetAllEvents() []EventEnvelope {
+	es.mutex.RLock()
+	defer es.mutex.RUnlock()
 	return es.orderedEvents
 }
 
 func (es *EventStore) PersistEvent(aggregateID string, eventType string, payload []byte) error {
-	UUID, err := uuid.NewV4()
+	es.mutex.Lock()
+	defer es.mutex.Unlock()
+	return es.persistEvent(aggregateID, eventType, payload)
+}
+
+// PersistEventExpectingVersion: persist an event only if the aggregate still has expectedVersion events, so writers
+// that loaded the aggregate before someone else changed it find out instead of overwriting the change
+func (es *EventStore) PersistEventExpectingVersion(aggregateID string, expectedVersion uint, eventType string, payload []byte) error {
+	es.mutex.Lock()
+	defer es.mutex.Unlock()
+
+	actualVersion := uint(len(es.eventsByID[aggregateID]))
+	if actualVersion != expectedVersion {
+		return VersionConflictError{aggregateID, expectedVersion, actualVersion}
+	}
+	return es.persistEvent(aggregateID, eventType, payload)
+}
+
+// PendingEvent: an event to be persisted by PersistEventsExpectingVersion
+type PendingEvent struct {
+	EventType string
+	Payload   []byte
+}
+
+// PersistEventsExpectingVersion: persist the aggregate's events one after the other, only if the aggregate still has
+// expectedVersion events. Either every event is persisted or none is, and no other writer's events come between them.
+func (es *EventStore) PersistEventsExpectingVersion(aggregateID string, expectedVersion uint, events []PendingEvent) error {
+	es.mutex.Lock()
+	defer es.mutex.Unlock()
+
+	actualVersion := uint(len(es.eventsByID[aggregateID]))
+	if actualVersion != expectedVersion {
+		return VersionConflictError{aggregateID, expectedVersion, actualVersion}
+	}
+
+	// Everything that can fail happens before the first event is persisted
+	envelopes := make([]EventEnvelope, 0, len(events))
+	for i, event := range events {
+		eventID, err := es.idGenerator.NewID()
+		if err != nil {
+			return err
+		}
+		envelopes = append(envelopes, EventEnvelope{
+			EventID:     eventID,
+			Order:       es.globalOrder + uint(i) + 1,
+			AggregateID: aggregateID,
+			EventType:   event.EventType,
+			Payload:     event.Payload,
+			RecordedAt:  es.clock.Now().UnixNano(),
+		})
+	}
+	for _, envelope := range envelopes {
+		err := es.persistEventEnvelope(envelope)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (es *EventStore) persistEvent(aggregateID string, eventType string, payload []byte) error {
+	eventID, err := es.idGenerator.NewID()
 	if err != nil {
 		return err
 	}
 	eventEnvelope := EventEnvelope{
-		EventID:     UUID.String(),
-		Order:       es.GlobalOrder() + 1,
+		EventID:     eventID,
+		Order:       es.globalOrder + 1,
 		AggregateID: aggregateID,
 		EventType:   eventType,
 		Payload:     payload,
-		RecordedAt:  time.Now().UnixNano(),
+		RecordedAt:  es.clock.Now().UnixNano(),
 	}
 
-	return es.PersistEventEnvelope(eventEnvelope)
+	return es.persistEventEnvelope(eventEnvelope)
 }
 
+// GetEventsByAggregateID: a copy of the aggregate's events keyed by version
 func (es *EventStore) GetEventsByAggregateID(aggregateID string) map[uint]EventEnvelope {
+	es.mutex.RLock()
+	defer es.mutex.RUnlock()
 	if aggregateEvents, ok := es.eventsByID[aggregateID]; ok {
-		return aggregateEvents
+		events := make(map[uint]EventEnvelope, len(aggregateEvents))
+		for version, envelope := range aggregateEvents {
+			events[version] = envelope
+		}
+		return events
 	}
 	return nil
 }
@@ -75,7 +187,7 @@ func (es *EventStore) WriteEventsToFile(filename string) error {
 	}
 	defer closeFileHandle(f)
 
-	for _, event := range es.orderedEvents {
+	for _, event := range es.GetAllEvents() {
 		eventJson, err := json.Marshal(event)
 		if err != nil {
 			return err
@@ -97,38 +209,86 @@ func (es *EventStore) WriteEventsToFile(filename string) error {
 func (es *EventStore) LoadEventsFromFile(filename string) error {
 	f, err := os.Open(filename)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	defer closeFileHandle(f)
 
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		eventEnvelope := EventEnvelope{}
-		err := json.Unmarshal(scanner.Bytes(), &eventEnvelope)
-		if err != nil {
+	return es.LoadEventsFromReader(f)
+}
+
+// LoadEventsFromReader: persist the events written by WriteEventsToFile, one JSON envelope per line. Lines can be any
+// length. Nothing is persisted unless every line decodes.
+func (es *EventStore) LoadEventsFromReader(r io.Reader) error {
+	reader := bufio.NewReader(r)
+	var envelopes []EventEnvelope
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
 			return err
 		}
-		err = es.PersistEventEnvelope(eventEnvelope)
+		if len(bytes.TrimSpace(line)) > 0 {
+			envelope, decodeErr := DecodeEnvelope(line)
+			if decodeErr != nil {
+				return errors.New(fmt.Sprintf("line %d: %s", lineNumber, decodeErr))
+			}
+			envelopes = append(envelopes, envelope)
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+
+	es.mutex.Lock()
+	defer es.mutex.Unlock()
+	for _, envelope := range envelopes {
+		err := es.persistEventEnvelope(envelope)
 		if err != nil {
 			return err
 		}
 	}
+	return nil
+}
 
-	if err := scanner.Err(); err != nil {
-		log.Fatal(err)
+// DecodeEnvelope: decode one line written by WriteEventsToFile
+func DecodeEnvelope(line []byte) (EventEnvelope, error) {
+	envelope := EventEnvelope{}
+	err := json.Unmarshal(line, &envelope)
+	if err != nil {
+		return EventEnvelope{}, err
 	}
-	return nil
+	if envelope.AggregateID == "" || envelope.EventType == "" {
+		return EventEnvelope{}, errors.New(fmt.Sprintf("event envelope requires an AggregateID and an EventType [envelope: %s]", truncate(line, 200)))
+	}
+	return envelope, nil
+}
+
+// truncate: at most the first n bytes of a line, for error messages about lines that may be huge
+func truncate(line []byte, n int) []byte {
+	if len(line) > n {
+		return append(line[:n:n], "..."...)
+	}
+	return line
 }
 
 func (es *EventStore) GlobalOrder() uint {
+	es.mutex.RLock()
+	defer es.mutex.RUnlock()
 	return es.globalOrder
 }
 
 func (es *EventStore) IncrementGlobalOrder() {
+	es.mutex.Lock()
+	defer es.mutex.Unlock()
 	es.globalOrder++
 }
 
 func (es *EventStore) PersistEventEnvelope(envelope EventEnvelope) error {
+	es.mutex.Lock()
+	defer es.mutex.Unlock()
+	return es.persistEventEnvelope(envelope)
+}
+
+func (es *EventStore) persistEventEnvelope(envelope EventEnvelope) error {
 	version := uint(0)
 	if _, ok := es.eventsByID[envelope.AggregateID]; ok {
 		version = uint(len(es.eventsByID[envelope.AggregateID]))
@@ -142,7 +302,10 @@ func (es *EventStore) PersistEventEnvelope(envelope EventEnvelope) error {
 		}
 	}
 	es.orderedEvents = append(es.orderedEvents, envelope)
-	es.IncrementGlobalOrder()
+	es.globalOrder++
+
+	close(es.persisted)
+	es.persisted = make(chan struct{})
 
 	return nil
 }
@@ -3,7 +3,10 @@ package Seacrest
 import (
 	"encoding/json"
 	"github.com/stretchr/testify/assert"
+	"os"
+	"strings"
 	"testing"
+	"time"
 )
 
 func Test_NewEventStore_GetAllEvents(t *testing.T) {
@@ -94,3 +97,90 @@ func Test_EventStore_GetEventsByAggregateID(t *testing.T) {
 	assert.Equal(t, event6.id, envelopes[2].AggregateID)
 	assert.Equal(t, event6.eventType, envelopes[2].EventType)
 }
+
+func Test_EventStore_PersistEventWithFakeClockAndIDs(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	start := time.Date(2021, time.January, 1, 9, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+	clock.AutoAdvance(time.Second)
+	eventStore := NewEventStore(WithClock(clock), WithIDGenerator(NewSequentialIDGenerator("event")))
+
+	// When
+	err := eventStore.PersistEvent("A", "event1", []byte(`{}`))
+	assert.Nil(t, err)
+	err = eventStore.PersistEvent("B", "event2", []byte(`{}`))
+	assert.Nil(t, err)
+
+	// Then
+	assert.Equal(t, []EventEnvelope{
+		{EventID: "event-1", Order: 1, AggregateID: "A", EventType: "event1", Payload: []byte(`{}`), RecordedAt: start.UnixNano()},
+		{EventID: "event-2", Order: 2, AggregateID: "B", EventType: "event2", Payload: []byte(`{}`), RecordedAt: start.Add(time.Second).UnixNano()},
+	}, eventStore.GetAllEvents())
+}
+
+func Test_EventStore_LoadEventsFromReaderWithLargeRecords(t *testing.T) {
+	t.Parallel()
+
+	// Given a record far bigger than bufio.Scanner's default 64KB limit
+	source := NewEventStore()
+	err := source.PersistEvent("A", "event1", []byte(`{"Name":"`+strings.Repeat("x", 1024*1024)+`"}`))
+	assert.Nil(t, err)
+	err = source.PersistEvent("B", "event2", []byte(`{}`))
+	assert.Nil(t, err)
+	var lines []string
+	for _, envelope := range source.GetAllEvents() {
+		line, err := json.Marshal(envelope)
+		assert.Nil(t, err)
+		lines = append(lines, string(line))
+	}
+	eventStore := NewEventStore()
+
+	// When
+	err = eventStore.LoadEventsFromReader(strings.NewReader(strings.Join(lines, "\n")))
+
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, source.GetAllEvents(), eventStore.GetAllEvents())
+}
+
+func Test_EventStore_LoadEventsFromReaderWithMalformedRecord(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	records := `{"EventID":"1","Order":1,"AggregateID":"A","EventType":"event1","Payload":"e30="}` + "\n" +
+		"\n" +
+		`{"EventID":"2","Order":2,"AggregateID":"A","EventType":"event2","Payload":` + "\n"
+	eventStore := NewEventStore()
+
+	// When
+	err := eventStore.LoadEventsFromReader(strings.NewReader(records))
+
+	// Then nothing is loaded
+	assert.Equal(t, "line 3: unexpected end of JSON input", err.Error())
+	assert.Len(t, eventStore.GetAllEvents(), 0)
+}
+
+func Test_EventStore_LoadEventsFromMissingFile(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	eventStore := NewEventStore()
+
+	// When
+	err := eventStore.LoadEventsFromFile("does-not-exist.txt")
+
+	// Then
+	assert.True(t, os.IsNotExist(err))
+}
+
+func Test_DecodeEnvelopeRequiresAggregateIDAndEventType(t *testing.T) {
+	t.Parallel()
+
+	// When
+	_, err := DecodeEnvelope([]byte(`{"EventID":"1","Order":1,"EventType":"event1"}`))
+
+	// Then
+	assert.Equal(t, `event envelope requires an AggregateID and an EventType [envelope: {"EventID":"1","Order":1,"EventType":"event1"}]`, err.Error())
+}
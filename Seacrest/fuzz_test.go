@@ -0,0 +1,77 @@
+package Seacrest
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func FuzzDecodeEnvelope(f *testing.F) {
+	f.Add([]byte(`{"EventID":"1","Order":1,"AggregateID":"A","EventType":"event1","Payload":"e30=","RecordedAt":1}`))
+	f.Add([]byte(`{"AggregateID":"A","EventType":"event1","Payload":null}`))
+	f.Add([]byte(`{"AggregateID":"","EventType":"event1"}`))
+	f.Add([]byte(`{"Order":-1}`))
+	f.Add([]byte(`[]`))
+
+	f.Fuzz(func(t *testing.T, line []byte) {
+		envelope, err := DecodeEnvelope(line)
+		if err != nil {
+			return
+		}
+
+		// anything that decodes must survive being written out and read back
+		encoded, err := json.Marshal(envelope)
+		if err != nil {
+			t.Fatalf("decoded envelope %+v cannot be encoded: %s", envelope, err)
+		}
+		decoded, err := DecodeEnvelope(encoded)
+		if err != nil {
+			t.Fatalf("re-encoded envelope %s cannot be decoded: %s", encoded, err)
+		}
+		if decoded.EventID != envelope.EventID || decoded.Order != envelope.Order ||
+			decoded.AggregateID != envelope.AggregateID || decoded.EventType != envelope.EventType ||
+			!bytes.Equal(decoded.Payload, envelope.Payload) || decoded.RecordedAt != envelope.RecordedAt {
+			t.Fatalf("envelope changed on the way through a file: %+v became %+v", envelope, decoded)
+		}
+	})
+}
+
+func FuzzLoadEventsFromReader(f *testing.F) {
+	f.Add([]byte(`{"EventID":"1","Order":1,"AggregateID":"A","EventType":"event1","Payload":"e30="}` + "\n" +
+		`{"EventID":"2","Order":2,"AggregateID":"A","EventType":"event2","Payload":"e30="}` + "\n"))
+	f.Add([]byte("\n\n" + `{"AggregateID":"A","EventType":"event1"}`))
+	f.Add([]byte(`{"AggregateID":"A","EventType":"event1"}` + "\n" + `{"AggregateID":"A"`))
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, records []byte) {
+		eventStore := NewEventStore()
+		err := eventStore.LoadEventsFromReader(bytes.NewReader(records))
+		if err != nil {
+			if len(eventStore.GetAllEvents()) != 0 {
+				t.Fatalf("failed load persisted %d events: %s", len(eventStore.GetAllEvents()), err)
+			}
+			return
+		}
+
+		lines := 0
+		for _, line := range bytes.Split(records, []byte("\n")) {
+			if len(bytes.TrimSpace(line)) > 0 {
+				lines++
+			}
+		}
+		if len(eventStore.GetAllEvents()) != lines {
+			t.Fatalf("loaded %d events from %d lines", len(eventStore.GetAllEvents()), lines)
+		}
+		byAggregate := 0
+		seen := map[string]bool{}
+		for _, envelope := range eventStore.GetAllEvents() {
+			if !seen[envelope.AggregateID] {
+				seen[envelope.AggregateID] = true
+				byAggregate += len(eventStore.GetEventsByAggregateID(envelope.AggregateID))
+			}
+		}
+		if byAggregate != lines {
+			t.Fatalf("loaded %d events but their aggregates hold %d", lines, byAggregate)
+		}
+	})
+}
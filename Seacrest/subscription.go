@@ -0,0 +1,69 @@
+package Seacrest
+
+import "sync"
+
+// Subscription: a stream of persisted events in global order. Events() is closed once the subscription is closed.
+type Subscription interface {
+	Events() <-chan EventEnvelope
+	Close()
+}
+
+type subscription struct {
+	events    chan EventEnvelope
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func (s *subscription) Events() <-chan EventEnvelope {
+	return s.events
+}
+
+func (s *subscription) Close() {
+	s.closeOnce.Do(func() {
+		close(s.closed)
+	})
+}
+
+// Subscribe: deliver every event after the first afterOrder events, catching up on events already persisted and then
+// following new ones as they are persisted. Pass 0 to receive every event.
+func (es *EventStore) Subscribe(afterOrder uint) Subscription {
+	s := &subscription{
+		events: make(chan EventEnvelope),
+		closed: make(chan struct{}),
+	}
+	go es.deliver(s, afterOrder)
+	return s
+}
+
+// deliver: send events to the subscription until it is closed. Persisted events are never changed, so the pending
+// events can be sent after the lock is released.
+func (es *EventStore) deliver(s *subscription, position uint) {
+	defer close(s.events)
+
+	for {
+		es.mutex.RLock()
+		var pending []EventEnvelope
+		if position < uint(len(es.orderedEvents)) {
+			pending = es.orderedEvents[position:]
+		}
+		persisted := es.persisted
+		es.mutex.RUnlock()
+
+		for _, envelope := range pending {
+			select {
+			case s.events <- envelope:
+				position++
+			case <-s.closed:
+				return
+			}
+		}
+
+		if len(pending) == 0 {
+			select {
+			case <-persisted:
+			case <-s.closed:
+				return
+			}
+		}
+	}
+}
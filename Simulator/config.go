@@ -0,0 +1,119 @@
+package Simulator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"time"
+)
+
+// Config: the workload of the simulated bank. Amounts are in the same units as account balances.
+type Config struct {
+	Customers           int          // customers who open an account, unless the simulation ends first
+	Seed                int64        // the same seed and config always produce the same events
+	Start               time.Time    // when the first customer can arrive
+	End                 time.Time    // nothing happens at or after this time
+	ArrivalsPerDay      float64      // the average rate new customers arrive at
+	DepositsPerMonth    float64      // the average rate each customer makes ad hoc deposits at
+	WithdrawalsPerMonth float64      // the average rate each customer makes ad hoc withdrawals at
+	DepositSize         Distribution // the size of ad hoc deposits
+	WithdrawalSize      Distribution // the size of ad hoc withdrawals, capped at what the customer has available
+	Salary              Recurring    // paid into the account every month
+	Rent                Recurring    // paid out of the account every month, failing if the customer can't cover it
+	CloseChance         float64      // the chance a customer empties and closes their account before the end
+}
+
+// Distribution: a log-normal distribution of amounts, which has the long tail of a few very large transactions that
+// real transaction sizes have
+type Distribution struct {
+	Median int     // half of all amounts are smaller than this
+	Spread float64 // the standard deviation of the natural logarithm of the amounts; 0 makes every amount the median
+	Min    int
+	Max    int
+}
+
+// Recurring: a payment made on the same day every month by some of the customers
+type Recurring struct {
+	Chance     float64      // the share of customers who make the payment
+	Amount     Distribution // each customer's amount is drawn when they arrive and stays the same every month
+	DayOfMonth int          // 1-28, or 0 to give each customer their own day
+}
+
+// LoadConfig: read a config from a JSON file. Times are RFC 3339, e.g. "2020-01-01T00:00:00Z".
+func LoadConfig(filename string) (Config, error) {
+	contents, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config := Config{}
+	err = json.Unmarshal(contents, &config)
+	if err != nil {
+		return Config{}, errors.New(fmt.Sprintf("cannot read simulation config %s: %s", filename, err))
+	}
+
+	err = config.Validate()
+	if err != nil {
+		return Config{}, err
+	}
+	return config, nil
+}
+
+// Validate: reject configs that cannot be simulated
+func (c Config) Validate() error {
+	if c.Customers <= 0 {
+		return errors.New(fmt.Sprintf("a simulation requires at least one customer [Customers: %d]", c.Customers))
+	}
+	if !c.End.After(c.Start) {
+		return errors.New(fmt.Sprintf("a simulation must end after it starts [Start: %s, End: %s]", c.Start, c.End))
+	}
+	if c.ArrivalsPerDay <= 0 {
+		return errors.New(fmt.Sprintf("customers must arrive at a rate greater than 0 [ArrivalsPerDay: %v]", c.ArrivalsPerDay))
+	}
+	if c.DepositsPerMonth < 0 || c.WithdrawalsPerMonth < 0 {
+		return errors.New(fmt.Sprintf("transaction rates cannot be negative [DepositsPerMonth: %v, WithdrawalsPerMonth: %v]", c.DepositsPerMonth, c.WithdrawalsPerMonth))
+	}
+	if c.CloseChance < 0 || c.CloseChance > 1 {
+		return errors.New(fmt.Sprintf("CloseChance must be between 0 and 1 [CloseChance: %v]", c.CloseChance))
+	}
+
+	var distributions []namedDistribution
+	if c.DepositsPerMonth > 0 {
+		distributions = append(distributions, namedDistribution{"DepositSize", c.DepositSize})
+	}
+	if c.WithdrawalsPerMonth > 0 {
+		distributions = append(distributions, namedDistribution{"WithdrawalSize", c.WithdrawalSize})
+	}
+	for _, payment := range []namedRecurring{{"Salary", c.Salary}, {"Rent", c.Rent}} {
+		if payment.Chance < 0 || payment.Chance > 1 {
+			return errors.New(fmt.Sprintf("%s Chance must be between 0 and 1 [Chance: %v]", payment.name, payment.Chance))
+		}
+		if payment.DayOfMonth < 0 || payment.DayOfMonth > 28 {
+			return errors.New(fmt.Sprintf("%s DayOfMonth must be between 1 and 28, or 0 [DayOfMonth: %d]", payment.name, payment.DayOfMonth))
+		}
+		if payment.Chance > 0 {
+			distributions = append(distributions, namedDistribution{payment.name + " Amount", payment.Amount})
+		}
+	}
+	for _, distribution := range distributions {
+		if distribution.Min < 1 || distribution.Max < distribution.Min || distribution.Median < distribution.Min || distribution.Median > distribution.Max {
+			return errors.New(fmt.Sprintf("%s requires 1 <= Min <= Median <= Max [%+v]", distribution.name, distribution.Distribution))
+		}
+		if distribution.Spread < 0 {
+			return errors.New(fmt.Sprintf("%s Spread cannot be negative [%+v]", distribution.name, distribution.Distribution))
+		}
+	}
+
+	return nil
+}
+
+type namedDistribution struct {
+	name string
+	Distribution
+}
+
+type namedRecurring struct {
+	name string
+	Recurring
+}
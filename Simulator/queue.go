@@ -0,0 +1,54 @@
+package Simulator
+
+import "time"
+
+type actionKind int
+
+const (
+	actionArrive actionKind = iota
+	actionVerify
+	actionOpen
+	actionDeposit
+	actionWithdraw
+	actionSalary
+	actionRent
+	actionClose
+)
+
+// action: something a customer will do at a point in the simulation
+type action struct {
+	at       time.Time
+	sequence uint // actions due at the same time happen in the order they were scheduled
+	kind     actionKind
+	customer *customer
+}
+
+// actionQueue: a container/heap of actions, earliest first
+type actionQueue []*action
+
+func (aq actionQueue) Len() int {
+	return len(aq)
+}
+
+func (aq actionQueue) Less(i, j int) bool {
+	if aq[i].at.Equal(aq[j].at) {
+		return aq[i].sequence < aq[j].sequence
+	}
+	return aq[i].at.Before(aq[j].at)
+}
+
+func (aq actionQueue) Swap(i, j int) {
+	aq[i], aq[j] = aq[j], aq[i]
+}
+
+func (aq *actionQueue) Push(x interface{}) {
+	*aq = append(*aq, x.(*action))
+}
+
+func (aq *actionQueue) Pop() interface{} {
+	old := *aq
+	last := old[len(old)-1]
+	old[len(old)-1] = nil
+	*aq = old[:len(old)-1]
+	return last
+}
@@ -0,0 +1,297 @@
+package Simulator
+
+import (
+	"container/heap"
+	"fmt"
+	"github.com/agemmell/banking-cqrs-es-go/CheckingAccountService"
+	"github.com/agemmell/banking-cqrs-es-go/CustomerService"
+	"github.com/agemmell/banking-cqrs-es-go/Seacrest"
+	"github.com/icrowley/fake"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// averageMonth: the length of a month when turning monthly rates into waits between transactions
+const averageMonth = time.Duration(365.25 / 12 * float64(24*time.Hour))
+
+// running: fake draws names and addresses from one global random source, so only one simulation can run at a time
+// if runs are to be repeatable
+var running sync.Mutex
+
+// Result: what happened during a simulation
+type Result struct {
+	Customers      int // customers who opened an account
+	ClosedAccounts int
+	Commands       int // commands handled by the services
+	Events         int // events persisted
+}
+
+// customer: a simulated customer and their checking account
+type customer struct {
+	id        string
+	accountID string
+	name      string
+	salary    int // 0 if the customer isn't paid a salary
+	salaryDay int
+	rent      int // 0 if the customer doesn't pay rent
+	rentDay   int
+	closed    bool
+}
+
+// Simulator: runs a workload through the real command handlers. Every command is handled at its business time on a
+// simulated clock shared with the event store, so events are recorded in the order they happened.
+type Simulator struct {
+	config     Config
+	random     *rand.Rand // workload decisions
+	ids        *rand.Rand // customer, account and event IDs, kept apart so IDs don't change the workload
+	clock      *Seacrest.FakeClock
+	eventStore *Seacrest.EventStore
+	customers  CustomerService.CustomerService
+	accounts   CheckingAccountService.CheckingAccountService
+	queue      actionQueue
+	scheduled  uint
+	result     Result
+}
+
+// Run: simulate the workload described by the config, returning the event store the events were persisted to
+func Run(config Config) (*Seacrest.EventStore, Result, error) {
+	err := config.Validate()
+	if err != nil {
+		return nil, Result{}, err
+	}
+
+	running.Lock()
+	defer running.Unlock()
+
+	s := Simulator{
+		config: config,
+		random: rand.New(rand.NewSource(config.Seed)),
+		ids:    rand.New(rand.NewSource(config.Seed + 1)),
+		clock:  Seacrest.NewFakeClock(config.Start),
+	}
+	fake.Seed(config.Seed)
+	s.eventStore = Seacrest.NewEventStore(Seacrest.WithClock(s.clock), Seacrest.WithIDGenerator(seededIDs{s.ids}))
+	s.customers = CustomerService.New(s.eventStore, CustomerService.WithClock(s.clock))
+	s.accounts = CheckingAccountService.New(s.eventStore, CheckingAccountService.WithClock(s.clock))
+
+	s.schedule(actionArrive, config.Start.Add(s.wait(config.ArrivalsPerDay, 24*time.Hour)), nil)
+	for s.queue.Len() > 0 {
+		next := heap.Pop(&s.queue).(*action)
+		if next.customer != nil && next.customer.closed {
+			continue
+		}
+		s.clock.Set(next.at)
+		err = s.run(next)
+		if err != nil {
+			return nil, s.result, err
+		}
+	}
+
+	s.result.Events = len(s.eventStore.GetAllEvents())
+	return s.eventStore, s.result, nil
+}
+
+// schedule: queue an action, unless it would happen after the simulation ends
+func (s *Simulator) schedule(kind actionKind, at time.Time, holder *customer) {
+	if !at.Before(s.config.End) {
+		return
+	}
+	s.scheduled++
+	heap.Push(&s.queue, &action{at: at, sequence: s.scheduled, kind: kind, customer: holder})
+}
+
+// wait: the time until the next of a series of events that happen at random at an average rate per period
+func (s *Simulator) wait(rate float64, period time.Duration) time.Duration {
+	return time.Duration(s.random.ExpFloat64() / rate * float64(period))
+}
+
+// amount: an amount drawn from a distribution
+func (s *Simulator) amount(distribution Distribution) int {
+	amount := int(math.Round(float64(distribution.Median) * math.Exp(distribution.Spread*s.random.NormFloat64())))
+	if amount < distribution.Min {
+		return distribution.Min
+	}
+	if amount > distribution.Max {
+		return distribution.Max
+	}
+	return amount
+}
+
+// nextPaymentDate: the first time on the given day of the month that is after the given time
+func nextPaymentDate(after time.Time, day int, hour int) time.Time {
+	after = after.UTC()
+	date := time.Date(after.Year(), after.Month(), day, hour, 0, 0, 0, time.UTC)
+	if !date.After(after) {
+		date = date.AddDate(0, 1, 0)
+	}
+	return date
+}
+
+// handle: send a command to whichever service handles it
+func (s *Simulator) handle(command interface{}) error {
+	s.result.Commands++
+	switch command := command.(type) {
+	case CustomerService.Command:
+		return s.customers.HandleCommand(command)
+	case CheckingAccountService.Command:
+		return s.accounts.HandleCommand(command)
+	}
+	return fmt.Errorf("no service handles %T", command)
+}
+
+// run: carry out an action and schedule what the customer does next
+func (s *Simulator) run(next *action) error {
+	holder := next.customer
+	now := next.at
+
+	switch next.kind {
+	case actionArrive:
+		err := s.arrive(now)
+		if err != nil {
+			return err
+		}
+		if s.result.Customers < s.config.Customers {
+			s.schedule(actionArrive, now.Add(s.wait(s.config.ArrivalsPerDay, 24*time.Hour)), nil)
+		}
+		return nil
+
+	case actionVerify:
+		s.schedule(actionOpen, now.Add(time.Duration(1+s.random.Intn(30))*time.Minute), holder)
+		return s.handle(CustomerService.VerifyKYC{ID: holder.id, VerifiedBy: "onboarding"})
+
+	case actionOpen:
+		err := s.handle(CheckingAccountService.OpenAccount{ID: holder.accountID, CustomerID: holder.id})
+		if err != nil {
+			return err
+		}
+		if s.config.DepositsPerMonth > 0 {
+			s.schedule(actionDeposit, now.Add(s.wait(s.config.DepositsPerMonth, averageMonth)), holder)
+		}
+		if s.config.WithdrawalsPerMonth > 0 {
+			s.schedule(actionWithdraw, now.Add(s.wait(s.config.WithdrawalsPerMonth, averageMonth)), holder)
+		}
+		if holder.salary > 0 {
+			s.schedule(actionSalary, nextPaymentDate(now, holder.salaryDay, 6), holder)
+		}
+		if holder.rent > 0 {
+			s.schedule(actionRent, nextPaymentDate(now, holder.rentDay, 8), holder)
+		}
+		if s.random.Float64() < s.config.CloseChance {
+			s.schedule(actionClose, now.Add(time.Duration(s.random.Int63n(int64(s.config.End.Sub(now))))), holder)
+		}
+		return nil
+
+	case actionDeposit:
+		s.schedule(actionDeposit, now.Add(s.wait(s.config.DepositsPerMonth, averageMonth)), holder)
+		return s.handle(CheckingAccountService.DepositMoney{ID: holder.accountID, Amount: s.amount(s.config.DepositSize), ActingAs: holder.id})
+
+	case actionWithdraw:
+		s.schedule(actionWithdraw, now.Add(s.wait(s.config.WithdrawalsPerMonth, averageMonth)), holder)
+		summary, err := s.accounts.GetAccountSummary(holder.accountID, CheckingAccountService.SystemPrincipal)
+		if err != nil {
+			return err
+		}
+		amount := s.amount(s.config.WithdrawalSize)
+		if amount > summary.AvailableBalance {
+			amount = summary.AvailableBalance
+		}
+		if amount <= 0 {
+			return nil
+		}
+		return s.handle(CheckingAccountService.WithdrawMoney{ID: holder.accountID, Amount: amount, ActingAs: holder.id})
+
+	case actionSalary:
+		s.schedule(actionSalary, nextPaymentDate(now, holder.salaryDay, 6), holder)
+		return s.handle(CheckingAccountService.DepositMoney{ID: holder.accountID, Amount: holder.salary, ActingAs: holder.id})
+
+	case actionRent:
+		s.schedule(actionRent, nextPaymentDate(now, holder.rentDay, 8), holder)
+		return s.handle(CheckingAccountService.WithdrawMoney{ID: holder.accountID, Amount: holder.rent, ActingAs: holder.id})
+
+	case actionClose:
+		summary, err := s.accounts.GetAccountSummary(holder.accountID, CheckingAccountService.SystemPrincipal)
+		if err != nil {
+			return err
+		}
+		if summary.LedgerBalance > 0 {
+			err = s.handle(CheckingAccountService.WithdrawMoney{ID: holder.accountID, Amount: summary.LedgerBalance, ActingAs: holder.id})
+			if err != nil {
+				return err
+			}
+		}
+		err = s.handle(CheckingAccountService.CloseAccount{ID: holder.accountID, ActingAs: holder.id})
+		if err != nil {
+			return err
+		}
+		holder.closed = true
+		s.result.ClosedAccounts++
+		return nil
+	}
+
+	return fmt.Errorf("unknown simulation action %d", next.kind)
+}
+
+// arrive: a new customer registers, then passes KYC and opens an account shortly afterwards
+func (s *Simulator) arrive(now time.Time) error {
+	newCustomer := &customer{name: fake.FullName()}
+	var err error
+	newCustomer.id, err = seededIDs{s.ids}.NewID()
+	if err != nil {
+		return err
+	}
+	newCustomer.accountID, err = seededIDs{s.ids}.NewID()
+	if err != nil {
+		return err
+	}
+	if s.random.Float64() < s.config.Salary.Chance {
+		newCustomer.salary = s.amount(s.config.Salary.Amount)
+		newCustomer.salaryDay = s.dayOfMonth(s.config.Salary.DayOfMonth)
+	}
+	if s.random.Float64() < s.config.Rent.Chance {
+		newCustomer.rent = s.amount(s.config.Rent.Amount)
+		newCustomer.rentDay = s.dayOfMonth(s.config.Rent.DayOfMonth)
+	}
+	s.result.Customers++
+
+	dateOfBirth := now.AddDate(-18-s.random.Intn(62), -s.random.Intn(12), -s.random.Intn(28))
+	err = s.handle(CustomerService.RegisterCustomer{
+		ID:          newCustomer.id,
+		Name:        newCustomer.name,
+		DateOfBirth: dateOfBirth.Format("2006-01-02"),
+		Address: CustomerService.Address{
+			Line1:    fake.StreetAddress(),
+			City:     fake.City(),
+			Postcode: fake.Zip(),
+			Country:  fake.Country(),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	s.schedule(actionVerify, now.Add(time.Duration(10+s.random.Intn(110))*time.Minute), newCustomer)
+	return nil
+}
+
+// dayOfMonth: the configured day, or a random one for each customer if none was configured
+func (s *Simulator) dayOfMonth(configured int) int {
+	if configured > 0 {
+		return configured
+	}
+	return 1 + s.random.Intn(28)
+}
+
+// seededIDs: version 4 UUIDs drawn from a seeded random source, so simulations can be repeated exactly
+type seededIDs struct {
+	random *rand.Rand
+}
+
+func (si seededIDs) NewID() (string, error) {
+	var id [16]byte
+	si.random.Read(id[:])
+	id[6] = id[6]&0x0f | 0x40 // version 4
+	id[8] = id[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10], id[10:]), nil
+}
@@ -0,0 +1,140 @@
+package Simulator
+
+import (
+	"github.com/agemmell/banking-cqrs-es-go/CheckingAccountService"
+	"github.com/agemmell/banking-cqrs-es-go/EventRegistry"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func smallConfig() Config {
+	return Config{
+		Customers:           50,
+		Seed:                7,
+		Start:               time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
+		End:                 time.Date(2020, time.April, 1, 0, 0, 0, 0, time.UTC),
+		ArrivalsPerDay:      5,
+		DepositsPerMonth:    2,
+		WithdrawalsPerMonth: 3,
+		DepositSize:         Distribution{Median: 200, Spread: 1, Min: 1, Max: 5000},
+		WithdrawalSize:      Distribution{Median: 150, Spread: 1, Min: 1, Max: 5000},
+		Salary:              Recurring{Chance: 0.5, Amount: Distribution{Median: 2500, Spread: 0.3, Min: 1000, Max: 9000}, DayOfMonth: 25},
+		Rent:                Recurring{Chance: 0.5, Amount: Distribution{Median: 1200, Spread: 0.3, Min: 500, Max: 4000}},
+		CloseChance:         0.2,
+	}
+}
+
+func Test_RunIsRepeatable(t *testing.T) {
+	t.Parallel()
+
+	// When
+	first, firstResult, err := Run(smallConfig())
+	assert.Nil(t, err)
+	second, secondResult, err := Run(smallConfig())
+	assert.Nil(t, err)
+
+	// Then
+	assert.Equal(t, firstResult, secondResult)
+	assert.Equal(t, first.GetAllEvents(), second.GetAllEvents())
+	assert.Equal(t, 50, firstResult.Customers)
+	assert.Equal(t, firstResult.Events, len(first.GetAllEvents()))
+}
+
+func Test_EventsAreRecordedInBusinessTimeOrder(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	config := smallConfig()
+
+	// When
+	eventStore, _, err := Run(config)
+	assert.Nil(t, err)
+
+	// Then every envelope is recorded when its event happened, so global order follows the business timeline
+	previous := int64(0)
+	for i, envelope := range eventStore.GetAllEvents() {
+		event, err := EventRegistry.Decode(envelope)
+		assert.Nil(t, err)
+		assert.Equal(t, uint(i+1), envelope.Order)
+		assert.Equal(t, event.EventTimestamp(), envelope.RecordedAt, "%s recorded at a different time to when it happened", envelope.EventType)
+		assert.GreaterOrEqual(t, envelope.RecordedAt, previous)
+		assert.GreaterOrEqual(t, envelope.RecordedAt, config.Start.UnixNano())
+		assert.Less(t, envelope.RecordedAt, config.End.UnixNano())
+		previous = envelope.RecordedAt
+	}
+}
+
+func Test_TransactionsFollowTheConfiguredPatterns(t *testing.T) {
+	t.Parallel()
+
+	// Given only salaries are paid in, and every customer closes their account
+	config := smallConfig()
+	config.DepositsPerMonth = 0
+	config.Salary.Chance = 1
+	config.CloseChance = 1
+
+	// When
+	eventStore, result, err := Run(config)
+	assert.Nil(t, err)
+
+	// Then
+	deposits := 0
+	closed := 0
+	for _, envelope := range eventStore.GetAllEvents() {
+		event, err := EventRegistry.Decode(envelope)
+		assert.Nil(t, err)
+		switch event := event.(type) {
+		case *CheckingAccountService.MoneyWasDeposited:
+			deposits++
+			assert.Greater(t, event.Amount, 0)
+			assert.Equal(t, 25, time.Unix(0, event.Timestamp).UTC().Day())
+		case *CheckingAccountService.MoneyWasWithdrawn:
+			assert.Greater(t, event.Amount, 0)
+			assert.GreaterOrEqual(t, event.Balance, 0)
+		case *CheckingAccountService.AccountWasClosed:
+			closed++
+		}
+	}
+	assert.Greater(t, deposits, 0)
+	assert.Equal(t, result.Customers, closed)
+	assert.Equal(t, result.Customers, result.ClosedAccounts)
+}
+
+func Test_LoadConfig(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	dir, err := ioutil.TempDir("", "simulator")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	invalid := filepath.Join(dir, "invalid.json")
+	err = ioutil.WriteFile(invalid, []byte(`{"Customers": 10, "Start": "2020-01-01T00:00:00Z", "End": "2019-01-01T00:00:00Z"}`), 0644)
+	assert.Nil(t, err)
+
+	// When
+	config, err := LoadConfig("../simulation.json")
+	_, invalidErr := LoadConfig(invalid)
+
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, int64(99), config.Seed)
+	assert.Equal(t, "a simulation must end after it starts [Start: 2020-01-01 00:00:00 +0000 UTC, End: 2019-01-01 00:00:00 +0000 UTC]", invalidErr.Error())
+}
+
+func Test_ValidateDistributions(t *testing.T) {
+	t.Parallel()
+
+	// Given a distribution that could produce deposits of nothing
+	config := smallConfig()
+	config.DepositSize.Min = 0
+
+	// When
+	err := config.Validate()
+
+	// Then
+	assert.Equal(t, "DepositSize requires 1 <= Min <= Median <= Max [{Median:200 Spread:1 Min:0 Max:5000}]", err.Error())
+}
@@ -1,13 +1,19 @@
 module github.com/agemmell/banking-cqrs-es-go
 
-go 1.14
+go 1.18
 
 require (
-	github.com/corpix/uarand v0.1.1 // indirect
 	github.com/icrowley/fake v0.0.0-20180203215853-4178557ae428
-	github.com/kr/pretty v0.1.0 // indirect
 	github.com/nu7hatch/gouuid v0.0.0-20131221200532-179d4d0c4d8d
 	github.com/stretchr/testify v1.6.1
 	golang.org/x/text v0.3.2
+)
+
+require (
+	github.com/corpix/uarand v0.1.1 // indirect
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/kr/pretty v0.1.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
 	gopkg.in/check.v1 v1.0.0-20180628173108-788fd7840127 // indirect
+	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c // indirect
 )
@@ -1,95 +1,449 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"github.com/agemmell/banking-cqrs-es-go/CheckingAccountService"
 	"github.com/agemmell/banking-cqrs-es-go/Projections"
+	"github.com/agemmell/banking-cqrs-es-go/ReadModelStore"
 	"github.com/agemmell/banking-cqrs-es-go/Seacrest"
+	"github.com/agemmell/banking-cqrs-es-go/Simulator"
 	"os"
+	"runtime"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// The simulated events, and the projection checkpoints and read models built from them
+const eventsFile = "generated_events.txt"
+const checkpointsDir = "checkpoints"
+const readModelsDir = "readmodels"
+
+const statementPageSize = 20
+const accountQueryLimit = 20
+
 func main() {
-	// Generate some Checking Account events
-	GenerateCheckingAccountEvents()
+	asOfTime := flag.String("as-of", "", "report balances as of this RFC 3339 time (e.g. 2020-06-30T23:59:00Z) instead of running the projections")
+	asOfPosition := flag.Uint("position", 0, "report balances as of this global event position instead of running the projections")
+	accountID := flag.String("account", "", "with -as-of or -position, report this account's balance rather than the bank's")
+	statementID := flag.String("statement", "", "print this account's statement instead of running the projections")
+	from := flag.String("from", "", "with -statement, list transactions from this RFC 3339 time")
+	to := flag.String("to", "", "with -statement, list transactions before this RFC 3339 time")
+	page := flag.Int("page", 1, "with -statement, the page of transactions to list")
+	findName := flag.String("find-name", "", "list the accounts whose holder's name starts with this, from the read models")
+	findStatus := flag.String("find-status", "", "list the accounts with this status (active, frozen, dormant or closed), from the read models")
+	findBalance := flag.String("find-balance", "", "list the accounts with a balance in this range (e.g. 1000:5000), from the read models")
+	workers := flag.Int("workers", runtime.NumCPU(), "how many goroutines rebuild the projections when there are no checkpoints")
+	deposit := flag.String("deposit", "", "deposit into an account, given as ACCOUNT:AMOUNT, while the projections are hosted")
+	flag.Parse()
 
-	// Run the projections
-	RunProjections()
+	// Simulate a year of customer activity, unless it has already been simulated. Delete the events file to simulate
+	// again.
+	if _, err := os.Stat(eventsFile); os.IsNotExist(err) {
+		GenerateCheckingAccountEvents()
+	}
+
+	if *findName != "" || *findStatus != "" || *findBalance != "" {
+		RunAccountQuery(*findName, *findStatus, *findBalance)
+		return
+	}
+
+	if *statementID != "" {
+		query := Projections.StatementQuery{Page: *page, PageSize: statementPageSize}
+		query.From = parseTimeOrExit(*from)
+		query.To = parseTimeOrExit(*to)
+		RunStatement(*statementID, query)
+		return
+	}
+
+	if *asOfTime == "" && *asOfPosition == 0 {
+		// Run the projections
+		RunProjections(*workers, *deposit)
+		return
+	}
+
+	asOf := CheckingAccountService.AsOf{Timestamp: parseTimeOrExit(*asOfTime), Position: *asOfPosition}
+	RunPointInTimeQuery(asOf, *accountID)
+}
+
+// parseBalanceRangeOrExit: a balance range written as min:max
+func parseBalanceRangeOrExit(value string) (int, int) {
+	bounds := strings.Split(value, ":")
+	if len(bounds) == 2 {
+		min, minErr := strconv.Atoi(bounds[0])
+		max, maxErr := strconv.Atoi(bounds[1])
+		if minErr == nil && maxErr == nil {
+			return min, max
+		}
+	}
+	handleErrorAndExit(errors.New(fmt.Sprintf("a balance range looks like 1000:5000, not %q", value)))
+	return 0, 0
+}
+
+// parseTimeOrExit: an RFC 3339 time as unix nanoseconds, or 0 if it is empty
+func parseTimeOrExit(value string) int64 {
+	if value == "" {
+		return 0
+	}
+	moment, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		handleErrorAndExit(err)
+	}
+	return moment.UnixNano()
 }
 
 func GenerateCheckingAccountEvents() {
-	eventStore := Seacrest.NewEventStore()
-	cas := CheckingAccountService.New(eventStore)
+	config, err := Simulator.LoadConfig("simulation.json")
+	if err != nil {
+		handleErrorAndExit(err)
+	}
 
-	fmt.Print("[generating events...")
+	fmt.Print("[simulating events...")
 	timer := time.Now()
-	err := cas.AutoGenerateEvents()
+	eventStore, result, err := Simulator.Run(config)
 	if err != nil {
-		fmt.Printf("error %+v", err)
-		os.Exit(1)
+		handleErrorAndExit(err)
 	}
 	diff := time.Now().Sub(timer)
 	fmt.Printf(" done] (%s)\n", diff.String())
+	fmt.Printf("  %d customers, %d closed accounts, %d commands, %d events\n", result.Customers, result.ClosedAccounts, result.Commands, result.Events)
 
 	fmt.Print("[writing events to file...")
 	timer = time.Now()
-	err = cas.WriteEventsToFile("generated_events.txt")
+	err = eventStore.WriteEventsToFile(eventsFile)
 	if err != nil {
 		handleErrorAndExit(err)
 	}
 	diff = time.Now().Sub(timer)
 	fmt.Printf(" done] (%s)\n", diff.String())
+
+	// Checkpoints and read models from earlier runs describe different events
+	for _, dir := range []string{checkpointsDir, readModelsDir} {
+		err = os.RemoveAll(dir)
+		if err != nil {
+			handleErrorAndExit(err)
+		}
+	}
 }
 
 func LoadCheckingAccountEvents(eventStore *Seacrest.EventStore) {
-	err := eventStore.LoadEventsFromFile("generated_events.txt")
+	err := eventStore.LoadEventsFromFile(eventsFile)
 	if err != nil {
 		handleErrorAndExit(err)
 	}
 }
 
-func RunProjections() {
-	eventStore := Seacrest.NewEventStore()
-	fmt.Print("[loading events into event store...")
+// RunProjections: bring the projections up to date and report them. Without checkpoints they are first built across
+// `workers` goroutines. The projections are then hosted, so they follow the event store as commands are handled;
+// a deposit given as ACCOUNT:AMOUNT is handled while they are, and is waited for before they are reported.
+func RunProjections(workers int, deposit string) {
+	eventStore := loadEventStore()
+
+	bankFunds := Projections.NewBankFundsProjector()
+	accountCounts := Projections.NewAccountCountsProjector()
+	highestBalances := Projections.NewHighestBalancesProjector()
+	balancePerMonth := Projections.NewBalancePerMonthProjector()
+	feeIncome := Projections.NewFeeIncomeProjector()
+	frozenAccounts := Projections.NewFrozenAccountsProjector()
+	customerAccounts := Projections.NewCustomerAccountsProjector()
+	bankFundsHistory := Projections.NewBankFundsHistoryProjector()
+	readModels, directory := openAccountDirectory()
+	accountDirectory := Projections.NewAccountDirectoryProjector(directory)
+	projectors := []Projections.Projector{bankFunds, accountCounts, highestBalances, balancePerMonth, feeIncome, frozenAccounts, customerAccounts, bankFundsHistory, accountDirectory}
+
+	// The account directory's checkpoints are committed to the read models along with the directory
+	fileCheckpoints, err := Projections.NewFileCheckpointStore(checkpointsDir)
+	if err != nil {
+		handleErrorAndExit(err)
+	}
+	checkpoints, err := Projections.NewReadModelCheckpointStore(readModels, fileCheckpoints, accountDirectory)
+	if err != nil {
+		handleErrorAndExit(err)
+	}
+	fmt.Printf("\nRunning projections:\n")
+	buildUncheckpointedProjections(eventStore, projectors, checkpoints, workers)
+
+	// Host the projections, catching them up on any events persisted since their checkpoints
 	timer := time.Now()
-	LoadCheckingAccountEvents(eventStore)
+	host, err := Projections.NewProjectionHost(eventStore, projectors...)
+	if err != nil {
+		handleErrorAndExit(err)
+	}
+	err = host.ResumeFrom(checkpoints, 500000)
+	if err != nil {
+		handleErrorAndExit(err)
+	}
+	for _, name := range host.Names() {
+		rebuilding, _ := host.Rebuilding(name)
+		if rebuilding {
+			fmt.Printf("[rebuilding %s: its checkpoint was saved by another version]\n", name)
+		}
+	}
+	lags := host.Lags()
+	host.Start()
+	waitForProjections(host, eventStore.GlobalOrder())
 	diff := time.Now().Sub(timer)
-	fmt.Printf(" done] (%s)\n", diff.String())
+	fmt.Printf("[%d projections hosted, caught up on %s] (%s)\n", len(projectors), describeLags(lags), diff.String())
 
-	// Run a projection
-	fmt.Printf("\nRunning projections:\n")
+	if deposit != "" {
+		accountID, amount := parseDepositOrExit(deposit)
+		timer = time.Now()
+		cas := CheckingAccountService.New(eventStore)
+		err = cas.HandleCommand(CheckingAccountService.DepositMoney{ID: accountID, Amount: amount, ActingAs: CheckingAccountService.SystemPrincipal})
+		if err != nil {
+			handleErrorAndExit(err)
+		}
+		waitForProjections(host, eventStore.GlobalOrder())
+		diff = time.Now().Sub(timer)
+		fmt.Printf("[deposited %d into %s, every projection has it at event %d] (%s)\n", amount, accountID, eventStore.GlobalOrder(), diff.String())
 
-	timer = time.Now()
-	err := Projections.TotalBankFunds(eventStore)
+		// The events are saved before the projections checkpoint, so no checkpoint is ever ahead of the events file
+		err = eventStore.WriteEventsToFile(eventsFile)
+		if err != nil {
+			handleErrorAndExit(err)
+		}
+	}
+
+	err = host.Stop()
 	if err != nil {
 		handleErrorAndExit(err)
 	}
-	diff = time.Now().Sub(timer)
-	fmt.Printf("[TotalBankFunds done] (%s)\n\n", diff.String())
+	fmt.Println()
 
-	timer = time.Now()
-	err = Projections.OpenClosedAccounts(eventStore)
+	// A projection rebuilt while it was hosted has been swapped for a new projector, so the read models are viewed
+	// through the host
+	views := map[string]func(projector Projections.Projector){
+		bankFunds.Name(): func(projector Projections.Projector) {
+			printBankFunds(projector.(*Projections.BankFundsProjector).BankFunds())
+		},
+		accountCounts.Name(): func(projector Projections.Projector) {
+			printAccountCounts(projector.(*Projections.AccountCountsProjector).AccountCounts())
+		},
+		highestBalances.Name(): func(projector Projections.Projector) {
+			printHighestBalances(projector.(*Projections.HighestBalancesProjector).HighestBalances())
+		},
+		balancePerMonth.Name(): func(projector Projections.Projector) {
+			printBalancePerMonth(projector.(*Projections.BalancePerMonthProjector).BalancePerMonth())
+		},
+		feeIncome.Name(): func(projector Projections.Projector) {
+			printFeeIncome(projector.(*Projections.FeeIncomeProjector).FeeIncome())
+		},
+		frozenAccounts.Name(): func(projector Projections.Projector) {
+			printFrozenAccounts(projector.(*Projections.FrozenAccountsProjector).FrozenAccounts())
+		},
+		customerAccounts.Name(): func(projector Projections.Projector) {
+			printCustomerAccounts(Projections.SummariseCustomerAccounts(projector.(*Projections.CustomerAccountsProjector).CustomerAccounts()))
+		},
+	}
+	for _, name := range host.Names() {
+		if view, ok := views[name]; ok {
+			err = host.View(name, view)
+			if err != nil {
+				handleErrorAndExit(err)
+			}
+		}
+	}
+	printAccountDirectory(directory)
+}
+
+// buildUncheckpointedProjections: when none of the projections have been checkpointed, build them all in a single
+// pass over the events spread across `workers` goroutines, and checkpoint them. Hosting builds projections one event
+// at a time, which is only worth it for the events since their checkpoints.
+func buildUncheckpointedProjections(eventStore *Seacrest.EventStore, projectors []Projections.Projector, checkpoints Projections.CheckpointStore, workers int) {
+	for _, projector := range projectors {
+		_, found, err := checkpoints.Load(projector.Name())
+		if err != nil {
+			handleErrorAndExit(err)
+		}
+		if found {
+			return
+		}
+	}
+
+	timer := time.Now()
+	runner := Projections.NewRunner(projectors...)
+	err := runner.ResumeFrom(checkpoints, 500000)
 	if err != nil {
 		handleErrorAndExit(err)
 	}
-	diff = time.Now().Sub(timer)
-	fmt.Printf("[OpenClosedAccounts done] (%s)\n\n", diff.String())
+	err = runner.RunParallel(eventStore, workers)
+	if err != nil {
+		handleErrorAndExit(err)
+	}
+	diff := time.Now().Sub(timer)
+	fmt.Printf("[%d projections built] (%s)\n", len(projectors), diff.String())
+}
 
-	timer = time.Now()
-	err = Projections.HighestBalanceOwners(eventStore)
+// waitForProjections: wait until every hosted projection has applied the events up to position
+func waitForProjections(host *Projections.ProjectionHost, position uint) {
+	for _, name := range host.Names() {
+		err := host.WaitForPosition(name, position, time.Minute)
+		if err != nil {
+			handleErrorAndExit(err)
+		}
+	}
+}
+
+// describeLags: the most events any projection was behind
+func describeLags(lags map[string]uint) string {
+	most := uint(0)
+	for _, lag := range lags {
+		if lag > most {
+			most = lag
+		}
+	}
+	return fmt.Sprintf("%d new events", most)
+}
+
+// parseDepositOrExit: a deposit written as ACCOUNT:AMOUNT
+func parseDepositOrExit(value string) (string, int) {
+	separator := strings.LastIndex(value, ":")
+	if separator > 0 {
+		amount, err := strconv.Atoi(value[separator+1:])
+		if err == nil {
+			return value[:separator], amount
+		}
+	}
+	handleErrorAndExit(errors.New(fmt.Sprintf("a deposit looks like ACCOUNT:1000, not %q", value)))
+	return "", 0
+}
+
+func openAccountDirectory() (*ReadModelStore.Store, *Projections.AccountDirectory) {
+	readModels, err := ReadModelStore.Open(readModelsDir)
 	if err != nil {
 		handleErrorAndExit(err)
 	}
-	diff = time.Now().Sub(timer)
-	fmt.Printf("[HighestBalanceOwners done] (%s)\n\n", diff.String())
+	directory, err := Projections.NewAccountDirectory(readModels)
+	if err != nil {
+		handleErrorAndExit(err)
+	}
+	return readModels, directory
+}
 
-	timer = time.Now()
-	err = Projections.TotalBalancePerMonth(eventStore)
+// RunAccountQuery: find accounts in the account directory the projections last committed, without loading any events.
+// The first of the name, status and balance range given picks the index; the others filter what it finds.
+func RunAccountQuery(name string, status string, balanceRange string) {
+	readModels, directory := openAccountDirectory()
+	accountDirectory := Projections.NewAccountDirectoryProjector(directory)
+	checkpoints, err := Projections.NewReadModelCheckpointStore(readModels, nil, accountDirectory)
 	if err != nil {
 		handleErrorAndExit(err)
 	}
-	diff = time.Now().Sub(timer)
-	fmt.Printf("[TotalBalancePerMonth done] (%s)\n\n", diff.String())
+	checkpoint, found, err := checkpoints.Load(accountDirectory.Name())
+	if err != nil {
+		handleErrorAndExit(err)
+	}
+	if !found {
+		handleErrorAndExit(errors.New("the account directory is empty; run the projections first"))
+	}
+
+	min, max := 0, 0
+	if balanceRange != "" {
+		min, max = parseBalanceRangeOrExit(balanceRange)
+	}
+	var accounts []Projections.AccountRecord
+	switch {
+	case name != "":
+		accounts, err = directory.NamesStartingWith(name)
+	case status != "":
+		accounts, err = directory.WithStatus(status)
+	default:
+		accounts, err = directory.WithBalanceBetween(min, max)
+	}
+	if err != nil {
+		handleErrorAndExit(err)
+	}
+
+	var matching []Projections.AccountRecord
+	for _, account := range accounts {
+		if status != "" && account.Status != status {
+			continue
+		}
+		if balanceRange != "" && (account.Balance < min || account.Balance > max) {
+			continue
+		}
+		matching = append(matching, account)
+	}
+	printAccountQuery(matching, checkpoint.Position, accountQueryLimit)
+}
+
+// RunPointInTimeQuery: report an account's balance, or the bank's funds, as they stood at a point in history
+func RunPointInTimeQuery(asOf CheckingAccountService.AsOf, accountID string) {
+	err := asOf.Validate()
+	if err != nil {
+		handleErrorAndExit(err)
+	}
+	eventStore := loadEventStore()
+
+	if accountID != "" {
+		cas := CheckingAccountService.New(eventStore)
+		summary, err := cas.GetAccountSummaryAsOf(accountID, CheckingAccountService.SystemPrincipal, asOf)
+		if err != nil {
+			handleErrorAndExit(err)
+		}
+		printAccountSummaryAsOf(summary, asOf)
+		return
+	}
+
+	// The history is indexed as the projections run, so only events persisted since their last checkpoint are indexed
+	fmt.Print("[indexing bank funds history...")
+	timer := time.Now()
+	history := Projections.NewBankFundsHistoryProjector()
+	checkpoints, err := Projections.NewFileCheckpointStore(checkpointsDir)
+	if err != nil {
+		handleErrorAndExit(err)
+	}
+	runner := Projections.NewRunner(history)
+	err = runner.ResumeFrom(checkpoints, 500000)
+	if err != nil {
+		handleErrorAndExit(err)
+	}
+	resumedFrom := runner.Position()
+	err = runner.Run(eventStore)
+	if err != nil {
+		handleErrorAndExit(err)
+	}
+	diff := time.Now().Sub(timer)
+	fmt.Printf(" done, %d new events] (%s)\n\n", runner.Position()-resumedFrom, diff.String())
+
+	if asOf.Position != 0 {
+		printBankFundsAsOf(history.AsOfPosition(asOf.Position), asOf)
+	} else {
+		printBankFundsAsOf(history.AsOfTimestamp(asOf.Timestamp), asOf)
+	}
+}
+
+// RunStatement: print a page of an account's statement
+func RunStatement(accountID string, query Projections.StatementQuery) {
+	eventStore := loadEventStore()
+
+	fmt.Print("[building statements...")
+	timer := time.Now()
+	statements := Projections.NewStatementsProjector()
+	err := Projections.NewRunner(statements).Run(eventStore)
+	if err != nil {
+		handleErrorAndExit(err)
+	}
+	diff := time.Now().Sub(timer)
+	fmt.Printf(" done] (%s)\n\n", diff.String())
+
+	statement, err := statements.Statement(accountID, query)
+	if err != nil {
+		handleErrorAndExit(err)
+	}
+	printStatement(statement)
+}
+
+func loadEventStore() *Seacrest.EventStore {
+	eventStore := Seacrest.NewEventStore()
+	fmt.Print("[loading events into event store...")
+	timer := time.Now()
+	LoadCheckingAccountEvents(eventStore)
+	diff := time.Now().Sub(timer)
+	fmt.Printf(" done] (%s)\n", diff.String())
+	return eventStore
 }
 
 func handleErrorAndExit(err error) {
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"github.com/agemmell/banking-cqrs-es-go/CheckingAccountService"
+	"github.com/agemmell/banking-cqrs-es-go/Projections"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"sort"
+	"time"
+)
+
+var printer = message.NewPrinter(language.English)
+
+func printBankFunds(bankFunds Projections.BankFunds) {
+	fmt.Printf("Total Banks Funds = $%s\n", printer.Sprintf("%d", bankFunds.Total))
+	fmt.Printf("Total Available Funds = $%s\n\n", printer.Sprintf("%d", bankFunds.Available()))
+}
+
+func printBankFundsAsOf(bankFunds Projections.BankFunds, asOf CheckingAccountService.AsOf) {
+	fmt.Printf("As of %s:\n", asOf)
+	printBankFunds(bankFunds)
+}
+
+func printAccountSummaryAsOf(summary CheckingAccountService.AccountSummary, asOf CheckingAccountService.AsOf) {
+	fmt.Printf("As of %s:\n", asOf)
+	fmt.Printf("Account %s (%s) was %s\n", summary.ID, summary.Name, summary.Status)
+	fmt.Printf("Ledger Balance = $%s\n", printer.Sprintf("%d", summary.LedgerBalance))
+	fmt.Printf("Available Balance = $%s\n\n", printer.Sprintf("%d", summary.AvailableBalance))
+}
+
+func printStatement(statement Projections.Statement) {
+	fmt.Printf("Statement for account %s (page %d of %d, %d transactions)\n", statement.AccountID, statement.Page, statement.Pages, statement.Transactions)
+	fmt.Printf("Opening Balance = $%s\n", printer.Sprintf("%d", statement.OpeningBalance))
+	for _, line := range statement.Lines {
+		date := time.Unix(0, line.Date).UTC().Format("2006-01-02")
+		fmt.Printf("%s  %-32s %12s %12s %12s\n", date, line.Description, amountOrBlank(line.Debit), amountOrBlank(line.Credit), printer.Sprintf("%d", line.Balance))
+	}
+	fmt.Printf("Closing Balance = $%s\n\n", printer.Sprintf("%d", statement.ClosingBalance))
+}
+
+// amountOrBlank: statement columns are left blank rather than showing 0
+func amountOrBlank(amount int) string {
+	if amount == 0 {
+		return ""
+	}
+	return printer.Sprintf("%d", amount)
+}
+
+func printAccountCounts(accountCounts Projections.AccountCounts) {
+	fmt.Printf("Open Accounts = %d\n", accountCounts.Open)
+	fmt.Printf("Closed Accounts = %d\n", accountCounts.Closed)
+	fmt.Printf("Dormant Accounts = %d\n\n", accountCounts.Dormant)
+}
+
+func printHighestBalances(topTen []Projections.AccountBalance) {
+	fmt.Println("Top Ten Balances:")
+	for i, account := range topTen {
+		fmt.Printf("%d. %s, available %s (%s: %s)\n", i+1, printer.Sprintf("%d", account.Balance), printer.Sprintf("%d", account.Available()), account.Name, account.ID)
+	}
+	fmt.Println()
+}
+
+func printBalancePerMonth(balancePerMonth []Projections.MonthlyBalance) {
+	fmt.Println("Total Banks Funds Per Month:")
+	for _, month := range balancePerMonth {
+		fmt.Printf("%s: opening %s, in %s, out %s, closing %s\n",
+			month.YearMonth,
+			printer.Sprintf("%d", month.Opening),
+			printer.Sprintf("%d", month.Inflows),
+			printer.Sprintf("%d", month.Outflows),
+			printer.Sprintf("%d", month.Closing),
+		)
+	}
+	fmt.Println()
+}
+
+func printFeeIncome(feeIncome []Projections.MonthlyFeeIncome) {
+	fmt.Println("Fee Income Per Month:")
+	for _, month := range feeIncome {
+		var feeTypes []string
+		for feeType := range month.ByFeeType {
+			feeTypes = append(feeTypes, feeType)
+		}
+		sort.Strings(feeTypes)
+
+		fmt.Printf("%s: %s\n", month.YearMonth, printer.Sprintf("%d", month.Total))
+		for _, feeType := range feeTypes {
+			fmt.Printf("  %s: %s\n", feeType, printer.Sprintf("%d", month.ByFeeType[feeType]))
+		}
+	}
+	fmt.Println()
+}
+
+func printFrozenAccounts(frozenAccounts []Projections.FrozenAccount) {
+	fmt.Printf("Frozen Accounts = %d\n", len(frozenAccounts))
+	for _, account := range frozenAccounts {
+		since := time.Unix(0, account.FrozenSince).Format("2006-01-02")
+		fmt.Printf("%s: %s (%s) frozen since %s, scope %s\n", account.ID, account.Name, account.ReasonCode, since, account.Scope)
+	}
+	fmt.Println()
+}
+
+func printCustomerAccounts(summary Projections.CustomerAccountsSummary) {
+	var counts []int
+	for count := range summary.OpenAccountCounts {
+		counts = append(counts, count)
+	}
+	sort.Ints(counts)
+
+	fmt.Printf("Customers = %d\n", summary.Customers)
+	for _, count := range counts {
+		fmt.Printf("  holding %d open accounts = %d\n", count, summary.OpenAccountCounts[count])
+	}
+	fmt.Println("Customers With The Most Accounts:")
+	for i, customer := range summary.MostAccounts {
+		fmt.Printf("%d. %s (%s):", i+1, customer.Name, customer.CustomerID)
+		for _, account := range customer.Accounts {
+			status := "open"
+			if account.Closed {
+				status = "closed"
+			}
+			fmt.Printf(" %s %s %s [%s];", account.Kind, account.ID, account.Role, status)
+		}
+		fmt.Println()
+	}
+	fmt.Println()
+}
+
+func printAccountDirectory(directory *Projections.AccountDirectory) {
+	fmt.Printf("Account Directory = %d accounts (search it with -find-name, -find-status or -find-balance)\n", directory.Len())
+}
+
+func printAccountQuery(accounts []Projections.AccountRecord, position uint, limit int) {
+	fmt.Printf("%d accounts found (as of event %d)\n", len(accounts), position)
+	for i, account := range accounts {
+		if i == limit {
+			fmt.Printf("... and %d more\n", len(accounts)-limit)
+			break
+		}
+		fmt.Printf("%s  %-8s %-8s %12s  %s\n", account.ID, account.Kind, account.Status, printer.Sprintf("%d", account.Balance), account.Name)
+	}
+}